@@ -0,0 +1,423 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/diagrams"
+	"github.com/anz-bank/sysl/pkg/integrationdiagram"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/pkg/errors"
+)
+
+// The C4 views drawn by sysl c4.
+const (
+	C4Context   = "context"
+	C4Container = "container"
+	C4Component = "component"
+)
+
+// StructurizrExt is the extension of Structurizr DSL output. C4 diagrams
+// written to files with this extension are generated as a Structurizr
+// workspace rather than C4-PlantUML.
+const StructurizrExt = ".dsl"
+
+// StructurizrHeader is the autogenerated code warning, which Structurizr
+// reads as comments as Graphviz does.
+const StructurizrHeader = integrationdiagram.DotHeader
+
+type c4Kind int
+
+const (
+	c4Person c4Kind = iota
+	c4System
+	c4Container
+	c4Component
+)
+
+// c4Element is a person, software system, container or component. Elements
+// are keyed by their app names. Systems and containers implied by the
+// namespaces of apps, but not defined themselves, have no app.
+type c4Element struct {
+	name        string
+	label       string
+	kind        c4Kind
+	parent      string
+	db          bool
+	external    bool
+	description string
+	technology  string
+}
+
+type c4Relation struct {
+	from, to  string
+	endpoints syslutil.StrSet
+}
+
+// c4Model maps the apps of a module onto the C4 abstractions:
+//
+//   - Apps with the ~human pattern are people.
+//   - Apps without a namespace, or with the ~system pattern, are systems.
+//   - Apps directly in the namespace of a system, or with the ~container or
+//     ~db pattern, are containers of that system.
+//   - Apps in the namespace of a container are its components.
+//
+// Relations are the calls between apps, labelled with the endpoints called.
+type c4Model struct {
+	elements  map[string]*c4Element
+	relations []*c4Relation
+}
+
+func buildC4Model(m *sysl.Module) *c4Model {
+	c := &c4Model{elements: map[string]*c4Element{}}
+	hasPattern := func(parts []string, patterns ...string) bool {
+		attrs := diagrams.GetApplicationAttrs(m, strings.Join(parts, " :: "))
+		set := syslutil.MakeStrSetFromAttr("patterns", attrs)
+		for _, p := range patterns {
+			if set.Contains(p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	appNames := make([]string, 0, len(m.GetApps()))
+	for appName := range m.GetApps() {
+		appNames = append(appNames, appName)
+	}
+	sort.Strings(appNames)
+	for _, appName := range appNames {
+		parts := m.GetApps()[appName].GetName().GetPart()
+		if hasPattern(parts, "human") {
+			c.add(appName, c4Person, "", m)
+			continue
+		}
+		sysLen := 1
+		for k := len(parts); k > 1; k-- {
+			if hasPattern(parts[:k], "system") {
+				sysLen = k
+				break
+			}
+		}
+		system := strings.Join(parts[:sysLen], " :: ")
+		if len(parts) == sysLen {
+			c.add(appName, c4System, "", m)
+			continue
+		}
+		conLen := sysLen + 1
+		for k := len(parts); k > conLen; k-- {
+			if hasPattern(parts[:k], "container", "db") {
+				conLen = k
+				break
+			}
+		}
+		container := strings.Join(parts[:conLen], " :: ")
+		c.add(system, c4System, "", m)
+		if len(parts) == conLen {
+			c.add(appName, c4Container, system, m)
+			continue
+		}
+		c.add(container, c4Container, system, m)
+		c.add(appName, c4Component, container, m)
+	}
+
+	index := map[integrationdiagram.AppPair]*c4Relation{}
+	for _, dep := range integrationdiagram.Dependencies(m, syslutil.MakeStrSet()) {
+		pair := integrationdiagram.AppPair{Self: dep.Self.Name, Target: dep.Target.Name}
+		if pair.Self == pair.Target {
+			continue
+		}
+		if _, has := index[pair]; !has {
+			index[pair] = &c4Relation{from: pair.Self, to: pair.Target, endpoints: syslutil.MakeStrSet()}
+			c.relations = append(c.relations, index[pair])
+		}
+		index[pair].endpoints.Insert(dep.Target.Endpoint)
+	}
+	return c
+}
+
+// add adds the element for an app, or the system or container implied by a
+// namespace, if it is not already in the model.
+func (c *c4Model) add(name string, kind c4Kind, parent string, m *sysl.Module) {
+	if _, has := c.elements[name]; has {
+		return
+	}
+	label := name
+	if i := strings.LastIndex(name, " :: "); i >= 0 {
+		label = name[i+len(" :: "):]
+	}
+	attrs := diagrams.GetApplicationAttrs(m, name)
+	patterns := syslutil.MakeStrSetFromAttr("patterns", attrs)
+	c.elements[name] = &c4Element{
+		name:        name,
+		label:       label,
+		kind:        kind,
+		parent:      parent,
+		db:          patterns.Contains("db"),
+		external:    patterns.Contains("external"),
+		description: attrs["description"].GetS(),
+		technology:  attrs["technology"].GetS(),
+	}
+}
+
+// contains reports whether the element named outer contains the one named
+// inner, directly or not.
+func (c *c4Model) contains(outer, inner string) bool {
+	for e := c.elements[c.elements[inner].parent]; e != nil; e = c.elements[e.parent] {
+		if e.name == outer {
+			return true
+		}
+	}
+	return false
+}
+
+// c4View is what is drawn of the model by a view: the elements inside the
+// boundary of the focus, the elements outside it and the relations between
+// them.
+type c4View struct {
+	kind    string
+	focus   *c4Element
+	inside  []*c4Element
+	outside []*c4Element
+	rels    []*c4Relation
+}
+
+// view returns a view of the model. The context view focuses on a system, or
+// on the whole landscape if focus is empty, the container view on a system
+// and the component view on a container.
+func (c *c4Model) view(kind, focus string) (*c4View, error) {
+	v := &c4View{kind: kind}
+	want := map[string]c4Kind{C4Context: c4System, C4Container: c4System, C4Component: c4Container}
+	wantKind, ok := want[kind]
+	switch {
+	case !ok:
+		return nil, errors.Errorf("view must be %s, %s or %s, not %q", C4Context, C4Container, C4Component, kind)
+	case focus == "" && kind != C4Context:
+		return nil, errors.Errorf("the %s view needs the %s to draw", kind, c4KindNames[wantKind])
+	case focus != "":
+		v.focus = c.elements[focus]
+		if v.focus == nil || v.focus.kind != wantKind {
+			return nil, errors.Errorf("%q is not a %s", focus, c4KindNames[wantKind])
+		}
+	}
+
+	// chain holds the focus and its ancestors, whose contents are drawn in
+	// place of them.
+	chain := map[string]bool{"": true}
+	if v.focus != nil && kind != C4Context {
+		for e := v.focus; e != nil; e = c.elements[e.parent] {
+			chain[e.name] = true
+		}
+	}
+	// inFocus reports whether an element is drawn in the focus of the view,
+	// which only shows relations with the focus.
+	inFocus := func(name string) bool {
+		if kind == C4Context {
+			return name == focus
+		}
+		return c.elements[name].parent == focus
+	}
+	visible := func(name string) string {
+		for e := c.elements[name]; e != nil; e = c.elements[e.parent] {
+			if !chain[e.name] && chain[e.parent] {
+				return e.name
+			}
+		}
+		return ""
+	}
+
+	drawn := syslutil.MakeStrSet()
+	index := map[integrationdiagram.AppPair]*c4Relation{}
+	for _, r := range c.relations {
+		pair := integrationdiagram.AppPair{Self: visible(r.from), Target: visible(r.to)}
+		if pair.Self == "" || pair.Target == "" || pair.Self == pair.Target {
+			continue
+		}
+		if v.focus != nil && !inFocus(pair.Self) && !inFocus(pair.Target) {
+			continue
+		}
+		if _, has := index[pair]; !has {
+			index[pair] = &c4Relation{from: pair.Self, to: pair.Target, endpoints: syslutil.MakeStrSet()}
+			v.rels = append(v.rels, index[pair])
+		}
+		for _, ep := range r.endpoints.ToSlice() {
+			index[pair].endpoints.Insert(ep)
+		}
+		drawn.Insert(pair.Self)
+		drawn.Insert(pair.Target)
+	}
+	for name, e := range c.elements {
+		if v.focus == nil && e.parent == "" || v.focus != nil && inFocus(name) {
+			drawn.Insert(name)
+		}
+	}
+
+	for _, name := range drawn.ToSortedSlice() {
+		e := c.elements[name]
+		if v.focus != nil && kind != C4Context && inFocus(name) {
+			v.inside = append(v.inside, e)
+		} else {
+			v.outside = append(v.outside, e)
+		}
+	}
+	return v, nil
+}
+
+//nolint:gochecknoglobals
+var (
+	c4IDRE     = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+	c4Escaper  = strings.NewReplacer(`"`, `\"`, "\n", `\n`)
+	c4Includes = map[string]string{
+		C4Context: "C4_Context", C4Container: "C4_Container", C4Component: "C4_Component",
+	}
+	c4KindNames = map[c4Kind]string{
+		c4Person: "person", c4System: "system", c4Container: "container", c4Component: "component",
+	}
+	c4Macros = map[c4Kind]string{
+		c4Person: "Person", c4System: "System", c4Container: "Container", c4Component: "Component",
+	}
+	c4DSLKinds = map[c4Kind]string{
+		c4Person: "person", c4System: "softwareSystem", c4Container: "container", c4Component: "component",
+	}
+	c4DSLViews = map[string]string{
+		C4Context: "systemContext", C4Container: "container", C4Component: "component",
+	}
+	c4Boundaries = map[string]string{C4Container: "System_Boundary", C4Component: "Container_Boundary"}
+)
+
+// c4ID returns an identifier for an element that C4-PlantUML and Structurizr
+// both accept.
+func c4ID(name string) string {
+	return strings.Trim(c4IDRE.ReplaceAllString(name, "_"), "_")
+}
+
+func c4Quote(s string) string {
+	return `"` + c4Escaper.Replace(s) + `"`
+}
+
+func (r *c4Relation) label() string {
+	return strings.Join(r.endpoints.ToSortedSlice(), ", ")
+}
+
+// GenerateC4 returns a view of the C4 model of a module as C4-PlantUML, or as
+// a Structurizr workspace if output ends with StructurizrExt.
+func GenerateC4(m *sysl.Module, kind, focus, title, output string) (string, error) {
+	c := buildC4Model(m)
+	v, err := c.view(kind, focus)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(output, StructurizrExt) {
+		return c.structurizr(v, title), nil
+	}
+	return v.plantuml(title), nil
+}
+
+func (v *c4View) plantuml(title string) string {
+	var sb strings.Builder
+	sb.WriteString(diagrams.PumlHeader)
+	sb.WriteString("@startuml\n")
+	fmt.Fprintf(&sb, "!include <C4/%s>\n", c4Includes[v.kind])
+	if title != "" {
+		fmt.Fprintf(&sb, "title %s\n", title)
+	}
+	for _, e := range v.outside {
+		writeC4Macro(&sb, "", e)
+	}
+	if v.focus != nil && len(v.inside) > 0 {
+		fmt.Fprintf(&sb, "%s(%s, %s) {\n", c4Boundaries[v.kind], c4ID(v.focus.name), c4Quote(v.focus.label))
+		for _, e := range v.inside {
+			writeC4Macro(&sb, "  ", e)
+		}
+		sb.WriteString("}\n")
+	}
+	for _, r := range v.rels {
+		fmt.Fprintf(&sb, "Rel(%s, %s, %s)\n", c4ID(r.from), c4ID(r.to), c4Quote(r.label()))
+	}
+	sb.WriteString("@enduml\n")
+	return sb.String()
+}
+
+func writeC4Macro(sb *strings.Builder, indent string, e *c4Element) {
+	macro := c4Macros[e.kind]
+	if e.db && e.kind != c4Person {
+		macro += "Db"
+	}
+	if e.external {
+		macro += "_Ext"
+	}
+	args := []string{c4ID(e.name), c4Quote(e.label)}
+	if e.kind == c4Container || e.kind == c4Component {
+		args = append(args, c4Quote(e.technology))
+	}
+	args = append(args, c4Quote(e.description))
+	fmt.Fprintf(sb, "%s%s(%s)\n", indent, macro, strings.Join(args, ", "))
+}
+
+// structurizr returns the whole model as a Structurizr workspace with the
+// view. Structurizr infers the relations between the parents of the apps, so
+// the calls are written between the apps themselves.
+func (c *c4Model) structurizr(v *c4View, title string) string {
+	children := map[string][]string{}
+	for name, e := range c.elements {
+		children[e.parent] = append(children[e.parent], name)
+	}
+	for _, names := range children {
+		sort.Strings(names)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(StructurizrHeader)
+	if title != "" {
+		fmt.Fprintf(&sb, "workspace %s {\n", c4Quote(title))
+	} else {
+		sb.WriteString("workspace {\n")
+	}
+	sb.WriteString("  model {\n")
+	var writeElements func(parent, indent string)
+	writeElements = func(parent, indent string) {
+		for _, name := range children[parent] {
+			e := c.elements[name]
+			args := []string{c4Quote(e.label), c4Quote(e.description)}
+			if e.kind == c4Container || e.kind == c4Component {
+				args = append(args, c4Quote(e.technology))
+			}
+			tags := []string{}
+			if e.db {
+				tags = append(tags, "Database")
+			}
+			if e.external {
+				tags = append(tags, "External")
+			}
+			if len(tags) > 0 {
+				args = append(args, c4Quote(strings.Join(tags, ",")))
+			}
+			fmt.Fprintf(&sb, "%s%s = %s %s", indent, c4ID(name), c4DSLKinds[e.kind], strings.Join(args, " "))
+			if len(children[name]) > 0 {
+				sb.WriteString(" {\n")
+				writeElements(name, indent+"  ")
+				fmt.Fprintf(&sb, "%s}", indent)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	writeElements("", "    ")
+	for _, r := range c.relations {
+		// Structurizr rejects relations between an element and its parent.
+		if c.contains(r.from, r.to) || c.contains(r.to, r.from) {
+			continue
+		}
+		fmt.Fprintf(&sb, "    %s -> %s %s\n", c4ID(r.from), c4ID(r.to), c4Quote(r.label()))
+	}
+	sb.WriteString("  }\n  views {\n")
+	if v.focus == nil {
+		sb.WriteString("    systemLandscape {\n")
+	} else {
+		fmt.Fprintf(&sb, "    %s %s {\n", c4DSLViews[v.kind], c4ID(v.focus.name))
+	}
+	sb.WriteString("      include *\n      autoLayout\n    }\n  }\n}\n")
+	return sb.String()
+}
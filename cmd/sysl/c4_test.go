@@ -0,0 +1,141 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/diagrams"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadC4Module(t *testing.T) *sysl.Module {
+	logger, _ := test.NewNullLogger()
+	mod, _, err := LoadSyslModule(testDir, "c4.sysl", afero.NewOsFs(), logger)
+	require.NoError(t, err)
+	return mod
+}
+
+func TestBuildC4Model(t *testing.T) {
+	t.Parallel()
+
+	c := buildC4Model(loadC4Module(t))
+	kinds := map[string]c4Kind{}
+	for name, e := range c.elements {
+		kinds[name] = e.kind
+	}
+	assert.Equal(t, map[string]c4Kind{
+		"Customer":                c4Person,
+		"Bank":                    c4System,
+		"Payments":                c4System,
+		"Bank :: WebApp":          c4Container,
+		"Bank :: Api":             c4Container,
+		"Bank :: Ledger":          c4Container,
+		"Bank :: Api :: Accounts": c4Component,
+	}, kinds)
+	assert.True(t, c.elements["Bank :: Ledger"].db)
+	assert.True(t, c.elements["Payments"].external)
+	assert.Equal(t, "React", c.elements["Bank :: WebApp"].technology)
+	assert.True(t, c.contains("Bank", "Bank :: Api :: Accounts"))
+	assert.False(t, c.contains("Bank :: Api :: Accounts", "Bank"))
+}
+
+func TestGenerateC4Context(t *testing.T) {
+	t.Parallel()
+
+	out, err := GenerateC4(loadC4Module(t), C4Context, "", "Landscape", "c4.puml")
+	require.NoError(t, err)
+	assert.Equal(t, diagrams.PumlHeader+`@startuml
+!include <C4/C4_Context>
+title Landscape
+System(Bank, "Bank", "Retail banking")
+Person(Customer, "Customer", "A customer of the bank")
+System_Ext(Payments, "Payments", "Card payment provider")
+Rel(Bank, Payments, "Pay")
+Rel(Customer, Bank, "Home")
+@enduml
+`, out)
+}
+
+func TestGenerateC4Container(t *testing.T) {
+	t.Parallel()
+
+	out, err := GenerateC4(loadC4Module(t), C4Container, "Bank", "", "c4.puml")
+	require.NoError(t, err)
+	assert.Equal(t, diagrams.PumlHeader+`@startuml
+!include <C4/C4_Container>
+Person(Customer, "Customer", "A customer of the bank")
+System_Ext(Payments, "Payments", "Card payment provider")
+System_Boundary(Bank, "Bank") {
+  Container(Bank_Api, "Api", "Go", "")
+  ContainerDb(Bank_Ledger, "Ledger", "", "")
+  Container(Bank_WebApp, "WebApp", "React", "")
+}
+Rel(Bank_Api, Payments, "Pay")
+Rel(Bank_Api, Bank_Ledger, "Query")
+Rel(Bank_WebApp, Bank_Api, "GetAccounts")
+Rel(Customer, Bank_WebApp, "Home")
+@enduml
+`, out)
+}
+
+func TestGenerateC4Component(t *testing.T) {
+	t.Parallel()
+
+	out, err := GenerateC4(loadC4Module(t), C4Component, "Bank :: Api", "", "c4.puml")
+	require.NoError(t, err)
+	assert.Equal(t, diagrams.PumlHeader+`@startuml
+!include <C4/C4_Component>
+ContainerDb(Bank_Ledger, "Ledger", "", "")
+Container_Boundary(Bank_Api, "Api") {
+  Component(Bank_Api_Accounts, "Accounts", "", "")
+}
+Rel(Bank_Api_Accounts, Bank_Ledger, "Query")
+@enduml
+`, out)
+}
+
+func TestGenerateC4Structurizr(t *testing.T) {
+	t.Parallel()
+
+	out, err := GenerateC4(loadC4Module(t), C4Container, "Bank", "Bank", "c4.dsl")
+	require.NoError(t, err)
+	assert.Equal(t, StructurizrHeader+`workspace "Bank" {
+  model {
+    Bank = softwareSystem "Bank" "Retail banking" {
+      Bank_Api = container "Api" "" "Go" {
+        Bank_Api_Accounts = component "Accounts" "" ""
+      }
+      Bank_Ledger = container "Ledger" "" "" "Database"
+      Bank_WebApp = container "WebApp" "" "React"
+    }
+    Customer = person "Customer" "A customer of the bank"
+    Payments = softwareSystem "Payments" "Card payment provider" "External"
+    Bank_Api -> Payments "Pay"
+    Bank_Api_Accounts -> Bank_Ledger "Query"
+    Bank_WebApp -> Bank_Api "GetAccounts"
+    Customer -> Bank_WebApp "Home"
+  }
+  views {
+    container Bank {
+      include *
+      autoLayout
+    }
+  }
+}
+`, out)
+}
+
+func TestGenerateC4BadFocus(t *testing.T) {
+	t.Parallel()
+
+	mod := loadC4Module(t)
+	_, err := GenerateC4(mod, C4Container, "", "", "c4.puml")
+	assert.EqualError(t, err, "the container view needs the system to draw")
+	_, err = GenerateC4(mod, C4Component, "Bank", "", "c4.puml")
+	assert.EqualError(t, err, `"Bank" is not a container`)
+	_, err = GenerateC4(mod, "landscape", "", "", "c4.puml")
+	assert.Error(t, err)
+}
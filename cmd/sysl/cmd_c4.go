@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type c4Cmd struct {
+	plantumlmixin
+	view      string
+	system    string
+	container string
+	title     string
+	output    string
+}
+
+func (p *c4Cmd) Name() string       { return "c4" }
+func (p *c4Cmd) MaxSyslModule() int { return 1 }
+
+func (p *c4Cmd) Configure(app *kingpin.Application) *kingpin.CmdClause {
+	cmd := app.Command(p.Name(), "Generate C4 system context, container and component diagrams")
+	cmd.Flag("view",
+		"C4 view to draw: "+C4Context+", "+C4Container+" or "+C4Component+" (default: "+C4Context+")",
+	).Default(C4Context).EnumVar(&p.view, C4Context, C4Container, C4Component)
+	cmd.Flag("system", "system to draw the context or containers of").StringVar(&p.system)
+	cmd.Flag("container", "container to draw the components of").StringVar(&p.container)
+	cmd.Flag("title", "diagram title").Short('t').StringVar(&p.title)
+
+	p.AddFlag(cmd)
+
+	cmd.Flag("output",
+		"output file, .png, .svg or .uml for C4-PlantUML or .dsl for Structurizr (default: c4.png)",
+	).Default("c4.png").Short('o').StringVar(&p.output)
+
+	EnsureFlagsNonEmpty(cmd)
+	return cmd
+}
+
+func (p *c4Cmd) Execute(args ExecuteArgs) error {
+	focus := p.system
+	if p.view == C4Component {
+		focus = p.container
+	}
+	out, err := GenerateC4(args.Modules[0], p.view, focus, p.title, p.output)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(p.output, StructurizrExt) {
+		return errors.Wrapf(afero.WriteFile(args.Filesystem, p.output, []byte(out), os.ModePerm), "writing %q", p.output)
+	}
+	return p.GenerateFromMap(map[string]string{p.output: out}, args.Filesystem)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// CacheDir holds the caches of a project, relative to the working directory.
+const CacheDir = ".sysl/cache"
+
+// parseCacheDir returns where parsed files are cached. Like the Go build
+// cache it is shared by every project of the user: caching in the project
+// would create the root marker, and with it change the root of the next run.
+func parseCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sysl", "modules"), nil
+}
+
+// parseCache returns the cache of parsed files, or nil if the user has no
+// cache directory.
+func parseCache() *parse.Cache {
+	dir, err := parseCacheDir()
+	if err != nil {
+		return nil
+	}
+	return &parse.Cache{Dir: dir, Version: cacheVersion(), Fs: afero.NewOsFs()}
+}
+
+type cacheCleanCmd struct {
+	renderCache string
+}
+
+func (p *cacheCleanCmd) Name() string       { return "cache clean" }
+func (p *cacheCleanCmd) MaxSyslModule() int { return 0 }
+
+func (p *cacheCleanCmd) Configure(app *kingpin.Application) *kingpin.CmdClause {
+	cmd := app.Command("cache", "Manage the caches of parsed files and rendered diagrams")
+	clean := cmd.Command("clean", "Remove the parsed files and the rendered diagrams")
+	clean.Flag("render-cache", "directory of previously rendered png and svg diagrams").
+		Default(RenderCacheDefault).StringVar(&p.renderCache)
+	return clean
+}
+
+func (p *cacheCleanCmd) Execute(args ExecuteArgs) error {
+	if dir, err := parseCacheDir(); err == nil {
+		args.Logger.Infof("Removing %s", dir)
+		if err := os.RemoveAll(dir); err != nil {
+			return errors.Wrapf(err, "removing %q", dir)
+		}
+	}
+	args.Logger.Infof("Removing %s", p.renderCache)
+	return errors.Wrapf(args.Filesystem.RemoveAll(p.renderCache), "removing %q", p.renderCache)
+}
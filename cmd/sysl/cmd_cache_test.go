@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withCacheHome points the user's cache directory at a new directory until the
+// returned func is called. Tests using it cannot run in parallel.
+func withCacheHome(t *testing.T) func() {
+	home, err := ioutil.TempDir("", "cache")
+	require.NoError(t, err)
+	env := map[string]string{}
+	for _, key := range []string{"HOME", "XDG_CACHE_HOME"} {
+		env[key] = os.Getenv(key)
+		require.NoError(t, os.Setenv(key, home))
+	}
+	return func() {
+		for key, value := range env {
+			_ = os.Setenv(key, value)
+		}
+		_ = os.RemoveAll(home)
+	}
+}
+
+func TestMain2WithParseCache(t *testing.T) {
+	defer withCacheHome(t)()
+	dir, err := parseCacheDir()
+	require.NoError(t, err)
+	out, err := ioutil.TempDir("", "out")
+	require.NoError(t, err)
+	defer os.RemoveAll(out)
+
+	logger, _ := test.NewNullLogger()
+	fs := afero.NewOsFs()
+	args := []string{"sysl", "pb", "-o", filepath.Join(out, "out.pb"), filepath.Join(testDir, "call.sysl")}
+	assert.Equal(t, 0, main2(args, fs, logger, main3))
+	_, err = os.Stat(filepath.Join(out, "out.pb"))
+	require.NoError(t, err)
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	require.NoError(t, os.RemoveAll(dir))
+	args = append([]string{"sysl", "--no-cache"}, args[1:]...)
+	assert.Equal(t, 0, main2(args, fs, logger, main3))
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+
+	memFs, fs := syslutil.WriteToMemOverlayFs("/")
+	args = []string{"sysl", "pb", "-o", "/out.pb", filepath.Join(testDir, "call.sysl")}
+	assert.Equal(t, 0, main2(args, fs, logger, main3))
+	syslutil.AssertFsHasExactly(t, memFs, "/out.pb")
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMain2WithCacheClean(t *testing.T) {
+	defer withCacheHome(t)()
+	dir, err := parseCacheDir()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(dir, os.ModePerm))
+
+	logger, _ := test.NewNullLogger()
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(RenderCacheDefault, "a.svg"), []byte("<svg/>"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/diagrams/b.svg", []byte("<svg/>"), 0644))
+	assert.Equal(t, 0, main2([]string{"sysl", "cache", "clean"}, fs, logger, main3))
+
+	exists, err := afero.Exists(fs, RenderCacheDefault)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Equal(t, 0, main2([]string{"sysl", "cache", "clean", "--render-cache", "/diagrams"}, fs, logger, main3))
+	exists, err = afero.Exists(fs, "/diagrams")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
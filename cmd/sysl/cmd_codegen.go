@@ -2,19 +2,28 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/anz-bank/sysl/pkg/codegen"
 	"github.com/anz-bank/sysl/pkg/eval"
 	"github.com/anz-bank/sysl/pkg/syslutil"
 	"github.com/anz-bank/sysl/pkg/validate"
+	"github.com/pkg/errors"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 type codegenCmd struct {
-	CmdContextParamCodegen
+	codegen.Params
 	outDir         string
 	appName        string
 	validateOnly   bool
 	enableDebugger bool
+	dapAddress     string
+	now            string
 }
 
 func (p *codegenCmd) Name() string       { return "codegen" }
@@ -24,21 +33,27 @@ func (p *codegenCmd) Configure(app *kingpin.Application) *kingpin.CmdClause {
 	cmd := app.Command(p.Name(), "Generate code").Alias("gen")
 	cmd.Flag("root-transform",
 		"sysl root directory for input transform file (default: .)").
-		Default(".").StringVar(&p.rootTransform)
-	cmd.Flag("transform", "path to transform file from the root transform directory").Required().StringVar(&p.transform)
-	cmd.Flag("grammar", "path to grammar file").Required().StringVar(&p.grammar)
+		Default(".").StringVar(&p.RootTransform)
+	cmd.Flag("transform", "path to transform file from the root transform directory").Required().StringVar(&p.Transform)
+	cmd.Flag("grammar", "path to grammar file").Required().StringVar(&p.Grammar)
 	cmd.Flag("app-name",
 		"name of the sysl app defined in sysl model."+
 			" if there are multiple apps defined in sysl model,"+
 			" code will be generated only for the given app").Default("").StringVar(&p.appName)
-	cmd.Flag("start", "start rule for the grammar").Default(".").StringVar(&p.start)
+	cmd.Flag("start", "start rule for the grammar").Default(".").StringVar(&p.Start)
 	cmd.Flag("outdir", "output directory").Default(".").StringVar(&p.outDir)
-	cmd.Flag("dep-path", "path passed to sysl transform").Default("").StringVar(&p.depPath)
-	cmd.Flag("basepath", "base path for ReST output").Default("").StringVar(&p.basePath)
+	cmd.Flag("dep-path", "path passed to sysl transform").Default("").StringVar(&p.DepPath)
+	cmd.Flag("basepath", "base path for ReST output").Default("").StringVar(&p.BasePath)
 	cmd.Flag("validate-only", "Only Perform validation on the transform grammar").BoolVar(&p.validateOnly)
 	cmd.Flag("disable-validator", "Disable validation on the transform grammar").
-		Default("false").BoolVar(&p.disableValidator)
+		Default("false").BoolVar(&p.DisableValidator)
 	cmd.Flag("debugger", "Enable the evaluation debugger on error").Default("false").BoolVar(&p.enableDebugger)
+	cmd.Flag("dap",
+		"serve the evaluation debugger over the Debug Adapter Protocol,"+
+			" on stdio or a TCP address (e.g. localhost:4711)").StringVar(&p.dapAddress)
+	cmd.Flag("now",
+		"time returned by Now() in transforms, in RFC 3339 format (e.g. 2020-02-29T13:45:00Z),"+
+			" for reproducible output").StringVar(&p.now)
 	EnsureFlagsNonEmpty(cmd, "app-name", "basepath", "dep-path")
 	return cmd
 }
@@ -46,12 +61,12 @@ func (p *codegenCmd) Configure(app *kingpin.Application) *kingpin.CmdClause {
 func (p *codegenCmd) Execute(args ExecuteArgs) error {
 	if p.validateOnly {
 		return validate.DoValidate(validate.Params{
-			RootTransform: p.rootTransform,
-			Transform:     p.transform,
-			Grammar:       p.grammar,
-			Start:         p.start,
-			DepPath:       p.depPath,
-			BasePath:      p.basePath,
+			RootTransform: p.RootTransform,
+			Transform:     p.Transform,
+			Grammar:       p.Grammar,
+			Start:         p.Start,
+			DepPath:       p.DepPath,
+			BasePath:      p.BasePath,
 			Filesystem:    args.Filesystem,
 			Logger:        args.Logger,
 		})
@@ -64,9 +79,90 @@ func (p *codegenCmd) Execute(args ExecuteArgs) error {
 		p.appName = args.DefaultAppName
 	}
 	eval.EnableDebugger = p.enableDebugger
-	output, err := GenerateCode(&p.CmdContextParamCodegen, args.Modules[0], p.appName, args.Filesystem, args.Logger)
+	if p.now != "" {
+		now, err := time.Parse(time.RFC3339, p.now)
+		if err != nil {
+			return errors.Wrap(err, "invalid --now")
+		}
+		eval.Clock = func() time.Time { return now }
+	}
+	if p.dapAddress != "" {
+		return p.debug(args)
+	}
+	return p.generate(args)
+}
+
+func (p *codegenCmd) generate(args ExecuteArgs) error {
+	output, err := codegen.GenerateCode(&p.Params, args.Modules[0], p.appName, args.Filesystem, args.Logger)
 	if err != nil {
+		switch e := err.(type) {
+		case *eval.Error:
+			args.Logger.Errorf("Evaluation Failed: %s", e.Trace())
+		case eval.TypeErrors:
+			for _, typeErr := range e {
+				args.Logger.Errorf("Type check failed: %s", typeErr.Error())
+			}
+		}
 		return err
 	}
-	return outputToFiles(output, syslutil.NewChrootFs(args.Filesystem, p.outDir))
+	return codegen.OutputToFiles(output, syslutil.NewChrootFs(args.Filesystem, p.outDir))
+}
+
+// debug runs code generation with the evaluation debugger served over the
+// Debug Adapter Protocol. Generation starts once the client has finished
+// configuring breakpoints.
+func (p *codegenCmd) debug(args ExecuteArgs) error {
+	conn, err := openDAPConn(p.dapAddress, args)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	session := eval.NewDAPSession(conn, conn)
+	if session.SourceRoot, err = filepath.Abs(p.RootTransform); err != nil {
+		return err
+	}
+	go func() {
+		if err := session.Serve(); err != nil {
+			args.Logger.Errorf("debug adapter: %s", err.Error())
+		}
+	}()
+	<-session.Configured()
+
+	eval.DebugAdapter = session
+	defer func() { eval.DebugAdapter = nil }()
+
+	exitCode := 0
+	err = p.generate(args)
+	if err != nil {
+		exitCode = 1
+		if outErr := session.Output("stderr", err.Error()+"\n"); outErr != nil {
+			args.Logger.Warnf("debug adapter: %s", outErr.Error())
+		}
+	}
+	if exitErr := session.Exit(exitCode); exitErr != nil {
+		args.Logger.Warnf("debug adapter: %s", exitErr.Error())
+	}
+	return err
+}
+
+type stdioConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (stdioConn) Close() error { return nil }
+
+// openDAPConn returns stdio, or waits for a single client to connect to the TCP address.
+func openDAPConn(address string, args ExecuteArgs) (io.ReadWriteCloser, error) {
+	if address == "stdio" {
+		return stdioConn{os.Stdin, os.Stdout}, nil
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+	args.Logger.Infof("debug adapter listening on %s", listener.Addr())
+	return listener.Accept()
 }
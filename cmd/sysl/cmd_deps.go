@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+
+	"github.com/anz-bank/sysl/pkg/integrationdiagram"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type depsCmd struct {
+	output    string
+	exclude   []string
+	clustered bool
+}
+
+func (p *depsCmd) Name() string       { return "deps" }
+func (p *depsCmd) MaxSyslModule() int { return 1 }
+
+func (p *depsCmd) Configure(app *kingpin.Application) *kingpin.CmdClause {
+	cmd := app.Command(p.Name(), "Export the dependencies between applications as a graph")
+	cmd.Flag("output",
+		"output file, .dot for Graphviz or .json (default: deps.dot)",
+	).Default("deps.dot").Short('o').StringVar(&p.output)
+	cmd.Flag("exclude", "apps to exclude").Short('e').StringsVar(&p.exclude)
+	cmd.Flag("clustered",
+		"group applications into clusters by namespace").Short('c').Default("false").BoolVar(&p.clustered)
+
+	EnsureFlagsNonEmpty(cmd)
+	return cmd
+}
+
+func (p *depsCmd) Execute(args ExecuteArgs) error {
+	out, err := integrationdiagram.GenerateDependencyGraph(args.Modules[0], p.output, p.exclude, p.clustered)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(afero.WriteFile(args.Filesystem, p.output, []byte(out), os.ModePerm), "writing %q", p.output)
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/anz-bank/sysl/pkg/diagrams"
 	"github.com/anz-bank/sysl/pkg/exporter"
 	sysl "github.com/anz-bank/sysl/pkg/sysl"
 	"github.com/sirupsen/logrus"
@@ -88,7 +89,7 @@ func (p *exportCmd) Execute(args ExecuteArgs) error {
 
 	if strings.Contains(p.out, "%(appname)") {
 		for appName, syslApp := range args.Modules[0].GetApps() {
-			outputFileName := MakeFormatParser(p.out).LabelApp(appName, "", syslApp.GetAttrs())
+			outputFileName := diagrams.MakeFormatParser(p.out).LabelApp(appName, "", syslApp.GetAttrs())
 			err := p.writeSwaggerForApp(args.Filesystem, outputFileName, syslApp, args.Logger)
 			if err != nil {
 				return err
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/loader"
+	"github.com/anz-bank/sysl/pkg/parse"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type importsCmd struct {
+	module string
+	format string
+	output io.Writer
+}
+
+func (p *importsCmd) Name() string       { return "imports" }
+func (p *importsCmd) MaxSyslModule() int { return 0 }
+
+func (p *importsCmd) Configure(app *kingpin.Application) *kingpin.CmdClause {
+	cmd := app.Command(p.Name(),
+		"Print how the files of a module import each other, marking import cycles and the unused files, "+
+			"none of whose apps or types other files refer to")
+	opts := []string{"tree", "dot", "json"}
+	cmd.Flag("format", fmt.Sprintf("output format: [%s]", strings.Join(opts, ","))).
+		Short('f').Default(opts[0]).EnumVar(&p.format, opts...)
+	cmd.Arg("MODULE", "input file without .sysl extension and with leading /, eg: "+
+		"/project_dir/my_models combine with --root if needed").
+		Required().StringVar(&p.module)
+	EnsureFlagsNonEmpty(cmd)
+	return cmd
+}
+
+func (p *importsCmd) Execute(args ExecuteArgs) error {
+	graph, _, err := loader.LoadImports(context.Background(), args.Filesystem, p.module, loader.Options{
+		Root:   args.Root,
+		Logger: args.Logger,
+	})
+	if err != nil {
+		return err
+	}
+
+	output := p.output
+	if output == nil {
+		output = os.Stdout
+	}
+	switch p.format {
+	case "dot":
+		_, err = io.WriteString(output, importsDot(graph))
+	case "json":
+		var out []byte
+		out, err = json.MarshalIndent(graph, "", "  ")
+		if err == nil {
+			_, err = fmt.Fprintf(output, "%s\n", out)
+		}
+	default:
+		_, err = io.WriteString(output, importsTree(graph))
+	}
+	return err
+}
+
+// importsTree returns the files imported from the root as a tree. The imports
+// of files already in the tree are not repeated.
+func importsTree(g *parse.ImportGraph) string {
+	var b strings.Builder
+	printed := map[string]bool{}
+	onPath := map[string]bool{}
+
+	var walk func(filename, prefix, childPrefix string)
+	walk = func(filename, prefix, childPrefix string) {
+		file := g.File(filename)
+		b.WriteString(prefix + filename)
+		switch {
+		case file == nil:
+			b.WriteString(" (not found)\n")
+			return
+		case onPath[filename]:
+			b.WriteString(" (cycle)\n")
+			return
+		case file.Unused:
+			b.WriteString(" (unused)")
+		}
+		if printed[filename] && len(file.Imports) > 0 {
+			b.WriteString(" (see above)\n")
+			return
+		}
+		b.WriteString("\n")
+		printed[filename] = true
+
+		onPath[filename] = true
+		for i, imported := range file.Imports {
+			if i == len(file.Imports)-1 {
+				walk(imported, childPrefix+"└── ", childPrefix+"    ")
+			} else {
+				walk(imported, childPrefix+"├── ", childPrefix+"│   ")
+			}
+		}
+		onPath[filename] = false
+	}
+	walk(g.Root, "", "")
+	return b.String()
+}
+
+// importsDot returns the import graph as a Graphviz digraph, with unused files
+// dashed and the imports of cycles red.
+func importsDot(g *parse.ImportGraph) string {
+	inCycle := map[[2]string]bool{}
+	for _, cycle := range g.Cycles {
+		for i := 1; i < len(cycle); i++ {
+			inCycle[[2]string{cycle[i-1], cycle[i]}] = true
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph imports {\n")
+	for _, file := range g.Files {
+		if file.Unused {
+			fmt.Fprintf(&b, "  %q [style=dashed];\n", file.Filename)
+		} else {
+			fmt.Fprintf(&b, "  %q;\n", file.Filename)
+		}
+	}
+	for _, file := range g.Files {
+		for _, imported := range file.Imports {
+			if inCycle[[2]string{file.Filename, imported}] {
+				fmt.Fprintf(&b, "  %q -> %q [color=red];\n", file.Filename, imported)
+			} else {
+				fmt.Fprintf(&b, "  %q -> %q;\n", file.Filename, imported)
+			}
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
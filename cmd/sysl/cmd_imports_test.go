@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func importsTestFs(t *testing.T) afero.Fs {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, syslutil.WriteFiles(fs, "", map[string]string{
+		"/specs/a.sysl": "import b\nimport c\nimport d\n\nA:\n    Ep:\n        B <- Ep\n        D <- Ep\n",
+		"/specs/b.sysl": "import a\nimport d\n\nB:\n    Ep:\n        ...\n",
+		"/specs/c.sysl": "C:\n    ...\n",
+		"/specs/d.sysl": "D:\n    Ep:\n        ...\n",
+	}))
+	return fs
+}
+
+func TestImportsTree(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	var out bytes.Buffer
+	cmd := &importsCmd{module: "a", format: "tree", output: &out}
+	require.NoError(t, cmd.Execute(ExecuteArgs{Root: "/specs", Filesystem: importsTestFs(t), Logger: logger}))
+	assert.Equal(t, `a.sysl
+├── b.sysl
+│   ├── a.sysl (cycle)
+│   └── d.sysl
+├── c.sysl (unused)
+└── d.sysl
+`, out.String())
+}
+
+func TestImportsDot(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	var out bytes.Buffer
+	cmd := &importsCmd{module: "a", format: "dot", output: &out}
+	require.NoError(t, cmd.Execute(ExecuteArgs{Root: "/specs", Filesystem: importsTestFs(t), Logger: logger}))
+	assert.Equal(t, `digraph imports {
+  "a.sysl";
+  "b.sysl";
+  "c.sysl" [style=dashed];
+  "d.sysl";
+  "a.sysl" -> "b.sysl" [color=red];
+  "a.sysl" -> "c.sysl";
+  "a.sysl" -> "d.sysl";
+  "b.sysl" -> "a.sysl" [color=red];
+  "b.sysl" -> "d.sysl";
+}
+`, out.String())
+}
+
+func TestMain2WithImports(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	fs := importsTestFs(t)
+	assert.Equal(t, 0, main2([]string{"sysl", "--root", "/specs", "imports", "--format", "json", "a"}, fs, logger, main3))
+	assert.Equal(t, 1, main2([]string{"sysl", "--root", "/specs", "imports", "missing"}, fs, logger, main3))
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func TestDoGenerateIntegrations(t *testing.T) {
+	t.Parallel()
+
+	argsData := []string{"sysl", "ints", "-o", "%(epname).png", "-j", "Project", "indirect_1.sysl"}
+	sysl := kingpin.New("sysl", "System Modelling Language Toolkit")
+
+	r := cmdRunner{}
+	assert.NoError(t, r.Configure(sysl))
+	selectedCommand, err := sysl.Parse(argsData[1:])
+	assert.Nil(t, err, "Cmd line parse failed for sysl ints")
+	assert.Equal(t, selectedCommand, "integrations")
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type lineageCmd struct {
+	plantumlmixin
+	typeName string
+	title    string
+	output   string
+}
+
+func (p *lineageCmd) Name() string       { return "lineage" }
+func (p *lineageCmd) MaxSyslModule() int { return 1 }
+
+func (p *lineageCmd) Configure(app *kingpin.Application) *kingpin.CmdClause {
+	cmd := app.Command(p.Name(), "Trace where a type flows through the endpoints of the model")
+	cmd.Flag("type", "type to trace, as App.Type").Required().StringVar(&p.typeName)
+	cmd.Flag("title", "diagram title (default: Lineage of <type>)").Short('t').StringVar(&p.title)
+
+	p.AddFlag(cmd)
+
+	cmd.Flag("output",
+		"output file, .png, .svg, .uml or .mmd for a data-flow diagram or .json for a lineage report "+
+			"(default: lineage.png)",
+	).Default("lineage.png").Short('o').StringVar(&p.output)
+
+	EnsureFlagsNonEmpty(cmd)
+	return cmd
+}
+
+func (p *lineageCmd) Execute(args ExecuteArgs) error {
+	out, err := GenerateLineage(args.Modules[0], p.typeName, p.title, p.output)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(p.output, ".json") {
+		return errors.Wrapf(afero.WriteFile(args.Filesystem, p.output, []byte(out), os.ModePerm), "writing %q", p.output)
+	}
+	return p.GenerateFromMap(map[string]string{p.output: out}, args.Filesystem)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/anz-bank/sysl/pkg/mod"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// modCommand returns the mod command the mod subcommands are added to.
+func modCommand(app *kingpin.Application) *kingpin.CmdClause {
+	if cmd := app.GetCommand("mod"); cmd != nil {
+		return cmd
+	}
+	return app.Command("mod", "Manage the sysl modules imported from other repositories")
+}
+
+// loadResolver returns the resolver of the sysl.mod nearest the working
+// directory.
+func loadResolver(fs afero.Fs) (*mod.Resolver, error) {
+	dir, err := mod.FindModDir(fs, ".")
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return nil, errors.Errorf("%s not found, create one with sysl mod init", mod.ModFileName)
+	}
+	return mod.LoadResolver(fs, dir)
+}
+
+type modInitCmd struct {
+	module string
+}
+
+func (p *modInitCmd) Name() string       { return "mod init" }
+func (p *modInitCmd) MaxSyslModule() int { return 0 }
+
+func (p *modInitCmd) Configure(app *kingpin.Application) *kingpin.CmdClause {
+	cmd := modCommand(app).Command("init", "Create a "+mod.ModFileName+" in the working directory")
+	cmd.Arg("path", "path of the module, eg: github.com/org/specs").Required().StringVar(&p.module)
+	return cmd
+}
+
+func (p *modInitCmd) Execute(args ExecuteArgs) error {
+	filename, err := filepath.Abs(mod.ModFileName)
+	if err != nil {
+		return err
+	}
+	exists, err := afero.Exists(args.Filesystem, filename)
+	switch {
+	case err != nil:
+		return err
+	case exists:
+		return errors.Errorf("%s already exists", mod.ModFileName)
+	}
+	modFile := &mod.ModFile{Module: p.module}
+	return afero.WriteFile(args.Filesystem, filename, modFile.Format(), 0644)
+}
+
+type modTidyCmd struct{}
+
+func (p *modTidyCmd) Name() string       { return "mod tidy" }
+func (p *modTidyCmd) MaxSyslModule() int { return 0 }
+
+func (p *modTidyCmd) Configure(app *kingpin.Application) *kingpin.CmdClause {
+	return modCommand(app).Command("tidy",
+		"Require the modules imported by the sysl files of the module, and only those, and record their checksums")
+}
+
+func (p *modTidyCmd) Execute(args ExecuteArgs) error {
+	resolver, err := loadResolver(args.Filesystem)
+	if err != nil {
+		return err
+	}
+	imports, err := mod.Imports(args.Filesystem, resolver.Dir)
+	if err != nil {
+		return err
+	}
+	if err := resolver.Tidy(imports); err != nil {
+		return err
+	}
+	return resolver.Save()
+}
+
+type modVendorCmd struct{}
+
+func (p *modVendorCmd) Name() string       { return "mod vendor" }
+func (p *modVendorCmd) MaxSyslModule() int { return 0 }
+
+func (p *modVendorCmd) Configure(app *kingpin.Application) *kingpin.CmdClause {
+	return modCommand(app).Command("vendor",
+		"Copy the required modules to "+mod.VendorDir+", which resolves them ahead of the module cache")
+}
+
+func (p *modVendorCmd) Execute(args ExecuteArgs) error {
+	resolver, err := loadResolver(args.Filesystem)
+	if err != nil {
+		return err
+	}
+	return resolver.Vendor()
+}
+
+type modGraphCmd struct {
+	output io.Writer
+}
+
+func (p *modGraphCmd) Name() string       { return "mod graph" }
+func (p *modGraphCmd) MaxSyslModule() int { return 0 }
+
+func (p *modGraphCmd) Configure(app *kingpin.Application) *kingpin.CmdClause {
+	return modCommand(app).Command("graph", "Print each requirement of the modules as: module requirement@version")
+}
+
+func (p *modGraphCmd) Execute(args ExecuteArgs) error {
+	resolver, err := loadResolver(args.Filesystem)
+	if err != nil {
+		return err
+	}
+	lines, err := resolver.Graph()
+	if err != nil {
+		return err
+	}
+
+	output := p.output
+	if output == nil {
+		output = os.Stdout
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(output, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain2WithModInitAndTidy(t *testing.T) {
+	t.Parallel()
+
+	wd, err := filepath.Abs(".")
+	require.NoError(t, err)
+	logger, _ := test.NewNullLogger()
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(wd, "model.sysl"),
+		[]byte("import //github.com/org/a/a\n\nModel:\n    ...\n"), 0644))
+
+	assert.Equal(t, 0, main2([]string{"sysl", "mod", "init", "github.com/org/specs"}, fs, logger, main3))
+	assert.Equal(t, 1, main2([]string{"sysl", "mod", "init", "github.com/org/specs"}, fs, logger, main3))
+
+	modFile := filepath.Join(wd, "sysl.mod")
+	data, err := afero.ReadFile(fs, modFile)
+	require.NoError(t, err)
+	assert.Equal(t, "module github.com/org/specs\n", string(data))
+	data = append(data, "\nreplace github.com/org/a => ../a\n"...)
+	require.NoError(t, afero.WriteFile(fs, modFile, data, 0644))
+
+	assert.Equal(t, 0, main2([]string{"sysl", "mod", "tidy"}, fs, logger, main3))
+	data, err = afero.ReadFile(fs, modFile)
+	require.NoError(t, err)
+	assert.Equal(t, `module github.com/org/specs
+
+require github.com/org/a v0.0.0
+
+replace github.com/org/a => ../a
+`, string(data))
+
+	var out bytes.Buffer
+	cmd := &modGraphCmd{output: &out}
+	require.NoError(t, cmd.Execute(ExecuteArgs{Filesystem: fs, Logger: logger}))
+	assert.Equal(t, "github.com/org/specs github.com/org/a@v0.0.0\n", out.String())
+}
+
+func TestMain2WithModReplace(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	fs := afero.NewMemMapFs()
+	require.NoError(t, syslutil.WriteFiles(fs, "", map[string]string{
+		"/specs/sysl.mod":   "module github.com/org/specs\n\nreplace github.com/org/a => ../a\n",
+		"/specs/model.sysl": "import //github.com/org/a/a\n\nModel:\n    ...\n",
+		"/a/a.sysl":         "A:\n    ...\n",
+	}))
+	args := []string{"sysl", "--root", "/specs", "pb", "--mode", "json", "-o", "/out.json", "model.sysl"}
+
+	// Modules are resolved with sysl.mod rather than the go command.
+	assert.Equal(t, 1, main2(args, fs, logger, main3))
+	require.NoError(t, afero.WriteFile(fs, "/specs/sysl.mod",
+		[]byte("module github.com/org/specs\n\nrequire github.com/org/a v0.0.0\n\nreplace github.com/org/a => ../a\n"), 0644))
+	assert.Equal(t, 0, main2(args, fs, logger, main3))
+	out, err := afero.ReadFile(fs, "/out.json")
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"A"`)
+	assert.Contains(t, string(out), `"Model"`)
+}
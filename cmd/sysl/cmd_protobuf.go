@@ -18,9 +18,9 @@ func (p *protobuf) Name() string       { return "protobuf" }
 func (p *protobuf) MaxSyslModule() int { return 1 }
 
 func (p *protobuf) Configure(app *kingpin.Application) *kingpin.CmdClause {
-	cmd := app.Command(p.Name(), "Generate textpb/json").Alias("pb")
+	cmd := app.Command(p.Name(), "Generate textpb/json/pb").Alias("pb")
 	cmd.Flag("output", "output file name").Short('o').Default("-").StringVar(&p.output)
-	opts := []string{"textpb", "json"}
+	opts := []string{"textpb", "json", "pb"}
 	cmd.Flag("mode", fmt.Sprintf("output mode: [%s]", strings.Join(opts, ","))).
 		Default(opts[0]).
 		EnumVar(&p.mode, opts...)
@@ -35,7 +35,14 @@ func (p *protobuf) Execute(args ExecuteArgs) error {
 	p.mode = strings.TrimSpace(p.mode)
 
 	toJSON := p.mode == "json" || p.mode == "" && strings.HasSuffix(p.output, ".json")
+	toPB := p.mode == "pb" || p.mode == "" && strings.HasSuffix(p.output, ".pb")
 
+	if toPB {
+		if p.output == "-" {
+			return pbutil.FBinaryPB(args.Logger.Out, args.Modules[0])
+		}
+		return pbutil.BinaryPB(args.Modules[0], p.output, args.Filesystem)
+	}
 	if toJSON {
 		if p.output == "-" {
 			return pbutil.FJSONPB(args.Logger.Out, args.Modules[0])
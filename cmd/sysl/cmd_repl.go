@@ -1,28 +1,99 @@
 package main
 
 import (
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/anz-bank/sysl/pkg/eval"
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/spf13/afero"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
-type replCmd struct{}
+const maxHistoryLines = 1000
+
+type replCmd struct {
+	module  string
+	history string
+	input   io.Reader
+	output  io.Writer
+}
 
 func (p *replCmd) Name() string       { return "repl" }
 func (p *replCmd) MaxSyslModule() int { return 0 }
 
 func (p *replCmd) Configure(app *kingpin.Application) *kingpin.CmdClause {
 	cmd := app.Command(p.Name(), "Enter a sysl REPL")
+	cmd.Arg("MODULE", "optional module whose apps and views are available in the REPL,"+
+		" also loadable with :load").StringVar(&p.module)
+	cmd.Flag("history", "file to persist REPL input to").Default(defaultHistoryFile()).StringVar(&p.history)
 	return cmd
 }
 
 func (p *replCmd) Execute(args ExecuteArgs) error {
+	config := eval.REPLConfig{
+		Load: func(name string) (*sysl.Module, error) {
+			mod, _, err := LoadSyslModule(args.Root, name, args.Filesystem, args.Logger)
+			return mod, err
+		},
+	}
+	if p.module != "" {
+		mod, err := config.Load(p.module)
+		if err != nil {
+			return err
+		}
+		config.Module = mod
+	}
+	if p.history != "" {
+		lines, f, err := openHistory(args.Filesystem, p.history)
+		if err != nil {
+			args.Logger.Warnf("history disabled: %s", err.Error())
+		} else {
+			defer f.Close()
+			config.History = lines
+			config.HistoryWriter = f
+		}
+	}
+
+	input, output := p.input, p.output
+	if input == nil {
+		input, output = os.Stdin, os.Stdout
+	}
 	s := &eval.Scope{}
-	repl := eval.NewREPL(os.Stdin, os.Stdout)
+	repl := eval.NewModuleREPL(input, output, config)
 	for {
 		if err := repl(s, nil, nil); err != nil {
 			return nil // means EOF
 		}
 	}
 }
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".sysl_history")
+}
+
+// openHistory returns the most recent lines of the history file, and the file opened for appending.
+func openHistory(fs afero.Fs, filename string) ([]string, afero.File, error) {
+	var lines []string
+	content, err := afero.ReadFile(fs, filename)
+	switch {
+	case err == nil:
+		lines = strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+		if len(lines) > maxHistoryLines {
+			lines = lines[len(lines)-maxHistoryLines:]
+		}
+	case !os.IsNotExist(err):
+		return nil, nil, err
+	}
+	f, err := fs.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	return lines, f, nil
+}
@@ -1,54 +1,148 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/anz-bank/sysl/pkg/loader"
+	"github.com/anz-bank/sysl/pkg/parse"
 	"github.com/anz-bank/sysl/pkg/sysl"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
-const syslRootMarker = ".sysl"
+const syslRootMarker = loader.RootMarker
 
 type cmdRunner struct {
 	commands map[string]Command
 
 	Root    string
 	modules []string
+	noCache bool
+	watch   bool
 }
 
 func (r *cmdRunner) Run(which string, fs afero.Fs, logger *logrus.Logger) error {
+	var cache *parse.Cache
+	if _, onOs := fs.(*afero.OsFs); onOs && !r.noCache {
+		// Models on other filesystems, as in tests, are not cached on the
+		// filesystem of the OS.
+		cache = parseCache()
+	}
+	if r.watch {
+		cmd, ok := r.commands[which]
+		if !ok || cmd.MaxSyslModule() == 0 {
+			return fmt.Errorf("--watch needs a command loading a MODULE")
+		}
+		w, err := newWatcher(fs, r.Root, r.modules)
+		if err != nil {
+			return err
+		}
+		return r.runWatching(context.Background(), which, fs, logger, cache, w)
+	}
+	_, err := r.run(which, fs, logger, cache, false)
+	return err
+}
+
+// run runs the command, returning the diagnostics found loading its modules.
+// Unless quiet, they are logged as they are found too.
+func (r *cmdRunner) run(
+	which string, fs afero.Fs, logger *logrus.Logger, cache *parse.Cache, quiet bool,
+) ([]parse.Diagnostic, error) {
+	var diagnostics []parse.Diagnostic
 	if cmd, ok := r.commands[which]; ok {
 		if cmd.Name() == which {
-			var module *sysl.Module
-			var err error
 			var appName string
 			var mods []*sysl.Module
 
 			if cmd.MaxSyslModule() > 0 {
 				for _, moduleName := range r.modules {
-					module, appName, err = LoadSyslModule(r.Root, moduleName, fs, logger)
+					result, err := loader.Load(context.Background(), fs, moduleName, loader.Options{
+						Root:             r.Root,
+						Logger:           logger,
+						Cache:            cache,
+						QuietDiagnostics: quiet,
+					})
+					diagnostics = append(diagnostics, result.Diagnostics...)
 					if err != nil {
-						return err
+						return diagnostics, err
 					}
-					mods = append(mods, module)
+					appName = result.DefaultApp
+					mods = append(mods, result.Module)
 				}
 			}
 
 			if len(mods) > cmd.MaxSyslModule() {
 				logger.Error("this command can accept max " + strconv.Itoa(cmd.MaxSyslModule()) + " module(s).")
-				return fmt.Errorf("this command can accept max " + strconv.Itoa(cmd.MaxSyslModule()) + " module(s).")
+				return diagnostics, fmt.Errorf("this command can accept max " + strconv.Itoa(cmd.MaxSyslModule()) +
+					" module(s).")
 			}
-			return cmd.Execute(ExecuteArgs{Modules: mods, Filesystem: fs,
-				Logger: logger, DefaultAppName: appName})
+			return diagnostics, cmd.Execute(ExecuteArgs{Modules: mods, Filesystem: fs,
+				Logger: logger, DefaultAppName: appName, Root: r.Root})
 		}
 	}
-	return nil
+	return diagnostics, nil
+}
+
+// runWatching runs the command, then runs it again each time the files the
+// watcher watches change, until ctx is done. Each run logs only the
+// diagnostics and errors that are new since the run before, and those that
+// are gone at the level they were logged at.
+func (r *cmdRunner) runWatching(
+	ctx context.Context, which string, fs afero.Fs, logger *logrus.Logger, cache *parse.Cache, w *watcher,
+) error {
+	if cache == nil {
+		// Unchanged files are still only parsed once.
+		cache = &parse.Cache{Dir: "/", Version: cacheVersion(), Fs: afero.NewMemMapFs()}
+	}
+
+	reported := map[string]logrus.Level{}
+	for {
+		diagnostics, err := r.run(which, fs, logger, cache, true)
+		current := map[string]logrus.Level{}
+		for _, d := range diagnostics {
+			current[d.String()] = d.Level
+		}
+		if err != nil {
+			current[err.Error()] = logrus.ErrorLevel
+		}
+		for _, msg := range sortedKeys(current) {
+			if _, has := reported[msg]; !has {
+				logger.Log(current[msg], msg)
+			}
+		}
+		for _, msg := range sortedKeys(reported) {
+			if _, has := current[msg]; !has {
+				logger.Logf(reported[msg], "Resolved: %s", msg)
+			}
+		}
+		reported = current
+
+		// Files the command writes do not run it again.
+		files, err := w.scan()
+		if err != nil {
+			return err
+		}
+		w.files = files
+		logger.Infof("Watching %s for changes", strings.Join(w.dirs, ", "))
+		if err := w.wait(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+func sortedKeys(m map[string]logrus.Level) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func (r *cmdRunner) Configure(app *kingpin.Application) error {
@@ -65,12 +159,27 @@ func (r *cmdRunner) Configure(app *kingpin.Application) error {
 		&validateCmd{},
 		&exportCmd{},
 		&replCmd{},
+		&depsCmd{},
+		&importsCmd{},
+		&c4Cmd{},
+		&lineageCmd{},
+		&siteCmd{},
+		&serveCmd{},
+		&cacheCleanCmd{},
+		&modInitCmd{},
+		&modTidyCmd{},
+		&modVendorCmd{},
+		&modGraphCmd{},
 	}
 	r.commands = map[string]Command{}
 
 	app.Flag("root",
 		"sysl root directory for input model file. If root is not found, the module directory becomes "+
 			"the root, but the module can not import with absolute paths (or imports must be relative).").StringVar(&r.Root)
+	app.Flag("no-cache", "parse every file, ignoring the parse cache").BoolVar(&r.noCache)
+	app.Flag("watch",
+		"run the command again each time the models in the root change, reporting only the diagnostics that changed").
+		BoolVar(&r.watch)
 
 	sort.Slice(commands, func(i, j int) bool {
 		return strings.Compare(commands[i].Name(), commands[j].Name()) < 0
@@ -79,7 +188,8 @@ func (r *cmdRunner) Configure(app *kingpin.Application) error {
 		c := cmd.Configure(app)
 		if cmd.MaxSyslModule() > 0 {
 			c.Arg("MODULE", "input files without .sysl extension and with leading /, eg: "+
-				"/project_dir/my_models combine with --root if needed").
+				"/project_dir/my_models combine with --root if needed, "+
+				"or a module compiled by the protobuf command (.pb, .json or .textpb)").
 				Required().StringsVar(&r.modules)
 		}
 		r.commands[cmd.Name()] = cmd
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/anz-bank/sysl/pkg/sequencediagram"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -14,6 +15,9 @@ type sequenceDiagramCmd struct {
 	appsFlag       []string
 	blackboxesFlag map[string]string
 	group          string
+	depth          int
+	auto           bool
+	index          string
 }
 
 func (p *sequenceDiagramCmd) Name() string       { return "sd" }
@@ -41,7 +45,7 @@ func (p *sequenceDiagramCmd) Configure(app *kingpin.Application) *kingpin.CmdCla
 	p.plantumlmixin.AddFlag(cmd)
 
 	cmd.Flag("output",
-		"output file (default: %(epname).png)",
+		"output file, .png, .svg, .uml or .mmd for Mermaid (default: %(epname).png)",
 	).Default("%(epname).png").Short('o').StringVar(&p.output)
 
 	cmd.Flag("endpoint",
@@ -62,23 +66,48 @@ func (p *sequenceDiagramCmd) Configure(app *kingpin.Application) *kingpin.CmdCla
 	cmd.Flag("groupby", "Enter the groupby attribute (apps having "+
 		"the same attribute value are grouped together in one box").Short('g').StringVar(&p.group)
 
+	cmd.Flag("depth",
+		"Follow calls this many levels deep, drawing deeper calls like blackboxes (default: 0, unlimited)",
+	).Default("0").IntVar(&p.depth)
+
+	cmd.Flag("auto",
+		"Generate a diagram for every endpoint of the apps given with --app, or of all apps, "+
+			"to an output such as %(appname)/%(epname).svg",
+	).Default("false").BoolVar(&p.auto)
+
+	cmd.Flag("index",
+		"Markdown index of the diagrams generated with --auto (default: index.md)",
+	).Default("index.md").StringVar(&p.index)
+
 	EnsureFlagsNonEmpty(cmd)
 	return cmd
 }
 
 func (p *sequenceDiagramCmd) Execute(args ExecuteArgs) error {
-	sequenceParams := &CmdContextParamSeqgen{
-		endpointFormat: p.endpointFormat,
-		appFormat:      p.appFormat,
-		title:          p.title,
-		output:         p.output,
-		endpointsFlag:  p.endpointsFlag,
-		appsFlag:       p.appsFlag,
-		blackboxesFlag: p.blackboxesFlag,
-		group:          p.group,
+	params := &sequencediagram.Params{
+		EndpointFormat: p.endpointFormat,
+		AppFormat:      p.appFormat,
+		Title:          p.title,
+		Output:         p.output,
+		Endpoints:      p.endpointsFlag,
+		Apps:           p.appsFlag,
+		BlackboxesFlag: p.blackboxesFlag,
+		Group:          p.group,
+		Depth:          p.depth,
+	}
+
+	if p.auto {
+		result, generated, err := sequencediagram.DoConstructAutoSequenceDiagrams(params, args.Modules[0], args.Logger)
+		if err != nil {
+			return err
+		}
+		if err := p.GenerateFromMap(result, args.Filesystem); err != nil {
+			return err
+		}
+		return sequencediagram.WriteIndex(p.index, generated, args.Filesystem)
 	}
 
-	result, err := DoConstructSequenceDiagrams(sequenceParams, args.Modules[0], args.Logger)
+	result, err := sequencediagram.DoConstructSequenceDiagrams(params, args.Modules[0], args.Logger)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func TestDoGenerateSequenceDiagrams(t *testing.T) {
+	t.Parallel()
+
+	argsData := []string{"sysl", "sd", "-o", "%(epname).png", "-a", "Project", "sequence_diagram_complex_format.sysl"}
+	sysl := kingpin.New("sysl", "System Modelling Language Toolkit")
+	r := cmdRunner{}
+	assert.NoError(t, r.Configure(sysl))
+	selectedCommand, err := sysl.Parse(argsData[1:])
+	assert.Nil(t, err, "Cmd line parse failed for sysl sd")
+	assert.Equal(t, "sd", selectedCommand)
+}
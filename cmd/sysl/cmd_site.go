@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/spf13/afero"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type siteCmd struct {
+	plantumlmixin
+	title      string
+	output     string
+	noDiagrams bool
+}
+
+func (p *siteCmd) Name() string       { return "site" }
+func (p *siteCmd) MaxSyslModule() int { return 1 }
+
+func (p *siteCmd) Configure(app *kingpin.Application) *kingpin.CmdClause {
+	cmd := app.Command(p.Name(), "Generate a static website to browse the model")
+	p.addSiteFlags(cmd)
+	cmd.Flag("output", "output directory (default: site)").Default("site").Short('o').StringVar(&p.output)
+
+	EnsureFlagsNonEmpty(cmd)
+	return cmd
+}
+
+func (p *siteCmd) addSiteFlags(cmd *kingpin.CmdClause) {
+	cmd.Flag("title", "site title").Short('t').StringVar(&p.title)
+	cmd.Flag("no-diagrams", "leave out the sequence and data diagrams").Default("false").BoolVar(&p.noDiagrams)
+	p.AddFlag(cmd)
+}
+
+func (p *siteCmd) Execute(args ExecuteArgs) error {
+	s := buildSite(args.Modules[0], p.title, !p.noDiagrams, args.Logger)
+	return s.write(p.output, args.Filesystem, &p.plantumlmixin)
+}
+
+type serveCmd struct {
+	siteCmd
+	address string
+}
+
+func (p *serveCmd) Name() string       { return "serve" }
+func (p *serveCmd) MaxSyslModule() int { return 1 }
+
+func (p *serveCmd) Configure(app *kingpin.Application) *kingpin.CmdClause {
+	cmd := app.Command(p.Name(), "Serve a website to browse the model")
+	p.addSiteFlags(cmd)
+	cmd.Flag("address", "address to serve on (default: localhost:6900)").
+		Default("localhost:6900").StringVar(&p.address)
+
+	EnsureFlagsNonEmpty(cmd)
+	return cmd
+}
+
+// Execute serves the site from memory, so nothing is written to disk.
+func (p *serveCmd) Execute(args ExecuteArgs) error {
+	fs := afero.NewMemMapFs()
+	s := buildSite(args.Modules[0], p.title, !p.noDiagrams, args.Logger)
+	if err := s.write("/", fs, &p.plantumlmixin); err != nil {
+		return err
+	}
+	args.Logger.Infof("serving the model on http://%s", p.address)
+	return http.ListenAndServe(p.address, siteHandler(fs))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+func TestValidatorDoValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		args     []string
+		isErrNil bool
+	}{
+		"Success": {
+			args: []string{
+				"src", "codegen", "--validate-only", "--root-transform", testDir, "--transform", "transform2.sysl", "--grammar",
+				filepath.Join(testDir, "grammar.sysl"), "--start", "goFile",
+				"--dep-path", "example.com/abc/asx/lmno"}, isErrNil: true},
+		"Grammar loading fail": {
+			args: []string{
+				"src", "codegen", "--validate-only", "--root-transform", testDir, "--transform", "transform2.sysl", "--grammar",
+				filepath.Join(testDir, "go.sysl"), "--start", "goFile",
+				"--dep-path", "example.com/abc/asx/lmno"}, isErrNil: false},
+		"Transform loading fail": {
+			args: []string{
+				"src", "codegen", "--validate-only", "--root-transform", testDir, "--transform", "tfm.sysl", "--grammar",
+				filepath.Join(testDir, "grammar.sysl"), "--start", "goFile",
+				"--dep-path", "example.com/abc/asx/lmno"}, isErrNil: false},
+		"Has validation messages": {
+			args: []string{
+				"src", "codegen", "--validate-only", "--root-transform", testDir, "--transform", "transform1.sysl", "--grammar",
+				filepath.Join(testDir, "grammar.sysl"), "--start", "goFile",
+				"--dep-path", "example.com/abc/asx/lmno"}, isErrNil: false},
+	}
+
+	for name, tt := range cases {
+		args := tt.args
+		isErrNil := tt.isErrNil
+		t.Run(name, func(t *testing.T) {
+			sysl := kingpin.New("sysl", "System Modelling Language Toolkit")
+
+			cmd := &codegenCmd{}
+			require.NotNil(t, cmd.Configure(sysl))
+
+			var selectedCmd string
+			var err error
+			if selectedCmd, err = sysl.Parse(args[1:]); err != nil {
+				assert.FailNow(t, "Failed to parse args")
+			}
+			require.Equal(t, cmd.Name(), selectedCmd)
+			l, _ := test.NewNullLogger()
+			execArgs := ExecuteArgs{
+				Modules:    nil,
+				Filesystem: afero.NewOsFs(),
+				Logger:     l,
+			}
+			err = cmd.Execute(execArgs)
+			if isErrNil {
+				assert.Nil(t, err, "Unexpected result")
+			} else {
+				assert.NotNil(t, err, "Unexpected result")
+			}
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/diagrams"
+	"github.com/anz-bank/sysl/pkg/integrationdiagram"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+)
+
+//nolint:gochecknoglobals
+var lineagePayloadRE = regexp.MustCompile(`^(?:(?:sequence|set|list) of\s+)?(.+?)\??$`)
+
+// lineageEndpoint is an endpoint through which the traced type flows: it takes
+// the type in its parameters, returns it, or calls endpoints that do.
+type lineageEndpoint struct {
+	integrationdiagram.AppElement
+	Params     []string `json:"params,omitempty"`
+	Returns    bool     `json:"returns,omitempty"`
+	Downstream []string `json:"downstream,omitempty"`
+}
+
+// lineageFlow is the type moving from one endpoint to another, passed as the
+// parameters of a call or returned from one.
+type lineageFlow struct {
+	From  integrationdiagram.AppElement `json:"from"`
+	To    integrationdiagram.AppElement `json:"to"`
+	Via   string                        `json:"via"`
+	Label string                        `json:"label,omitempty"`
+}
+
+// Lineage traces where a type travels through the endpoints of a module.
+// Carriers are the types holding the traced type, itself included, since an
+// endpoint taking an Order takes the Customer in it too.
+type Lineage struct {
+	Type      string             `json:"type"`
+	Carriers  []string           `json:"carriers"`
+	Endpoints []*lineageEndpoint `json:"endpoints"`
+	Flows     []lineageFlow      `json:"flows"`
+
+	mod       *sysl.Module
+	carriers  syslutil.StrSet
+	endpoints map[integrationdiagram.AppElement]*lineageEndpoint
+}
+
+// BuildLineage traces typeName, given as App.Type, through the endpoints of
+// mod.
+func BuildLineage(mod *sysl.Module, typeName string) (*Lineage, error) {
+	i := strings.LastIndex(typeName, ".")
+	if i < 0 {
+		return nil, fmt.Errorf("type must be given as App.Type, not %q", typeName)
+	}
+	if mod.GetApps()[typeName[:i]].GetTypes()[typeName[i+1:]] == nil {
+		return nil, fmt.Errorf("type %q not found", typeName)
+	}
+	l := &Lineage{
+		Type:      typeName,
+		Endpoints: []*lineageEndpoint{},
+		Flows:     []lineageFlow{},
+		mod:       mod,
+		carriers:  syslutil.MakeStrSet(typeName),
+		endpoints: map[integrationdiagram.AppElement]*lineageEndpoint{},
+	}
+	l.findCarriers()
+	l.Carriers = l.carriers.ToSortedSlice()
+	l.findEndpoints()
+	l.findFlows()
+	l.findDownstream()
+	return l, nil
+}
+
+// findCarriers adds the types with fields of a carrier type until there are no
+// more to add.
+func (l *Lineage) findCarriers() {
+	for added := true; added; {
+		added = false
+		for _, appName := range sortedAppNames(l.mod) {
+			for typeName, t := range l.mod.GetApps()[appName].GetTypes() {
+				name := appName + "." + typeName
+				if l.carriers.Contains(name) {
+					continue
+				}
+				fields := t.GetTuple().GetAttrDefs()
+				if t.GetRelation() != nil {
+					fields = t.GetRelation().GetAttrDefs()
+				}
+				for _, field := range fields {
+					if l.carries(appName, field) {
+						l.carriers.Insert(name)
+						added = true
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+// carries reports whether t is a carrier type or a collection of one. Foreign
+// keys refer to a carrier without holding it, so do not count.
+func (l *Lineage) carries(ctxApp string, t *sysl.Type) bool {
+	switch {
+	case t.GetTypeRef() != nil:
+		if len(t.GetTypeRef().GetRef().GetPath()) > 1 {
+			return false
+		}
+		app, name := resolveTypeRef(ctxApp, t.GetTypeRef())
+		return l.carriers.Contains(app + "." + name)
+	case t.GetList() != nil:
+		return l.carries(ctxApp, t.GetList().GetType())
+	case t.GetSet() != nil:
+		return l.carries(ctxApp, t.GetSet())
+	case t.GetSequence() != nil:
+		return l.carries(ctxApp, t.GetSequence())
+	}
+	return false
+}
+
+// returnsCarrier reports whether a return payload, such as "sequence of
+// Order" or "ok <: Orders.Order", names a carrier type.
+func (l *Lineage) returnsCarrier(ctxApp, payload string) bool {
+	for _, part := range strings.Split(payload, ",") {
+		if i := strings.Index(part, "<:"); i >= 0 {
+			part = part[i+2:]
+		}
+		m := lineagePayloadRE.FindStringSubmatch(strings.TrimSpace(part))
+		if m == nil {
+			continue
+		}
+		name := m[1]
+		if !strings.Contains(name, ".") {
+			name = ctxApp + "." + name
+		}
+		if l.carriers.Contains(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Lineage) findEndpoints() {
+	for _, appName := range sortedAppNames(l.mod) {
+		app := l.mod.GetApps()[appName]
+		for _, epName := range siteEndpointNames(app) {
+			ep := app.GetEndpoints()[epName]
+			e := &lineageEndpoint{AppElement: integrationdiagram.AppElement{Name: appName, Endpoint: epName}}
+			for _, param := range ep.GetParam() {
+				if l.carries(appName, param.GetType()) {
+					e.Params = append(e.Params, param.GetName())
+				}
+			}
+			e.Returns = l.returnsCarrier(appName, diagrams.GetReturnPayload(ep.GetStmt()))
+			if len(e.Params) > 0 || e.Returns {
+				l.addEndpoint(e)
+			}
+		}
+	}
+}
+
+func (l *Lineage) addEndpoint(e *lineageEndpoint) *lineageEndpoint {
+	if existing, ok := l.endpoints[e.AppElement]; ok {
+		return existing
+	}
+	l.endpoints[e.AppElement] = e
+	l.Endpoints = append(l.Endpoints, e)
+	return e
+}
+
+// findFlows follows the calls to endpoints taking or returning the type. The
+// callers join the lineage as the producers of what they pass and the
+// consumers of what is returned.
+func (l *Lineage) findFlows() {
+	seen := map[lineageFlow]bool{}
+	for _, appName := range sortedAppNames(l.mod) {
+		app := l.mod.GetApps()[appName]
+		for _, epName := range siteEndpointNames(app) {
+			caller := integrationdiagram.AppElement{Name: appName, Endpoint: epName}
+			lineageCalls(app.GetEndpoints()[epName].GetStmt(), func(call *sysl.Call) {
+				target := integrationdiagram.AppElement{Name: syslutil.GetAppName(call.GetTarget()), Endpoint: call.GetEndpoint()}
+				callee := l.endpoints[target]
+				if callee == nil || callee.AppElement == caller {
+					return
+				}
+				flows := []lineageFlow{}
+				if len(callee.Params) > 0 {
+					flows = append(flows, lineageFlow{
+						From: caller, To: callee.AppElement, Via: "param", Label: strings.Join(callee.Params, ", "),
+					})
+				}
+				if callee.Returns {
+					flows = append(flows, lineageFlow{From: callee.AppElement, To: caller, Via: "return"})
+				}
+				for _, f := range flows {
+					if !seen[f] {
+						seen[f] = true
+						l.Flows = append(l.Flows, f)
+					}
+				}
+			})
+		}
+	}
+	for _, f := range l.Flows {
+		l.addEndpoint(&lineageEndpoint{AppElement: f.From})
+		l.addEndpoint(&lineageEndpoint{AppElement: f.To})
+	}
+	sort.Slice(l.Endpoints, func(i, j int) bool {
+		a, b := l.Endpoints[i], l.Endpoints[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Endpoint < b.Endpoint
+	})
+}
+
+// findDownstream lists the endpoints the type reaches from each endpoint.
+func (l *Lineage) findDownstream() {
+	next := map[integrationdiagram.AppElement][]integrationdiagram.AppElement{}
+	for _, f := range l.Flows {
+		next[f.From] = append(next[f.From], f.To)
+	}
+	for _, e := range l.Endpoints {
+		reached := syslutil.MakeStrSet()
+		queue := append([]integrationdiagram.AppElement{}, next[e.AppElement]...)
+		for len(queue) > 0 {
+			el := queue[0]
+			queue = queue[1:]
+			if el == e.AppElement || reached.Contains(lineageName(el)) {
+				continue
+			}
+			reached.Insert(lineageName(el))
+			queue = append(queue, next[el]...)
+		}
+		e.Downstream = reached.ToSortedSlice()
+	}
+}
+
+// lineageCalls calls f with each call in stmts, however deeply nested.
+func lineageCalls(stmts []*sysl.Statement, f func(*sysl.Call)) {
+	for _, stmt := range stmts {
+		switch s := stmt.Stmt.(type) {
+		case *sysl.Statement_Call:
+			f(s.Call)
+		case *sysl.Statement_Cond:
+			lineageCalls(s.Cond.GetStmt(), f)
+		case *sysl.Statement_Loop:
+			lineageCalls(s.Loop.GetStmt(), f)
+		case *sysl.Statement_LoopN:
+			lineageCalls(s.LoopN.GetStmt(), f)
+		case *sysl.Statement_Foreach:
+			lineageCalls(s.Foreach.GetStmt(), f)
+		case *sysl.Statement_Group:
+			lineageCalls(s.Group.GetStmt(), f)
+		case *sysl.Statement_Alt:
+			for _, choice := range s.Alt.GetChoice() {
+				lineageCalls(choice.GetStmt(), f)
+			}
+		}
+	}
+}
+
+func lineageName(e integrationdiagram.AppElement) string {
+	return e.Name + " <- " + e.Endpoint
+}
+
+// JSON returns the lineage report.
+func (l *Lineage) JSON() (string, error) {
+	out, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out) + "\n", nil
+}
+
+// PlantUML returns the lineage as a data-flow diagram, with the endpoints in
+// their apps and the endpoints holding the type highlighted.
+func (l *Lineage) PlantUML(title string) string {
+	var sb strings.Builder
+	sb.WriteString("@startuml\n")
+	if title != "" {
+		fmt.Fprintf(&sb, "title %s\n", title)
+	}
+	sb.WriteString(diagrams.PumlHeader)
+	sb.WriteString("left to right direction\nhide stereotype\n")
+	sb.WriteString("skinparam rectangle {\n  BackgroundColor FloralWhite\n  BorderColor Black\n")
+	sb.WriteString("  BackgroundColor<<holds>> Orchid\n}\nskinparam ArrowColor Crimson\n")
+	aliases := l.aliases()
+	l.eachApp(func(appName string, eps []*lineageEndpoint) {
+		fmt.Fprintf(&sb, "package \"%s\" {\n", appName)
+		for _, e := range eps {
+			stereotype := ""
+			if len(e.Params) > 0 || e.Returns {
+				stereotype = " <<holds>>"
+			}
+			fmt.Fprintf(&sb, "  rectangle \"%s\" as %s%s\n", e.Endpoint, aliases[e.AppElement], stereotype)
+		}
+		sb.WriteString("}\n")
+	})
+	for _, f := range l.Flows {
+		fmt.Fprintf(&sb, "%s --> %s : %s\n", aliases[f.From], aliases[f.To], f.label())
+	}
+	sb.WriteString("@enduml\n")
+	return sb.String()
+}
+
+// Mermaid returns the lineage as a Mermaid flowchart.
+func (l *Lineage) Mermaid(title string) string {
+	var sb strings.Builder
+	diagrams.WriteMermaidPreamble(&sb, title)
+	sb.WriteString("flowchart LR\n")
+	sb.WriteString("classDef default fill:FloralWhite,stroke:Black\nclassDef holds fill:Orchid\n")
+	aliases := l.aliases()
+	i := 0
+	l.eachApp(func(appName string, eps []*lineageEndpoint) {
+		fmt.Fprintf(&sb, "subgraph A%d[\"%s\"]\n", i, diagrams.MermaidText(appName))
+		i++
+		for _, e := range eps {
+			class := ""
+			if len(e.Params) > 0 || e.Returns {
+				class = ":::holds"
+			}
+			fmt.Fprintf(&sb, "  %s[\"%s\"]%s\n", aliases[e.AppElement], diagrams.MermaidText(e.Endpoint), class)
+		}
+		sb.WriteString("end\n")
+	})
+	for _, f := range l.Flows {
+		fmt.Fprintf(&sb, "%s -->|%s| %s\n", aliases[f.From], diagrams.MermaidEdgeText(f.label()), aliases[f.To])
+	}
+	return sb.String()
+}
+
+func (f lineageFlow) label() string {
+	if f.Label == "" {
+		return f.Via
+	}
+	return f.Via + " " + f.Label
+}
+
+func (l *Lineage) aliases() map[integrationdiagram.AppElement]string {
+	aliases := map[integrationdiagram.AppElement]string{}
+	for i, e := range l.Endpoints {
+		aliases[e.AppElement] = fmt.Sprintf("_%d", i)
+	}
+	return aliases
+}
+
+// eachApp calls f with each app of the lineage and its endpoints, in order.
+func (l *Lineage) eachApp(f func(appName string, eps []*lineageEndpoint)) {
+	for i := 0; i < len(l.Endpoints); {
+		j := i
+		for j < len(l.Endpoints) && l.Endpoints[j].Name == l.Endpoints[i].Name {
+			j++
+		}
+		f(l.Endpoints[i].Name, l.Endpoints[i:j])
+		i = j
+	}
+}
+
+// GenerateLineage traces typeName through mod and returns the report for
+// output: JSON if output ends with ".json", else a data-flow diagram.
+func GenerateLineage(mod *sysl.Module, typeName, title, output string) (string, error) {
+	l, err := BuildLineage(mod, typeName)
+	if err != nil {
+		return "", err
+	}
+	if title == "" {
+		title = "Lineage of " + typeName
+	}
+	switch {
+	case strings.HasSuffix(output, ".json"):
+		return l.JSON()
+	case diagrams.IsMermaidOutput(output):
+		return l.Mermaid(title), nil
+	default:
+		return l.PlantUML(title), nil
+	}
+}
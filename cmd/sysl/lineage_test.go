@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/integrationdiagram"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadLineageModule(t *testing.T) *sysl.Module {
+	logger, _ := test.NewNullLogger()
+	mod, _, err := LoadSyslModule(testDir, "lineage.sysl", afero.NewOsFs(), logger)
+	require.NoError(t, err)
+	return mod
+}
+
+func TestBuildLineage(t *testing.T) {
+	t.Parallel()
+
+	l, err := BuildLineage(loadLineageModule(t), "Bank.Customer")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bank.Account", "Bank.Customer", "Bank.Statement"}, l.Carriers)
+
+	endpoints := map[string]lineageEndpoint{}
+	for _, e := range l.Endpoints {
+		endpoints[lineageName(e.AppElement)] = *e
+	}
+	openAccount := integrationdiagram.AppElement{Name: "Bank", Endpoint: "OpenAccount"}
+	assert.Equal(t, map[string]lineageEndpoint{
+		"Bank <- GetStatement": {
+			AppElement: integrationdiagram.AppElement{Name: "Bank", Endpoint: "GetStatement"},
+			Returns:    true,
+			Downstream: []string{"Web <- ShowStatement"},
+		},
+		"Bank <- OpenAccount": {
+			AppElement: openAccount,
+			Params:     []string{"customer"},
+			Returns:    true,
+			Downstream: []string{"Crm <- Record", "Web <- Open"},
+		},
+		"Crm <- Record": {
+			AppElement: integrationdiagram.AppElement{Name: "Crm", Endpoint: "Record"},
+			Params:     []string{"holder"},
+			Downstream: []string{},
+		},
+		"Web <- Open": {
+			AppElement: integrationdiagram.AppElement{Name: "Web", Endpoint: "Open"},
+			Downstream: []string{"Bank <- OpenAccount", "Crm <- Record"},
+		},
+		"Web <- ShowStatement": {
+			AppElement: integrationdiagram.AppElement{Name: "Web", Endpoint: "ShowStatement"},
+			Downstream: []string{},
+		},
+	}, endpoints)
+	assert.Contains(t, l.Flows, lineageFlow{
+		From: integrationdiagram.AppElement{Name: "Web", Endpoint: "Open"}, To: openAccount, Via: "param", Label: "customer",
+	})
+	assert.Contains(t, l.Flows, lineageFlow{
+		From: openAccount, To: integrationdiagram.AppElement{Name: "Web", Endpoint: "Open"}, Via: "return",
+	})
+	assert.Len(t, l.Flows, 4)
+}
+
+func TestBuildLineageWithoutFlows(t *testing.T) {
+	t.Parallel()
+
+	l, err := BuildLineage(loadLineageModule(t), "Bank.Branch")
+	require.NoError(t, err)
+	require.Len(t, l.Endpoints, 1)
+	assert.Equal(t, "Branches", l.Endpoints[0].Endpoint)
+	assert.True(t, l.Endpoints[0].Returns)
+	assert.Empty(t, l.Flows)
+}
+
+func TestBuildLineageUnknownType(t *testing.T) {
+	t.Parallel()
+
+	mod := loadLineageModule(t)
+	_, err := BuildLineage(mod, "Bank.Nope")
+	assert.EqualError(t, err, `type "Bank.Nope" not found`)
+	_, err = BuildLineage(mod, "Customer")
+	assert.EqualError(t, err, `type must be given as App.Type, not "Customer"`)
+}
+
+func TestGenerateLineage(t *testing.T) {
+	t.Parallel()
+
+	mod := loadLineageModule(t)
+	out, err := GenerateLineage(mod, "Bank.Customer", "", "lineage.json")
+	require.NoError(t, err)
+	var report Lineage
+	require.NoError(t, json.Unmarshal([]byte(out), &report))
+	assert.Equal(t, "Bank.Customer", report.Type)
+	assert.Len(t, report.Endpoints, 5)
+
+	out, err = GenerateLineage(mod, "Bank.Customer", "", "lineage.svg")
+	require.NoError(t, err)
+	assert.Contains(t, out, "title Lineage of Bank.Customer\n")
+	assert.Contains(t, out, `rectangle "OpenAccount" as _1 <<holds>>`)
+	assert.Contains(t, out, "_3 --> _1 : param customer\n")
+
+	out, err = GenerateLineage(mod, "Bank.Customer", "Customer data", "lineage.mmd")
+	require.NoError(t, err)
+	assert.Contains(t, out, "title: Customer data\n")
+	assert.Contains(t, out, "_0 -->|return| _4\n")
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"os"
+	"runtime"
+	"strconv"
 
+	"github.com/anz-bank/sysl/pkg/diagrams"
 	"github.com/spf13/afero"
 
 	"gopkg.in/alecthomas/kingpin.v2"
@@ -11,18 +14,33 @@ import (
 const (
 	PlantUMLEnvVar  = "SYSL_PLANTUML"
 	PlantUMLDefault = "http://localhost:8080/plantuml"
+
+	// RenderCacheDefault is where rendered diagrams are cached, relative to the
+	// working directory.
+	RenderCacheDefault = CacheDir + "/diagrams"
 )
 
 type plantumlmixin struct {
-	value string
+	value       string
+	jobs        int
+	renderCache string
+	noCache     bool
 }
 
 func (p *plantumlmixin) AddFlag(cmd *kingpin.CmdClause) {
 	cmd.Flag("plantuml",
-		"base url of plantuml server (default: "+PlantUMLEnvVar+" or "+
+		"base url of plantuml server, path of a plantuml executable or jar, or "+diagrams.BuiltinRenderer+
+			" to render sequence diagrams without plantuml (default: "+PlantUMLEnvVar+" or "+
 			PlantUMLDefault+" see "+
 			"http://plantuml.com/server.html#install for more info)",
 	).Short('p').StringVar(&p.value)
+	cmd.Flag("jobs", "number of diagrams to render at once").
+		Default(strconv.Itoa(runtime.NumCPU())).IntVar(&p.jobs)
+	cmd.Flag("render-cache", "directory of previously rendered png and svg diagrams, which grows without limit "+
+		"until removed with sysl cache clean").
+		Default(RenderCacheDefault).StringVar(&p.renderCache)
+	cmd.Flag("no-render-cache", "render every diagram, ignoring the render cache").
+		Default("false").BoolVar(&p.noCache)
 }
 
 func (p *plantumlmixin) Value() string {
@@ -35,11 +53,23 @@ func (p *plantumlmixin) Value() string {
 	return p.value
 }
 
+// renderer returns the renderer for the flags, caching its output unless the
+// cache is disabled.
+func (p *plantumlmixin) renderer(fs afero.Fs) (diagrams.Renderer, error) {
+	r, err := diagrams.MakeRenderer(p.Value())
+	if err != nil || p.noCache || p.renderCache == "" {
+		return r, err
+	}
+	return diagrams.CachingRenderer{Renderer: r, Key: p.Value(), Dir: p.renderCache, Fs: fs}, nil
+}
+
+// GenerateFromMap writes each diagram in m to the output it is keyed by.
+// Every diagram is attempted, and the failures are returned together as
+// RenderErrors.
 func (p *plantumlmixin) GenerateFromMap(m map[string]string, fs afero.Fs) error {
-	for k, v := range m {
-		if err := OutputPlantuml(k, p.Value(), v, fs); err != nil {
-			return err
-		}
+	r, err := p.renderer(fs)
+	if err != nil {
+		return err
 	}
-	return nil
+	return diagrams.OutputDiagrams(m, r, p.jobs, fs)
 }
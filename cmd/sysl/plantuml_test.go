@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/diagrams"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSequenceUML = `@startuml
+control "Api" as _0
+[->_0 : GetUser
+[<--_0 : User
+@enduml
+`
+
+func TestGenerateFromMapUsesRenderCache(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	p := &plantumlmixin{value: diagrams.BuiltinRenderer, jobs: 4, renderCache: "/cache"}
+	m := map[string]string{"/a.svg": testSequenceUML, "/b.svg": testSequenceUML, "/c.uml": testSequenceUML}
+	require.NoError(t, p.GenerateFromMap(m, fs))
+
+	entries, err := afero.ReadDir(fs, "/cache")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// Unchanged diagrams come from the cache rather than the renderer.
+	cached := "/cache/" + entries[0].Name()
+	require.NoError(t, afero.WriteFile(fs, cached, []byte("cached"), 0644))
+	require.NoError(t, p.GenerateFromMap(map[string]string{"/a.svg": testSequenceUML}, fs))
+	out, err := afero.ReadFile(fs, "/a.svg")
+	require.NoError(t, err)
+	assert.Equal(t, "cached", string(out))
+
+	p.noCache = true
+	require.NoError(t, p.GenerateFromMap(map[string]string{"/a.svg": testSequenceUML}, fs))
+	out, err = afero.ReadFile(fs, "/a.svg")
+	require.NoError(t, err)
+	assert.NotEqual(t, "cached", string(out))
+}
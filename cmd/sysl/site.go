@@ -0,0 +1,568 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/datamodeldiagram"
+	"github.com/anz-bank/sysl/pkg/diagrams"
+	"github.com/anz-bank/sysl/pkg/integrationdiagram"
+	"github.com/anz-bank/sysl/pkg/sequencediagram"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/sirupsen/logrus"
+)
+
+// siteLink is a link to a page of the site.
+type siteLink struct {
+	Name string
+	Path string
+}
+
+type siteAttr struct {
+	Name  string
+	Value string
+}
+
+type siteField struct {
+	Name string
+	Type template.HTML
+}
+
+// siteDiagram is a diagram drawn on a page. Diagrams that could not be
+// rendered are shown as their PlantUML source, with the error.
+type siteDiagram struct {
+	Path   string
+	Source string
+	Error  string
+}
+
+type siteApp struct {
+	siteLink
+	Docstring string
+	Attrs     []siteAttr
+	Endpoints []*siteEndpoint
+	Types     []*siteType
+	Calls     []siteLink
+	Callers   []siteLink
+	Diagram   *siteDiagram
+}
+
+type siteEndpoint struct {
+	siteLink
+	App       siteLink
+	Docstring string
+	Attrs     []siteAttr
+	Params    []siteField
+	Stmts     template.HTML
+	Callers   []siteLink
+	Diagram   *siteDiagram
+}
+
+type siteType struct {
+	siteLink
+	App       siteLink
+	Kind      template.HTML
+	Docstring string
+	Attrs     []siteAttr
+	Fields    []siteField
+	Items     []string
+	UsedBy    []siteLink
+}
+
+// siteEntry is an entry of the client-side search index.
+type siteEntry struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Path string `json:"path"`
+	Text string `json:"text"`
+}
+
+// site is a browsable model of a module, with a page for each app, endpoint
+// and type. Pages are written flat in one directory, so links between them
+// are just file names.
+type site struct {
+	Title string
+	Apps  []*siteApp
+
+	mod       *sysl.Module
+	paths     map[string]string
+	used      syslutil.StrSet
+	endpoints map[string]*siteEndpoint
+	types     map[string]*siteType
+	usedBy    map[string]map[siteLink]struct{}
+	diagrams  map[string]*siteDiagram
+}
+
+//nolint:gochecknoglobals
+var siteSlugRE = regexp.MustCompile(`[^A-Za-z0-9_.]+`)
+
+func siteAppKey(app string) string          { return "app\x00" + app }
+func siteEndpointKey(app, ep string) string { return "ep\x00" + app + "\x00" + ep }
+func siteTypeKey(app, t string) string      { return "type\x00" + app + "\x00" + t }
+
+// register reserves a unique page path for a key, derived from its name.
+func (s *site) register(key, prefix, name string) string {
+	slug := prefix + "-" + strings.Trim(siteSlugRE.ReplaceAllString(name, "-"), "-")
+	path := slug + ".html"
+	for i := 2; s.used.Contains(path); i++ {
+		path = slug + "-" + strconv.Itoa(i) + ".html"
+	}
+	s.used.Insert(path)
+	s.paths[key] = path
+	return path
+}
+
+// link returns the HTML of a link to the page of key, or just the text if
+// there is no such page.
+func (s *site) link(key, text string) string {
+	if path, has := s.paths[key]; has {
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(path), html.EscapeString(text))
+	}
+	return html.EscapeString(text)
+}
+
+// buildSite collects the pages of the site for a module. The diagrams drawn
+// on them are left in diagrams to be rendered, unless withDiagrams is false.
+func buildSite(mod *sysl.Module, title string, withDiagrams bool, logger *logrus.Logger) *site {
+	s := &site{
+		Title:     title,
+		mod:       mod,
+		paths:     map[string]string{},
+		used:      syslutil.MakeStrSet("index.html"),
+		endpoints: map[string]*siteEndpoint{},
+		types:     map[string]*siteType{},
+		usedBy:    map[string]map[siteLink]struct{}{},
+		diagrams:  map[string]*siteDiagram{},
+	}
+	appNames := sortedAppNames(mod)
+	for _, appName := range appNames {
+		app := mod.GetApps()[appName]
+		s.register(siteAppKey(appName), "app", appName)
+		for _, epName := range siteEndpointNames(app) {
+			s.register(siteEndpointKey(appName, epName), "ep", appName+"-"+epName)
+		}
+		for _, typeName := range sortedTypeNames(app) {
+			s.register(siteTypeKey(appName, typeName), "type", appName+"-"+typeName)
+		}
+	}
+	for _, appName := range appNames {
+		s.Apps = append(s.Apps, s.buildApp(appName))
+	}
+	s.linkCalls()
+	for key, users := range s.usedBy {
+		for user := range users {
+			s.types[key].UsedBy = append(s.types[key].UsedBy, user)
+		}
+		sortLinks(s.types[key].UsedBy)
+	}
+	if withDiagrams {
+		s.addDiagrams(logger)
+	}
+	return s
+}
+
+func sortedAppNames(mod *sysl.Module) []string {
+	names := make([]string, 0, len(mod.GetApps()))
+	for name := range mod.GetApps() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// siteEndpointNames returns the endpoints of an app, other than the "..."
+// placeholder of apps without endpoints.
+func siteEndpointNames(app *sysl.Application) []string {
+	names := make([]string, 0, len(app.GetEndpoints()))
+	for name := range app.GetEndpoints() {
+		if name != "..." {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedTypeNames(app *sysl.Application) []string {
+	names := make([]string, 0, len(app.GetTypes()))
+	for name := range app.GetTypes() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortLinks(links []siteLink) {
+	sort.Slice(links, func(i, j int) bool { return links[i].Name < links[j].Name })
+}
+
+func siteAttrs(attrs map[string]*sysl.Attribute) []siteAttr {
+	out := make([]siteAttr, 0, len(attrs))
+	for name, attr := range attrs {
+		out = append(out, siteAttr{Name: name, Value: siteAttrValue(attr)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func siteAttrValue(attr *sysl.Attribute) string {
+	switch x := attr.GetAttribute().(type) {
+	case *sysl.Attribute_S:
+		return x.S
+	case *sysl.Attribute_I:
+		return strconv.FormatInt(x.I, 10)
+	case *sysl.Attribute_N:
+		return strconv.FormatFloat(x.N, 'g', -1, 64)
+	case *sysl.Attribute_A:
+		elts := make([]string, 0, len(x.A.GetElt()))
+		for _, elt := range x.A.GetElt() {
+			elts = append(elts, siteAttrValue(elt))
+		}
+		return strings.Join(elts, ", ")
+	}
+	return ""
+}
+
+func (s *site) buildApp(appName string) *siteApp {
+	app := s.mod.GetApps()[appName]
+	a := &siteApp{
+		siteLink:  siteLink{Name: appName, Path: s.paths[siteAppKey(appName)]},
+		Docstring: app.GetDocstring(),
+		Attrs:     siteAttrs(app.GetAttrs()),
+	}
+	for _, epName := range siteEndpointNames(app) {
+		ep := app.GetEndpoints()[epName]
+		key := siteEndpointKey(appName, epName)
+		e := &siteEndpoint{
+			siteLink:  siteLink{Name: epName, Path: s.paths[key]},
+			App:       a.siteLink,
+			Docstring: ep.GetDocstring(),
+			Attrs:     siteAttrs(ep.GetAttrs()),
+		}
+		user := siteLink{Name: appName + " <- " + epName, Path: e.Path}
+		for _, param := range ep.GetParam() {
+			e.Params = append(e.Params, siteField{
+				Name: param.GetName(),
+				Type: template.HTML(s.typeHTML(appName, param.GetType(), user)), //nolint:gosec
+			})
+		}
+		var sb strings.Builder
+		s.writeStmts(&sb, ep.GetStmt())
+		e.Stmts = template.HTML(sb.String()) //nolint:gosec
+		s.endpoints[key] = e
+		a.Endpoints = append(a.Endpoints, e)
+	}
+	for _, typeName := range sortedTypeNames(app) {
+		t := s.buildType(a, typeName, app.GetTypes()[typeName])
+		s.types[siteTypeKey(appName, typeName)] = t
+		a.Types = append(a.Types, t)
+	}
+	return a
+}
+
+func (s *site) buildType(a *siteApp, typeName string, t *sysl.Type) *siteType {
+	st := &siteType{
+		siteLink:  siteLink{Name: typeName, Path: s.paths[siteTypeKey(a.Name, typeName)]},
+		App:       a.siteLink,
+		Docstring: t.GetDocstring(),
+		Attrs:     siteAttrs(t.GetAttrs()),
+	}
+	user := siteLink{Name: a.Name + "." + typeName, Path: st.Path}
+	var fields map[string]*sysl.Type
+	switch x := t.GetType().(type) {
+	case *sysl.Type_Tuple_:
+		st.Kind, fields = "tuple", x.Tuple.GetAttrDefs()
+	case *sysl.Type_Relation_:
+		st.Kind, fields = "relation", x.Relation.GetAttrDefs()
+	case *sysl.Type_Enum_:
+		st.Kind = "enum"
+		for item := range x.Enum.GetItems() {
+			st.Items = append(st.Items, item)
+		}
+		sort.Strings(st.Items)
+	default:
+		st.Kind = template.HTML("alias of " + s.typeHTML(a.Name, t, user)) //nolint:gosec
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		st.Fields = append(st.Fields, siteField{
+			Name: name,
+			Type: template.HTML(s.typeHTML(a.Name, fields[name], user)), //nolint:gosec
+		})
+	}
+	return st
+}
+
+// resolveTypeRef returns the app and name of the type a reference names, as
+// the type checker resolves them.
+func resolveTypeRef(ctxApp string, ref *sysl.ScopedRef) (string, string) {
+	scope := ref.GetRef()
+	if len(scope.GetPath()) == 0 {
+		return ctxApp, strings.Join(scope.GetAppname().GetPart(), ".")
+	}
+	if scope.GetAppname() == nil {
+		return ctxApp, scope.GetPath()[0]
+	}
+	return syslutil.GetAppName(scope.GetAppname()), scope.GetPath()[0]
+}
+
+// typeHTML describes a type, linking any types it refers to, which are noted
+// as used by user.
+func (s *site) typeHTML(ctxApp string, t *sysl.Type, user siteLink) string {
+	opt := ""
+	if t.GetOpt() {
+		opt = "?"
+	}
+	switch x := t.GetType().(type) {
+	case *sysl.Type_Primitive_:
+		return strings.ToLower(x.Primitive.String()) + opt
+	case *sysl.Type_TypeRef:
+		app, name := resolveTypeRef(ctxApp, x.TypeRef)
+		key := siteTypeKey(app, name)
+		if _, has := s.paths[key]; !has {
+			// Refer to the type in the context app if it is not in the app named.
+			if _, has := s.paths[siteTypeKey(ctxApp, name)]; has {
+				app, key = ctxApp, siteTypeKey(ctxApp, name)
+			}
+		}
+		if _, has := s.paths[key]; has && user.Path != s.paths[key] {
+			if s.usedBy[key] == nil {
+				s.usedBy[key] = map[siteLink]struct{}{}
+			}
+			s.usedBy[key][user] = struct{}{}
+		}
+		label := strings.Join(x.TypeRef.GetRef().GetPath(), ".")
+		if label == "" {
+			label = name
+		}
+		if app != ctxApp {
+			label = app + "." + label
+		}
+		return s.link(key, label) + opt
+	case *sysl.Type_Sequence:
+		return "sequence of " + s.typeHTML(ctxApp, x.Sequence, user) + opt
+	case *sysl.Type_Set:
+		return "set of " + s.typeHTML(ctxApp, x.Set, user) + opt
+	case *sysl.Type_List_:
+		return "list of " + s.typeHTML(ctxApp, x.List.GetType(), user) + opt
+	case *sysl.Type_Map_:
+		return "map of " + s.typeHTML(ctxApp, x.Map.GetKey(), user) + " to " +
+			s.typeHTML(ctxApp, x.Map.GetValue(), user) + opt
+	case *sysl.Type_OneOf_:
+		types := make([]string, 0, len(x.OneOf.GetType()))
+		for _, t := range x.OneOf.GetType() {
+			types = append(types, s.typeHTML(ctxApp, t, user))
+		}
+		return "one of " + strings.Join(types, " | ") + opt
+	case *sysl.Type_Tuple_:
+		return "tuple" + opt
+	case *sysl.Type_Relation_:
+		return "relation" + opt
+	case *sysl.Type_Enum_:
+		return "enum" + opt
+	}
+	return ""
+}
+
+// writeStmts writes the statements of an endpoint as nested lists, linking
+// the endpoints called.
+func (s *site) writeStmts(sb *strings.Builder, stmts []*sysl.Statement) {
+	if len(stmts) == 0 {
+		return
+	}
+	sb.WriteString("<ul>\n")
+	for _, stmt := range stmts {
+		sb.WriteString("<li>")
+		var nested []*sysl.Statement
+		switch x := stmt.GetStmt().(type) {
+		case *sysl.Statement_Call:
+			target := syslutil.GetAppName(x.Call.GetTarget())
+			sb.WriteString(s.link(siteEndpointKey(target, x.Call.GetEndpoint()), target+" <- "+x.Call.GetEndpoint()))
+		case *sysl.Statement_Action:
+			sb.WriteString(html.EscapeString(x.Action.GetAction()))
+		case *sysl.Statement_Ret:
+			sb.WriteString("return " + html.EscapeString(x.Ret.GetPayload()))
+		case *sysl.Statement_Cond:
+			sb.WriteString("if " + html.EscapeString(x.Cond.GetTest()))
+			nested = x.Cond.GetStmt()
+		case *sysl.Statement_Loop:
+			sb.WriteString(strings.ToLower(x.Loop.GetMode().String()) + " " + html.EscapeString(x.Loop.GetCriterion()))
+			nested = x.Loop.GetStmt()
+		case *sysl.Statement_LoopN:
+			sb.WriteString("loop " + strconv.Itoa(int(x.LoopN.GetCount())) + " times")
+			nested = x.LoopN.GetStmt()
+		case *sysl.Statement_Foreach:
+			sb.WriteString("for each " + html.EscapeString(x.Foreach.GetCollection()))
+			nested = x.Foreach.GetStmt()
+		case *sysl.Statement_Group:
+			sb.WriteString(html.EscapeString(x.Group.GetTitle()))
+			nested = x.Group.GetStmt()
+		case *sysl.Statement_Alt:
+			sb.WriteString("one of\n<ul>\n")
+			for _, choice := range x.Alt.GetChoice() {
+				sb.WriteString("<li>" + html.EscapeString(choice.GetCond()))
+				s.writeStmts(sb, choice.GetStmt())
+				sb.WriteString("</li>\n")
+			}
+			sb.WriteString("</ul>")
+		}
+		s.writeStmts(sb, nested)
+		sb.WriteString("</li>\n")
+	}
+	sb.WriteString("</ul>\n")
+}
+
+// linkCalls links the callers and callees of each app and endpoint.
+func (s *site) linkCalls() {
+	apps := map[string]*siteApp{}
+	for _, a := range s.Apps {
+		apps[a.Name] = a
+	}
+	calls := map[string]syslutil.StrSet{}
+	callers := map[string]syslutil.StrSet{}
+	for _, dep := range integrationdiagram.Dependencies(s.mod, syslutil.MakeStrSet()) {
+		from, to := dep.Self, dep.Target
+		if calls[from.Name] == nil {
+			calls[from.Name] = syslutil.MakeStrSet()
+		}
+		if callers[to.Name] == nil {
+			callers[to.Name] = syslutil.MakeStrSet()
+		}
+		calls[from.Name].Insert(to.Name)
+		callers[to.Name].Insert(from.Name)
+		if e := s.endpoints[siteEndpointKey(to.Name, to.Endpoint)]; e != nil {
+			caller := siteLink{
+				Name: from.Name + " <- " + from.Endpoint,
+				Path: s.paths[siteEndpointKey(from.Name, from.Endpoint)],
+			}
+			if !containsLink(e.Callers, caller) {
+				e.Callers = append(e.Callers, caller)
+				sortLinks(e.Callers)
+			}
+		}
+	}
+	for name, a := range apps {
+		for _, callee := range calls[name].ToSortedSlice() {
+			a.Calls = append(a.Calls, siteLink{Name: callee, Path: s.paths[siteAppKey(callee)]})
+		}
+		for _, caller := range callers[name].ToSortedSlice() {
+			a.Callers = append(a.Callers, siteLink{Name: caller, Path: s.paths[siteAppKey(caller)]})
+		}
+	}
+}
+
+func containsLink(links []siteLink, link siteLink) bool {
+	for _, l := range links {
+		if l == link {
+			return true
+		}
+	}
+	return false
+}
+
+// addDiagrams adds a sequence diagram to each endpoint that calls others, and
+// a data diagram to each app with types.
+func (s *site) addDiagrams(logger *logrus.Logger) {
+	for _, a := range s.Apps {
+		app := s.mod.GetApps()[a.Name]
+		for _, e := range a.Endpoints {
+			if !makesCalls(app.GetEndpoints()[e.Name].GetStmt()) {
+				continue
+			}
+			sd := &sequencediagram.SequenceDiagParam{
+				AppLabeler:      diagrams.ConstructFormatParser(app.GetAttrs()["appfmt"].GetS(), "%(appname)"),
+				EndpointLabeler: diagrams.ConstructFormatParser(app.GetAttrs()["epfmt"].GetS(), "%(epname)"),
+				Endpoints:       []string{a.Name + " <- " + e.Name},
+				Title:           a.Name + " <- " + e.Name,
+				Blackboxes:      map[string]*sequencediagram.Upto{},
+			}
+			e.Diagram = &siteDiagram{Path: strings.TrimSuffix(e.Path, ".html") + ".svg"}
+			uml, err := sequencediagram.GenerateSequenceDiag(s.mod, sd, logger)
+			if err != nil {
+				e.Diagram.Error = err.Error()
+				continue
+			}
+			e.Diagram.Source = uml
+			s.diagrams[e.Diagram.Path] = e.Diagram
+		}
+		if len(a.Types) > 0 {
+			var sb strings.Builder
+			v := datamodeldiagram.MakeDataModelView(diagrams.ConstructFormatParser("", "%(classname)"), s.mod, &sb, a.Name, "")
+			a.Diagram = &siteDiagram{
+				Path:   strings.TrimSuffix(a.Path, ".html") + ".svg",
+				Source: v.GenerateDataView(&datamodeldiagram.DataModelParam{Mod: s.mod, App: app, Title: a.Name}),
+			}
+			s.diagrams[a.Diagram.Path] = a.Diagram
+		}
+	}
+}
+
+// makesCalls reports whether any of the statements, or those nested in them,
+// is a call.
+func makesCalls(stmts []*sysl.Statement) bool {
+	for _, stmt := range stmts {
+		var nested []*sysl.Statement
+		switch x := stmt.GetStmt().(type) {
+		case *sysl.Statement_Call:
+			return true
+		case *sysl.Statement_Cond:
+			nested = x.Cond.GetStmt()
+		case *sysl.Statement_Loop:
+			nested = x.Loop.GetStmt()
+		case *sysl.Statement_LoopN:
+			nested = x.LoopN.GetStmt()
+		case *sysl.Statement_Foreach:
+			nested = x.Foreach.GetStmt()
+		case *sysl.Statement_Group:
+			nested = x.Group.GetStmt()
+		case *sysl.Statement_Alt:
+			for _, choice := range x.Alt.GetChoice() {
+				nested = append(nested, choice.GetStmt()...)
+			}
+		}
+		if makesCalls(nested) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchIndex returns an entry for each page of the site.
+func (s *site) searchIndex() []siteEntry {
+	entries := []siteEntry{}
+	for _, a := range s.Apps {
+		entries = append(entries, siteEntry{Name: a.Name, Kind: "app", Path: a.Path, Text: searchText(a.Docstring, a.Attrs)})
+		for _, e := range a.Endpoints {
+			entries = append(entries, siteEntry{
+				Name: a.Name + " <- " + e.Name, Kind: "endpoint", Path: e.Path, Text: searchText(e.Docstring, e.Attrs),
+			})
+		}
+		for _, t := range a.Types {
+			entries = append(entries, siteEntry{
+				Name: a.Name + "." + t.Name, Kind: "type", Path: t.Path, Text: searchText(t.Docstring, t.Attrs),
+			})
+		}
+	}
+	return entries
+}
+
+// searchText is the text searched besides the name: the docstring and the
+// description attribute, which most models use in place of a docstring.
+func searchText(docstring string, attrs []siteAttr) string {
+	for _, attr := range attrs {
+		if attr.Name == "description" {
+			return strings.TrimSpace(docstring + " " + attr.Value)
+		}
+	}
+	return docstring
+}
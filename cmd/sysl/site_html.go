@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/diagrams"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+const siteLayout = `{{define "layout"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}{{with .Site.Title}} - {{.}}{{end}}</title>
+<link rel="stylesheet" href="style.css">
+<script src="search.js"></script>
+</head>
+<body>
+<header>
+<a class="home" href="index.html">{{or .Site.Title "Sysl model"}}</a>
+<input id="search" type="search" placeholder="Search apps, endpoints and types" oninput="syslSearch(this.value)">
+<ul id="results"></ul>
+</header>
+<main>
+{{template "content" .}}
+</main>
+</body>
+</html>
+{{end}}
+{{define "doc"}}{{with .}}<p class="doc">{{.}}</p>{{end}}{{end}}
+{{define "attrs"}}{{if .}}<table class="attrs">
+{{range .}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>{{end}}{{end}}
+{{define "links"}}<ul>
+{{range .}}<li>{{if .Path}}<a href="{{.Path}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</li>
+{{end}}</ul>{{end}}
+{{define "fields"}}<table class="fields">
+{{range .}}<tr><th>{{.Name}}</th><td>{{.Type}}</td></tr>
+{{end}}</table>{{end}}
+{{define "diagram"}}{{with .}}<section class="diagram">
+{{if .Error}}<details><summary>The diagram could not be rendered: {{.Error}}</summary>
+<pre>{{.Source}}</pre></details>{{else}}<img src="{{.Path}}" alt="diagram">{{end}}
+</section>{{end}}{{end}}`
+
+const siteIndex = `{{define "content"}}<h1>{{or .Site.Title "Sysl model"}}</h1>
+<ul class="apps">
+{{range .Site.Apps}}<li><a href="{{.Path}}">{{.Name}}</a>{{with .Docstring}} <span class="doc">{{.}}</span>{{end}}</li>
+{{end}}</ul>
+{{end}}`
+
+const siteAppPage = `{{define "content"}}{{with .App}}<h1>{{.Name}}</h1>
+{{template "doc" .Docstring}}
+{{template "attrs" .Attrs}}
+{{if .Endpoints}}<h2>Endpoints</h2>{{template "links" .Endpoints}}{{end}}
+{{if .Types}}<h2>Types</h2>{{template "links" .Types}}{{end}}
+{{if .Calls}}<h2>Calls</h2>{{template "links" .Calls}}{{end}}
+{{if .Callers}}<h2>Called by</h2>{{template "links" .Callers}}{{end}}
+{{template "diagram" .Diagram}}
+{{end}}{{end}}`
+
+const siteEndpointPage = `{{define "content"}}{{with .Endpoint}}
+<h1><a href="{{.App.Path}}">{{.App.Name}}</a> &lt;- {{.Name}}</h1>
+{{template "doc" .Docstring}}
+{{template "attrs" .Attrs}}
+{{if .Params}}<h2>Parameters</h2>{{template "fields" .Params}}{{end}}
+{{if .Stmts}}<h2>Statements</h2>
+<div class="stmts">{{.Stmts}}</div>{{end}}
+{{if .Callers}}<h2>Called by</h2>{{template "links" .Callers}}{{end}}
+{{template "diagram" .Diagram}}
+{{end}}{{end}}`
+
+const siteTypePage = `{{define "content"}}{{with .Type}}<h1><a href="{{.App.Path}}">{{.App.Name}}</a>.{{.Name}}</h1>
+<p class="kind">{{.Kind}}</p>
+{{template "doc" .Docstring}}
+{{template "attrs" .Attrs}}
+{{if .Fields}}<h2>Fields</h2>{{template "fields" .Fields}}{{end}}
+{{if .Items}}<h2>Items</h2><ul>{{range .Items}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .UsedBy}}<h2>Used by</h2>{{template "links" .UsedBy}}{{end}}
+{{end}}{{end}}`
+
+const siteStyle = `body { font-family: sans-serif; margin: 0; color: #222; }
+header { background: #2b4a6f; padding: 0.5em 1em; position: relative; }
+header a.home { color: white; font-weight: bold; text-decoration: none; margin-right: 1em; }
+#search { width: 24em; padding: 0.3em; }
+#results { position: absolute; background: white; list-style: none; margin: 0; padding: 0;
+  box-shadow: 0 2px 6px rgba(0, 0, 0, 0.3); z-index: 1; }
+#results li { padding: 0.3em 1em; }
+#results .kind { color: #888; margin-left: 1em; font-size: smaller; }
+main { padding: 1em 2em; }
+.doc { color: #555; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 0.2em 1em 0.2em 0; vertical-align: top; }
+.diagram img { max-width: 100%; }
+`
+
+const siteSearch = `function syslSearch(query) {
+  var results = document.getElementById("results");
+  results.innerHTML = "";
+  var terms = query.toLowerCase().split(/\s+/).filter(function (t) { return t; });
+  if (terms.length === 0) {
+    return;
+  }
+  syslSearchIndex.filter(function (e) {
+    var text = (e.name + " " + e.kind + " " + e.text).toLowerCase();
+    return terms.every(function (t) { return text.indexOf(t) >= 0; });
+  }).slice(0, 50).forEach(function (e) {
+    var li = document.createElement("li");
+    var a = document.createElement("a");
+    a.href = e.path;
+    a.textContent = e.name;
+    var kind = document.createElement("span");
+    kind.className = "kind";
+    kind.textContent = e.kind;
+    li.appendChild(a);
+    li.appendChild(kind);
+    results.appendChild(li);
+  });
+}
+`
+
+//nolint:gochecknoglobals
+var siteTemplates = map[string]*template.Template{
+	"index":    template.Must(template.Must(template.New("layout").Parse(siteLayout)).Parse(siteIndex)),
+	"app":      template.Must(template.Must(template.New("layout").Parse(siteLayout)).Parse(siteAppPage)),
+	"endpoint": template.Must(template.Must(template.New("layout").Parse(siteLayout)).Parse(siteEndpointPage)),
+	"type":     template.Must(template.Must(template.New("layout").Parse(siteLayout)).Parse(siteTypePage)),
+}
+
+// sitePage is what the templates of the pages are executed with.
+type sitePage struct {
+	Site     *site
+	Title    string
+	App      *siteApp
+	Endpoint *siteEndpoint
+	Type     *siteType
+}
+
+// write renders the diagrams of the site with p and writes it to dir. Pages
+// show the source of diagrams that could not be rendered instead.
+func (s *site) write(dir string, fs afero.Fs, p *plantumlmixin) error {
+	if err := fs.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	if len(s.diagrams) > 0 {
+		m := map[string]string{}
+		for path, d := range s.diagrams {
+			m[filepath.Join(dir, path)] = d.Source
+		}
+		r, err := p.renderer(fs)
+		if err != nil {
+			return err
+		}
+		for output, err := range diagrams.OutputAll(m, r, p.jobs, fs) {
+			s.diagrams[filepath.Base(output)].Error = err.Error()
+		}
+	}
+
+	index, err := json.Marshal(s.searchIndex())
+	if err != nil {
+		return err
+	}
+	files := map[string]string{
+		"style.css": siteStyle,
+		"search.js": "var syslSearchIndex = " + string(index) + ";\n\n" + siteSearch,
+	}
+	pages := map[string]sitePage{"index.html": {Site: s, Title: "Index"}}
+	for _, a := range s.Apps {
+		pages[a.Path] = sitePage{Site: s, Title: a.Name, App: a}
+		for _, e := range a.Endpoints {
+			pages[e.Path] = sitePage{Site: s, Title: a.Name + " <- " + e.Name, Endpoint: e}
+		}
+		for _, t := range a.Types {
+			pages[t.Path] = sitePage{Site: s, Title: a.Name + "." + t.Name, Type: t}
+		}
+	}
+	for path, page := range pages {
+		var sb strings.Builder
+		if err := siteTemplates[page.kind()].ExecuteTemplate(&sb, "layout", page); err != nil {
+			return errors.Wrapf(err, "rendering %q", path)
+		}
+		files[path] = sb.String()
+	}
+
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		if err := afero.WriteFile(fs, path, []byte(content), os.ModePerm); err != nil {
+			return errors.Wrapf(err, "writing %q", path)
+		}
+	}
+	return nil
+}
+
+func (p sitePage) kind() string {
+	switch {
+	case p.App != nil:
+		return "app"
+	case p.Endpoint != nil:
+		return "endpoint"
+	case p.Type != nil:
+		return "type"
+	}
+	return "index"
+}
+
+// siteHandler serves a site written to the root of fs.
+func siteHandler(fs afero.Fs) http.Handler {
+	return http.FileServer(afero.NewHttpFs(fs).Dir("/"))
+}
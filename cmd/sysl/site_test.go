@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/diagrams"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadSite(t *testing.T, withDiagrams bool) *site {
+	logger, _ := test.NewNullLogger()
+	mod, _, err := LoadSyslModule(testDir, "site.sysl", afero.NewOsFs(), logger)
+	require.NoError(t, err)
+	return buildSite(mod, "Shop model", withDiagrams, logger)
+}
+
+func TestBuildSite(t *testing.T) {
+	t.Parallel()
+
+	s := loadSite(t, false)
+	require.Len(t, s.Apps, 2)
+	orders, shop := s.Apps[0], s.Apps[1]
+	assert.Equal(t, siteLink{Name: "Orders", Path: "app-Orders.html"}, orders.siteLink)
+	assert.Equal(t, []siteLink{{Name: "Orders", Path: "app-Orders.html"}}, shop.Calls)
+	assert.Equal(t, []siteLink{{Name: "Shop", Path: "app-Shop.html"}}, orders.Callers)
+
+	require.Len(t, shop.Endpoints, 1)
+	checkout := shop.Endpoints[0]
+	assert.Equal(t, "ep-Shop-Checkout.html", checkout.Path)
+	assert.Equal(t, `<a href="type-Orders-Order.html">Orders.Order</a>`, string(checkout.Params[0].Type))
+	assert.Contains(t, string(checkout.Stmts), `<a href="ep-Orders-Ship.html">Orders &lt;- Ship</a>`)
+	caller := siteLink{Name: "Shop <- Checkout", Path: checkout.Path}
+	assert.Equal(t, []siteLink{caller}, s.endpoints[siteEndpointKey("Orders", "Place")].Callers)
+
+	order := s.types[siteTypeKey("Orders", "Order")]
+	require.NotNil(t, order)
+	assert.Equal(t, []siteLink{caller}, order.UsedBy)
+	fields := map[string]string{}
+	for _, f := range order.Fields {
+		fields[f.Name] = string(f.Type)
+	}
+	assert.Equal(t, map[string]string{
+		"id":       "int",
+		"lines":    `set of <a href="type-Orders-Line.html">Line</a>`,
+		"customer": `<a href="type-Orders-Customer.html">Customer</a>?`,
+	}, fields)
+	assert.Empty(t, s.diagrams)
+}
+
+func TestBuildSiteWithDiagrams(t *testing.T) {
+	t.Parallel()
+
+	s := loadSite(t, true)
+	paths := []string{}
+	for path := range s.diagrams {
+		paths = append(paths, path)
+	}
+	assert.ElementsMatch(t, []string{"ep-Shop-Checkout.svg", "app-Orders.svg"}, paths)
+}
+
+func TestSiteWrite(t *testing.T) {
+	t.Parallel()
+
+	s := loadSite(t, true)
+	fs := afero.NewMemMapFs()
+	p := &plantumlmixin{value: diagrams.BuiltinRenderer, jobs: 1, noCache: true}
+	require.NoError(t, s.write("/", fs, p))
+
+	for _, path := range []string{
+		"/index.html", "/style.css", "/search.js",
+		"/app-Shop.html", "/app-Orders.html", "/ep-Shop-Checkout.html", "/ep-Shop-Checkout.svg",
+		"/type-Orders-Order.html", "/type-Orders-Line.html", "/type-Orders-Customer.html",
+	} {
+		exists, err := afero.Exists(fs, path)
+		require.NoError(t, err)
+		assert.True(t, exists, path)
+	}
+
+	// The builtin renderer only draws sequence diagrams, so the data diagram
+	// is shown as its source.
+	page, err := afero.ReadFile(fs, "/app-Orders.html")
+	require.NoError(t, err)
+	assert.Contains(t, string(page), "could not be rendered")
+	assert.NotContains(t, string(page), `<img src="app-Orders.svg"`)
+
+	index, err := afero.ReadFile(fs, "/search.js")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(index), "var syslSearchIndex = "))
+	assert.Contains(t, string(index), `"name":"Shop","kind":"app","path":"app-Shop.html","text":"The shop front."`)
+}
+
+func TestSiteHandler(t *testing.T) {
+	t.Parallel()
+
+	s := loadSite(t, false)
+	fs := afero.NewMemMapFs()
+	require.NoError(t, s.write("/", fs, &plantumlmixin{}))
+
+	srv := httptest.NewServer(siteHandler(fs))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/type-Orders-Order.html")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/anz-bank/sysl/pkg/syslutil"
@@ -17,22 +19,6 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-const currentWorkingDirectory = "."
-
-type folderTestStructure struct {
-	name,
-	module,
-	root,
-	expectedRoot,
-	rootMarkerPath string
-	rootFound bool
-	structure folderStructure
-}
-
-type folderStructure struct {
-	folders, files []string
-}
-
 func assertLogEntry(
 	t *testing.T,
 	entry *logrus.Entry,
@@ -212,7 +198,7 @@ func TestMain2SeqdiagWithNonsensicalOutput(t *testing.T) {
 	)
 	assert.NotEqual(t, 0, rc)
 	assertLogEntry(t, hook.LastEntry(), logrus.ErrorLevel,
-		`extension must be svg, png or uml, not "zzz"`)
+		`/out.zzz: extension must be svg, png, uml, mmd or dot, not "zzz"`)
 }
 
 func TestMain2WithBlackboxParams(t *testing.T) {
@@ -428,6 +414,117 @@ func TestMain2WithGenerateIntegrations(t *testing.T) {
 	syslutil.AssertFsHasExactly(t, memFs, out)
 }
 
+func TestMain2WithDeps(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	memFs, fs := syslutil.WriteToMemOverlayFs("/")
+	out := "/deps.json"
+	rc := main2(
+		[]string{
+			"sysl",
+			"deps",
+			"--root", testDir,
+			"-o", out,
+			"indirect_1.sysl",
+		},
+		fs, logger, main3,
+	)
+	assert.Zero(t, rc)
+	syslutil.AssertFsHasExactly(t, memFs, out)
+}
+
+func TestMain2WithC4(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	memFs, fs := syslutil.WriteToMemOverlayFs("/")
+	out := "/c4.dsl"
+	rc := main2(
+		[]string{
+			"sysl",
+			"c4",
+			"--root", testDir,
+			"--view", "container",
+			"--system", "Bank",
+			"-o", out,
+			"c4.sysl",
+		},
+		fs, logger, main3,
+	)
+	assert.Zero(t, rc)
+	syslutil.AssertFsHasExactly(t, memFs, out)
+}
+
+func TestMain2WithAutoSequenceDiagrams(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	memFs, fs := syslutil.WriteToMemOverlayFs("/")
+	rc := main2(
+		[]string{
+			"sysl",
+			"sd",
+			"--root", testDir,
+			"--auto",
+			"--depth", "1",
+			"-o", "/sd/%(appname)/%(epname).mmd",
+			"--index", "/sd/index.md",
+			"sequence_diagram_auto.sysl",
+		},
+		fs, logger, main3,
+	)
+	assert.Zero(t, rc)
+	syslutil.AssertFsHasExactly(t, memFs,
+		"/sd/index.md", "/sd/Api/GetItems.mmd", "/sd/Db/Query.mmd", "/sd/Web/Browse.mmd")
+}
+
+func TestMain2WithLineage(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	memFs, fs := syslutil.WriteToMemOverlayFs("/")
+	out := "/lineage.json"
+	rc := main2(
+		[]string{
+			"sysl",
+			"lineage",
+			"--root", testDir,
+			"--type", "Bank.Customer",
+			"-o", out,
+			"lineage.sysl",
+		},
+		fs, logger, main3,
+	)
+	assert.Zero(t, rc)
+	syslutil.AssertFsHasExactly(t, memFs, out)
+}
+
+func TestMain2WithSite(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	memFs, fs := syslutil.WriteToMemOverlayFs("/")
+	rc := main2(
+		[]string{
+			"sysl",
+			"site",
+			"--root", testDir,
+			"--no-diagrams",
+			"-o", "/site",
+			"site.sysl",
+		},
+		fs, logger, main3,
+	)
+	assert.Zero(t, rc)
+	syslutil.AssertFsHasExactly(t, memFs,
+		"/site/index.html", "/site/style.css", "/site/search.js",
+		"/site/app-Orders.html", "/site/app-Shop.html",
+		"/site/ep-Orders-Place.html", "/site/ep-Orders-Ship.html", "/site/ep-Shop-Checkout.html",
+		"/site/type-Orders-Customer.html", "/site/type-Orders-Line.html", "/site/type-Orders-Order.html",
+	)
+}
+
 func TestMain2WithGenerateCode(t *testing.T) {
 	t.Parallel()
 
@@ -558,6 +655,31 @@ func TestMain2WithJSONModeStdout(t *testing.T) {
 	syslutil.AssertFsHasExactly(t, memFs)
 }
 
+func TestMain2WithCompiledModule(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	memFs, fs := syslutil.WriteToMemOverlayFs("/")
+	pb := func(mode, out, module string) {
+		args := []string{"sysl", "pb", "--mode", mode, "-o", out, module}
+		require.Equal(t, 0, main2(args, fs, logger, main3), args)
+	}
+	pb("json", "/want.json", filepath.Join(testDir, "call.sysl"))
+	want, err := afero.ReadFile(memFs, "/want.json")
+	require.NoError(t, err)
+
+	for mode, ext := range map[string]string{"pb": ".pb", "json": ".json", "textpb": ".textpb"} {
+		pb(mode, "/call"+ext, filepath.Join(testDir, "call.sysl"))
+		pb("json", "/got.json", "/call"+ext)
+		got, err := afero.ReadFile(memFs, "/got.json")
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(got), mode)
+	}
+
+	require.NoError(t, afero.WriteFile(fs, "/bad.pb", []byte("not a module"), 0644))
+	assert.Equal(t, 1, main2([]string{"sysl", "pb", "/bad.pb"}, fs, logger, main3))
+}
+
 func TestMain2WithEmptySdParams(t *testing.T) {
 	t.Parallel()
 
@@ -683,7 +805,7 @@ func TestSwaggerExportTargetDir(t *testing.T) {
 	logger, _ := test.NewNullLogger()
 	tmp1, err := ioutil.TempDir("", "tmp1")
 	assert.Nil(t, err)
-	main2([]string{"sysl", "export", "-o", tmp1 + "/SIMPLE_SWAGGER_EXAMPLE1.yaml", "-a", "testapp",
+	main2([]string{"sysl", "--no-cache", "export", "-o", tmp1 + "/SIMPLE_SWAGGER_EXAMPLE1.yaml", "-a", "testapp",
 		syslDir + "exporter/test-data/SIMPLE_SWAGGER_EXAMPLE.sysl"}, afero.NewOsFs(), logger, main3)
 	_, err = ioutil.ReadFile(tmp1 + "/SIMPLE_SWAGGER_EXAMPLE1.yaml")
 	assert.Nil(t, err)
@@ -695,7 +817,7 @@ func TestSwaggerExportJson(t *testing.T) {
 	logger, _ := test.NewNullLogger()
 	tmp2, err := ioutil.TempDir("", "tmp2")
 	assert.Nil(t, err)
-	main2([]string{"sysl", "export", "-o", tmp2 + "/SIMPLE_SWAGGER_EXAMPLE2.json",
+	main2([]string{"sysl", "--no-cache", "export", "-o", tmp2 + "/SIMPLE_SWAGGER_EXAMPLE2.json",
 		"-a", "testapp", syslDir + "exporter/test-data/SIMPLE_SWAGGER_EXAMPLE.sysl"}, afero.NewOsFs(), logger, main3)
 	_, err = ioutil.ReadFile(tmp2 + "/SIMPLE_SWAGGER_EXAMPLE2.json")
 	assert.Nil(t, err)
@@ -714,7 +836,7 @@ func TestSwaggerExportInvalid(t *testing.T) {
 func TestSwaggerAppExportNoDir(t *testing.T) {
 	t.Parallel()
 	logger, _ := test.NewNullLogger()
-	main2([]string{"sysl", "export", "-o", "out/%(appname).yaml",
+	main2([]string{"sysl", "--no-cache", "export", "-o", "out/%(appname).yaml",
 		syslDir + "exporter/test-data/multiple/SIMPLE_SWAGGER_EXAMPLE_MULTIPLE.sysl"}, afero.NewOsFs(), logger, main3)
 	for _, file := range []string{"out/single.yaml", "out/multiple.yaml"} {
 		_, err := ioutil.ReadFile(file)
@@ -728,7 +850,7 @@ func TestSwaggerAppExportDirExists(t *testing.T) {
 	logger, _ := test.NewNullLogger()
 	tmp3, err := ioutil.TempDir("", "tmp3")
 	assert.Nil(t, err)
-	main2([]string{"sysl", "export", "-o", tmp3 + "/%(appname).yaml",
+	main2([]string{"sysl", "--no-cache", "export", "-o", tmp3 + "/%(appname).yaml",
 		syslDir + "exporter/test-data/multiple/SIMPLE_SWAGGER_EXAMPLE_MULTIPLE.sysl"}, afero.NewOsFs(), logger, main3)
 	for _, file := range []string{tmp3 + "/single.yaml", tmp3 + "/multiple.yaml"} {
 		_, err := ioutil.ReadFile(file)
@@ -737,163 +859,6 @@ func TestSwaggerAppExportDirExists(t *testing.T) {
 	os.RemoveAll(tmp3)
 }
 
-func TestHandleProjectRoot(t *testing.T) {
-	successfulTest := folderStructure{
-		folders: []string{
-			"./SuccessfulTest/path/to/module",
-			fmt.Sprintf("./SuccessfulTest/%s", syslRootMarker),
-			"./SuccessfulTest/path/to/another/module",
-			fmt.Sprintf("./SuccessfulTest/path/to/another/%s", syslRootMarker),
-		},
-		files: []string{
-			"./SuccessfulTest/path/to/module/test.sysl",
-			"./SuccessfulTest/test2.sysl",
-			"./SuccessfulTest/path/to/another/module/test3.sysl",
-		},
-	}
-
-	definedRootNoMarker := folderStructure{
-		folders: []string{
-			"./DefinedRootAndSyslRootUndefinedTest/path/to/module/",
-		},
-		files: []string{
-			"./DefinedRootAndSyslRootUndefinedTest/path/to/module/test.sysl",
-		},
-	}
-
-	definedRootFlagAndMarkerFound := folderStructure{
-		folders: []string{
-			"./DefinedRootAndSyslRootDefinedTest/path/to/module/",
-			fmt.Sprintf("./DefinedRootAndSyslRootDefinedTest/path/%s", syslRootMarker),
-		},
-		files: []string{
-			"./DefinedRootAndSyslRootDefinedTest/path/to/module/test.sysl",
-		},
-	}
-
-	undefinedRoot := folderStructure{
-		folders: []string{
-			"./UndefinedRootAndUndefinedSyslRoot/",
-		},
-		files: []string{
-			"./UndefinedRootAndUndefinedSyslRoot/test.sysl",
-		},
-	}
-	systemRoot := syslutil.MustAbsolute(t, string(os.PathSeparator))
-	tests := []folderTestStructure{
-		{
-			name:         "Successful test: finding a root marker",
-			root:         "",
-			module:       successfulTest.files[0],
-			structure:    successfulTest,
-			expectedRoot: syslutil.MustAbsolute(t, "SuccessfulTest"),
-			rootFound:    true,
-		},
-		{
-			name:         "Successful test: finding a root marker in the same directory as the module",
-			root:         "",
-			module:       successfulTest.files[1],
-			structure:    successfulTest,
-			expectedRoot: syslutil.MustAbsolute(t, "SuccessfulTest"),
-			rootFound:    true,
-		},
-		{
-			name:         "Successful test: finding the closest root marker",
-			root:         "",
-			module:       successfulTest.files[2],
-			structure:    successfulTest,
-			expectedRoot: syslutil.MustAbsolute(t, "SuccessfulTest/path/to/another"),
-			rootFound:    true,
-		},
-		{
-			name: "Root flag is defined and root marker does not exist",
-			root: "DefinedRootAndSyslRootUndefinedTest/path/",
-			module: syslutil.MustRelative(t, "DefinedRootAndSyslRootUndefinedTest/path/",
-				definedRootNoMarker.files[0]),
-			structure:    definedRootNoMarker,
-			expectedRoot: "DefinedRootAndSyslRootUndefinedTest/path/",
-			rootFound:    true,
-		},
-		{
-			name:         "Defined relative root",
-			root:         currentWorkingDirectory,
-			module:       filepath.Clean(definedRootNoMarker.files[0]),
-			structure:    definedRootNoMarker,
-			expectedRoot: currentWorkingDirectory,
-			rootFound:    true,
-		},
-		{
-			root:         systemRoot,
-			name:         "Defined absolute path root",
-			module:       syslutil.MustAbsolute(t, definedRootNoMarker.files[0]),
-			structure:    definedRootNoMarker,
-			expectedRoot: systemRoot,
-			rootFound:    true,
-		},
-		{
-			name:         "Defined relative root with absolute module path rooted at root",
-			root:         currentWorkingDirectory,
-			module:       filepath.Join(systemRoot, filepath.Clean(definedRootNoMarker.files[0])),
-			structure:    definedRootNoMarker,
-			expectedRoot: currentWorkingDirectory,
-			rootFound:    true,
-		},
-		{
-			name:           "Defined root flag and root",
-			root:           currentWorkingDirectory,
-			module:         syslutil.MustRelative(t, currentWorkingDirectory, definedRootFlagAndMarkerFound.files[0]),
-			structure:      definedRootFlagAndMarkerFound,
-			expectedRoot:   currentWorkingDirectory,
-			rootMarkerPath: syslutil.MustAbsolute(t, "./DefinedRootAndSyslRootDefinedTest/path/"),
-			rootFound:      true,
-		},
-		{
-			name:           "Defined root flag and root marker with absolute path module rooted at root",
-			root:           "./DefinedRootAndSyslRootDefinedTest/",
-			module:         "/path/to/module/test.sysl",
-			structure:      definedRootFlagAndMarkerFound,
-			expectedRoot:   "./DefinedRootAndSyslRootDefinedTest/",
-			rootMarkerPath: syslutil.MustAbsolute(t, "./DefinedRootAndSyslRootDefinedTest/path/"),
-			rootFound:      true,
-		},
-		{
-			name:         "Root is not defined",
-			root:         "",
-			module:       undefinedRoot.files[0],
-			structure:    undefinedRoot,
-			expectedRoot: filepath.Dir(undefinedRoot.files[0]),
-			rootFound:    false,
-		},
-	}
-
-	for _, ts := range tests {
-		ts := ts
-		t.Run(ts.name, func(t *testing.T) {
-			t.Parallel()
-
-			logger, _ := test.NewNullLogger()
-			fs := afero.NewMemMapFs()
-			syslutil.BuildFolderTest(t, fs, ts.structure.folders, ts.structure.files)
-
-			config := newProjectConfiguration()
-			err := config.configureProject(ts.root, ts.module, fs, logger)
-
-			require.Equal(t, ts.rootFound, config.rootIsFound)
-			require.NoError(t, err)
-			require.Equal(t, ts.expectedRoot, config.root)
-			require.Equal(t, ts.getExpectedModule(t), config.module)
-		})
-	}
-}
-
-func (ts folderTestStructure) getExpectedModule(t *testing.T) string {
-	// if root is defined, expected root and root param is the same and module is not changed
-	if ts.expectedRoot == ts.root {
-		return ts.module
-	}
-	return syslutil.MustRelative(t, ts.expectedRoot, ts.module)
-}
-
 func TestCodegenGrammarImportDefOut(t *testing.T) {
 	t.Parallel()
 	logger, _ := test.NewNullLogger()
@@ -911,3 +876,76 @@ func TestCodegenGrammarImport(t *testing.T) {
 		"-o", "out.sysl", "-a", "go"}, fs, logger, main3)
 	syslutil.AssertFsHasExactly(t, memFs, "/out.sysl")
 }
+
+func TestReplLoadsModuleAndHistory(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	memFs, fs := syslutil.WriteToMemOverlayFs("../../tests")
+	require.NoError(t, afero.WriteFile(memFs, "/history", []byte("1 + 1\n"), 0600))
+
+	var out bytes.Buffer
+	cmd := &replCmd{
+		module:  "eval_error.sysl",
+		history: "/history",
+		input:   strings.NewReader(":apps\n:history\n"),
+		output:  &out,
+	}
+	require.NoError(t, cmd.Execute(ExecuteArgs{Root: "/", Filesystem: fs, Logger: logger}))
+
+	assert.Contains(t, out.String(), "* ErrorApp\n")
+	assert.Contains(t, out.String(), "   1  1 + 1\n   2  :apps\n")
+	history, err := afero.ReadFile(memFs, "/history")
+	require.NoError(t, err)
+	assert.Equal(t, "1 + 1\n:apps\n:history\n", string(history))
+}
+
+type loadAppArgs struct {
+	root   string
+	models string
+}
+
+func TestLoadAppReturnError(t *testing.T) {
+	t.Parallel()
+
+	args := loadAppArgs{
+		"demo/simple/", "",
+	}
+	_, fs := syslutil.WriteToMemOverlayFs(args.root)
+	logger, _ := test.NewNullLogger()
+	_, _, err := LoadSyslModule(args.root, args.models, fs, logger)
+	assert.Error(t, err)
+}
+
+func TestLoadApp(t *testing.T) {
+	t.Parallel()
+
+	args := loadAppArgs{
+		testDir, "sequence_diagram_test.sysl",
+	}
+	memFs, fs := syslutil.WriteToMemOverlayFs("/")
+	logger, _ := test.NewNullLogger()
+	mod, name, err := LoadSyslModule(args.root, args.models, fs, logger)
+	require.NoError(t, err)
+	assert.NotNil(t, mod)
+	syslutil.AssertFsHasExactly(t, memFs)
+	apps := mod.GetApps()
+	app := apps["Database"]
+
+	assert.Equal(t, "Database", name)
+	assert.Equal(t, []string{"Database"}, app.GetName().GetPart())
+
+	appPatternsAttr := app.GetAttrs()["patterns"].GetA().GetElt()
+	patterns := make([]string, 0, len(appPatternsAttr))
+	for _, val := range appPatternsAttr {
+		patterns = append(patterns, val.GetS())
+	}
+	assert.Equal(t, []string{"db"}, patterns)
+
+	queryUserParams := app.GetEndpoints()["QueryUser"].GetParam()
+	params := make([]string, 0, len(queryUserParams))
+	for _, val := range queryUserParams {
+		params = append(params, val.GetName())
+	}
+	assert.Equal(t, []string{"user_id"}, params)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anz-bank/sysl/pkg/loader"
+	"github.com/anz-bank/sysl/pkg/mod"
+	"github.com/spf13/afero"
+)
+
+// watchInterval is how often the watched files are checked for changes, and
+// watchDebounce how long they must stay unchanged before the command runs
+// again, so that a burst of saves runs it once.
+const (
+	watchInterval = 250 * time.Millisecond
+	watchDebounce = 500 * time.Millisecond
+)
+
+// watcher polls the files models are loaded from. Polling the filesystem
+// rather than subscribing to events works with any afero.Fs.
+type watcher struct {
+	fs       afero.Fs
+	dirs     []string
+	interval time.Duration
+	debounce time.Duration
+	files    map[string]watchedFile
+}
+
+type watchedFile struct {
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte
+}
+
+// newWatcher returns a watcher of the project roots of the modules.
+func newWatcher(fs afero.Fs, root string, modules []string) (*watcher, error) {
+	w := &watcher{fs: fs, interval: watchInterval, debounce: watchDebounce}
+	seen := map[string]bool{}
+	for _, module := range modules {
+		dir, err := loader.ProjectRoot(fs, root, module)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[dir] {
+			seen[dir] = true
+			w.dirs = append(w.dirs, dir)
+		}
+	}
+	return w, nil
+}
+
+// isWatched returns whether models can import the file, or resolve imports
+// with it.
+func isWatched(filename string) bool {
+	switch filepath.Base(filename) {
+	case mod.ModFileName, mod.SumFileName:
+		return true
+	}
+	switch filepath.Ext(filename) {
+	case ".sysl", ".yaml", ".yml", ".json", ".pb", ".textpb":
+		return true
+	}
+	return false
+}
+
+// scan returns the watched files under the dirs, except in hidden directories.
+// Only files whose size or modification time changed since the files of the
+// watcher were scanned are read again.
+func (w *watcher) scan() (map[string]watchedFile, error) {
+	files := map[string]watchedFile{}
+	for _, dir := range w.dirs {
+		err := afero.Walk(w.fs, dir, func(path string, info os.FileInfo, err error) error {
+			switch {
+			case os.IsNotExist(err):
+				// Removed while walking, which the next scan sees.
+				return nil
+			case err != nil:
+				return err
+			case info.IsDir():
+				if path != dir && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			case !isWatched(path):
+				return nil
+			}
+
+			f := watchedFile{modTime: info.ModTime(), size: info.Size()}
+			if old, has := w.files[path]; has && old.modTime.Equal(f.modTime) && old.size == f.size {
+				f.sum = old.sum
+			} else {
+				data, err := afero.ReadFile(w.fs, path)
+				if err != nil {
+					return err
+				}
+				f.sum = sha256.Sum256(data)
+			}
+			files[path] = f
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// changed returns whether files differ from the files of the watcher.
+func (w *watcher) changed(files map[string]watchedFile) bool {
+	if len(files) != len(w.files) {
+		return true
+	}
+	for path, f := range files {
+		if old, has := w.files[path]; !has || old.sum != f.sum {
+			return true
+		}
+	}
+	return false
+}
+
+// wait returns once the watched files change and then stay unchanged for the
+// debounce duration, or with the error of ctx once it is done.
+func (w *watcher) wait(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var changedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		files, err := w.scan()
+		if err != nil {
+			return err
+		}
+		switch {
+		case w.changed(files):
+			w.files = files
+			changedAt = time.Now()
+		case !changedAt.IsZero() && time.Since(changedAt) >= w.debounce:
+			return nil
+		}
+	}
+}
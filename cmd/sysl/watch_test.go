@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// waitFor returns whether condition holds before the timeout.
+func waitFor(condition func() bool) bool {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return false
+}
+
+// newTempFs returns a filesystem with an empty /specs, rooted at a new
+// temporary directory that is removed when the returned func is called. Unlike
+// a MemMapFs, its files can be written while the watcher is reading them.
+func newTempFs(t *testing.T) (afero.Fs, func()) {
+	dir, err := ioutil.TempDir("", "watch")
+	require.NoError(t, err)
+	fs := afero.NewBasePathFs(afero.NewOsFs(), dir)
+	require.NoError(t, fs.Mkdir("/specs", 0755))
+	return fs, func() { _ = os.RemoveAll(dir) }
+}
+
+func newTestWatcher(t *testing.T, fs afero.Fs) *watcher {
+	w := &watcher{fs: fs, dirs: []string{"/specs"}, interval: time.Millisecond, debounce: 20 * time.Millisecond}
+	files, err := w.scan()
+	require.NoError(t, err)
+	w.files = files
+	return w
+}
+
+func TestWatcherWait(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/specs/a.sysl", []byte("A:\n    ...\n"), 0644))
+	w := newTestWatcher(t, fs)
+
+	// Files models can't import are not watched.
+	require.NoError(t, afero.WriteFile(fs, "/specs/out.svg", []byte("<svg/>"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/specs/.sysl/cache/x.sysl", []byte("X:\n    ...\n"), 0644))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, w.wait(ctx))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, afero.WriteFile(fs, "/specs/a.sysl", []byte("A:\n    ..."+strings.Repeat(".", i)+"\n"), 0644))
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, w.wait(ctx))
+	assert.Len(t, w.files, 1)
+	assert.Equal(t, int64(len("A:\n    .....\n")), w.files["/specs/a.sysl"].size)
+}
+
+func TestRunWatching(t *testing.T) {
+	t.Parallel()
+
+	logger, hook := test.NewNullLogger()
+	fs, cleanup := newTempFs(t)
+	defer cleanup()
+	require.NoError(t, afero.WriteFile(fs, "/specs/a.sysl", []byte("A:\n    ...\n  B <-\n"), 0644))
+
+	app := kingpin.New("sysl", "")
+	runner := cmdRunner{}
+	require.NoError(t, runner.Configure(app))
+	which, err := app.Parse([]string{"--root", "/specs", "pb", "--mode", "json", "-o", "/out.json", "a"})
+	require.NoError(t, err)
+
+	logged := func(level logrus.Level, prefix string) bool {
+		for _, entry := range hook.AllEntries() {
+			if entry.Level == level && strings.HasPrefix(entry.Message, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	eventually := func(level logrus.Level, prefix string) {
+		assert.True(t, waitFor(func() bool { return logged(level, prefix) }), prefix)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- runner.runWatching(ctx, which, fs, logger, nil, newTestWatcher(t, fs))
+	}()
+	eventually(logrus.ErrorLevel, "a.sysl:3:3: error:")
+	eventually(logrus.InfoLevel, "Watching /specs")
+
+	require.NoError(t, afero.WriteFile(fs, "/specs/a.sysl", []byte("A:\n    ...\n"), 0644))
+	eventually(logrus.ErrorLevel, "Resolved: a.sysl:3:3: error:")
+	assert.True(t, waitFor(func() bool {
+		exists, err := afero.Exists(fs, "/out.json")
+		return err == nil && exists
+	}))
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
+
+func TestRunWatchingLogsDiagnosticsOnce(t *testing.T) {
+	t.Parallel()
+
+	logger, hook := test.NewNullLogger()
+	fs, cleanup := newTempFs(t)
+	defer cleanup()
+	require.NoError(t, afero.WriteFile(fs, "/specs/a.sysl", []byte("import b\n\nA:\n    ...\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/specs/b.sysl", []byte("import a\n\nB:\n    ...\n"), 0644))
+
+	app := kingpin.New("sysl", "")
+	runner := cmdRunner{}
+	require.NoError(t, runner.Configure(app))
+	which, err := app.Parse([]string{"--root", "/specs", "pb", "--mode", "json", "-o", "/out.json", "a"})
+	require.NoError(t, err)
+
+	cycles := func() int {
+		n := 0
+		for _, entry := range hook.AllEntries() {
+			if strings.Contains(entry.Message, "cycle") {
+				n++
+			}
+		}
+		return n
+	}
+	watching := func() int {
+		n := 0
+		for _, entry := range hook.AllEntries() {
+			if strings.HasPrefix(entry.Message, "Watching /specs") {
+				n++
+			}
+		}
+		return n
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- runner.runWatching(ctx, which, fs, logger, nil, newTestWatcher(t, fs))
+	}()
+	assert.True(t, waitFor(func() bool { return watching() == 1 }))
+	assert.Equal(t, 1, cycles())
+
+	require.NoError(t, afero.WriteFile(fs, "/specs/b.sysl", []byte("import a\n\nB:\n    ...\n\nC:\n    ...\n"), 0644))
+	assert.True(t, waitFor(func() bool { return watching() == 2 }))
+	assert.Equal(t, 1, cycles())
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
@@ -1,4 +1,4 @@
-package main
+package codegen
 
 import (
 	"io"
@@ -21,9 +21,26 @@ import (
 // Node can be string or node
 type Node []interface{}
 
+// CodeGenOutput is the code generated for a file, serialized with Serialize.
 type CodeGenOutput struct {
-	filename string
-	output   Node
+	Filename string
+	Output   Node
+}
+
+// Params configures the generation of code from a model, as the flags of
+// sysl codegen do.
+type Params struct {
+	// RootTransform is the directory the transform is loaded from.
+	RootTransform string
+	Transform     string
+	// Grammar is the path of the grammar the transform results are written
+	// out with.
+	Grammar string
+	// Start is the start rule of the grammar.
+	Start            string
+	DepPath          string
+	BasePath         string
+	DisableValidator bool
 }
 
 func getKeyFromValueMap(v *sysl.Value, key string) *sysl.Value {
@@ -38,7 +55,7 @@ func processChoice(
 	obj *sysl.Value,
 	choice *parser.Choice,
 	logger *logrus.Logger,
-) Node {
+) (Node, error) {
 	var result Node
 
 	for i, seq := range choice.Sequence {
@@ -85,7 +102,10 @@ func processChoice(
 
 					for _, valueItem := range valueList {
 						// Drill down the rule
-						node := processRule(g, valueItem, x.Rulename.Name, logger)
+						node, err := processRule(g, valueItem, x.Rulename.Name, logger)
+						if err != nil {
+							return nil, err
+						}
 						// Check post-conditions
 						if len(node) == 0 {
 							fullScan = false
@@ -99,7 +119,10 @@ func processChoice(
 					if v.GetList() != nil || v.GetSet() != nil {
 						logger.Warnf("Got List or Set instead of map")
 					}
-					node := processRule(g, v, x.Rulename.Name, logger)
+					node, err := processRule(g, v, x.Rulename.Name, logger)
+					if err != nil {
+						return nil, err
+					}
 					// Check post-conditions
 					if len(node) == 0 {
 						logger.Warnf("could not process rule: ( %s )", x.Rulename.Name)
@@ -115,7 +138,10 @@ func processChoice(
 				seqResult = append(seqResult, ruleResult)
 			case *parser.Atom_Choices:
 				// minc, maxc := parser.GetMinMaxCount(term)
-				node := processChoice(g, obj, x.Choices, logger)
+				node, err := processChoice(g, obj, x.Choices, logger)
+				if err != nil {
+					return nil, err
+				}
 				if len(node) == 0 {
 					logger.Warnf("could not process Choice\n")
 					fullScan = false
@@ -123,8 +149,7 @@ func processChoice(
 				}
 				seqResult = append(seqResult, node)
 			default:
-				logger.Warningf("processChoice: choice %d : %T", i, x)
-				panic("Unexpected atom type")
+				return nil, errors.Errorf("unexpected atom type %T in choice %d", x, i)
 			}
 			if !fullScan {
 				break
@@ -134,10 +159,10 @@ func processChoice(
 			result = append(result, seqResult)
 		}
 	}
-	return result
+	return result, nil
 }
 
-func processRule(g *parser.Grammar, obj *sysl.Value, ruleName string, logger *logrus.Logger) Node {
+func processRule(g *parser.Grammar, obj *sysl.Value, ruleName string, logger *logrus.Logger) (Node, error) {
 	var str string
 	if x := obj.GetMap(); x != nil {
 		for key := range x.Items {
@@ -149,13 +174,12 @@ func processRule(g *parser.Grammar, obj *sysl.Value, ruleName string, logger *lo
 	if rule == nil {
 		root := Node{}
 		if eval.IsCollectionType(obj) {
-			return nil
+			return nil, nil
 		}
 		// Should we convert int and bools to string and return?
-		return append(root, obj.GetS())
+		return append(root, obj.GetS()), nil
 	}
-	root := processChoice(g, obj, rule.Choices, logger)
-	return root
+	return processChoice(g, obj, rule.Choices, logger)
 }
 
 func readGrammar(filename, grammarName, startRule string) (*parser.Grammar, error) {
@@ -195,7 +219,11 @@ func applyTranformToModel(model, transform *sysl.Module, params ...string) (*sys
 	s["basePath"] = eval.MakeValueString(params[4])
 	var result *sysl.Value
 
-	if perTypeTransform(view.Param) {
+	perType, err := perTypeTransform(view.Param)
+	if err != nil {
+		return nil, err
+	}
+	if perType {
 		result = eval.MakeValueList()
 		var tNames []string
 		for tName := range modelApp.Types {
@@ -206,16 +234,24 @@ func applyTranformToModel(model, transform *sysl.Module, params ...string) (*sys
 			t := modelApp.Types[tName]
 			s["typeName"] = eval.MakeValueString(tName)
 			s["type"] = eval.TypeToValue(t)
-			eval.AppendItemToValueList(result.GetList(), eval.EvaluateView(transform, params[1], params[3], s))
+			val, err := eval.EvaluateView(transform, params[1], params[3], s)
+			if err != nil {
+				return nil, err
+			}
+			eval.AppendItemToValueList(result.GetList(), val)
 		}
 	} else {
-		result = eval.EvaluateView(transform, params[1], params[3], s)
+		var err error
+		result, err = eval.EvaluateView(transform, params[1], params[3], s)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return result, nil
 }
 
-func perTypeTransform(params []*sysl.Param) bool {
+func perTypeTransform(params []*sysl.Param) (bool, error) {
 	paramMap := make(map[string]struct{})
 
 	for _, p := range params {
@@ -224,12 +260,12 @@ func perTypeTransform(params []*sysl.Param) bool {
 
 	if _, has := paramMap["app"]; has {
 		if _, has := paramMap["type"]; has {
-			return true
+			return true, nil
 		}
 	} else {
-		panic("Expecting at least an app <: sysl.App")
+		return false, errors.New("expecting at least an app <: sysl.App")
 	}
-	return false
+	return false, nil
 }
 
 // Serialize serializes node to string
@@ -252,43 +288,47 @@ func Serialize(w io.Writer, delim string, node Node) error {
 // GenerateCode transform input sysl model to code in the target language described by
 // grammar and a sysl transform
 func GenerateCode(
-	codegenParams *CmdContextParamCodegen,
+	codegenParams *Params,
 	model *sysl.Module, modelAppName string,
 	fs afero.Fs, logger *logrus.Logger) ([]*CodeGenOutput, error) {
 	var codeOutput []*CodeGenOutput
-	depPath := codegenParams.depPath
-	basePath := codegenParams.basePath
+	depPath := codegenParams.DepPath
+	basePath := codegenParams.BasePath
 
-	logger.Debugf("root-transform: %s\n", codegenParams.rootTransform)
-	logger.Debugf("transform: %s\n", codegenParams.transform)
-	logger.Debugf("dep-path: %s\n", codegenParams.depPath)
-	logger.Debugf("grammar: %s\n", codegenParams.grammar)
-	logger.Debugf("start: %s\n", codegenParams.start)
-	logger.Debugf("basePath: %s\n", codegenParams.basePath)
+	logger.Debugf("root-transform: %s\n", codegenParams.RootTransform)
+	logger.Debugf("transform: %s\n", codegenParams.Transform)
+	logger.Debugf("dep-path: %s\n", codegenParams.DepPath)
+	logger.Debugf("grammar: %s\n", codegenParams.Grammar)
+	logger.Debugf("start: %s\n", codegenParams.Start)
+	logger.Debugf("basePath: %s\n", codegenParams.BasePath)
 
-	transformFs := syslutil.NewChrootFs(fs, codegenParams.rootTransform)
+	transformFs := syslutil.NewChrootFs(fs, codegenParams.RootTransform)
 	tfmParser := parse.NewParser()
-	tx, transformAppName, err := parse.LoadAndGetDefaultApp(codegenParams.transform, transformFs, tfmParser)
+	tx, transformAppName, err := parse.LoadAndGetDefaultApp(codegenParams.Transform, transformFs, tfmParser)
 	if err != nil {
 		return nil, err
 	}
 
-	g, err := readGrammar(codegenParams.grammar, "gen", codegenParams.start)
+	g, err := readGrammar(codegenParams.Grammar, "gen", codegenParams.Start)
 	if err != nil {
 		return nil, err
 	}
 
-	if !codegenParams.disableValidator {
-		grammarSysl, err := validate.LoadGrammar(codegenParams.grammar, fs)
+	if !codegenParams.DisableValidator {
+		grammarSysl, err := validate.LoadGrammar(codegenParams.Grammar, fs)
 		if err != nil {
 			msg.NewMsg(msg.WarnValidationSkipped, []string{err.Error()}).LogMsg()
 		} else {
 			validator := validate.NewValidator(grammarSysl, tx.GetApps()[transformAppName], tfmParser)
-			validator.Validate(codegenParams.start, codegenParams.depPath, codegenParams.basePath)
+			validator.Validate(codegenParams.Start, codegenParams.DepPath, codegenParams.BasePath)
 			validator.LogMessages()
 		}
 	}
 
+	if err := eval.CheckTypes(tx, transformAppName); err != nil {
+		return nil, err
+	}
+
 	fileNames, err := applyTranformToModel(model, tx, modelAppName, transformAppName,
 		depPath, "filename", basePath)
 	if err != nil {
@@ -305,44 +345,53 @@ func GenerateCode(
 		logger.Println(filename)
 
 		if result.GetMap() != nil {
-			codeOutput = appendCodeOutput(g, result, logger, codeOutput, filename)
+			if codeOutput, err = appendCodeOutput(g, result, logger, codeOutput, filename); err != nil {
+				return nil, err
+			}
 		} else if result.GetList() != nil {
 			for _, v := range result.GetList().Value {
-				codeOutput = appendCodeOutput(g, v, logger, codeOutput, filename)
+				if codeOutput, err = appendCodeOutput(g, v, logger, codeOutput, filename); err != nil {
+					return nil, err
+				}
 			}
 		}
 	case fileNames.GetList() != nil && result.GetList() != nil:
 		fileValues := fileNames.GetList().Value
 		for i, v := range result.GetList().Value {
 			filename := fileValues[i].GetMap().Items["filename"].GetS()
-			codeOutput = appendCodeOutput(g, v, logger, codeOutput, filename)
+			if codeOutput, err = appendCodeOutput(g, v, logger, codeOutput, filename); err != nil {
+				return nil, err
+			}
 		}
 	default:
-		panic("Unexpected combination for filenames and transformation results")
+		return nil, errors.New("unexpected combination for filenames and transformation results")
 	}
 
 	return codeOutput, nil
 }
 
 func appendCodeOutput(g *parser.Grammar, v *sysl.Value,
-	logger *logrus.Logger, codeOutput []*CodeGenOutput, filename string) []*CodeGenOutput {
-	r := processRule(g, v, g.Start, logger)
-	codeOutput = append(codeOutput, &CodeGenOutput{filename, r})
-	return codeOutput
+	logger *logrus.Logger, codeOutput []*CodeGenOutput, filename string) ([]*CodeGenOutput, error) {
+	r, err := processRule(g, v, g.Start, logger)
+	if err != nil {
+		return nil, err
+	}
+	return append(codeOutput, &CodeGenOutput{filename, r}), nil
 }
 
-func outputToFiles(output []*CodeGenOutput, fs afero.Fs) error {
+// OutputToFiles writes the generated code to files in fs.
+func OutputToFiles(output []*CodeGenOutput, fs afero.Fs) error {
 	for _, o := range output {
-		f, err := fs.Create(o.filename)
+		f, err := fs.Create(o.Filename)
 		if err != nil {
-			return errors.Wrapf(err, "unable to create %q", o.filename)
+			return errors.Wrapf(err, "unable to create %q", o.Filename)
 		}
 		logrus.Infoln("Writing file: " + f.Name())
-		if err := Serialize(f, " ", o.output); err != nil {
-			return errors.Wrapf(err, "error writing to %q", o.filename)
+		if err := Serialize(f, " ", o.Output); err != nil {
+			return errors.Wrapf(err, "error writing to %q", o.Filename)
 		}
 		if err := f.Close(); err != nil {
-			return errors.Wrapf(err, "error closing %q", o.filename)
+			return errors.Wrapf(err, "error closing %q", o.Filename)
 		}
 	}
 	return nil
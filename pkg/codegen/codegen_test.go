@@ -1,4 +1,4 @@
-package main
+package codegen
 
 import (
 	"bytes"
@@ -6,22 +6,24 @@ import (
 	"testing"
 
 	"github.com/spf13/afero"
-	kingpin "gopkg.in/alecthomas/kingpin.v2"
 
 	"github.com/anz-bank/sysl/pkg/eval"
+	"github.com/anz-bank/sysl/pkg/parse"
 	"github.com/anz-bank/sysl/pkg/syslutil"
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+const testDir = "../../tests/"
+
 func TestGenerateCode(t *testing.T) {
 	t.Parallel()
 
 	output, err := GenerateCodeWithParams(testDir, "model.sysl", testDir, "test.gen.sysl",
 		filepath.Join(testDir, "test.gen.g"), "javaFile", "dep_path", "/some_value")
 	require.NoError(t, err)
-	root := output[0].output
+	root := output[0].Output
 	assert.Len(t, output, 1)
 	assert.Len(t, root, 1)
 	n1 := root[0].(Node)
@@ -62,7 +64,7 @@ func TestGenerateCodeNoComment(t *testing.T) {
 		filepath.Join(testDir, "test.gen.g"), "javaFile", "dep_path", "/some_value")
 	require.NoError(t, err)
 	assert.Len(t, output, 1)
-	root := output[0].output
+	root := output[0].Output
 	assert.Len(t, root, 1)
 	n1 := root[0].(Node)
 	assert.Len(t, n1, 3)
@@ -92,7 +94,7 @@ func TestGenerateCodeNoPackage(t *testing.T) {
 	output, err := GenerateCodeWithParams(testDir, "model.sysl", testDir, "test.gen_no_package.sysl",
 		filepath.Join(testDir, "test.gen.g"), "javaFile", "dep_path", "")
 	require.NoError(t, err)
-	root := output[0].output
+	root := output[0].Output
 	assert.Nil(t, root)
 }
 
@@ -102,7 +104,7 @@ func TestGenerateCodeMultipleAnnotations(t *testing.T) {
 	output, err := GenerateCodeWithParams(testDir, "model.sysl", testDir, "test.gen_multiple_annotations.sysl",
 		filepath.Join(testDir, "test.gen.g"), "javaFile", "dep_path", "")
 	require.NoError(t, err)
-	root := output[0].output
+	root := output[0].Output
 	assert.Nil(t, root)
 }
 
@@ -113,9 +115,9 @@ func TestGenerateCodePerType(t *testing.T) {
 		filepath.Join(testDir, "test.gen.g"), "javaFile", "dep_path", "")
 	require.NoError(t, err)
 	assert.Len(t, output, 1)
-	assert.Equal(t, "Request.java", output[0].filename)
+	assert.Equal(t, "Request.java", output[0].Filename)
 
-	root := output[0].output
+	root := output[0].Output
 	assert.Len(t, root, 1)
 
 	requestRoot := root[0].(Node)
@@ -136,7 +138,7 @@ func TestGenerateCodePutDepPackageAndParamTypeInComment(t *testing.T) {
 	output, err := GenerateCodeWithParams(testDir, "model_with_deps.sysl", testDir, "xform_with_deps.sysl",
 		filepath.Join(testDir, "test.gen.g"), "javaFile", "", "", "ModelWithDeps")
 	require.NoError(t, err)
-	root := output[0].output
+	root := output[0].Output
 	assert.Len(t, output, 1)
 	assert.Len(t, root, 1)
 	n1 := root[0].(Node)
@@ -159,7 +161,7 @@ func TestGenerateCodePutDepPackageInCommentUsingSets(t *testing.T) {
 		"xform_with_deps_pkg_set.sysl",
 		filepath.Join(testDir, "test.gen.g"), "javaFile", "", "", "ModelWithDeps")
 	require.NoError(t, err)
-	root := output[0].output
+	root := output[0].Output
 	assert.Len(t, output, 1)
 	assert.Len(t, root, 1)
 	n1 := root[0].(Node)
@@ -182,7 +184,7 @@ func TestGenerateCodePutDepPackageInCommentUsingLists(t *testing.T) {
 		"xform_with_deps_pkg_list.sysl",
 		filepath.Join(testDir, "test.gen.g"), "javaFile", "", "", "ModelWithDeps")
 	require.NoError(t, err)
-	root := output[0].output
+	root := output[0].Output
 	assert.Len(t, output, 1)
 	assert.Len(t, root, 1)
 	n1 := root[0].(Node)
@@ -205,7 +207,7 @@ func TestNamesFromCalls(t *testing.T) {
 		"xform_names_from_calls.sysl",
 		filepath.Join(testDir, "test.gen.g"), "javaFile", "", "", "ModelWithDeps")
 	require.NoError(t, err)
-	root := output[0].output
+	root := output[0].Output
 	assert.Len(t, output, 1)
 	assert.Len(t, root, 1)
 	n1 := root[0].(Node)
@@ -227,7 +229,7 @@ func TestSerialize(t *testing.T) {
 		filepath.Join(testDir, "test.gen.g"), "javaFile", "dep_path", "/some_value")
 	require.NoError(t, err)
 	out := new(bytes.Buffer)
-	require.NoError(t, Serialize(out, " ", output[0].output))
+	require.NoError(t, Serialize(out, " ", output[0].Output))
 	golden := "package com.example.gen \n comment1 comment2 import import1 \n import dep_path \n /some_value "
 	assert.Equal(t, golden, out.String())
 }
@@ -244,7 +246,8 @@ func TestOutputForPureTokenOnlyRule(t *testing.T) {
 	eval.AddItemToValueMap(obj, "tail", eval.MakeValueBool(true))
 	eval.AddItemToValueMap(obj, "body", m)
 	logger, _ := test.NewNullLogger()
-	output := processRule(g, obj, "pureToken", logger)
+	output, err := processRule(g, obj, "pureToken", logger)
+	require.NoError(t, err)
 	assert.NotNil(t, output)
 
 	root := output[0].(Node)
@@ -276,81 +279,32 @@ func GenerateCodeWithParamsFs(
 	rootModel, model, rootTransform, transform, grammar, start string,
 	depPath string, basePath string, fs afero.Fs, appname ...string,
 ) ([]*CodeGenOutput, error) {
-	cmdContextParamCodegen := &CmdContextParamCodegen{
-		rootTransform: rootTransform,
-		transform:     transform,
-		grammar:       grammar,
-		depPath:       depPath,
-		start:         start,
-		basePath:      basePath,
+	params := &Params{
+		RootTransform: rootTransform,
+		Transform:     transform,
+		Grammar:       grammar,
+		DepPath:       depPath,
+		Start:         start,
+		BasePath:      basePath,
 	}
 	logger, _ := test.NewNullLogger()
-	mod, modAppName, err := LoadSyslModule(rootModel, model, fs, logger)
+	mod, modAppName, err := parse.LoadAndGetDefaultApp(model, syslutil.NewChrootFs(fs, rootModel), parse.NewParser())
 	if err != nil {
 		return nil, err
 	}
 	if len(appname) == 0 {
 		appname = []string{modAppName}
 	}
-	return GenerateCode(cmdContextParamCodegen, mod, appname[0], fs, logger)
+	return GenerateCode(params, mod, appname[0], fs, logger)
 }
 
-func TestValidatorDoValidate(t *testing.T) {
+func TestGenerateCodeTypeError(t *testing.T) {
 	t.Parallel()
 
-	cases := map[string]struct {
-		args     []string
-		isErrNil bool
-	}{
-		"Success": {
-			args: []string{
-				"src", "codegen", "--validate-only", "--root-transform", testDir, "--transform", "transform2.sysl", "--grammar",
-				filepath.Join(testDir, "grammar.sysl"), "--start", "goFile",
-				"--dep-path", "example.com/abc/asx/lmno"}, isErrNil: true},
-		"Grammar loading fail": {
-			args: []string{
-				"src", "codegen", "--validate-only", "--root-transform", testDir, "--transform", "transform2.sysl", "--grammar",
-				filepath.Join(testDir, "go.sysl"), "--start", "goFile",
-				"--dep-path", "example.com/abc/asx/lmno"}, isErrNil: false},
-		"Transform loading fail": {
-			args: []string{
-				"src", "codegen", "--validate-only", "--root-transform", testDir, "--transform", "tfm.sysl", "--grammar",
-				filepath.Join(testDir, "grammar.sysl"), "--start", "goFile",
-				"--dep-path", "example.com/abc/asx/lmno"}, isErrNil: false},
-		"Has validation messages": {
-			args: []string{
-				"src", "codegen", "--validate-only", "--root-transform", testDir, "--transform", "transform1.sysl", "--grammar",
-				filepath.Join(testDir, "grammar.sysl"), "--start", "goFile",
-				"--dep-path", "example.com/abc/asx/lmno"}, isErrNil: false},
-	}
-
-	for name, tt := range cases {
-		args := tt.args
-		isErrNil := tt.isErrNil
-		t.Run(name, func(t *testing.T) {
-			sysl := kingpin.New("sysl", "System Modelling Language Toolkit")
-
-			cmd := &codegenCmd{}
-			require.NotNil(t, cmd.Configure(sysl))
-
-			var selectedCmd string
-			var err error
-			if selectedCmd, err = sysl.Parse(args[1:]); err != nil {
-				assert.FailNow(t, "Failed to parse args")
-			}
-			require.Equal(t, cmd.Name(), selectedCmd)
-			l, _ := test.NewNullLogger()
-			execArgs := ExecuteArgs{
-				Modules:    nil,
-				Filesystem: afero.NewOsFs(),
-				Logger:     l,
-			}
-			err = cmd.Execute(execArgs)
-			if isErrNil {
-				assert.Nil(t, err, "Unexpected result")
-			} else {
-				assert.NotNil(t, err, "Unexpected result")
-			}
-		})
-	}
+	_, err := GenerateCodeWithParams(testDir, "model.sysl", testDir, "test.gen_type_error.sysl",
+		filepath.Join(testDir, "test.gen.g"), "javaFile", "dep_path", "")
+	require.Error(t, err)
+	require.IsType(t, eval.TypeErrors{}, err)
+	assert.EqualError(t, err,
+		"test.gen_type_error.sysl:19:27: argument 1 of ToUpper is string, got int (in view javaFile)")
 }
@@ -0,0 +1,121 @@
+package datamodeldiagram
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/diagrams"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Params configures the data model diagrams generated from a model, as the
+// flags of sysl datamodel do.
+type Params struct {
+	Title string
+	// Output is the output each diagram is keyed by, formatted for each
+	// endpoint of the project such as %(epname).svg.
+	Output string
+	// Project is the app whose endpoints list the apps to draw.
+	Project string
+	// Filter, if not empty, is a regular expression the outputs must match.
+	Filter string
+	// ClassFormat formats the name of each type, such as %(classname).
+	ClassFormat string
+	// ER draws the tables as entity-relationship diagrams.
+	ER bool
+}
+
+// GenerateDataModels returns a data model diagram for each endpoint of the
+// project, keyed by its output.
+func GenerateDataModels(params *Params, model *sysl.Module, logger *logrus.Logger) (map[string]string, error) {
+	outmap := make(map[string]string)
+
+	logger.Debugf("project: %v\n", params.Project)
+	logger.Debugf("title: %s\n", params.Title)
+	logger.Debugf("filter: %s\n", params.Filter)
+	logger.Debugf("output: %s\n", params.Output)
+	logger.Debugf("er: %t\n", params.ER)
+
+	spclass := diagrams.ConstructFormatParser("", params.ClassFormat)
+
+	var filter *regexp.Regexp
+	if params.Filter != "" {
+		var err error
+		if filter, err = regexp.Compile(params.Filter); err != nil {
+			return nil, errors.Wrap(err, "invalid filter")
+		}
+	}
+
+	// The "project" app that specifies the data models to be built
+	var app *sysl.Application
+	var exists bool
+	if app, exists = model.GetApps()[params.Project]; !exists {
+		return nil, fmt.Errorf("project not found in sysl")
+	}
+
+	// Iterate over each endpoint within the selected project
+	for epname, endpt := range app.GetEndpoints() {
+		outputDir := params.Output
+		if strings.Contains(outputDir, "%(epname)") {
+			of := diagrams.MakeFormatParser(params.Output)
+			outputDir = of.FmtOutput(params.Project, epname, endpt.GetLongName(), endpt.GetAttrs())
+		}
+		if filter != nil && !filter.MatchString(outputDir) {
+			continue
+		}
+		if params.ER {
+			generateERDiagram(outmap, model, endpt.GetStmt(), params.Title, outputDir)
+			continue
+		}
+		generateDataModel(spclass, outmap, model, endpt.GetStmt(), params.Title, params.Project, outputDir)
+	}
+	return outmap, nil
+}
+
+func generateDataModel(pclass diagrams.ClassLabeler, outmap map[string]string, mod *sysl.Module,
+	stmts []*sysl.Statement, title, project, outDir string) {
+	apps := mod.GetApps()
+
+	// Parse all the applications in the project
+	for _, stmt := range stmts {
+		if a, ok := stmt.Stmt.(*sysl.Statement_Action); ok {
+			var stringBuilder strings.Builder
+			app := apps[a.Action.Action]
+			if app != nil {
+				dataParam := &DataModelParam{
+					Mod:     mod,
+					App:     app,
+					Title:   title,
+					Project: project,
+				}
+				v := MakeDataModelView(pclass, dataParam.Mod, &stringBuilder, dataParam.Title, dataParam.Project)
+				if diagrams.IsMermaidOutput(outDir) {
+					outmap[outDir] = v.GenerateMermaidDataView(dataParam)
+				} else {
+					outmap[outDir] = v.GenerateDataView(dataParam)
+				}
+			}
+		}
+	}
+}
+
+// generateERDiagram draws the tables of the applications of the project as
+// entity-relationship diagrams.
+func generateERDiagram(outmap map[string]string, mod *sysl.Module, stmts []*sysl.Statement, title, outDir string) {
+	for _, stmt := range stmts {
+		if a, ok := stmt.Stmt.(*sysl.Statement_Action); ok {
+			if app := mod.GetApps()[a.Action.Action]; app != nil {
+				var stringBuilder strings.Builder
+				v := MakeERDiagramView(app, &stringBuilder)
+				if diagrams.IsMermaidOutput(outDir) {
+					outmap[outDir] = v.GenerateMermaidERDiagram(title)
+				} else {
+					outmap[outDir] = v.GenerateERDiagram(title)
+				}
+			}
+		}
+	}
+}
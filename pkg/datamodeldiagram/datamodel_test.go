@@ -0,0 +1,191 @@
+package datamodeldiagram
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+
+	"github.com/anz-bank/sysl/pkg/diagrams"
+	"github.com/anz-bank/sysl/pkg/parse"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDir = "../../tests/"
+
+// loadModule loads a model from root as sysl would with --root.
+func loadModule(root, filename string) (*sysl.Module, string, error) {
+	return parse.LoadAndGetDefaultApp(filename, syslutil.NewChrootFs(afero.NewOsFs(), root), parse.NewParser())
+}
+
+type dataArgs struct {
+	root     string
+	title    string
+	output   string
+	project  string
+	modules  string
+	expected map[string]string
+}
+
+func comparePUML(t *testing.T, expected, actual map[string]string) {
+	for name, goldenFile := range expected {
+		golden, err := ioutil.ReadFile(goldenFile)
+		assert.Nil(t, err)
+		golden = syslutil.HandleCRLF(golden)
+		assert.Equal(t, string(golden), actual[name])
+	}
+	assert.Equal(t, len(expected), len(actual))
+}
+
+func TestGenerateDataDiagFail(t *testing.T) {
+	t.Parallel()
+	_, err := parse.NewParser().Parse("doesn't-exist.sysl", syslutil.NewChrootFs(afero.NewOsFs(), ""))
+	require.Error(t, err)
+}
+
+func TestDoConstructDataDiagrams(t *testing.T) {
+	args := &dataArgs{
+		root:    testDir,
+		modules: "data.sysl",
+		output:  "%(epname).png",
+		project: "Project",
+		title:   "empdata",
+		expected: map[string]string{
+			"Relational-Model.png": filepath.Join(testDir, "relational-model-golden.puml"),
+			"Object-Model.png":     filepath.Join(testDir, "object-model-golden.puml"),
+		},
+	}
+	result, err := DoConstructDataDiagramsWithParams(args.root, "", args.title, args.output, args.project,
+		args.modules)
+	assert.Nil(t, err, "Generating the data diagrams failed")
+	comparePUML(t, args.expected, result)
+}
+
+func DoConstructDataDiagramsWithParams(
+	rootModel, filter, title, output, project, modules string,
+) (map[string]string, error) {
+	classFormat := "%(classname)"
+	params := &Params{
+		Filter:      filter,
+		Title:       title,
+		Output:      output,
+		Project:     project,
+		ClassFormat: classFormat,
+	}
+
+	logger, _ := test.NewNullLogger()
+	mod, _, err := loadModule(rootModel, modules)
+	if err != nil {
+		return nil, err
+	}
+	return GenerateDataModels(params, mod, logger)
+}
+
+func TestDoConstructMermaidDataDiagrams(t *testing.T) {
+	result, err := DoConstructDataDiagramsWithParams(testDir, "", "empdata", "%(epname).mmd", "Project",
+		"data.sysl")
+	require.NoError(t, err)
+
+	expected := `---
+title: empdata
+---
+` + diagrams.MermaidHeader + `classDiagram
+class _0["Address"] {
++city : string
++line_1 : string
+}
+class _1["Customer"] {
++addresses : Set~Address~
++customer_id : int
+}
+class _2["Order"] {
++customer : Customer
++order_id : int
+}
+_1 *-- "0..*" _0
+_2 *-- "1..1" _1
+`
+	assert.Equal(t, expected, result["Object-Model.mmd"])
+	assert.Contains(t, result["Relational-Model.mmd"], "+petId : EmployeeTendsPet.petId FK\n")
+	assert.Contains(t, result["Relational-Model.mmd"], "_3 --> _0\n")
+}
+
+func TestDoConstructDataDiagramsWithSequences(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "orders.sysl", []byte(`Project:
+  Orders:
+    Orders
+
+Orders:
+  !type Line:
+    sku <: string
+
+  !type Order:
+    lines <: sequence of Line
+    tags <: sequence of string
+`), 0644))
+	mod, _, err := parse.LoadAndGetDefaultApp("orders.sysl", fs, parse.NewParser())
+	require.NoError(t, err)
+
+	logger, _ := test.NewNullLogger()
+	result, err := GenerateDataModels(&Params{
+		ClassFormat: "%(classname)",
+		Output:      "%(epname).mmd",
+		Project:     "Project",
+	}, mod, logger)
+	require.NoError(t, err)
+	assert.Contains(t, result["Orders.mmd"], "+lines : Sequence~Line~\n+tags : Sequence~string~\n")
+	assert.Contains(t, result["Orders.mmd"], "_1 *-- \"0..*\" _0\n")
+}
+
+func TestDoConstructERDiagrams(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	mod, _, err := loadModule(testDir, "erd.sysl")
+	require.NoError(t, err)
+
+	for output, golden := range map[string]string{
+		"Bank.png": "erd-golden.puml",
+		"Bank.mmd": "erd-golden.mmd",
+	} {
+		result, err := GenerateDataModels(&Params{
+			Output:  strings.Replace(output, "Bank", "%(epname)", 1),
+			Project: "Project",
+			ER:      true,
+		}, mod, logger)
+		require.NoError(t, err)
+		comparePUML(t, map[string]string{output: filepath.Join(testDir, golden)}, result)
+	}
+}
+
+func TestDoConstructERDiagramsWithCompositeColumns(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "shop.sysl", []byte(`Project:
+  Shop:
+    Shop
+
+Shop:
+    !table Product:
+        productId <: int [~pk]
+        tags <: sequence of string
+        sizes <: set of int
+`), 0644))
+	mod, _, err := parse.LoadAndGetDefaultApp("shop.sysl", fs, parse.NewParser())
+	require.NoError(t, err)
+
+	logger, _ := test.NewNullLogger()
+	result, err := GenerateDataModels(&Params{Output: "%(epname).mmd", Project: "Project", ER: true}, mod, logger)
+	require.NoError(t, err)
+	assert.Contains(t, result["Shop.mmd"], "  set sizes\n  sequence tags\n")
+}
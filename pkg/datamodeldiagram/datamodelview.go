@@ -1,10 +1,11 @@
-package main
+package datamodeldiagram
 
 import (
 	"fmt"
 	"sort"
 	"strings"
 
+	"github.com/anz-bank/sysl/pkg/diagrams"
 	proto "github.com/anz-bank/sysl/pkg/sysl"
 )
 
@@ -14,21 +15,23 @@ const entityLessThanArrow = `<< (`
 const entityGreaterThanArrow = `) >>`
 const classString = `class`
 
+// DataModelParam is the application drawn by a DataModelView.
 type DataModelParam struct {
-	ClassLabeler
-	mod     *proto.Module
-	app     *proto.Application
-	project string
-	title   string
+	diagrams.ClassLabeler
+	Mod     *proto.Module
+	App     *proto.Application
+	Project string
+	Title   string
 }
 
 type DataModelView struct {
-	ClassLabeler
+	diagrams.ClassLabeler
 	mod           *proto.Module
 	stringBuilder *strings.Builder
-	symbols       map[string]*_var
+	symbols       map[string]*diagrams.Var
 	project       string
 	title         string
+	mermaid       bool
 }
 
 type RelationshipParam struct {
@@ -44,7 +47,7 @@ type EntityViewParam struct {
 }
 
 func MakeDataModelView(
-	p ClassLabeler, mod *proto.Module, stringBuilder *strings.Builder,
+	p diagrams.ClassLabeler, mod *proto.Module, stringBuilder *strings.Builder,
 	title, project string,
 ) *DataModelView {
 	return &DataModelView{
@@ -53,27 +56,27 @@ func MakeDataModelView(
 		stringBuilder: stringBuilder,
 		project:       project,
 		title:         title,
-		symbols:       make(map[string]*_var),
+		symbols:       make(map[string]*diagrams.Var),
 	}
 }
 
 func (v *DataModelView) UniqueVarForAppName(appName string) string {
 	if s, ok := v.symbols[appName]; ok {
-		return s.alias
+		return s.Alias
 	}
 
 	i := len(v.symbols)
 	alias := fmt.Sprintf("_%d", i)
 	label := v.LabelClass(appName)
-	s := &_var{
-		agent: makeAgent(map[string]*proto.Attribute{}),
-		order: i,
-		label: label,
-		alias: alias,
+	s := &diagrams.Var{
+		Agent: diagrams.MakeAgent(map[string]*proto.Attribute{}),
+		Order: i,
+		Label: label,
+		Alias: alias,
 	}
 	v.symbols[appName] = s
 
-	return s.alias
+	return s.Alias
 }
 
 func (v *DataModelView) drawRelationship(relationshipMap map[string]map[string]RelationshipParam, viewType string) {
@@ -90,6 +93,10 @@ func (v *DataModelView) drawRelationship(relationshipMap map[string]map[string]R
 		sort.Strings(childNames)
 		for _, childName := range childNames {
 			for cnt := relationshipMap[relName][childName].Count; cnt > 0; cnt-- {
+				if v.mermaid {
+					v.writeMermaidRelationship(relName, viewType, relationshipMap[relName][childName])
+					continue
+				}
 				v.stringBuilder.WriteString(fmt.Sprintf("%s %s \"%s\" %s\n", relName, viewType,
 					relationshipMap[relName][childName].Relationship, relationshipMap[relName][childName].Entity))
 			}
@@ -104,8 +111,7 @@ func (v *DataModelView) drawRelation(
 ) {
 	entityTokens := strings.Split(viewParam.entityName, ".")
 	encEntity := v.UniqueVarForAppName(entityTokens[len(entityTokens)-1])
-	v.stringBuilder.WriteString(fmt.Sprintf("%s \"%s\" as %s %s%s,%s%s {\n", classString, viewParam.entityName,
-		encEntity, entityLessThanArrow, viewParam.entityHeader, viewParam.entityColor, entityGreaterThanArrow))
+	v.writeClassStart(viewParam, encEntity)
 
 	// sort and iterate over attributes
 	attrNames := []string{}
@@ -118,7 +124,11 @@ func (v *DataModelView) drawRelation(
 		var s string
 		if typeRef := attrType.GetTypeRef(); typeRef != nil {
 			targetEntity := v.UniqueVarForAppName(typeRef.GetRef().Path[0])
-			s = fmt.Sprintf("+ %s : **%s.%s** <<FK>>\n",
+			fkFormat := "+ %s : **%s.%s** <<FK>>\n"
+			if v.mermaid {
+				fkFormat = "+%s : %s.%s FK\n"
+			}
+			s = fmt.Sprintf(fkFormat,
 				attrName,
 				typeRef.GetRef().Path[0],
 				typeRef.GetRef().Path[1])
@@ -139,7 +149,7 @@ func (v *DataModelView) drawRelation(
 				}
 			}
 		} else {
-			s = fmt.Sprintf("+ %s : %s\n", attrName, strings.ToLower(attrType.GetPrimitive().String()))
+			s = v.primitiveAttr(attrName, attrType)
 		}
 		v.stringBuilder.WriteString(s)
 	}
@@ -153,12 +163,9 @@ func (v *DataModelView) drawTuple(
 ) {
 	entityTokens := strings.Split(viewParam.entityName, ".")
 	encEntity := v.UniqueVarForAppName(entityTokens[len(entityTokens)-1])
-	v.stringBuilder.WriteString(fmt.Sprintf("%s \"%s\" as %s %s%s,%s%s {\n", classString, viewParam.entityName,
-		encEntity, entityLessThanArrow, viewParam.entityHeader, viewParam.entityColor, entityGreaterThanArrow))
+	v.writeClassStart(viewParam, encEntity)
 	var relation string
 	var collectionString string
-	var path []string
-	var isPrimitiveList bool
 
 	// sort and iterate over attributes
 	attrNames := []string{}
@@ -167,6 +174,8 @@ func (v *DataModelView) drawTuple(
 	}
 	sort.Strings(attrNames)
 	for _, attrName := range attrNames {
+		var path []string
+		var isPrimitiveList bool
 		attrType := entity.AttrDefs[attrName]
 		if _, exists := relationshipMap[encEntity]; !exists {
 			relationshipMap[encEntity] = map[string]RelationshipParam{}
@@ -180,7 +189,7 @@ func (v *DataModelView) drawTuple(
 					isPrimitiveList = true
 					path = append(path, strings.ToLower(attrType.GetList().GetType().GetPrimitive().String()))
 				}
-				collectionString = fmt.Sprintf("+ %s : **List <%s>**\n", attrName, path[0])
+				collectionString = v.collectionAttr(attrName, "List", path[0])
 				relation = `0..*`
 			case attrType.GetSet() != nil:
 				if attrType.GetSet().GetPrimitive() == proto.Type_NO_Primitive {
@@ -189,11 +198,20 @@ func (v *DataModelView) drawTuple(
 					isPrimitiveList = true
 					path = append(path, strings.ToLower(attrType.GetSet().GetPrimitive().String()))
 				}
-				collectionString = fmt.Sprintf("+ %s : **Set <%s>**\n", attrName, path[0])
+				collectionString = v.collectionAttr(attrName, "Set", path[0])
+				relation = `0..*`
+			case attrType.GetSequence() != nil:
+				if attrType.GetSequence().GetPrimitive() == proto.Type_NO_Primitive {
+					path = attrType.GetSequence().GetTypeRef().GetRef().Path
+				} else {
+					isPrimitiveList = true
+					path = append(path, strings.ToLower(attrType.GetSequence().GetPrimitive().String()))
+				}
+				collectionString = v.collectionAttr(attrName, "Sequence", path[0])
 				relation = `0..*`
 			default:
 				path = attrType.GetTypeRef().GetRef().Path
-				collectionString = fmt.Sprintf("+ %s : **%s**\n", attrName, path[0])
+				collectionString = v.collectionAttr(attrName, "", path[0])
 				relation = `1..1 `
 			}
 			v.stringBuilder.WriteString(collectionString)
@@ -213,24 +231,77 @@ func (v *DataModelView) drawTuple(
 				}
 			}
 		} else {
-			v.stringBuilder.WriteString(fmt.Sprintf("+ %s : %s\n", attrName, strings.ToLower(attrType.GetPrimitive().String())))
+			v.stringBuilder.WriteString(v.primitiveAttr(attrName, attrType))
 		}
 	}
 	v.stringBuilder.WriteString("}\n")
 }
 
+func (v *DataModelView) writeClassStart(viewParam EntityViewParam, encEntity string) {
+	if v.mermaid {
+		fmt.Fprintf(v.stringBuilder, "%s %s[\"%s\"] {\n", classString, encEntity, diagrams.MermaidText(viewParam.entityName))
+		return
+	}
+	v.stringBuilder.WriteString(fmt.Sprintf("%s \"%s\" as %s %s%s,%s%s {\n", classString, viewParam.entityName,
+		encEntity, entityLessThanArrow, viewParam.entityHeader, viewParam.entityColor, entityGreaterThanArrow))
+}
+
+func (v *DataModelView) primitiveAttr(attrName string, attrType *proto.Type) string {
+	if v.mermaid {
+		return fmt.Sprintf("+%s : %s\n", attrName, strings.ToLower(attrType.GetPrimitive().String()))
+	}
+	return fmt.Sprintf("+ %s : %s\n", attrName, strings.ToLower(attrType.GetPrimitive().String()))
+}
+
+// collectionAttr formats an attribute referring to another type, in a List or
+// Set or Sequence if collection is not empty.
+func (v *DataModelView) collectionAttr(attrName, collection, elem string) string {
+	switch {
+	case v.mermaid && collection == "":
+		return fmt.Sprintf("+%s : %s\n", attrName, elem)
+	case v.mermaid:
+		return fmt.Sprintf("+%s : %s~%s~\n", attrName, collection, elem)
+	case collection == "":
+		return fmt.Sprintf("+ %s : **%s**\n", attrName, elem)
+	default:
+		return fmt.Sprintf("+ %s : **%s <%s>**\n", attrName, collection, elem)
+	}
+}
+
+// writeMermaidRelationship draws a relationship with Mermaid's arrows, which
+// have no crow's foot for relations.
+func (v *DataModelView) writeMermaidRelationship(relName, viewType string, rel RelationshipParam) {
+	if viewType == relationArrow {
+		fmt.Fprintf(v.stringBuilder, "%s --> %s\n", relName, rel.Entity)
+		return
+	}
+	fmt.Fprintf(v.stringBuilder, "%s %s \"%s\" %s\n", relName, viewType,
+		strings.TrimSpace(rel.Relationship), rel.Entity)
+}
+
+// GenerateMermaidDataView returns the data model as a Mermaid classDiagram.
+func (v *DataModelView) GenerateMermaidDataView(dataParam *DataModelParam) string {
+	v.mermaid = true
+	return v.GenerateDataView(dataParam)
+}
+
 func (v *DataModelView) GenerateDataView(dataParam *DataModelParam) string {
 	var isRelation bool
 	relationshipMap := map[string]map[string]RelationshipParam{}
-	v.stringBuilder.WriteString("@startuml\n")
-	if dataParam.title != "" {
-		fmt.Fprintf(v.stringBuilder, "title %s\n", dataParam.title)
+	if v.mermaid {
+		diagrams.WriteMermaidPreamble(v.stringBuilder, dataParam.Title)
+		v.stringBuilder.WriteString("classDiagram\n")
+	} else {
+		v.stringBuilder.WriteString("@startuml\n")
+		if dataParam.Title != "" {
+			fmt.Fprintf(v.stringBuilder, "title %s\n", dataParam.Title)
+		}
+		v.stringBuilder.WriteString(diagrams.PumlHeader)
 	}
-	v.stringBuilder.WriteString(PumlHeader)
 
 	// sort and iterate over each entity type the selected application
 	// *Type_Tuple_ OR *Type_Relation_
-	typeMap := dataParam.app.GetTypes()
+	typeMap := dataParam.App.GetTypes()
 	entityNames := []string{}
 	for entityName := range typeMap {
 		entityNames = append(entityNames, entityName)
@@ -261,6 +332,8 @@ func (v *DataModelView) GenerateDataView(dataParam *DataModelParam) string {
 	} else {
 		v.drawRelationship(relationshipMap, tupleArrow)
 	}
-	v.stringBuilder.WriteString("@enduml\n")
+	if !v.mermaid {
+		v.stringBuilder.WriteString("@enduml\n")
+	}
 	return v.stringBuilder.String()
 }
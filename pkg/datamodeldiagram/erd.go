@@ -0,0 +1,257 @@
+package datamodeldiagram
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/diagrams"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+)
+
+// ERDHeader hides the class circles PlantUML draws on entities, which mean
+// nothing in an entity-relationship diagram.
+const ERDHeader = `hide circle
+skinparam linetype ortho
+`
+
+//nolint:gochecknoglobals
+var mermaidEntityRE = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// erColumn is a column of a table in an entity-relationship diagram.
+type erColumn struct {
+	name string
+	typ  string
+	pk   bool
+	fk   bool
+	opt  bool
+}
+
+// erRelationship is a foreign key from the child table to the parent. The
+// cardinality at each end follows from the key: the child has at most one row
+// for each parent row when the foreign key is unique, and the parent is
+// optional when the foreign key is.
+type erRelationship struct {
+	child, parent string
+	column        string
+	unique        bool
+	opt           bool
+}
+
+// ERDiagramView draws the relations of an application, the tables of its
+// !table definitions, as an entity-relationship diagram in crow's foot
+// notation.
+type ERDiagramView struct {
+	app           *sysl.Application
+	stringBuilder *strings.Builder
+	aliases       map[string]string
+	mermaid       bool
+}
+
+func MakeERDiagramView(app *sysl.Application, stringBuilder *strings.Builder) *ERDiagramView {
+	return &ERDiagramView{
+		app:           app,
+		stringBuilder: stringBuilder,
+		aliases:       map[string]string{},
+	}
+}
+
+// GenerateERDiagram returns the PlantUML entity-relationship diagram.
+func (v *ERDiagramView) GenerateERDiagram(title string) string {
+	v.stringBuilder.WriteString("@startuml\n")
+	if title != "" {
+		fmt.Fprintf(v.stringBuilder, "title %s\n", title)
+	}
+	v.stringBuilder.WriteString(diagrams.PumlHeader)
+	v.stringBuilder.WriteString(ERDHeader)
+	rels := []erRelationship{}
+	for _, name := range v.tableNames() {
+		columns, tableRels := v.table(name)
+		rels = append(rels, tableRels...)
+		fmt.Fprintf(v.stringBuilder, "entity \"%s\" as %s {\n", name, v.alias(name))
+		keys := 0
+		for _, c := range columns {
+			if c.pk {
+				keys++
+			}
+		}
+		for i, c := range columns {
+			if keys > 0 && i == keys {
+				v.stringBuilder.WriteString("  --\n")
+			}
+			mandatory := "  "
+			if !c.opt {
+				mandatory = "* "
+			}
+			fmt.Fprintf(v.stringBuilder, "  %s%s : %s%s\n", mandatory, c.name, c.typ, erKeyMarkers(c, " <<%s>>"))
+		}
+		v.stringBuilder.WriteString("}\n")
+	}
+	for _, r := range rels {
+		fmt.Fprintf(v.stringBuilder, "%s %s %s : %s\n", v.alias(r.child), r.arrow(), v.alias(r.parent), r.column)
+	}
+	v.stringBuilder.WriteString("@enduml\n")
+	return v.stringBuilder.String()
+}
+
+// GenerateMermaidERDiagram returns the entity-relationship diagram as a
+// Mermaid erDiagram.
+func (v *ERDiagramView) GenerateMermaidERDiagram(title string) string {
+	v.mermaid = true
+	diagrams.WriteMermaidPreamble(v.stringBuilder, title)
+	v.stringBuilder.WriteString("erDiagram\n")
+	rels := []erRelationship{}
+	for _, name := range v.tableNames() {
+		columns, tableRels := v.table(name)
+		rels = append(rels, tableRels...)
+		fmt.Fprintf(v.stringBuilder, "%s {\n", v.alias(name))
+		for _, c := range columns {
+			comment := ""
+			if c.opt {
+				comment = ` "optional"`
+			}
+			fmt.Fprintf(v.stringBuilder, "  %s %s%s%s\n",
+				mermaidEntityRE.ReplaceAllString(c.typ, "_"), c.name, erKeyMarkers(c, " %s"), comment)
+		}
+		v.stringBuilder.WriteString("}\n")
+	}
+	for _, r := range rels {
+		fmt.Fprintf(v.stringBuilder, "%s %s %s : \"%s\"\n", v.alias(r.child), r.arrow(), v.alias(r.parent), r.column)
+	}
+	return v.stringBuilder.String()
+}
+
+// alias returns the name a table is drawn as. PlantUML entities get short
+// aliases like the other diagrams, Mermaid entities their sanitised names.
+func (v *ERDiagramView) alias(table string) string {
+	if alias, ok := v.aliases[table]; ok {
+		return alias
+	}
+	alias := fmt.Sprintf("_%d", len(v.aliases))
+	if v.mermaid {
+		alias = mermaidEntityRE.ReplaceAllString(table, "_")
+	}
+	v.aliases[table] = alias
+	return alias
+}
+
+func (v *ERDiagramView) tableNames() []string {
+	names := []string{}
+	for name, t := range v.app.GetTypes() {
+		if t.GetRelation() != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// table returns the columns of a table, primary key columns first, and its
+// foreign keys.
+func (v *ERDiagramView) table(name string) ([]erColumn, []erRelationship) {
+	rel := v.app.GetTypes()[name].GetRelation()
+	pks := syslutil.MakeStrSet(rel.GetPrimaryKey().GetAttrName()...)
+	unique := syslutil.MakeStrSet()
+	if len(rel.GetPrimaryKey().GetAttrName()) == 1 {
+		unique.Insert(rel.GetPrimaryKey().GetAttrName()[0])
+	}
+	for _, key := range rel.GetKey() {
+		if len(key.GetAttrName()) == 1 {
+			unique.Insert(key.GetAttrName()[0])
+		}
+	}
+
+	columns := []erColumn{}
+	rels := []erRelationship{}
+	for colName, t := range rel.GetAttrDefs() {
+		c := erColumn{name: colName, pk: pks.Contains(colName), opt: t.GetOpt()}
+		if t.GetPrimitive() != sysl.Type_NO_Primitive {
+			c.typ = strings.ToLower(t.GetPrimitive().String())
+		} else if path := t.GetTypeRef().GetRef().GetPath(); len(path) > 0 {
+			c.fk = true
+			c.typ = v.columnType(path)
+			rels = append(rels, erRelationship{
+				child:  name,
+				parent: path[0],
+				column: colName,
+				unique: unique.Contains(colName) || syslutil.HasPattern(t.GetAttrs(), "unique"),
+				opt:    c.opt,
+			})
+		} else {
+			c.typ = compositeType(t)
+		}
+		columns = append(columns, c)
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		if columns[i].pk != columns[j].pk {
+			return columns[i].pk
+		}
+		return columns[i].name < columns[j].name
+	})
+	sort.Slice(rels, func(i, j int) bool { return rels[i].column < rels[j].column })
+	return columns, rels
+}
+
+// compositeType names the kind of a column that is neither primitive nor a
+// foreign key, as neither diagram has a syntax for its element types.
+func compositeType(t *sysl.Type) string {
+	switch {
+	case t.GetList() != nil || t.GetSequence() != nil:
+		return "sequence"
+	case t.GetSet() != nil:
+		return "set"
+	case t.GetMap() != nil:
+		return "map"
+	case t.GetTuple() != nil:
+		return "tuple"
+	default:
+		return "any"
+	}
+}
+
+// columnType returns the type of the column a foreign key refers to, or the
+// name of the referenced table when it is not a column of this application.
+func (v *ERDiagramView) columnType(path []string) string {
+	if len(path) > 1 {
+		if t := v.app.GetTypes()[path[0]].GetRelation().GetAttrDefs()[path[1]]; t != nil {
+			if t.GetPrimitive() != sysl.Type_NO_Primitive {
+				return strings.ToLower(t.GetPrimitive().String())
+			}
+			if ref := t.GetTypeRef().GetRef().GetPath(); len(ref) > 0 {
+				return v.columnType(ref)
+			}
+		}
+	}
+	return strings.Join(path, ".")
+}
+
+// arrow returns the crow's foot line from the child to the parent, which
+// PlantUML and Mermaid spell alike.
+func (r erRelationship) arrow() string {
+	child, parent := "}o", "||"
+	if r.unique {
+		child = "|o"
+	}
+	if r.opt {
+		parent = "o|"
+	}
+	return child + "--" + parent
+}
+
+// erKeyMarkers formats the PK and FK markers of a column, or nothing if it is
+// not a key.
+func erKeyMarkers(c erColumn, format string) string {
+	markers := []string{}
+	if c.pk {
+		markers = append(markers, "PK")
+	}
+	if c.fk {
+		markers = append(markers, "FK")
+	}
+	if len(markers) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(format, strings.Join(markers, ", "))
+}
@@ -0,0 +1,223 @@
+package diagrams
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// PumlHeader starts every PlantUML diagram with the autogenerated code
+// warning.
+const PumlHeader = `''''''''''''''''''''''''''''''''''''''''''
+''                                      ''
+''  AUTOGENERATED CODE -- DO NOT EDIT!  ''
+''                                      ''
+''''''''''''''''''''''''''''''''''''''''''
+
+`
+
+// OutputPlantuml writes a diagram to output, rendering png and svg with the
+// renderer described by plantuml (see MakeRenderer).
+func OutputPlantuml(output, plantuml, umlInput string, fs afero.Fs) error {
+	r, err := MakeRenderer(plantuml)
+	if err != nil {
+		return err
+	}
+	return OutputDiagram(output, r, umlInput, fs)
+}
+
+// OutputDiagram writes a diagram to output in the format of its extension.
+func OutputDiagram(output string, r Renderer, umlInput string, fs afero.Fs) error {
+	var buf bytes.Buffer
+	if err := WriteDiagram(&buf, output[len(output)-3:], r, umlInput); err != nil {
+		return err
+	}
+	return writeDiagramFile(DiagramFile(output), buf.Bytes(), fs)
+}
+
+// WriteDiagram writes a diagram to w in format, the extension of an output:
+// png and svg are rendered with r, and PlantUML, Mermaid and Graphviz source
+// is written as is.
+func WriteDiagram(w io.Writer, format string, r Renderer, umlInput string) error {
+	var out []byte
+	switch format {
+	case "png", "svg":
+		var err error
+		if out, err = r.Render(format, umlInput); err != nil {
+			return err
+		}
+
+	case "uml", "mmd", "dot":
+		// Mermaid and Graphviz diagrams are rendered by their viewers, so are written as is.
+		out = []byte(umlInput)
+
+	default:
+		return fmt.Errorf("extension must be svg, png, uml, mmd or dot, not %#v", format)
+	}
+	_, err := w.Write(out)
+	return err
+}
+
+// OutputDiagrams writes each diagram in m to the output it is keyed by,
+// rendering up to jobs diagrams at once. Every diagram is attempted, and the
+// failures are returned together as RenderErrors.
+func OutputDiagrams(m map[string]string, r Renderer, jobs int, fs afero.Fs) error {
+	failures := OutputAll(m, r, jobs, fs)
+	outputs := make([]string, 0, len(failures))
+	for output := range failures {
+		outputs = append(outputs, output)
+	}
+	sort.Strings(outputs)
+
+	var errs RenderErrors
+	for _, output := range outputs {
+		errs = append(errs, errors.Wrap(failures[output], output))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// OutputAll writes each diagram in m to the output it is keyed by, rendering
+// up to jobs diagrams at once, and returns the errors of those that failed.
+func OutputAll(m map[string]string, r Renderer, jobs int, fs afero.Fs) map[string]error {
+	outputs := make([]string, 0, len(m))
+	for output := range m {
+		outputs = append(outputs, output)
+	}
+	sort.Strings(outputs)
+
+	if jobs < 1 {
+		jobs = 1
+	}
+	errs := make([]error, len(outputs))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				errs[i] = OutputDiagram(outputs[i], r, m[outputs[i]], fs)
+			}
+		}()
+	}
+	for i := range outputs {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	failures := map[string]error{}
+	for i, err := range errs {
+		if err != nil {
+			failures[outputs[i]] = err
+		}
+	}
+	return failures
+}
+
+// DiagramFile returns the file a diagram is written to for an output, which is
+// the output itself except for PlantUML source.
+func DiagramFile(output string) string {
+	if strings.HasSuffix(output, ".uml") {
+		return strings.TrimSuffix(output, ".uml") + ".puml"
+	}
+	return output
+}
+
+// writeDiagramFile writes a diagram, creating the directory it goes in, as
+// output patterns like %(appname)/%(epname).svg write one per app.
+func writeDiagramFile(output string, content []byte, fs afero.Fs) error {
+	if err := fs.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+		return errors.Wrapf(err, "writing %q", output)
+	}
+	return errors.Wrapf(afero.WriteFile(fs, output, content, os.ModePerm), "writing %q", output)
+}
+
+func sendHTTPRequest(url string) ([]byte, error) {
+	resp, err := http.Get(url) //nolint:gosec
+	if err != nil {
+		return nil, errors.Errorf("Unable to create http request to %s, Error:%s", url, err.Error())
+	}
+	defer resp.Body.Close()
+
+	out, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Errorf("Unable to read body.")
+	}
+	// PlantUML servers answer a diagram they cannot render with an image of
+	// the error, which must not be written out as the diagram.
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, errors.Errorf("Request to %s failed: %s", url, resp.Status)
+	}
+	return out, nil
+}
+
+// The functions below ported from https://github.com/dougn/python-plantuml/blob/master/plantuml.py
+func DeflateAndEncode(text []byte) (string, error) {
+	var buf bytes.Buffer
+	zw, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := zw.Write(text); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return encode(buf.Bytes()), nil
+}
+
+func encode(data []byte) string {
+	var buf bytes.Buffer
+	i := 0
+	for wholeTripleBytes := len(data) / 3 * 3; i < wholeTripleBytes; i += 3 {
+		encode3bytes(&buf, data[i], data[i+1], data[i+2])
+	}
+	switch len(data) - i {
+	case 1:
+		encode3bytes(&buf, data[i], 0, 0)
+	case 2:
+		encode3bytes(&buf, data[i], data[i+1], 0)
+	}
+	return buf.String()
+}
+
+// 3 bytes takes 24 bits. This splits 24 bits into 4 bytes of which lower 6-bit takes account.
+func encode3bytes(w io.ByteWriter, b1, b2, b3 byte) {
+	if err := w.WriteByte(encode6bit(0x3F & (b1 >> 2))); err != nil {
+		panic(err)
+	}
+	if err := w.WriteByte(encode6bit(0x3F & (((b1 & 0x3) << 4) | (b2 >> 4)))); err != nil {
+		panic(err)
+	}
+	if err := w.WriteByte(encode6bit(0x3F & (((b2 & 0xF) << 2) | (b3 >> 6)))); err != nil {
+		panic(err)
+	}
+	if err := w.WriteByte(encode6bit(0x3F & b3)); err != nil {
+		panic(err)
+	}
+}
+
+func encode6bit(b byte) byte {
+	// 6 bit makes value 0 to 63. The func maps 0-63 to characters
+	// '0'-'9', 'A'-'Z', 'a'-'z', '-', '_'. '?' should never be reached.
+	if b > 63 {
+		panic("unexpected character!")
+	}
+	return "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_"[b]
+}
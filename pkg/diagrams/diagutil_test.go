@@ -1,6 +1,9 @@
-package main
+package diagrams
 
 import (
+	"bytes"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/anz-bank/sysl/pkg/syslutil"
@@ -90,7 +93,7 @@ func TestEncode6bit(t *testing.T) {
 
 	for _, v := range data {
 		v := v
-		t.Run(string(int(v.input)), func(tt *testing.T) {
+		t.Run(strconv.Itoa(int(v.input)), func(tt *testing.T) {
 			actual := encode6bit(v.input)
 			assert.Equal(tt, v.expected, actual)
 		})
@@ -143,3 +146,61 @@ func TestEncode(t *testing.T) {
 	//Then
 	assert.NotNil(t, r)
 }
+
+func TestOutputPlantumlWithMermaid(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, OutputPlantuml("/test.mmd", "http://unreachable.invalid", "sequenceDiagram\n", fs))
+	syslutil.AssertFsHasExactly(t, fs, "/test.mmd")
+	out, err := afero.ReadFile(fs, "/test.mmd")
+	require.NoError(t, err)
+	assert.Equal(t, "sequenceDiagram\n", string(out))
+}
+
+func TestOutputPlantumlWithBuiltinRenderer(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, OutputPlantuml("/test.svg", BuiltinRenderer, testPlantumlInput, fs))
+	syslutil.AssertFsHasExactly(t, fs, "/test.svg")
+	out, err := afero.ReadFile(fs, "/test.svg")
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "QueryUser")
+}
+
+func TestOutputDiagramsReportsAllFailures(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	err := OutputDiagrams(map[string]string{
+		"/a.wrong":  testPlantumlInput,
+		"/b.png":    testPlantumlInput,
+		"/c.uml":    testPlantumlInput,
+		"/d.svg":    "@startuml\n[Api] as _0\n@enduml\n",
+		"/e.svg":    testPlantumlInput,
+		"/f.wrong2": testPlantumlInput,
+	}, SequenceSVGRenderer{}, 2, fs)
+	require.Error(t, err)
+	errs, ok := err.(RenderErrors)
+	require.True(t, ok)
+	require.Len(t, errs, 4)
+	for i, output := range []string{"/a.wrong", "/b.png", "/d.svg", "/f.wrong2"} {
+		assert.Contains(t, errs[i].Error(), output)
+	}
+	syslutil.AssertFsHasExactly(t, fs, "/c.puml", "/e.svg")
+}
+
+func TestWriteDiagram(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteDiagram(&buf, "svg", SequenceSVGRenderer{}, testPlantumlInput))
+	assert.True(t, strings.HasPrefix(buf.String(), "<svg"))
+
+	buf.Reset()
+	require.NoError(t, WriteDiagram(&buf, "uml", SequenceSVGRenderer{}, testPlantumlInput))
+	assert.Equal(t, testPlantumlInput, buf.String())
+
+	assert.Error(t, WriteDiagram(&buf, "txt", SequenceSVGRenderer{}, testPlantumlInput))
+}
@@ -1,10 +1,12 @@
-package main
+package diagrams
 
 import (
+	"encoding/json"
 	"regexp"
 	"strings"
 
 	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -251,3 +253,25 @@ func removePercentSymbol(src string) string {
 
 	return src
 }
+
+// ConstructFormatParser returns a parser for the format former, or latter if
+// former is empty, as when an attribute of the model overrides a flag.
+func ConstructFormatParser(former, latter string) *FormatParser {
+	fmtstr := former
+	if former == "" {
+		fmtstr = latter
+	}
+
+	return MakeFormatParser(escapeWordBoundary(fmtstr))
+}
+
+func escapeWordBoundary(src string) string {
+	result, err := json.Marshal(src)
+	syslutil.PanicOnError(err)
+	escapeStr := strings.Replace(string(result), `\u0008`, `\\b`, -1)
+	var val string
+	err = json.Unmarshal([]byte(escapeStr), &val)
+	syslutil.PanicOnError(err)
+
+	return val
+}
@@ -0,0 +1,32 @@
+package diagrams
+
+import (
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+)
+
+type EndpointLabelerParam struct {
+	EndpointName string
+	Human        string
+	HumanSender  string
+	NeedsInt     string
+	Args         string
+	Patterns     string
+	Controls     string
+	Attrs        map[string]*sysl.Attribute
+}
+
+type EndpointLabeler interface {
+	LabelEndpoint(*EndpointLabelerParam) string
+}
+
+type AppLabeler interface {
+	LabelApp(appName, controls string, attrs map[string]*sysl.Attribute) string
+}
+
+type ClassLabeler interface {
+	LabelClass(className string) string
+}
+
+type VarManager interface {
+	UniqueVarForAppName(appName string) string
+}
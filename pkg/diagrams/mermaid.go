@@ -0,0 +1,61 @@
+package diagrams
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// MermaidExt is the extension of Mermaid diagram output. Diagrams written to
+// files with this extension are generated as Mermaid rather than PlantUML.
+const MermaidExt = ".mmd"
+
+const MermaidHeader = `%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%
+%%                                      %%
+%%  AUTOGENERATED CODE -- DO NOT EDIT!  %%
+%%                                      %%
+%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%%
+
+`
+
+//nolint:gochecknoglobals
+var (
+	mermaidEscaper = strings.NewReplacer(
+		"#", "#35;",
+		";", "#59;",
+		`"`, "#quot;",
+		"<", "#lt;",
+		">", "#gt;",
+		"\n", "<br/>",
+	)
+
+	// plantumlColorRE matches the colour markup sequence diagrams add to the
+	// labels of arguments.
+	plantumlColorRE = regexp.MustCompile(`</?color[^>]*>`)
+)
+
+// IsMermaidOutput reports whether a diagram written to output is Mermaid.
+func IsMermaidOutput(output string) bool {
+	return strings.HasSuffix(output, MermaidExt)
+}
+
+// MermaidText escapes characters that Mermaid treats as statement or label
+// delimiters, dropping any PlantUML colour markup.
+func MermaidText(s string) string {
+	return mermaidEscaper.Replace(plantumlColorRE.ReplaceAllString(s, ""))
+}
+
+// WriteMermaidPreamble writes the front matter carrying the title, which
+// Mermaid requires before anything else, and the autogenerated code warning.
+func WriteMermaidPreamble(w io.Writer, title string) {
+	if title != "" {
+		fmt.Fprintf(w, "---\ntitle: %s\n---\n", MermaidText(title))
+	}
+	fmt.Fprint(w, MermaidHeader)
+}
+
+// MermaidEdgeText escapes an edge label, including the pipes delimiting it.
+func MermaidEdgeText(label string) string {
+	return strings.ReplaceAll(MermaidText(label), "|", "#124;")
+}
@@ -0,0 +1,153 @@
+package diagrams
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// BuiltinRenderer selects the pure-Go SVG renderer in place of a PlantUML
+// server, executable or jar. It only renders sequence diagrams.
+const BuiltinRenderer = "builtin"
+
+// Renderer renders a PlantUML diagram as an image of the given format, "png"
+// or "svg".
+type Renderer interface {
+	Render(format, uml string) ([]byte, error)
+}
+
+// MakeRenderer returns the renderer described by the --plantuml flag: the
+// builtin renderer, the base url of a PlantUML server, a PlantUML jar run with
+// java, or else a PlantUML executable. Jars and executables must be found when
+// the renderer is made, so a mistyped flag fails once rather than per diagram.
+func MakeRenderer(plantuml string) (Renderer, error) {
+	switch {
+	case plantuml == BuiltinRenderer:
+		return SequenceSVGRenderer{}, nil
+	case strings.HasPrefix(plantuml, "http://") || strings.HasPrefix(plantuml, "https://"):
+		return HTTPRenderer{URL: plantuml}, nil
+	case strings.HasSuffix(plantuml, ".jar"):
+		if _, err := os.Stat(plantuml); err != nil {
+			return nil, errors.Errorf("--plantuml: PlantUML jar %s not found", plantuml)
+		}
+		if _, err := exec.LookPath("java"); err != nil {
+			return nil, errors.Errorf("--plantuml: java is needed to run PlantUML jar %s: %s", plantuml, err)
+		}
+		return ExecRenderer{Command: []string{"java", "-Djava.awt.headless=true", "-jar", plantuml}}, nil
+	default:
+		if _, err := exec.LookPath(plantuml); err != nil {
+			return nil, errors.Errorf("--plantuml: %#v is not a PlantUML server url, jar or executable: %s",
+				plantuml, err)
+		}
+		return ExecRenderer{Command: []string{plantuml}}, nil
+	}
+}
+
+// HTTPRenderer renders diagrams with a PlantUML server.
+type HTTPRenderer struct {
+	URL string
+}
+
+func (r HTTPRenderer) Render(format, uml string) ([]byte, error) {
+	encoded, err := DeflateAndEncode([]byte(uml))
+	if err != nil {
+		return nil, err
+	}
+	out, err := sendHTTPRequest(fmt.Sprintf("%s/%s/%s", r.URL, format, encoded))
+	if err != nil {
+		return nil, err
+	}
+	return append(out, byte('\n')), nil
+}
+
+// ExecRenderer renders diagrams by piping them through a local PlantUML
+// command, such as "plantuml" or "java -jar plantuml.jar".
+type ExecRenderer struct {
+	Command []string
+}
+
+func (r ExecRenderer) Render(format, uml string) ([]byte, error) {
+	args := append(append([]string{}, r.Command[1:]...), "-pipe", "-charset", "UTF-8", "-t"+format)
+	cmd := exec.Command(r.Command[0], args...) //nolint:gosec
+	cmd.Stdin = strings.NewReader(uml)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, "running %s: %s", strings.Join(r.Command, " "), strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// CachingRenderer serves diagrams rendered before from a content-addressed
+// cache in Dir, so unchanged diagrams are not sent to the Renderer again.
+// Entries are keyed on Key, which identifies the Renderer, the format and the
+// deflate-encoded diagram. Only diagrams rendered without error are cached.
+type CachingRenderer struct {
+	Renderer
+	Key string
+	Dir string
+	Fs  afero.Fs
+}
+
+func (r CachingRenderer) Render(format, uml string) ([]byte, error) {
+	encoded, err := DeflateAndEncode([]byte(uml))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(r.Key + "\n" + format + "\n" + encoded))
+	path := filepath.Join(r.Dir, hex.EncodeToString(sum[:])+"."+format)
+	if out, err := afero.ReadFile(r.Fs, path); err == nil {
+		return out, nil
+	}
+
+	out, err := r.Renderer.Render(format, uml)
+	if err != nil {
+		return nil, err
+	}
+	// The cache only saves time, so failing to fill it is not an error.
+	_ = r.store(path, out)
+	return out, nil
+}
+
+// store writes an entry through a temporary file, so concurrent renders of
+// the same diagram never read a partial entry.
+func (r CachingRenderer) store(path string, out []byte) error {
+	if err := r.Fs.MkdirAll(r.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	f, err := afero.TempFile(r.Fs, r.Dir, "render")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(out)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = r.Fs.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = r.Fs.Remove(f.Name())
+	}
+	return err
+}
+
+// RenderErrors is the list of diagrams that could not be written.
+type RenderErrors []error
+
+func (e RenderErrors) Error() string {
+	lines := make([]string, 0, len(e))
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
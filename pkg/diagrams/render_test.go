@@ -0,0 +1,129 @@
+package diagrams
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeRenderer(t *testing.T) {
+	t.Parallel()
+
+	for plantuml, expected := range map[string]Renderer{
+		BuiltinRenderer: SequenceSVGRenderer{},
+		plantumlDotCom:  HTTPRenderer{URL: plantumlDotCom},
+	} {
+		r, err := MakeRenderer(plantuml)
+		require.NoError(t, err)
+		assert.Equal(t, expected, r)
+	}
+
+	if _, err := exec.LookPath("sh"); err == nil {
+		r, err := MakeRenderer("sh")
+		require.NoError(t, err)
+		assert.Equal(t, ExecRenderer{Command: []string{"sh"}}, r)
+	}
+	if _, err := exec.LookPath("java"); err == nil {
+		jar, err := ioutil.TempFile("", "plantuml*.jar")
+		require.NoError(t, err)
+		defer os.Remove(jar.Name())
+		require.NoError(t, jar.Close())
+		r, err := MakeRenderer(jar.Name())
+		require.NoError(t, err)
+		assert.Equal(t, ExecRenderer{Command: []string{"java", "-Djava.awt.headless=true", "-jar", jar.Name()}}, r)
+	}
+}
+
+func TestMakeRendererNotFound(t *testing.T) {
+	t.Parallel()
+
+	_, err := MakeRenderer("localhost:8080")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `--plantuml: "localhost:8080" is not a PlantUML server url, jar or executable`)
+
+	_, err = MakeRenderer("/nonexistent/plantuml.jar")
+	assert.EqualError(t, err, "--plantuml: PlantUML jar /nonexistent/plantuml.jar not found")
+}
+
+func TestExecRenderer(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	// Echo the diagram back, ignoring the arguments added for PlantUML.
+	out, err := ExecRenderer{Command: []string{"sh", "-c", "cat", "plantuml"}}.Render("svg", testPlantumlInput)
+	require.NoError(t, err)
+	assert.Equal(t, testPlantumlInput, string(out))
+}
+
+func TestExecRendererMissingCommand(t *testing.T) {
+	t.Parallel()
+
+	_, err := ExecRenderer{Command: []string{"/nonexistent/plantuml"}}.Render("svg", testPlantumlInput)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/nonexistent/plantuml")
+}
+
+type countingRenderer struct {
+	calls int
+}
+
+func (r *countingRenderer) Render(format, uml string) ([]byte, error) {
+	r.calls++
+	return []byte(format + ":" + uml), nil
+}
+
+func TestCachingRenderer(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	counter := &countingRenderer{}
+	r := CachingRenderer{Renderer: counter, Key: "test", Dir: "/cache", Fs: fs}
+
+	for i := 0; i < 2; i++ {
+		out, err := r.Render("svg", testPlantumlInput)
+		require.NoError(t, err)
+		assert.Equal(t, "svg:"+testPlantumlInput, string(out))
+	}
+	assert.Equal(t, 1, counter.calls)
+
+	_, err := r.Render("png", testPlantumlInput)
+	require.NoError(t, err)
+	_, err = CachingRenderer{Renderer: counter, Key: "other", Dir: "/cache", Fs: fs}.Render("svg", testPlantumlInput)
+	require.NoError(t, err)
+	assert.Equal(t, 3, counter.calls)
+
+	entries, err := afero.ReadDir(fs, "/cache")
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
+
+func TestHTTPRendererErrorStatus(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("<svg>Syntax Error?</svg>"))
+	}))
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	r := CachingRenderer{Renderer: HTTPRenderer{URL: server.URL}, Key: server.URL, Dir: "/cache", Fs: fs}
+	_, err := r.Render("svg", testPlantumlInput)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400 Bad Request")
+
+	entries, err := afero.ReadDir(fs, "/cache")
+	if err == nil {
+		assert.Empty(t, entries)
+	}
+}
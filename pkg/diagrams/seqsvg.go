@@ -0,0 +1,403 @@
+package diagrams
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SequenceSVGRenderer renders sequence diagrams as SVG without PlantUML. It
+// understands the subset of PlantUML written by SequenceDiagramVisitor and
+// fails on anything else, such as integration or data model diagrams.
+type SequenceSVGRenderer struct{}
+
+func (SequenceSVGRenderer) Render(format, uml string) ([]byte, error) {
+	if format != "svg" {
+		return nil, errors.Errorf("the %s renderer only renders svg, not %s", BuiltinRenderer, format)
+	}
+	d, err := parseSequenceUML(uml)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(d.svg()), nil
+}
+
+const (
+	svgMargin       = 20
+	svgCharWidth    = 7
+	svgLineHeight   = 16
+	svgHeaderHeight = 30
+	svgMinColWidth  = 120
+	svgExternalGap  = 40
+	svgActiveWidth  = 10
+)
+
+type seqEventKind int
+
+const (
+	seqMessage seqEventKind = iota
+	seqNote
+	seqDivider
+	seqBlockStart
+	seqBlockElse
+	seqBlockEnd
+	seqActivate
+	seqDeactivate
+)
+
+// seqExternal is the alias PlantUML uses for the edge of the diagram.
+const seqExternal = "["
+
+type seqEvent struct {
+	kind     seqEventKind
+	from, to string
+	dashed   bool
+	text     string
+}
+
+type seqParticipant struct {
+	alias string
+	label string
+	x     int
+}
+
+type seqDiagram struct {
+	title        string
+	participants []*seqParticipant
+	byAlias      map[string]*seqParticipant
+	events       []seqEvent
+	external     bool
+}
+
+//nolint:gochecknoglobals
+var (
+	seqParticipantRE = regexp.MustCompile(
+		`^(?:actor|boundary|control|database|entity|participant|collections|queue)\s+(?:"([^"]*)"|(\w+))(?:\s+as\s+(\w+))?`)
+	seqMessageRE  = regexp.MustCompile(`^(\[|\w+)\s*(<--|<-|-->|->)\s*(\[|\w+)\s*(?::\s*(.*))?$`)
+	seqActivateRE = regexp.MustCompile(`^(activate|deactivate)\s+(\w+)$`)
+	seqNoteRE     = regexp.MustCompile(`^note\s+(?:over\s+(\w+)|(left|right))\s*:\s*(.*)$`)
+	seqDividerRE  = regexp.MustCompile(`^==\s*(.*?)\s*==$`)
+	seqBlockRE    = regexp.MustCompile(`^(opt|loop|alt|group|par|critical|break)\b\s*(.*)$`)
+	seqElseRE     = regexp.MustCompile(`^else\b\s*(.*)$`)
+	seqTitleRE    = regexp.MustCompile(`^title\s+(.*)$`)
+	seqIgnoredRE  = regexp.MustCompile(`^(?:$|'|@startuml|@enduml|skinparam\s|hide\s|scale\s|autonumber|box\s|end box)`)
+
+	// plantumlIconRE matches the OpenIconic icons sysl adds to labels.
+	plantumlIconRE = regexp.MustCompile(`<&[\w-]+>`)
+)
+
+func seqText(s string) string {
+	return plantumlIconRE.ReplaceAllString(plantumlColorRE.ReplaceAllString(s, ""), "")
+}
+
+func parseSequenceUML(uml string) (*seqDiagram, error) {
+	d := &seqDiagram{byAlias: map[string]*seqParticipant{}}
+	blocks := 0
+	scanner := bufio.NewScanner(strings.NewReader(uml))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if seqIgnoredRE.MatchString(line) {
+			continue
+		}
+		switch {
+		case seqTitleRE.MatchString(line):
+			d.title = seqText(seqTitleRE.FindStringSubmatch(line)[1])
+		case seqParticipantRE.MatchString(line):
+			m := seqParticipantRE.FindStringSubmatch(line)
+			label, alias := m[1]+m[2], m[3]
+			if alias == "" {
+				alias = label
+			}
+			if _, has := d.byAlias[alias]; !has {
+				d.participant(alias).label = seqText(label)
+			}
+		case seqMessageRE.MatchString(line):
+			m := seqMessageRE.FindStringSubmatch(line)
+			from, to := d.participant(m[1]), d.participant(m[3])
+			if strings.HasPrefix(m[2], "<") {
+				from, to = to, from
+			}
+			d.events = append(d.events, seqEvent{
+				kind: seqMessage, from: from.alias, to: to.alias, dashed: strings.Contains(m[2], "--"), text: seqText(m[4]),
+			})
+		case seqActivateRE.MatchString(line):
+			m := seqActivateRE.FindStringSubmatch(line)
+			kind := seqActivate
+			if m[1] == "deactivate" {
+				kind = seqDeactivate
+			}
+			d.events = append(d.events, seqEvent{kind: kind, to: d.participant(m[2]).alias})
+		case seqNoteRE.MatchString(line):
+			m := seqNoteRE.FindStringSubmatch(line)
+			if m[1] != "" {
+				d.participant(m[1])
+			}
+			d.events = append(d.events, seqEvent{kind: seqNote, to: m[1], from: m[2], text: seqText(m[3])})
+		case seqDividerRE.MatchString(line):
+			d.events = append(d.events, seqEvent{kind: seqDivider, text: seqText(seqDividerRE.FindStringSubmatch(line)[1])})
+		case seqBlockRE.MatchString(line):
+			m := seqBlockRE.FindStringSubmatch(line)
+			blocks++
+			d.events = append(d.events, seqEvent{kind: seqBlockStart, from: m[1], text: seqText(m[2])})
+		case seqElseRE.MatchString(line) && blocks > 0:
+			d.events = append(d.events, seqEvent{kind: seqBlockElse, text: seqText(seqElseRE.FindStringSubmatch(line)[1])})
+		case line == "end" && blocks > 0:
+			blocks--
+			d.events = append(d.events, seqEvent{kind: seqBlockEnd})
+		default:
+			return nil, errors.Errorf("the %s renderer only renders sequence diagrams, line %d: %q",
+				BuiltinRenderer, n, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(d.participants) == 0 {
+		return nil, errors.Errorf("the %s renderer found no participants to render", BuiltinRenderer)
+	}
+	return d, nil
+}
+
+// participant returns the participant with the alias, declaring it if it has
+// not been, as PlantUML does.
+func (d *seqDiagram) participant(alias string) *seqParticipant {
+	if alias == seqExternal {
+		d.external = true
+		return &seqParticipant{alias: seqExternal}
+	}
+	if p, has := d.byAlias[alias]; has {
+		return p
+	}
+	p := &seqParticipant{alias: alias, label: alias}
+	d.participants = append(d.participants, p)
+	d.byAlias[alias] = p
+	return p
+}
+
+func textWidth(s string) int {
+	return len([]rune(s)) * svgCharWidth
+}
+
+// layout places the participants far enough apart for their labels and the
+// labels of the messages between them, returning the width of the diagram.
+func (d *seqDiagram) layout() int {
+	index := map[string]int{}
+	gaps := make([]int, len(d.participants))
+	for i, p := range d.participants {
+		index[p.alias] = i
+		gaps[i] = svgMinColWidth
+		if w := textWidth(p.label) + svgMargin; w > gaps[i] {
+			gaps[i] = w
+		}
+	}
+	for _, e := range d.events {
+		if e.kind != seqMessage || e.from == seqExternal || e.to == seqExternal {
+			continue
+		}
+		i, j := index[e.from], index[e.to]
+		if i > j {
+			i, j = j, i
+		}
+		need := textWidth(e.text) + svgMargin
+		if i == j {
+			j++
+		}
+		for k := i; k < j && k < len(gaps)-1; k++ {
+			if g := need / (j - i); g > gaps[k] {
+				gaps[k] = g
+			}
+		}
+	}
+
+	x := svgMargin + textWidth(d.participants[0].label)/2 + svgMargin/2
+	if d.external {
+		x += svgExternalGap
+	}
+	if gaps[0]/2 > x {
+		x = gaps[0] / 2
+	}
+	for i, p := range d.participants {
+		p.x = x
+		x += gaps[i]
+	}
+	return x - gaps[len(gaps)-1]/2 + svgMargin
+}
+
+// seqCanvas collects the SVG elements drawn below and above the messages.
+type seqCanvas struct {
+	back, front strings.Builder
+	width       int
+	y           int
+}
+
+func (c *seqCanvas) text(sb *strings.Builder, x, y int, anchor, s string) {
+	fmt.Fprintf(sb, `<text x="%d" y="%d" text-anchor="%s">%s</text>`+"\n", x, y, anchor, html.EscapeString(s))
+}
+
+func (c *seqCanvas) box(x, y, w, h int, fill string) {
+	fmt.Fprintf(&c.front, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="black"/>`+"\n",
+		x, y, w, h, fill)
+}
+
+type seqBlock struct {
+	kind, label string
+	y           int
+	elses       []seqEvent
+	elseY       []int
+}
+
+func (d *seqDiagram) svg() string {
+	c := &seqCanvas{width: d.layout(), y: svgMargin}
+	if d.title != "" {
+		c.y += svgLineHeight
+		c.text(&c.front, c.width/2, c.y, "middle", d.title)
+		c.y += svgMargin / 2
+	}
+	top := c.y
+	c.y += svgHeaderHeight + svgMargin
+
+	active := map[string][]int{}
+	blocks := []*seqBlock{}
+	var last *seqEvent
+	for i := range d.events {
+		e := &d.events[i]
+		switch e.kind {
+		case seqMessage:
+			d.drawMessage(c, e, active)
+			last = e
+		case seqNote:
+			d.drawNote(c, e, last)
+		case seqDivider:
+			fmt.Fprintf(&c.back, `<line x1="0" y1="%d" x2="%d" y2="%d" stroke="gray" stroke-dasharray="2,2"/>`+"\n",
+				c.y, c.width, c.y)
+			w := textWidth(e.text) + svgMargin
+			c.box(c.width/2-w/2, c.y-svgLineHeight/2-2, w, svgLineHeight+4, "#EEEEEE")
+			c.text(&c.front, c.width/2, c.y+4, "middle", e.text)
+			c.y += svgHeaderHeight
+		case seqBlockStart:
+			blocks = append(blocks, &seqBlock{kind: e.from, label: e.text, y: c.y})
+			c.y += svgHeaderHeight
+		case seqBlockElse:
+			b := blocks[len(blocks)-1]
+			b.elses = append(b.elses, *e)
+			b.elseY = append(b.elseY, c.y)
+			c.y += svgHeaderHeight
+		case seqBlockEnd:
+			d.drawBlock(c, blocks[len(blocks)-1], len(blocks)-1)
+			blocks = blocks[:len(blocks)-1]
+			c.y += svgMargin / 2
+		case seqActivate:
+			active[e.to] = append(active[e.to], c.y-svgLineHeight/2)
+		case seqDeactivate:
+			d.drawActivation(c, e.to, active)
+		}
+	}
+	for _, p := range d.participants {
+		for len(active[p.alias]) > 0 {
+			d.drawActivation(c, p.alias, active)
+		}
+	}
+
+	bottom := c.y
+	for _, p := range d.participants {
+		fmt.Fprintf(&c.back, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="gray" stroke-dasharray="5,5"/>`+"\n",
+			p.x, top+svgHeaderHeight, p.x, bottom)
+		for _, y := range []int{top, bottom} {
+			w := textWidth(p.label) + svgMargin
+			c.box(p.x-w/2, y, w, svgHeaderHeight, "#FEFECE")
+			c.text(&c.front, p.x, y+svgHeaderHeight/2+4, "middle", p.label)
+		}
+	}
+	height := bottom + svgHeaderHeight + svgMargin
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" `+
+		`font-family="sans-serif" font-size="12">`+"\n", c.width, height, c.width, height)
+	sb.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" ` +
+		`markerHeight="8" orient="auto"><path d="M0,0 L10,5 L0,10 z"/></marker></defs>` + "\n")
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="white"/>`+"\n", c.width, height)
+	sb.WriteString(c.back.String())
+	sb.WriteString(c.front.String())
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
+
+func (d *seqDiagram) x(alias string) int {
+	if alias == seqExternal {
+		return svgMargin / 2
+	}
+	return d.byAlias[alias].x
+}
+
+func (d *seqDiagram) drawMessage(c *seqCanvas, e *seqEvent, active map[string][]int) {
+	dash := ""
+	if e.dashed {
+		dash = ` stroke-dasharray="5,3"`
+	}
+	from, to := d.x(e.from), d.x(e.to)
+	if e.from == e.to {
+		offset := svgActiveWidth / 2 * len(active[e.from])
+		c.text(&c.front, from+offset+5, c.y, "start", e.text)
+		fmt.Fprintf(&c.front, `<polyline points="%d,%d %d,%d %d,%d %d,%d" fill="none" stroke="black"%s `+
+			`marker-end="url(#arrow)"/>`+"\n",
+			from+offset, c.y+4, from+offset+30, c.y+4, from+offset+30, c.y+16, from+offset, c.y+16, dash)
+		c.y += svgHeaderHeight + svgLineHeight/2
+		return
+	}
+	c.text(&c.front, (from+to)/2, c.y, "middle", e.text)
+	fmt.Fprintf(&c.front, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"%s marker-end="url(#arrow)"/>`+"\n",
+		from, c.y+5, to, c.y+5, dash)
+	c.y += svgHeaderHeight
+}
+
+// drawNote draws a note over a participant, or beside the target of the last
+// message as PlantUML does for left and right notes.
+func (d *seqDiagram) drawNote(c *seqCanvas, e *seqEvent, last *seqEvent) {
+	w := textWidth(e.text) + svgMargin/2
+	var x int
+	switch {
+	case e.to != "":
+		x = d.x(e.to) - w/2
+	case last != nil && e.from == "left":
+		x = d.x(last.to) - w - svgActiveWidth
+	case last != nil:
+		x = d.x(last.to) + svgActiveWidth
+	default:
+		x = svgMargin
+	}
+	c.box(x, c.y-svgLineHeight+4, w, svgLineHeight+4, "#FBFB77")
+	c.text(&c.front, x+svgMargin/4, c.y, "start", e.text)
+	c.y += svgHeaderHeight
+}
+
+func (d *seqDiagram) drawBlock(c *seqCanvas, b *seqBlock, depth int) {
+	inset := svgMargin/2 + depth*svgActiveWidth/2
+	fmt.Fprintf(&c.back, `<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="black"/>`+"\n",
+		inset, b.y-svgLineHeight, c.width-2*inset, c.y-b.y+svgLineHeight/2)
+	tag := textWidth(b.kind) + svgMargin/2
+	fmt.Fprintf(&c.back, `<rect x="%d" y="%d" width="%d" height="%d" fill="#EEEEEE" stroke="black"/>`+"\n",
+		inset, b.y-svgLineHeight, tag, svgLineHeight+2)
+	c.text(&c.front, inset+svgMargin/4, b.y-2, "start", b.kind)
+	c.text(&c.front, inset+tag+svgMargin/4, b.y-2, "start", "["+b.label+"]")
+	for i, y := range b.elseY {
+		fmt.Fprintf(&c.back, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black" stroke-dasharray="5,3"/>`+"\n",
+			inset, y-svgLineHeight, c.width-inset, y-svgLineHeight)
+		c.text(&c.front, inset+svgMargin/4, y-2, "start", "["+b.elses[i].text+"]")
+	}
+}
+
+func (d *seqDiagram) drawActivation(c *seqCanvas, alias string, active map[string][]int) {
+	starts := active[alias]
+	if len(starts) == 0 {
+		return
+	}
+	start := starts[len(starts)-1]
+	active[alias] = starts[:len(starts)-1]
+	x := d.x(alias) - svgActiveWidth/2 + svgActiveWidth/2*len(active[alias])
+	fmt.Fprintf(&c.back, `<rect x="%d" y="%d" width="%d" height="%d" fill="white" stroke="black"/>`+"\n",
+		x, start, svgActiveWidth, c.y-svgLineHeight-start)
+}
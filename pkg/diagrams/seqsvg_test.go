@@ -0,0 +1,62 @@
+package diagrams
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceSVGRenderer(t *testing.T) {
+	t.Parallel()
+
+	out, err := SequenceSVGRenderer{}.Render("svg", testPlantumlInput)
+	require.NoError(t, err)
+
+	var doc struct {
+		XMLName xml.Name
+	}
+	require.NoError(t, xml.Unmarshal(out, &doc))
+	assert.Equal(t, "svg", doc.XMLName.Local)
+	for _, label := range []string{"Profile", "WebFrontend", "Database", "GET /users/{user_id}/profile",
+		"WebFrontend &lt;- RequestProfile", `stroke-dasharray="5,3"`} {
+		assert.Contains(t, string(out), label)
+	}
+}
+
+func TestSequenceSVGRendererBlocks(t *testing.T) {
+	t.Parallel()
+
+	out, err := SequenceSVGRenderer{}.Render("svg", `@startuml
+participant "<color blue>A</color> <&timer>" as _0
+participant "B" as _1
+_0 -> _1 : call
+alt ok
+  _1 --> _0 : yes
+else failed
+  note right: retry
+  loop 3 times
+    _0 -> _0 : retry
+  end
+end
+@enduml
+`)
+	require.NoError(t, err)
+	s := string(out)
+	assert.NotContains(t, s, "color")
+	assert.NotContains(t, s, "&lt;&amp;timer")
+	for _, label := range []string{">alt<", "[ok]", "[failed]", ">loop<", "[3 times]", "retry"} {
+		assert.Contains(t, s, label)
+	}
+}
+
+func TestSequenceSVGRendererUnsupported(t *testing.T) {
+	t.Parallel()
+
+	_, err := SequenceSVGRenderer{}.Render("png", testPlantumlInput)
+	require.Error(t, err)
+
+	_, err = SequenceSVGRenderer{}.Render("svg", "@startuml\n[Api] as _0\n@enduml\n")
+	require.Error(t, err)
+}
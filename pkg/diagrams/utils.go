@@ -0,0 +1,81 @@
+package diagrams
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+)
+
+//nolint:gochecknoglobals
+var isoCtrlRE = regexp.MustCompile("^iso_ctrl_(.*)_txt$")
+
+func MergeAttributes(app, edpnt map[string]*sysl.Attribute) map[string]*sysl.Attribute {
+	result := map[string]*sysl.Attribute{}
+	for k, v := range app {
+		result[k] = v
+	}
+	for k, v := range edpnt {
+		result[k] = v
+	}
+
+	return result
+}
+
+func GetApplicationAttrs(m *sysl.Module, appName string) map[string]*sysl.Attribute {
+	if app, ok := m.Apps[appName]; ok {
+		return app.Attrs
+	}
+	return nil
+}
+
+func GetSortedISOCtrlSlice(attrs map[string]*sysl.Attribute) []string {
+	s := make([]string, 0, len(attrs))
+
+	for k := range attrs {
+		match := isoCtrlRE.FindStringSubmatch(k)
+		if len(match) > 1 {
+			s = append(s, match[1])
+		}
+	}
+	sort.Strings(s)
+	return s
+}
+
+func GetSortedISOCtrlStr(attrs map[string]*sysl.Attribute) string {
+	return strings.Join(GetSortedISOCtrlSlice(attrs), ", ")
+}
+
+func GetReturnPayload(stmts []*sysl.Statement) string {
+	for _, v := range stmts {
+		var subStmts []*sysl.Statement
+		switch stmt := v.Stmt.(type) {
+		case *sysl.Statement_Call, *sysl.Statement_Action:
+			continue
+		case *sysl.Statement_Ret:
+			return stmt.Ret.GetPayload()
+		case *sysl.Statement_Alt:
+			for _, c := range stmt.Alt.Choice {
+				if p := GetReturnPayload(c.Stmt); len(p) > 0 {
+					return p
+				}
+			}
+		case *sysl.Statement_Cond:
+			subStmts = stmt.Cond.Stmt
+		case *sysl.Statement_Loop:
+			subStmts = stmt.Loop.Stmt
+		case *sysl.Statement_LoopN:
+			subStmts = stmt.LoopN.Stmt
+		case *sysl.Statement_Foreach:
+			subStmts = stmt.Foreach.Stmt
+		case *sysl.Statement_Group:
+			subStmts = stmt.Group.Stmt
+		}
+
+		if p := GetReturnPayload(subStmts); len(p) > 0 {
+			return p
+		}
+	}
+	return ""
+}
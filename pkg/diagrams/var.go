@@ -0,0 +1,61 @@
+package diagrams
+
+import (
+	"fmt"
+
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+)
+
+// Agent is the kind of participant an application is drawn as, ordered by
+// Category.
+type Agent struct {
+	Category int
+	Name     string
+}
+
+//nolint:gochecknoglobals
+var agents = map[string]Agent{
+	"human":    {0, "actor"},
+	"ui":       {1, "boundary"},
+	"cron":     {2, "control"},
+	"db":       {4, "database"},
+	"external": {5, "control"},
+}
+
+// MakeAgent returns the agent for the patterns attribute of an application.
+func MakeAgent(attrs map[string]*sysl.Attribute) Agent {
+	if patterns, ok := attrs["patterns"]; ok {
+		if x := patterns.GetA(); x != nil {
+			for _, y := range x.Elt {
+				if v, ok := agents[y.GetS()]; ok {
+					return v
+				}
+			}
+		}
+	}
+
+	return Agent{3, "control"}
+}
+
+// Var is a participant of a diagram, drawn with Label and referred to by
+// Alias.
+type Var struct {
+	Agent
+	Order int
+	Label string
+	Alias string
+}
+
+func (s Var) String() string {
+	return fmt.Sprintf(`%s "%s" as %s`, s.Name, s.Label, s.Alias)
+}
+
+// MermaidString declares the participant in a Mermaid sequenceDiagram, which
+// only distinguishes actors from other participants.
+func (s Var) MermaidString() string {
+	kind := "participant"
+	if s.Name == "actor" {
+		kind = "actor"
+	}
+	return fmt.Sprintf("%s %s as %s", kind, s.Alias, MermaidText(s.Label))
+}
@@ -0,0 +1,568 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/pkg/errors"
+)
+
+const dapThreadID = 1
+
+type stepMode int
+
+const (
+	modeRun stepMode = iota
+	modeEntry
+	modePause
+	modeStepIn
+	modeStepOver
+	modeStepOut
+)
+
+var errDAPTerminated = errors.New("debug session terminated")
+
+// DAPSession exposes the evaluation debugger over the Debug Adapter Protocol,
+// so that editors can set breakpoints in transform files, step through views,
+// inspect the Scope and evaluate watch expressions.
+//
+// Serve handles requests from the client, while evaluations attached to the
+// session through DebugAdapter block whenever they stop.
+type DAPSession struct {
+	// SourceRoot is the directory that source file names in the model are
+	// relative to. It is used to report absolute paths to the client.
+	SourceRoot string
+
+	in  *bufio.Reader
+	out io.Writer
+
+	writeMu sync.Mutex
+	seq     int
+
+	mu          sync.Mutex
+	breakpoints map[string][]*sysl.SourceContext
+	mode        stepMode
+	stepDepth   int
+	lastStop    *sysl.SourceContext
+	paused      *pausedState
+	terminated  bool
+
+	configured     chan struct{}
+	configuredOnce sync.Once
+	resume         chan struct{}
+}
+
+// pausedState holds everything the client may inspect while evaluation is stopped.
+// Variable references are only valid until evaluation resumes.
+type pausedState struct {
+	app    *sysl.Application
+	frames []dapFrame
+	refs   []interface{}
+}
+
+type dapFrame struct {
+	view  string
+	sc    *sysl.SourceContext
+	scope Scope
+}
+
+// NewDAPSession creates a session reading requests from r and writing
+// responses and events to w.
+func NewDAPSession(r io.Reader, w io.Writer) *DAPSession {
+	return &DAPSession{
+		in:          bufio.NewReader(r),
+		out:         w,
+		breakpoints: map[string][]*sysl.SourceContext{},
+		configured:  make(chan struct{}),
+		resume:      make(chan struct{}),
+	}
+}
+
+// Configured is closed once the client has finished configuring the session,
+// i.e. breakpoints are set and evaluation may start.
+func (s *DAPSession) Configured() <-chan struct{} {
+	return s.configured
+}
+
+// Serve handles client requests until the client disconnects or r is closed.
+func (s *DAPSession) Serve() error {
+	defer s.terminate()
+	for {
+		content, err := readDAPMessage(s.in)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		var req dapRequest
+		if err := json.Unmarshal(content, &req); err != nil {
+			return errors.Wrap(err, "invalid debug adapter message")
+		}
+		if req.Type != "request" {
+			continue
+		}
+		done, err := s.handle(&req)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// Output sends text to the client's debug console.
+func (s *DAPSession) Output(category, text string) error {
+	return s.sendEvent("output", dapOutputEventBody{Category: category, Output: text})
+}
+
+// Exit tells the client that evaluation has finished with the exit code.
+func (s *DAPSession) Exit(code int) error {
+	if err := s.sendEvent("exited", dapExitedEventBody{ExitCode: code}); err != nil {
+		return err
+	}
+	return s.sendEvent("terminated", nil)
+}
+
+func (s *DAPSession) handle(req *dapRequest) (bool, error) {
+	switch req.Command {
+	case "initialize":
+		if err := s.respond(req, dapCapabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsEvaluateForHovers:        true,
+			SupportsTerminateRequest:         true,
+		}); err != nil {
+			return false, err
+		}
+		return false, s.sendEvent("initialized", nil)
+	case "launch", "attach":
+		var args dapLaunchArguments
+		if err := s.decode(req, &args); err != nil {
+			return false, s.respondError(req, err)
+		}
+		if args.StopOnEntry {
+			s.mu.Lock()
+			s.mode = modeEntry
+			s.mu.Unlock()
+		}
+		return false, s.respond(req, nil)
+	case "setBreakpoints":
+		return false, s.setBreakpoints(req)
+	case "setExceptionBreakpoints":
+		return false, s.respond(req, map[string]interface{}{"breakpoints": []dapBreakpoint{}})
+	case "configurationDone":
+		s.configuredOnce.Do(func() { close(s.configured) })
+		return false, s.respond(req, nil)
+	case "threads":
+		return false, s.respond(req, map[string]interface{}{
+			"threads": []dapThread{{ID: dapThreadID, Name: "transform"}},
+		})
+	case "stackTrace":
+		return false, s.stackTrace(req)
+	case "scopes":
+		return false, s.scopes(req)
+	case "variables":
+		return false, s.variables(req)
+	case "evaluate":
+		return false, s.evaluate(req)
+	case "continue":
+		return false, s.step(req, modeRun, map[string]interface{}{"allThreadsContinued": true})
+	case "next":
+		return false, s.step(req, modeStepOver, nil)
+	case "stepIn":
+		return false, s.step(req, modeStepIn, nil)
+	case "stepOut":
+		return false, s.step(req, modeStepOut, nil)
+	case "pause":
+		s.mu.Lock()
+		s.mode = modePause
+		s.mu.Unlock()
+		return false, s.respond(req, nil)
+	case "disconnect", "terminate":
+		s.terminate()
+		return req.Command == "disconnect", s.respond(req, nil)
+	default:
+		return false, s.respondError(req, errors.Errorf("unsupported request: %s", req.Command))
+	}
+}
+
+func (s *DAPSession) setBreakpoints(req *dapRequest) error {
+	var args dapSetBreakpointsArguments
+	if err := s.decode(req, &args); err != nil {
+		return s.respondError(req, err)
+	}
+	if len(args.Breakpoints) == 0 {
+		for _, line := range args.Lines {
+			args.Breakpoints = append(args.Breakpoints, dapSourceBreakpoint{Line: line})
+		}
+	}
+
+	file := filepath.ToSlash(args.Source.Path)
+	if file == "" {
+		file = args.Source.Name
+	}
+	bps := make([]*sysl.SourceContext, 0, len(args.Breakpoints))
+	result := make([]dapBreakpoint, 0, len(args.Breakpoints))
+	for _, b := range args.Breakpoints {
+		col := int32(-1)
+		if b.Column > 0 {
+			col = int32(b.Column)
+		}
+		bps = append(bps, &sysl.SourceContext{
+			File:  file,
+			Start: &sysl.SourceContext_Location{Line: int32(b.Line), Col: col},
+		})
+		result = append(result, dapBreakpoint{Verified: true, Line: b.Line, Column: b.Column, Source: &args.Source})
+	}
+
+	s.mu.Lock()
+	s.breakpoints[file] = bps
+	s.mu.Unlock()
+	return s.respond(req, map[string]interface{}{"breakpoints": result})
+}
+
+func (s *DAPSession) stackTrace(req *dapRequest) error {
+	var args dapStackTraceArguments
+	if err := s.decode(req, &args); err != nil {
+		return s.respondError(req, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused == nil {
+		return s.respondError(req, errors.New("not paused"))
+	}
+
+	frames := make([]dapStackFrame, 0, len(s.paused.frames))
+	for i, f := range s.paused.frames {
+		name := f.view
+		if name == "" {
+			name = "<transform>"
+		}
+		frame := dapStackFrame{ID: i + 1, Name: name}
+		if f.sc != nil && f.sc.Start != nil {
+			path := f.sc.File
+			if s.SourceRoot != "" && !filepath.IsAbs(path) {
+				path = filepath.Join(s.SourceRoot, path)
+			}
+			frame.Source = &dapSource{Name: filepath.Base(path), Path: path}
+			frame.Line = int(f.sc.Start.Line)
+			frame.Column = int(f.sc.Start.Col) + 1
+		}
+		frames = append(frames, frame)
+	}
+	if args.StartFrame > 0 && args.StartFrame < len(frames) {
+		frames = frames[args.StartFrame:]
+	}
+	if args.Levels > 0 && args.Levels < len(frames) {
+		frames = frames[:args.Levels]
+	}
+	return s.respond(req, map[string]interface{}{"stackFrames": frames, "totalFrames": len(s.paused.frames)})
+}
+
+func (s *DAPSession) scopes(req *dapRequest) error {
+	var args dapScopesArguments
+	if err := s.decode(req, &args); err != nil {
+		return s.respondError(req, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	frame, err := s.frame(args.FrameID)
+	if err != nil {
+		return s.respondError(req, err)
+	}
+	return s.respond(req, map[string]interface{}{
+		"scopes": []dapScope{{Name: "Scope", VariablesReference: s.paused.addRef(frame.scope)}},
+	})
+}
+
+func (s *DAPSession) variables(req *dapRequest) error {
+	var args dapVariablesArguments
+	if err := s.decode(req, &args); err != nil {
+		return s.respondError(req, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused == nil || args.VariablesReference < 1 || args.VariablesReference > len(s.paused.refs) {
+		return s.respondError(req, errors.Errorf("invalid variables reference: %d", args.VariablesReference))
+	}
+	return s.respond(req, map[string]interface{}{"variables": s.paused.variables(args.VariablesReference)})
+}
+
+func (s *DAPSession) evaluate(req *dapRequest) error {
+	var args dapEvaluateArguments
+	if err := s.decode(req, &args); err != nil {
+		return s.respondError(req, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	frame, err := s.frame(args.FrameID)
+	if err != nil {
+		return s.respondError(req, err)
+	}
+	result := strings.TrimSuffix(parseExpression(args.Expression, s.paused.app, &frame.scope), "\n")
+	return s.respond(req, map[string]interface{}{"result": result, "variablesReference": 0})
+}
+
+func (s *DAPSession) step(req *dapRequest, mode stepMode, body interface{}) error {
+	s.mu.Lock()
+	wasPaused := s.paused != nil
+	if wasPaused {
+		s.mode = mode
+		s.stepDepth = len(s.paused.frames)
+		s.paused = nil
+	}
+	s.mu.Unlock()
+
+	if err := s.respond(req, body); err != nil {
+		return err
+	}
+	if wasPaused {
+		s.resume <- struct{}{}
+	}
+	return nil
+}
+
+// terminate stops any further debugging, releasing a stopped evaluation.
+func (s *DAPSession) terminate() {
+	s.mu.Lock()
+	wasPaused := s.paused != nil
+	s.terminated = true
+	s.paused = nil
+	s.mu.Unlock()
+
+	s.configuredOnce.Do(func() { close(s.configured) })
+	if wasPaused {
+		s.resume <- struct{}{}
+	}
+}
+
+// frame returns the paused frame with the DAP frame id, or the innermost frame for id 0.
+// Callers must hold s.mu.
+func (s *DAPSession) frame(id int) (*dapFrame, error) {
+	if s.paused == nil {
+		return nil, errors.New("not paused")
+	}
+	if id == 0 {
+		id = 1
+	}
+	if id < 1 || id > len(s.paused.frames) {
+		return nil, errors.Errorf("invalid frame id: %d", id)
+	}
+	return &s.paused.frames[id-1], nil
+}
+
+// attach returns the DebugFunc for an evaluation.
+func (s *DAPSession) attach(ee *exprEval) DebugFunc {
+	return func(scope *Scope, app *sysl.Application, expr *sysl.Expr) error {
+		frames := stackFrames(ee.exprStack.s)
+		reason, err := s.shouldStop(expr, len(frames))
+		if err != nil {
+			panic(err)
+		}
+		if reason == "" {
+			return nil
+		}
+		return s.stop(reason, app, frames)
+	}
+}
+
+func (s *DAPSession) shouldStop(expr *sysl.Expr, depth int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.terminated {
+		return "", errDAPTerminated
+	}
+	sc := expr.SourceContext
+	if sc == nil || sc.Start == nil {
+		return "", nil
+	}
+	// Expressions on the line evaluation last stopped at are skipped, so that
+	// stepping moves through a transform line by line.
+	if s.lastStop != nil {
+		if sc.File == s.lastStop.File && sc.Start.Line == s.lastStop.Start.Line {
+			return "", nil
+		}
+		s.lastStop = nil
+	}
+
+	reason := ""
+	switch s.mode {
+	case modeEntry:
+		reason = "entry"
+	case modePause:
+		reason = "pause"
+	case modeStepIn:
+		reason = "step"
+	case modeStepOver:
+		if depth <= s.stepDepth {
+			reason = "step"
+		}
+	case modeStepOut:
+		if depth < s.stepDepth {
+			reason = "step"
+		}
+	}
+	if reason == "" {
+		for _, bps := range s.breakpoints {
+			if hitsBreakpoint(bps, sc) {
+				reason = "breakpoint"
+				break
+			}
+		}
+	}
+	if reason != "" {
+		s.lastStop = sc
+		s.mode = modeRun
+	}
+	return reason, nil
+}
+
+// stop blocks the evaluation until the client resumes it.
+func (s *DAPSession) stop(reason string, app *sysl.Application, frames []dapFrame) error {
+	s.mu.Lock()
+	if s.terminated {
+		s.mu.Unlock()
+		panic(errDAPTerminated)
+	}
+	s.paused = &pausedState{app: app, frames: frames}
+	s.mu.Unlock()
+
+	if err := s.sendEvent("stopped", dapStoppedEventBody{
+		Reason:            reason,
+		ThreadID:          dapThreadID,
+		AllThreadsStopped: true,
+	}); err != nil {
+		return err
+	}
+	<-s.resume
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.terminated {
+		panic(errDAPTerminated)
+	}
+	return nil
+}
+
+// stackFrames groups the expression stack by view, innermost first. Each
+// frame is located at the innermost expression of its view that has a source
+// context, which for calling views is the call expression.
+func stackFrames(stack []*exprData) []dapFrame {
+	frames := []dapFrame{}
+	for i := len(stack) - 1; i >= 0; i-- {
+		d := stack[i]
+		if n := len(frames); n == 0 || frames[n-1].view != d.view {
+			frames = append(frames, dapFrame{view: d.view, scope: d.args})
+		}
+		if f := &frames[len(frames)-1]; f.sc == nil && d.e.SourceContext != nil {
+			f.sc = d.e.SourceContext
+		}
+	}
+	return frames
+}
+
+func (p *pausedState) addRef(v interface{}) int {
+	p.refs = append(p.refs, v)
+	return len(p.refs)
+}
+
+func (p *pausedState) variables(ref int) []dapVariable {
+	result := []dapVariable{}
+	switch x := p.refs[ref-1].(type) {
+	case Scope:
+		for _, name := range sortedKeys(x) {
+			result = append(result, p.variable(name, x[name]))
+		}
+	case *sysl.Value:
+		switch v := x.Value.(type) {
+		case *sysl.Value_Map_:
+			for _, name := range sortedKeys(v.Map.Items) {
+				result = append(result, p.variable(name, v.Map.Items[name]))
+			}
+		case *sysl.Value_List_:
+			for i, item := range v.List.Value {
+				result = append(result, p.variable(fmt.Sprintf("[%d]", i), item))
+			}
+		case *sysl.Value_Set:
+			for i, item := range v.Set.Value {
+				result = append(result, p.variable(fmt.Sprintf("[%d]", i), item))
+			}
+		}
+	}
+	return result
+}
+
+func (p *pausedState) variable(name string, v *sysl.Value) dapVariable {
+	if v == nil {
+		return dapVariable{Name: name, Value: "null"}
+	}
+	variable := dapVariable{Name: name, Type: getValueType(v).String()}
+	switch x := v.Value.(type) {
+	case *sysl.Value_Map_:
+		variable.Value = fmt.Sprintf("map[%d]", len(x.Map.Items))
+		variable.VariablesReference = p.addRef(v)
+	case *sysl.Value_List_:
+		variable.Value = fmt.Sprintf("list[%d]", len(x.List.Value))
+		variable.VariablesReference = p.addRef(v)
+	case *sysl.Value_Set:
+		variable.Value = fmt.Sprintf("set[%d]", len(x.Set.Value))
+		variable.VariablesReference = p.addRef(v)
+	default:
+		variable.Value = unaryString(v).GetS()
+	}
+	return variable
+}
+
+func sortedKeys(m map[string]*sysl.Value) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *DAPSession) decode(req *dapRequest, v interface{}) error {
+	if len(req.Arguments) == 0 {
+		return nil
+	}
+	return errors.Wrapf(json.Unmarshal(req.Arguments, v), "invalid arguments for %s", req.Command)
+}
+
+func (s *DAPSession) respond(req *dapRequest, body interface{}) error {
+	return s.send(func(seq int) interface{} {
+		return dapResponse{
+			Seq: seq, Type: "response", RequestSeq: req.Seq, Success: true, Command: req.Command, Body: body,
+		}
+	})
+}
+
+func (s *DAPSession) respondError(req *dapRequest, err error) error {
+	return s.send(func(seq int) interface{} {
+		return dapResponse{
+			Seq: seq, Type: "response", RequestSeq: req.Seq, Success: false, Command: req.Command, Message: err.Error(),
+		}
+	})
+}
+
+func (s *DAPSession) sendEvent(event string, body interface{}) error {
+	return s.send(func(seq int) interface{} {
+		return dapEvent{Seq: seq, Type: "event", Event: event, Body: body}
+	})
+}
+
+func (s *DAPSession) send(msg func(seq int) interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.seq++
+	return writeDAPMessage(s.out, msg(s.seq))
+}
@@ -0,0 +1,188 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dapTestClient struct {
+	t   *testing.T
+	in  *bufio.Reader
+	out io.Writer
+	seq int
+}
+
+func (c *dapTestClient) request(command string, args interface{}) map[string]interface{} {
+	c.seq++
+	req := map[string]interface{}{"seq": c.seq, "type": "request", "command": command}
+	if args != nil {
+		req["arguments"] = args
+	}
+	require.NoError(c.t, writeDAPMessage(c.out, req))
+	msg := c.read()
+	require.Equal(c.t, "response", msg["type"], "%v", msg)
+	require.Equal(c.t, command, msg["command"])
+	require.True(c.t, msg["success"].(bool), "%v", msg)
+	body, _ := msg["body"].(map[string]interface{})
+	return body
+}
+
+func (c *dapTestClient) event(name string) map[string]interface{} {
+	msg := c.read()
+	require.Equal(c.t, "event", msg["type"], "%v", msg)
+	require.Equal(c.t, name, msg["event"])
+	body, _ := msg["body"].(map[string]interface{})
+	return body
+}
+
+func (c *dapTestClient) read() map[string]interface{} {
+	content, err := readDAPMessage(c.in)
+	require.NoError(c.t, err)
+	var msg map[string]interface{}
+	require.NoError(c.t, json.Unmarshal(content, &msg))
+	return msg
+}
+
+func TestDAPSession(t *testing.T) {
+	t.Parallel()
+
+	mod, err := parse.NewParser().Parse("eval_expr.sysl", syslutil.NewChrootFs(afero.NewOsFs(), testDir))
+	require.NoError(t, err)
+	txApp := mod.Apps["TransformApp"]
+	view := txApp.Views["TestDotScope"]
+
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	session := NewDAPSession(serverIn, serverOut)
+	session.SourceRoot = "/models"
+	served := make(chan error, 1)
+	go func() { served <- session.Serve() }()
+	client := &dapTestClient{t: t, in: bufio.NewReader(clientIn), out: clientOut}
+
+	client.request("initialize", map[string]interface{}{"adapterID": "sysl"})
+	client.event("initialized")
+	bps := client.request("setBreakpoints", map[string]interface{}{
+		"source":      map[string]interface{}{"path": "/models/eval_expr.sysl"},
+		"breakpoints": []map[string]interface{}{{"line": 153}},
+	})
+	assert.Len(t, bps["breakpoints"], 1)
+	client.request("configurationDone", nil)
+	<-session.Configured()
+
+	type result struct {
+		val *sysl.Value
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		ee := newExprEval(txApp)
+		ee.view = "TestDotScope"
+		ee.dbg = session.attach(ee)
+		s := Scope{}
+		s.AddApp("app", mod.Apps[modelAppName])
+		val, err := ee.eval(s, view.Expr)
+		done <- result{val, err}
+	}()
+
+	stopped := client.event("stopped")
+	assert.Equal(t, "breakpoint", stopped["reason"])
+
+	trace := client.request("stackTrace", map[string]interface{}{"threadId": dapThreadID})
+	frames := trace["stackFrames"].([]interface{})
+	require.Len(t, frames, 1)
+	frame := frames[0].(map[string]interface{})
+	assert.Equal(t, "TestDotScope", frame["name"])
+	assert.EqualValues(t, 153, frame["line"])
+	assert.Equal(t, "/models/eval_expr.sysl", frame["source"].(map[string]interface{})["path"])
+
+	scopes := client.request("scopes", map[string]interface{}{"frameId": 1})["scopes"].([]interface{})
+	require.Len(t, scopes, 1)
+	ref := scopes[0].(map[string]interface{})["variablesReference"]
+	vars := client.request("variables", map[string]interface{}{"variablesReference": ref})["variables"].([]interface{})
+	names := map[string]map[string]interface{}{}
+	for _, v := range vars {
+		v := v.(map[string]interface{})
+		names[v["name"].(string)] = v
+	}
+	require.Contains(t, names, "app")
+	assert.Equal(t, "ValueMap", names["app"]["type"])
+	assert.NotZero(t, names["app"]["variablesReference"])
+
+	eval := client.request("evaluate", map[string]interface{}{"expression": ".name", "frameId": 1})
+	assert.Equal(t, modelAppName, eval["result"])
+
+	client.request("next", map[string]interface{}{"threadId": dapThreadID})
+	assert.Equal(t, "step", client.event("stopped")["reason"])
+	frame = client.request("stackTrace", nil)["stackFrames"].([]interface{})[0].(map[string]interface{})
+	assert.EqualValues(t, 155, frame["line"])
+
+	client.request("continue", map[string]interface{}{"threadId": dapThreadID})
+	res := <-done
+	require.NoError(t, res.err)
+	assert.Equal(t, modelAppName, res.val.GetMap().Items["out"].GetS())
+
+	client.request("disconnect", nil)
+	require.NoError(t, <-served)
+}
+
+func TestDAPSessionTerminate(t *testing.T) {
+	t.Parallel()
+
+	mod, err := parse.NewParser().Parse("eval_expr.sysl", syslutil.NewChrootFs(afero.NewOsFs(), testDir))
+	require.NoError(t, err)
+	txApp := mod.Apps["TransformApp"]
+
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	session := NewDAPSession(serverIn, serverOut)
+	go func() { _ = session.Serve() }()
+	client := &dapTestClient{t: t, in: bufio.NewReader(clientIn), out: clientOut}
+
+	client.request("launch", map[string]interface{}{"stopOnEntry": true})
+	client.request("configurationDone", nil)
+
+	done := make(chan error, 1)
+	go func() {
+		ee := newExprEval(txApp)
+		ee.dbg = session.attach(ee)
+		s := Scope{}
+		s.AddApp("app", mod.Apps[modelAppName])
+		_, err := ee.eval(s, txApp.Views["TestDotScope"].Expr)
+		done <- err
+	}()
+
+	assert.Equal(t, "entry", client.event("stopped")["reason"])
+	client.request("terminate", nil)
+	err = <-done
+	require.Error(t, err)
+	assert.Equal(t, errDAPTerminated, err.(*Error).Err)
+}
+
+func TestStackFrames(t *testing.T) {
+	t.Parallel()
+
+	at := func(line int32) *sysl.SourceContext {
+		return &sysl.SourceContext{File: "a.sysl", Start: &sysl.SourceContext_Location{Line: line}}
+	}
+	stack := []*exprData{
+		{e: &sysl.Expr{SourceContext: at(1)}, view: "Outer"},
+		{e: &sysl.Expr{SourceContext: at(2)}, view: "Outer"},
+		{e: &sysl.Expr{SourceContext: at(10)}, view: "Inner"},
+		{e: &sysl.Expr{}, view: "Inner"},
+	}
+	frames := stackFrames(stack)
+	require.Len(t, frames, 2)
+	assert.Equal(t, "Inner", frames[0].view)
+	assert.EqualValues(t, 10, frames[0].sc.Start.Line)
+	assert.Equal(t, "Outer", frames[1].view)
+	assert.EqualValues(t, 2, frames[1].sc.Start.Line)
+}
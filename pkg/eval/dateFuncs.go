@@ -0,0 +1,155 @@
+package eval
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"time"
+
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/pkg/errors"
+)
+
+// Dates, datetimes and UUIDs are represented in sysl.Values as strings in the
+// layouts below, so they serialise like any other string. GoFuncMap functions
+// taking a DATE or DATETIME receive them as a time.Time.
+const (
+	DateLayout     = "2006-01-02"
+	DateTimeLayout = time.RFC3339Nano
+)
+
+//nolint:gochecknoglobals
+var (
+	// Clock returns the current time for the Now function. It is a variable,
+	// as the Go functions transforms call are given only their arguments; set
+	// it before evaluating to generate reproducible output.
+	Clock = time.Now
+
+	// UUIDSource supplies the random bytes of the UUIDs generated by NewUUID.
+	// Like Clock, set it before evaluating to generate reproducible output.
+	UUIDSource io.Reader = rand.Reader
+)
+
+//nolint:gochecknoglobals
+var (
+	uuidRegEx = regexp.MustCompile("^[[:xdigit:]]{8}-[[:xdigit:]]{4}-[[:xdigit:]]{4}-[[:xdigit:]]{4}-[[:xdigit:]]{12}$")
+
+	dateType     = &sysl.Type{Type: &sysl.Type_Primitive_{Primitive: sysl.Type_DATE}}
+	dateTimeType = &sysl.Type{Type: &sysl.Type_Primitive_{Primitive: sysl.Type_DATETIME}}
+	uuidType     = &sysl.Type{Type: &sysl.Type_Primitive_{Primitive: sysl.Type_UUID}}
+)
+
+// MakeValueDate returns the value of t as a DATE.
+func MakeValueDate(t time.Time) *sysl.Value {
+	return MakeValueString(t.Format(DateLayout))
+}
+
+// MakeValueDateTime returns the value of t as a DATETIME.
+func MakeValueDateTime(t time.Time) *sysl.Value {
+	return MakeValueString(t.Format(DateTimeLayout))
+}
+
+// parseDateTime parses a DATE or DATETIME value. Dates are midnight UTC.
+func parseDateTime(s string) (time.Time, error) {
+	if t, err := time.Parse(DateTimeLayout, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid date or datetime: %q", s)
+	}
+	return t, nil
+}
+
+// Now exposes the time given by Clock to sysl transforms.
+func Now() time.Time {
+	return Clock()
+}
+
+// ParseTime exposes time.Parse to sysl transforms, returning a DATETIME.
+func ParseTime(layout, value string) time.Time {
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		panic(errors.Wrapf(err, "ParseTime"))
+	}
+	return t
+}
+
+// ParseDate parses a DATE with the layout, as for ParseTime.
+func ParseDate(layout, value string) time.Time {
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		panic(errors.Wrapf(err, "ParseDate"))
+	}
+	return t
+}
+
+// FormatTime exposes time.Time.Format to sysl transforms.
+func FormatTime(t time.Time, layout string) string {
+	return t.Format(layout)
+}
+
+// TimeAdd adds a duration, such as "1h30m", to a DATETIME.
+func TimeAdd(t time.Time, duration string) time.Time {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		panic(errors.Wrapf(err, "TimeAdd"))
+	}
+	return t.Add(d)
+}
+
+// TimeSub returns the duration t-u, in the format accepted by TimeAdd.
+func TimeSub(t, u time.Time) string {
+	return t.Sub(u).String()
+}
+
+// TimeBefore exposes time.Time.Before to sysl transforms.
+func TimeBefore(t, u time.Time) bool {
+	return t.Before(u)
+}
+
+// TimeAfter exposes time.Time.After to sysl transforms.
+func TimeAfter(t, u time.Time) bool {
+	return t.After(u)
+}
+
+// TimeEqual exposes time.Time.Equal to sysl transforms.
+func TimeEqual(t, u time.Time) bool {
+	return t.Equal(u)
+}
+
+// DateAdd exposes time.Time.AddDate to sysl transforms.
+func DateAdd(t time.Time, years, months, days int) time.Time {
+	return t.AddDate(years, months, days)
+}
+
+// DateSub returns the number of whole days from u to t.
+func DateSub(t, u time.Time) int {
+	return int(t.Sub(u).Hours() / 24)
+}
+
+// NewUUID returns a random (version 4) UUID read from UUIDSource.
+func NewUUID() string {
+	var u [16]byte
+	if _, err := io.ReadFull(UUIDSource, u[:]); err != nil {
+		panic(errors.Wrapf(err, "NewUUID"))
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	s := hex.EncodeToString(u[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// IsUUID reports whether s is a UUID in its canonical textual form.
+func IsUUID(s string) bool {
+	return uuidRegEx.MatchString(s)
+}
+
+// ParseUUID returns s as a UUID, which is an error if it is not one.
+func ParseUUID(s string) string {
+	if !IsUUID(s) {
+		panic(errors.Errorf("ParseUUID: invalid UUID: %q", s))
+	}
+	return s
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
 	"github.com/antlr/antlr4/runtime/Go/antlr"
@@ -26,6 +27,12 @@ type repl struct {
 	breakpoints []*sysl.SourceContext
 	step        bool
 	lastCommand string
+
+	module  *sysl.Module
+	app     *sysl.Application
+	load    func(name string) (*sysl.Module, error)
+	history *history
+	unbound bool
 }
 
 func (r *repl) run(scope *Scope, app *sysl.Application, expr *sysl.Expr) error {
@@ -40,15 +47,23 @@ func (r *repl) handleInput(scope *Scope, app *sysl.Application, expr *sysl.Expr)
 		if expr != nil && expr.SourceContext.Text != "" {
 			writeOutput(expr.SourceContext.Text+"\n", r.output)
 		}
+		r.bindModule(scope)
+		if r.module != nil && app == nil {
+			app = r.app
+		}
 		writeOutput("sysl> ", r.output)
-		if !r.input.Scan() {
+		text, ok := r.readInput()
+		if !ok {
 			r.step = false
 			return fmt.Errorf("input closed")
 		}
-		text := r.input.Text()
 		if len(text) == 0 {
 			text = r.lastCommand
 		}
+		if strings.HasPrefix(text, ":") {
+			app = r.command(text, scope, app)
+			continue
+		}
 		switch len(text) {
 		case 0:
 			printUsage(r.output)
@@ -85,12 +100,18 @@ func (r *repl) handleInput(scope *Scope, app *sysl.Application, expr *sysl.Expr)
 	}
 }
 func (r *repl) isBreakpoint(expr *sysl.Expr) bool {
-	if expr.SourceContext == nil {
+	return hitsBreakpoint(r.breakpoints, expr.SourceContext)
+}
+
+// hitsBreakpoint reports whether sc is at one of the breakpoints. Breakpoint
+// files match either as a suffix of the expression's file, or, for absolute
+// paths supplied by editors, when the expression's file is a suffix of them.
+func hitsBreakpoint(breakpoints []*sysl.SourceContext, sc *sysl.SourceContext) bool {
+	if sc == nil || sc.Start == nil {
 		return false
 	}
-	sc := expr.SourceContext
-	for _, bsc := range r.breakpoints {
-		if strings.HasSuffix(sc.File, bsc.File) &&
+	for _, bsc := range breakpoints {
+		if (strings.HasSuffix(sc.File, bsc.File) || strings.HasSuffix(bsc.File, "/"+sc.File)) &&
 			sc.Start.Line == bsc.Start.Line &&
 			(sc.Start.Col == bsc.Start.Col || bsc.Start.Col < 0) {
 			return true
@@ -135,6 +156,12 @@ c		Continue execution until the next breakpoint
 s		Step execution
 q		quit
 EXPRESSION	Print the value of the supplied EXPRESSION
+:load MODULE	Load a sysl module, making its apps and views available
+:apps		List the apps of the loaded module
+:app NAME	Select the app whose views can be called, bound to 'app'
+:views		List the views of the selected app
+:type EXPR	Print the type of EXPR
+:history	List previous input
 `
 	writeOutput(text, out)
 }
@@ -163,17 +190,40 @@ func dumpApp(out io.Writer, app *sysl.Application) {
 	}
 }
 
-func parseExpression(text string, app *sysl.Application, scope *Scope) string {
+func newExprParser(text string) (*parser.SyslParser, *antlr.CommonTokenStream, func()) {
 	errorListener := parse.SyslParserErrorListener{}
 	lexer := parser.NewSyslLexer(antlr.NewInputStream(text))
 	lexer.SetMode(parser.SyslLexerVIEW_TRANSFORM)
-	defer parser.DeleteLexerState(lexer)
 	stream := antlr.NewCommonTokenStream(lexer, 0)
-	listener := parse.NewTreeShapeListener()
 
 	p := parser.NewSyslParser(stream)
 	p.AddErrorListener(&errorListener)
 	p.BuildParseTrees = true
+	return p, stream, func() { parser.DeleteLexerState(lexer) }
+}
+
+// parseExpr parses text as a single expression, without evaluating it.
+func parseExpr(text string) (expr *sysl.Expr, err error) {
+	p, _, done := newExprParser(text)
+	defer done()
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("invalid expression: %v", r)
+		}
+	}()
+
+	listener := parse.NewTreeShapeListener()
+	antlr.NewParseTreeWalker().Walk(listener, p.Expr())
+	if expr = listener.TopExpr(); expr == nil {
+		return nil, errors.Errorf("invalid expression: %s", text)
+	}
+	return expr, nil
+}
+
+func parseExpression(text string, app *sysl.Application, scope *Scope) string {
+	p, stream, done := newExprParser(text)
+	defer done()
+	listener := parse.NewTreeShapeListener()
 
 	defer func() {
 		_ = recover() //nolint:errcheck
@@ -237,6 +287,7 @@ func NewREPL(input io.Reader, output io.Writer) DebugFunc {
 		output:      output,
 		breakpoints: []*sysl.SourceContext{},
 		step:        true,
+		history:     &history{},
 	}
 	return r.run
 }
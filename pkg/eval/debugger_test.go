@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/anz-bank/sysl/pkg/parse"
@@ -181,3 +182,84 @@ func TestREPL_CallView(t *testing.T) {
 	result := output.String()
 	require.Contains(t, result, "servicehandler.go\n")
 }
+
+func TestModuleREPL(t *testing.T) {
+	filename := filepath.Join(testDir, "transform1.sysl")
+	mod, _, err := parse.LoadAndGetDefaultApp(filename, afero.NewOsFs(), parse.NewParser())
+	require.NoError(t, err)
+
+	input := bytes.NewBufferString(strings.Join([]string{
+		":apps",
+		":views",
+		"TfmFilenameInvalid2(app).foo",
+		":type 1 + 2",
+		":type app.name",
+		":type TfmFilenameInvalid2(app)",
+		`:type "a" + 1`,
+		"out = [1, 2] -> (n:",
+		"  twice = n * 2",
+		")",
+		":type out",
+		":history",
+		":bogus",
+	}, "\n") + "\n")
+	output := bytes.Buffer{}
+	history := bytes.Buffer{}
+
+	scope := &Scope{}
+	r := NewModuleREPL(input, &output, REPLConfig{
+		Module:        mod,
+		History:       []string{"previous"},
+		HistoryWriter: &history,
+	})
+	require.Error(t, r(scope, nil, nil))
+
+	result := output.String()
+	require.Contains(t, result, "* CodeGenTransform\n")
+	require.Contains(t, result, "TfmFilenameInvalid2(app <: sysl.App) -> string\n")
+	require.Contains(t, result, "servicehandler.go\n")
+	require.Contains(t, result, "sysl> int\n")
+	require.Contains(t, result, "sysl> unknown\n")
+	require.Contains(t, result, "sysl> string\n")
+	require.Contains(t, result, "unsupported operation string ADD int\n")
+	require.Contains(t, result, "sysl> sequence of map\n")
+	require.Contains(t, result, "   1  previous\n")
+	require.Contains(t, result, "   9  out = [1, 2] -> (n:\n")
+	require.Contains(t, result, "unknown command :bogus")
+	require.Contains(t, (*scope)["module"].GetMap().Items["apps"].GetMap().Items, "CodeGenTransform")
+	require.Len(t, (*scope)["out"].GetList().Value, 2)
+	require.True(t, strings.HasPrefix(history.String(), ":apps\n:views\n"))
+}
+
+func TestModuleREPL_Load(t *testing.T) {
+	input := bytes.NewBufferString(":views\n:load transform1\n:app Missing\n:app CodeGenTransform\n:views\n")
+	output := bytes.Buffer{}
+
+	var loaded string
+	r := NewModuleREPL(input, &output, REPLConfig{
+		Load: func(name string) (*sysl.Module, error) {
+			loaded = name
+			mod, _, err := parse.LoadAndGetDefaultApp(filepath.Join(testDir, name+".sysl"),
+				afero.NewOsFs(), parse.NewParser())
+			return mod, err
+		},
+	})
+	require.Error(t, r(&Scope{}, nil, nil))
+
+	result := output.String()
+	require.Equal(t, "transform1", loaded)
+	require.Contains(t, result, "no app selected\n")
+	require.Contains(t, result, "loaded 1 app(s)\n")
+	require.Contains(t, result, "app Missing does not exist\n")
+	require.Contains(t, result, "filename(app <: sysl.App) -> string\n")
+}
+
+func Test_bracketDepth(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 0, bracketDepth("a = b"))
+	require.Equal(t, 1, bracketDepth("a = b -> (:"))
+	require.Equal(t, 0, bracketDepth(`a = "(" + ')'`))
+	require.Equal(t, 3, bracketDepth("f([{"))
+	require.Equal(t, 0, bracketDepth("a -> (:\n  b = [1]\n)"))
+}
@@ -0,0 +1,127 @@
+// Package eval evaluates the views of sysl transforms.
+//
+// The operations expressions are evaluated with, from operators to the Go
+// functions of GoFuncMap such as ParseDate, fail by panicking with an error.
+// The eval of the enclosing expression recovers the panic, so EvaluateApp and
+// EvaluateView return it as an *Error recording where evaluation failed.
+package eval
+
+import (
+	"fmt"
+	"strings"
+
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/pkg/errors"
+)
+
+// StackFrame is a single entry in the transform call stack of an Error.
+type StackFrame struct {
+	View          string
+	Text          string
+	SourceContext *sysl.SourceContext
+}
+
+// Error is returned when the evaluation of a transform fails. It records the
+// expression that failed, the view it belongs to and the stack of expressions
+// being evaluated at the time, innermost first.
+type Error struct {
+	Err           error
+	View          string
+	SourceContext *sysl.SourceContext
+	Stack         []StackFrame
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	if loc := formatLocation(e.SourceContext); loc != "" {
+		b.WriteString(loc + ": ")
+	}
+	b.WriteString(e.Err.Error())
+	if e.View != "" {
+		fmt.Fprintf(&b, " (in view %s)", e.View)
+	}
+	return b.String()
+}
+
+// Cause returns the underlying error, for use with errors.Cause.
+func (e *Error) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the underlying error, for use with the standard errors package.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Trace returns the error followed by the transform call stack, one frame per line.
+func (e *Error) Trace() string {
+	lines := []string{e.Error()}
+	for _, f := range e.Stack {
+		line := "\t... " + f.Text
+		if loc := formatLocation(f.SourceContext); loc != "" {
+			line += " @ " + loc
+		}
+		if f.View != "" {
+			line += " [" + f.View + "]"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatLocation(sc *sysl.SourceContext) string {
+	if sc == nil || sc.Start == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d:%d", sc.File, sc.Start.Line, sc.Start.Col)
+}
+
+// newError wraps the value recovered from a failed evaluation, capturing the
+// current state of the expression stack.
+func (ee *exprEval) newError(r interface{}) *Error {
+	var err error
+	switch x := r.(type) {
+	case *Error:
+		return x
+	case error:
+		err = x
+	default:
+		err = errors.Errorf("%v", x)
+	}
+
+	stack := ee.exprStack.s
+	result := &Error{
+		Err:   err,
+		Stack: make([]StackFrame, 0, len(stack)),
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		result.Stack = append(result.Stack, StackFrame{
+			View:          stack[i].view,
+			Text:          getExprText(stack[i].e),
+			SourceContext: stack[i].e.SourceContext,
+		})
+	}
+	if len(result.Stack) > 0 {
+		result.View = result.Stack[0].View
+	}
+	// Literals and synthesised expressions have no source context, so report
+	// the location of the nearest enclosing expression that does.
+	for _, f := range result.Stack {
+		if f.SourceContext != nil {
+			result.SourceContext = f.SourceContext
+			break
+		}
+	}
+	return result
+}
+
+// TypeErrors is the list of errors found by CheckTypes.
+type TypeErrors []*Error
+
+func (e TypeErrors) Error() string {
+	lines := make([]string, 0, len(e))
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
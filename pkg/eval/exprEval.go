@@ -91,10 +91,13 @@ func evalTransformUsingValueList(
 	return evalTransformUsingAppender(ee, x, assign, v, listAppender)
 }
 
-// Eval expr
-// TODO: Add type checks
+// Eval expr. Views are checked ahead of evaluation by CheckTypes.
 func Eval(ee *exprEval, assign Scope, e *sysl.Expr) *sysl.Value {
-	val, _ := ee.eval(assign, e) //nolint:errcheck
+	val, err := ee.eval(assign, e)
+	if err != nil {
+		// Unwound by the enclosing ee.eval, which returns the error to its caller.
+		panic(err)
+	}
 	return val
 }
 
@@ -115,7 +118,7 @@ func (ee *exprEval) evalTransform(assign Scope, x *sysl.Expr_Transform_, e *sysl
 
 	switch argValue.Value.(type) {
 	case *sysl.Value_Set, *sysl.Value_List_:
-		switch e.Type.Type.(type) {
+		switch e.GetType().GetType().(type) {
 		case *sysl.Type_Set:
 			logrus.Debugf("Evaluation Argvalue as a set: %d times\n", len(GetValueSlice(argValue)))
 			setResult := MakeValueSet()
@@ -152,7 +155,7 @@ func (ee *exprEval) evalTransform(assign Scope, x *sysl.Expr_Transform_, e *sysl
 				AppendItemToValueList(resultList, res)
 			}
 			delete(assign, scopeVar)
-			if e.Type.GetSet() != nil {
+			if e.GetType().GetSet() != nil {
 				return &sysl.Value{
 					Value: &sysl.Value_Set{
 						Set: resultList,
@@ -186,12 +189,14 @@ func evalCall(ee *exprEval, assign Scope, x *sysl.Expr_Call_) *sysl.Value {
 		callScope := make(Scope)
 
 		for i, argExpr := range x.Call.Arg {
-			// TODO: Add type checks
 			callScope[params[i].Name] = Eval(ee, assign, argExpr)
 		}
 		if callTransform.Expr.Type == nil {
 			callTransform.Expr.Type = callTransform.RetType
 		}
+		caller := ee.view
+		ee.view = x.Call.Func
+		defer func() { ee.view = caller }()
 		return Eval(ee, callScope, callTransform.Expr)
 	} else if strings.HasPrefix(x.Call.Func, ".") {
 		switch x.Call.Func[1:] {
@@ -303,33 +308,50 @@ func evalList(ee *exprEval, assign Scope, x *sysl.Expr_List_) *sysl.Value {
 
 var EnableDebugger = false
 
-func EvaluateApp(app *sysl.Application, view *sysl.View, s Scope) *sysl.Value {
+// DebugAdapter, when set, debugs evaluations over the Debug Adapter Protocol
+// instead of the terminal REPL enabled by EnableDebugger.
+var DebugAdapter *DAPSession
+
+// EvaluateApp evaluates the view of app using the Scope. Evaluation failures
+// are returned as an *Error.
+func EvaluateApp(app *sysl.Application, view *sysl.View, s Scope) (*sysl.Value, error) {
+	return evaluateApp(app, "", view, s)
+}
+
+func evaluateApp(app *sysl.Application, viewName string, view *sysl.View, s Scope) (*sysl.Value, error) {
 	ee := exprEval{
 		txApp:     app,
 		exprStack: exprStack{},
 		logger:    logrus.StandardLogger(),
+		view:      viewName,
 	}
 
-	if EnableDebugger {
+	switch {
+	case DebugAdapter != nil:
+		ee.dbg = DebugAdapter.attach(&ee)
+	case EnableDebugger:
 		ee.dbg = NewREPL(os.Stdin, os.Stdout)
 	}
 
-	val, err := ee.eval(s, view.Expr)
-	if err != nil {
-		logrus.Panic(err.Error())
-	}
-	return val
+	return ee.eval(s, view.Expr)
 }
 
-// EvaluateView evaluate the view using the Scope
-func EvaluateView(mod *sysl.Module, appName, viewName string, s Scope) *sysl.Value {
-	txApp := mod.Apps[appName]
-	view := txApp.Views[viewName]
+// EvaluateView evaluate the view using the Scope. Evaluation failures are
+// returned as an *Error.
+func EvaluateView(mod *sysl.Module, appName, viewName string, s Scope) (*sysl.Value, error) {
+	txApp, has := mod.Apps[appName]
+	if !has {
+		return nil, errors.Errorf("app %s does not exist", appName)
+	}
+	view, has := txApp.Views[viewName]
+	if !has {
+		return nil, errors.Errorf("view %s does not exist in app %s", viewName, appName)
+	}
 	if view.Expr.Type == nil {
 		view.Expr.Type = view.RetType
 	}
 
-	return EvaluateApp(txApp, view, s)
+	return evaluateApp(txApp, viewName, view, s)
 }
 
 type exprEval struct {
@@ -337,6 +359,7 @@ type exprEval struct {
 	exprStack exprStack
 	logger    *logrus.Logger
 	dbg       DebugFunc
+	view      string
 }
 
 func logentry(logger *logrus.Logger, expr *sysl.Expr) *logrus.Entry {
@@ -358,16 +381,11 @@ func (ee *exprEval) LogEntry() *logrus.Entry {
 	return logentry(ee.logger, ee.exprStack.Peek().e)
 }
 
-func (ee *exprEval) handlePanic() {
+// handlePanic converts a panic raised while evaluating an expression into an
+// *Error, which is then returned by the eval call that deferred it.
+func (ee *exprEval) handlePanic(err *error) {
 	if r := recover(); r != nil {
-		ee.logger.Errorf("Evaluation Failed: ")
-		stack := ee.exprStack.s
-		for i := len(stack) - 1; i >= 0; i-- {
-			logentry(ee.logger, stack[i].e).Errorf("... %s", getExprText(stack[i].e))
-		}
-		if ee.dbg == nil {
-			os.Exit(1)
-		}
+		*err = ee.newError(r)
 	}
 }
 
@@ -375,6 +393,10 @@ func (ee *exprEval) eval(scope Scope, expr *sysl.Expr) (val *sysl.Value, err err
 	entry := logentry(ee.logger, expr)
 	entry.Tracef("Entering: %s", getExprText(expr))
 
+	ee.exprStack.Push(scope, expr, ee.view)
+	defer ee.exprStack.Pop()
+	defer ee.handlePanic(&err)
+
 	if ee.dbg != nil {
 		if err := ee.dbg(&scope, ee.txApp, expr); err != nil {
 			ee.logger.Warnf("REPL error: %s", err.Error())
@@ -382,10 +404,6 @@ func (ee *exprEval) eval(scope Scope, expr *sysl.Expr) (val *sysl.Value, err err
 		}
 	}
 
-	ee.exprStack.Push(scope, expr)
-	defer ee.exprStack.Pop()
-	defer ee.handlePanic()
-
 	switch e := expr.Expr.(type) {
 	case *sysl.Expr_Transform_:
 		val = ee.evalTransform(scope, e, expr)
@@ -406,15 +424,9 @@ func (ee *exprEval) eval(scope Scope, expr *sysl.Expr) (val *sysl.Value, err err
 	case *sysl.Expr_List_:
 		val = evalList(ee, scope, e)
 	case *sysl.Expr_Unexpr:
-		val, err = ee.eval(scope, e.Unexpr.Arg)
-		if err != nil {
-			return nil, err
-		}
-		val = evalUnaryFunc(e.Unexpr.Op, val)
+		val = evalUnaryFunc(e.Unexpr.Op, Eval(ee, scope, e.Unexpr.Arg))
 	default:
-		return nil, fmt.Errorf("unhandled sysl.Expr type '%s' @ %s:%d:%d",
-			reflect.TypeOf(expr.Expr).String(),
-			expr.SourceContext.File, expr.SourceContext.Start.Line, expr.SourceContext.Start.Col)
+		return nil, ee.newError(errors.Errorf("unhandled sysl.Expr type '%s'", reflect.TypeOf(expr.Expr).String()))
 	}
 	entry.Tracef("Result: %s", val.String())
 	return val, err
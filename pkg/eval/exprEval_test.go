@@ -1,8 +1,11 @@
 package eval
 
 import (
+	"bytes"
+	"io"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus/hooks/test"
 
@@ -224,7 +227,8 @@ func TestEvalGetAppAttributes(t *testing.T) {
 
 	s := Scope{}
 	s.AddApp("app", mod.Apps[modelAppName])
-	out := EvaluateView(mod, "TransformApp", "GetAppAttributes", s)
+	out, err := EvaluateView(mod, "TransformApp", "GetAppAttributes", s)
+	require.NoError(t, err)
 	assert.Equal(t, "com.example.gen", out.GetMap().Items["out"].GetS())
 	assert.Nil(t, out.GetMap().Items["Nil"])
 	assert.False(t, out.GetMap().Items["stringInNull"].GetB())
@@ -280,7 +284,8 @@ func TestEvalNullCheckAppAttrs(t *testing.T) {
 
 	s := Scope{}
 	s.AddApp("app", mod.Apps[modelAppName])
-	out := EvaluateView(mod, "TransformApp", "NullCheckAppAttrs", s)
+	out, err := EvaluateView(mod, "TransformApp", "NullCheckAppAttrs", s)
+	require.NoError(t, err)
 
 	assert.False(t, out.GetMap().Items["NotHasAttrName"].GetB())
 	assert.True(t, out.GetMap().Items["NotHasAttrFoo"].GetB())
@@ -354,7 +359,8 @@ func TestEvalStringOps(t *testing.T) {
 
 	s := Scope{}
 	s.AddApp("app", mod.Apps[todoAppName])
-	out := EvaluateView(mod, "TransformApp", "StringOps", s)
+	out, err := EvaluateView(mod, "TransformApp", "StringOps", s)
+	require.NoError(t, err)
 	assert.NotNil(t, out.GetMap())
 	items := out.GetMap().Items
 
@@ -391,6 +397,53 @@ func TestEvalStringOps(t *testing.T) {
 	assert.Len(t, items["tabs"].GetList().Value, 2)
 }
 
+// Not parallel, as it replaces the Clock and UUIDSource.
+func TestEvalDateOps(t *testing.T) {
+	defer func(clock func() time.Time, source io.Reader) { Clock, UUIDSource = clock, source }(Clock, UUIDSource)
+	Clock = func() time.Time { return time.Date(2020, 2, 29, 13, 45, 0, 0, time.UTC) }
+	UUIDSource = bytes.NewReader(make([]byte, 16))
+
+	mod, err := parse.NewParser().Parse("eval_expr.sysl", syslutil.NewChrootFs(afero.NewOsFs(), testDir))
+	require.NoError(t, err)
+
+	s := Scope{}
+	s.AddApp("app", mod.Apps[todoAppName])
+	out, err := EvaluateView(mod, "TransformApp", "StringOps", s)
+	require.NoError(t, err)
+	items := out.GetMap().Items
+
+	assert.Equal(t, "2020-02-29T13:45:00Z", items["Now"].GetS())
+	assert.Equal(t, "2020-02-29T13:45:00Z", items["ParseTime"].GetS())
+	assert.Equal(t, "2020-02-29", items["ParseDate"].GetS())
+	assert.Equal(t, "Feb 29, 2020", items["FormatTime"].GetS())
+	assert.Equal(t, "2020-02-29T15:15:00Z", items["TimeAdd"].GetS())
+	assert.Equal(t, "1h30m0s", items["TimeSub"].GetS())
+	assert.True(t, items["TimeBefore"].GetB())
+	assert.False(t, items["TimeAfter"].GetB())
+	assert.True(t, items["TimeEqual"].GetB())
+	assert.Equal(t, "2020-03-30", items["DateAdd"].GetS())
+	assert.Equal(t, int64(29), items["DateSub"].GetI())
+	assert.Equal(t, "00000000-0000-4000-8000-000000000000", items["NewUUID"].GetS())
+	assert.True(t, items["IsUUID"].GetB())
+	assert.Equal(t, "123E4567-E89B-12D3-A456-426614174000", items["ParseUUID"].GetS())
+}
+
+func TestEvalDateOpsInvalid(t *testing.T) {
+	t.Parallel()
+
+	assert.Panics(t, func() { ParseTime("2006-01-02", "29/02/2020") })
+	assert.Panics(t, func() { TimeAdd(time.Time{}, "an hour") })
+	assert.Panics(t, func() { ParseUUID("123e4567") })
+	assert.False(t, IsUUID("123e4567-e89b-12d3-a456-42661417400g"))
+
+	// Strings are only passed on as dates if they are in a date layout.
+	assert.True(t, isValueExpectedType(MakeValueString("2020-02-29"), dateType))
+	assert.True(t, isValueExpectedType(MakeValueString("2020-02-29T13:45:00+10:00"), dateTimeType))
+	assert.False(t, isValueExpectedType(MakeValueString("29/02/2020"), dateType))
+	assert.Nil(t, evalGoFunc("DateAdd", MakeValueList(MakeValueString("29/02/2020"),
+		MakeValueI64(0), MakeValueI64(0), MakeValueI64(1))))
+}
+
 func TestIncorrectArgsToGoFunc(t *testing.T) {
 	t.Parallel()
 
@@ -400,7 +453,8 @@ func TestIncorrectArgsToGoFunc(t *testing.T) {
 
 	s := Scope{}
 	s.AddApp("app", mod.Apps[todoAppName])
-	out := EvaluateView(mod, "TransformApp", "IncorrectArgsToGoFunc", s)
+	out, err := EvaluateView(mod, "TransformApp", "IncorrectArgsToGoFunc", s)
+	require.NoError(t, err)
 	assert.NotNil(t, out.GetMap())
 	items := out.GetMap().Items
 	contains, has := items["Contains"]
@@ -420,7 +474,8 @@ func TestEvalFlatten(t *testing.T) {
 
 	s := Scope{}
 	s.AddApp("app", mod.Apps[todoAppName])
-	out := EvaluateView(mod, "TransformApp", "Flatten", s)
+	out, err := EvaluateView(mod, "TransformApp", "Flatten", s)
+	require.NoError(t, err)
 	assert.NotNil(t, out.GetMap().Items["names"].GetSet())
 	l := out.GetMap().Items["names"].GetSet().Value
 	assert.Len(t, l, 4)
@@ -451,7 +506,8 @@ func TestEvalWhere(t *testing.T) {
 
 	s := Scope{}
 	s.AddApp("app", mod.Apps[modelAppName])
-	out := EvaluateView(mod, "TransformApp", "Where", s)
+	out, err := EvaluateView(mod, "TransformApp", "Where", s)
+	require.NoError(t, err)
 
 	numbers1 := out.GetMap().Items["greaterThanOne"].GetSet().Value
 	assert.Len(t, numbers1, 2)
@@ -481,7 +537,8 @@ func TestEvalLinks(t *testing.T) {
 
 	s := Scope{}
 	s.AddApp("app", mod.Apps[modelAppName])
-	out := EvaluateView(mod, "TransformApp", "Links", s)
+	out, err := EvaluateView(mod, "TransformApp", "Links", s)
+	require.NoError(t, err)
 	assert.NotNil(t, out.GetMap().Items["links"].GetSet())
 	l := out.GetMap().Items["links"].GetSet().Value
 	assert.Len(t, l, 5)
@@ -510,7 +567,9 @@ func TestDotScope(t *testing.T) {
 
 	s := Scope{}
 	s.AddApp("app", mod.Apps[modelAppName])
-	out := EvaluateView(mod, "TransformApp", "TestDotScope", s).GetMap().Items
+	val, err := EvaluateView(mod, "TransformApp", "TestDotScope", s)
+	require.NoError(t, err)
+	out := val.GetMap().Items
 	assert.Len(t, out, 3)
 }
 
@@ -523,7 +582,8 @@ func TestListOfTypeNames(t *testing.T) {
 
 	s := Scope{}
 	s.AddApp("app", mod.Apps[modelAppName])
-	out := EvaluateView(mod, "TransformApp", "ListOfTypeNames", s)
+	out, err := EvaluateView(mod, "TransformApp", "ListOfTypeNames", s)
+	require.NoError(t, err)
 	l := out.GetList()
 	assert.NotNil(t, l)
 	assert.Len(t, l.Value, 2)
@@ -602,3 +662,51 @@ func Test_isInternalMap(t *testing.T) {
 		})
 	}
 }
+
+func TestEvaluateViewError(t *testing.T) {
+	t.Parallel()
+
+	mod, err := parse.NewParser().Parse("eval_error.sysl", syslutil.NewChrootFs(afero.NewOsFs(), testDir))
+	require.NoError(t, err)
+	require.NotNil(t, mod)
+
+	names := MakeValueList()
+	AppendItemToValueList(names.GetList(), MakeValueString("a"))
+	AppendItemToValueList(names.GetList(), MakeValueString("b"))
+	attrs := MakeValueMap()
+	AddItemToValueMap(attrs, "names", names)
+	app := MakeValueMap()
+	AddItemToValueMap(app, "attrs", attrs)
+
+	s := Scope{"app": app}
+	out, err := EvaluateView(mod, "ErrorApp", "CallsSingle", s)
+	require.Error(t, err)
+	assert.Nil(t, out)
+
+	evalErr, ok := err.(*Error)
+	require.True(t, ok, "%T", err)
+	assert.Equal(t, "Single", evalErr.View)
+	require.NotNil(t, evalErr.SourceContext)
+	assert.Equal(t, "eval_error.sysl", evalErr.SourceContext.File)
+	assert.Equal(t, int32(5), evalErr.SourceContext.Start.Line)
+	assert.Contains(t, err.Error(), "unarySingle expecting array length 1, got 2")
+	assert.Contains(t, err.Error(), "(in view Single)")
+
+	var views []string
+	for _, f := range evalErr.Stack {
+		views = append(views, f.View)
+	}
+	assert.Equal(t, "Single", views[0])
+	assert.Equal(t, "CallsSingle", views[len(views)-1])
+	assert.Contains(t, evalErr.Trace(), "Call -> Single()")
+}
+
+func TestEvaluateViewMissingView(t *testing.T) {
+	t.Parallel()
+
+	mod, err := parse.NewParser().Parse("eval_error.sysl", syslutil.NewChrootFs(afero.NewOsFs(), testDir))
+	require.NoError(t, err)
+
+	_, err = EvaluateView(mod, "ErrorApp", "Missing", Scope{})
+	assert.EqualError(t, err, "view Missing does not exist in app ErrorApp")
+}
@@ -4,6 +4,7 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 
 	sysl "github.com/anz-bank/sysl/pkg/sysl"
 	"github.com/pkg/errors"
@@ -87,6 +88,21 @@ var (
 		"TrimRight":     {reflect.ValueOf(strings.TrimRight), []*sysl.Type{stringType, stringType}, stringType},
 		"TrimSpace":     {reflect.ValueOf(strings.TrimSpace), []*sysl.Type{stringType}, stringType},
 		"TrimSuffix":    {reflect.ValueOf(strings.TrimSuffix), []*sysl.Type{stringType, stringType}, stringType},
+
+		"Now":        {reflect.ValueOf(Now), []*sysl.Type{}, dateTimeType},
+		"ParseTime":  {reflect.ValueOf(ParseTime), []*sysl.Type{stringType, stringType}, dateTimeType},
+		"ParseDate":  {reflect.ValueOf(ParseDate), []*sysl.Type{stringType, stringType}, dateType},
+		"FormatTime": {reflect.ValueOf(FormatTime), []*sysl.Type{dateTimeType, stringType}, stringType},
+		"TimeAdd":    {reflect.ValueOf(TimeAdd), []*sysl.Type{dateTimeType, stringType}, dateTimeType},
+		"TimeSub":    {reflect.ValueOf(TimeSub), []*sysl.Type{dateTimeType, dateTimeType}, stringType},
+		"TimeBefore": {reflect.ValueOf(TimeBefore), []*sysl.Type{dateTimeType, dateTimeType}, boolType},
+		"TimeAfter":  {reflect.ValueOf(TimeAfter), []*sysl.Type{dateTimeType, dateTimeType}, boolType},
+		"TimeEqual":  {reflect.ValueOf(TimeEqual), []*sysl.Type{dateTimeType, dateTimeType}, boolType},
+		"DateAdd":    {reflect.ValueOf(DateAdd), []*sysl.Type{dateType, intType, intType, intType}, dateType},
+		"DateSub":    {reflect.ValueOf(DateSub), []*sysl.Type{dateType, dateType}, intType},
+		"NewUUID":    {reflect.ValueOf(NewUUID), []*sysl.Type{}, uuidType},
+		"IsUUID":     {reflect.ValueOf(IsUUID), []*sysl.Type{stringType}, boolType},
+		"ParseUUID":  {reflect.ValueOf(ParseUUID), []*sysl.Type{stringType}, uuidType},
 	}
 )
 
@@ -98,8 +114,14 @@ func valueToReflectValue(v *sysl.Value, t *sysl.Type) reflect.Value {
 			return reflect.ValueOf(v.GetB())
 		case sysl.Type_INT:
 			return reflect.ValueOf(int(v.GetI()))
-		case sysl.Type_STRING:
+		case sysl.Type_STRING, sysl.Type_UUID:
 			return reflect.ValueOf(v.GetS())
+		case sysl.Type_DATE, sysl.Type_DATETIME:
+			t, err := parseDateTime(v.GetS())
+			if err != nil {
+				panic(err)
+			}
+			return reflect.ValueOf(t)
 		}
 	case *sysl.Type_List_:
 		listOf := x.List.Type
@@ -127,6 +149,14 @@ func isValueExpectedType(v *sysl.Value, t *sysl.Type) bool {
 	vType := getValueType(v)
 	type1, has := valueTypeToPrimitiveType[vType]
 	inType := t.GetPrimitive()
+	// Dates, datetimes and UUIDs are strings in the appropriate format.
+	switch inType {
+	case sysl.Type_DATE, sysl.Type_DATETIME:
+		_, err := parseDateTime(v.GetS())
+		return vType == ValueString && err == nil
+	case sysl.Type_UUID:
+		return vType == ValueString && IsUUID(v.GetS())
+	}
 	// if both are primitive types
 	if has && inType != sysl.Type_NO_Primitive {
 		return inType == type1
@@ -172,6 +202,13 @@ func isReflectValueExpectedType(r reflect.Value, typ *sysl.Type) bool {
 }
 
 func reflectToValue(r reflect.Value, typ *sysl.Type) *sysl.Value {
+	if t, ok := r.Interface().(time.Time); ok {
+		if typ.GetPrimitive() == sysl.Type_DATE {
+			return MakeValueDate(t)
+		}
+		return MakeValueDateTime(t)
+	}
+
 	if !isReflectValueExpectedType(r, typ) {
 		logrus.Warnf("Got %s, Expected Value type: %v \n", r.Kind(), typ.Type)
 	}
@@ -185,7 +222,7 @@ func reflectToValue(r reflect.Value, typ *sysl.Type) *sysl.Value {
 		}
 		return MakeValueI64(r.Int())
 	case reflect.String:
-		if typ.GetPrimitive() != sysl.Type_STRING {
+		if p := typ.GetPrimitive(); p != sysl.Type_STRING && p != sysl.Type_UUID {
 			logrus.Warnf("Got string, Expected Value type: %v \n", typ.Type)
 		}
 		return MakeValueString(r.String())
@@ -0,0 +1,326 @@
+package eval
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/sirupsen/logrus"
+)
+
+// REPLConfig configures a REPL created with NewModuleREPL.
+type REPLConfig struct {
+	// Module, if set, is loaded when the REPL starts.
+	Module *sysl.Module
+	// Load loads a module by name for the :load command.
+	Load func(name string) (*sysl.Module, error)
+	// History is the input of previous sessions, oldest first.
+	History []string
+	// HistoryWriter, if set, has each line of input appended to it.
+	HistoryWriter io.Writer
+}
+
+// NewModuleREPL returns a REPL whose expressions can call the views of a
+// loaded module, and which supports the :load, :apps, :app, :views, :type and
+// :history commands.
+func NewModuleREPL(input io.Reader, output io.Writer, config REPLConfig) DebugFunc {
+	r := repl{
+		input:       bufio.NewScanner(input),
+		output:      output,
+		breakpoints: []*sysl.SourceContext{},
+		step:        true,
+		load:        config.Load,
+		history:     &history{lines: config.History, out: config.HistoryWriter},
+	}
+	if config.Module != nil {
+		r.setModule(config.Module)
+	}
+	return r.run
+}
+
+type history struct {
+	lines []string
+	out   io.Writer
+}
+
+func (h *history) add(lines ...string) {
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		h.lines = append(h.lines, line)
+		if h.out != nil {
+			if _, err := io.WriteString(h.out, line+"\n"); err != nil {
+				logrus.Warnf("Failed to write history: %s", err.Error())
+				h.out = nil
+			}
+		}
+	}
+}
+
+// readInput reads a line of input, continuing onto following lines while
+// brackets are left open, e.g. by a transform.
+func (r *repl) readInput() (string, bool) {
+	if !r.input.Scan() {
+		return "", false
+	}
+	lines := []string{r.input.Text()}
+	for bracketDepth(strings.Join(lines, "\n")) > 0 {
+		writeOutput("....  ", r.output)
+		if !r.input.Scan() {
+			break
+		}
+		lines = append(lines, r.input.Text())
+	}
+	r.history.add(lines...)
+	if len(lines) == 1 {
+		return lines[0], true
+	}
+	return strings.Join(lines, "\n") + "\n", true
+}
+
+// bracketDepth returns the number of brackets opened but not closed in text.
+func bracketDepth(text string) int {
+	depth := 0
+	var quote rune
+	for _, c := range text {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+// command runs a REPL command, returning the app whose views are now callable.
+func (r *repl) command(text string, scope *Scope, app *sysl.Application) *sysl.Application {
+	r.bindModule(scope)
+	name, arg := text, ""
+	if i := strings.IndexAny(text, " \t"); i >= 0 {
+		name, arg = text[:i], strings.TrimSpace(text[i:])
+	}
+
+	switch name {
+	case ":load":
+		if r.load == nil {
+			writeOutput("no module loader available\n", r.output)
+			break
+		}
+		mod, err := r.load(arg)
+		if err != nil {
+			writeOutput(err.Error()+"\n", r.output)
+			break
+		}
+		r.setModule(mod)
+		r.bindModule(scope)
+		writeOutput(fmt.Sprintf("loaded %d app(s)\n", len(mod.Apps)), r.output)
+		return r.app
+	case ":apps":
+		if r.module == nil {
+			writeOutput("no module loaded\n", r.output)
+			break
+		}
+		for _, name := range sortedAppNames(r.module) {
+			marker := "  "
+			if r.module.Apps[name] == app {
+				marker = "* "
+			}
+			writeOutput(marker+name+"\n", r.output)
+		}
+	case ":app":
+		selected, has := r.module.GetApps()[arg]
+		if !has {
+			writeOutput(fmt.Sprintf("app %s does not exist\n", arg), r.output)
+			break
+		}
+		r.app = selected
+		scope.AddApp("app", selected)
+		return selected
+	case ":views":
+		if app == nil {
+			writeOutput("no app selected\n", r.output)
+			break
+		}
+		names := make([]string, 0, len(app.Views))
+		for name := range app.Views {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			writeOutput(formatView(name, app.Views[name])+"\n", r.output)
+		}
+	case ":type":
+		writeOutput(exprType(arg, r.module, app, scope)+"\n", r.output)
+	case ":history":
+		for i, line := range r.history.lines {
+			writeOutput(fmt.Sprintf("%4d  %s\n", i+1, line), r.output)
+		}
+	case ":help", ":?":
+		printUsage(r.output)
+	default:
+		writeOutput(fmt.Sprintf("unknown command %s, try :help\n", name), r.output)
+	}
+	return app
+}
+
+// setModule makes mod the loaded module, selecting the first app with views.
+func (r *repl) setModule(mod *sysl.Module) {
+	r.module = mod
+	r.app = nil
+	r.unbound = true
+	names := sortedAppNames(mod)
+	for _, name := range names {
+		if len(mod.Apps[name].Views) > 0 {
+			r.app = mod.Apps[name]
+			return
+		}
+	}
+	if len(names) == 1 {
+		r.app = mod.Apps[names[0]]
+	}
+}
+
+// bindModule adds a newly loaded module and its selected app to the scope.
+func (r *repl) bindModule(scope *Scope) {
+	if !r.unbound {
+		return
+	}
+	r.unbound = false
+	scope.AddModule("module", r.module)
+	if r.app != nil {
+		scope.AddApp("app", r.app)
+	} else {
+		delete(*scope, "app")
+	}
+}
+
+func sortedAppNames(mod *sysl.Module) []string {
+	names := make([]string, 0, len(mod.Apps))
+	for name := range mod.Apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func formatView(name string, view *sysl.View) string {
+	params := make([]string, 0, len(view.Param))
+	for _, p := range view.Param {
+		params = append(params, p.Name+" <: "+syslutil.FormatType(p.Type))
+	}
+	result := name + "(" + strings.Join(params, ", ") + ")"
+	if view.RetType != nil {
+		result += " -> " + syslutil.FormatType(view.RetType)
+	}
+	return result
+}
+
+// exprType returns the type of the expression in text, as inferred by the type
+// checker against the views of app. The expression is not evaluated, so names
+// bound in the scope contribute only the type of their value. Type errors, such
+// as an operator applied to the wrong types, are returned in place of the type.
+func exprType(text string, mod *sysl.Module, app *sysl.Application, scope *Scope) string {
+	expr, err := parseExpr(text)
+	if err != nil {
+		return err.Error()
+	}
+	if val, has := (*scope)[expr.GetName()]; has && expr.GetName() != "" {
+		return valueTypeName(val)
+	}
+	if mod == nil {
+		mod = &sysl.Module{}
+	}
+	if app == nil {
+		app = &sysl.Application{}
+	}
+	env := typeEnv{}
+	for name, val := range *scope {
+		env[name] = valueSyslType(val)
+	}
+	tc := &typeChecker{mod: mod, app: app, views: map[string]*sysl.Type{}}
+	t := tc.checkExpr(env, expr, nil)
+	if len(tc.errs) > 0 {
+		return tc.errs.Error()
+	}
+	// Show the declared result of a view called directly, as :views does.
+	if view, has := app.Views[expr.GetCall().GetFunc()]; has && known(view.RetType) {
+		t = view.RetType
+	}
+	if !known(t) {
+		return "unknown"
+	}
+	return syslutil.FormatType(t)
+}
+
+// valueSyslType returns the type of a value, nil if it cannot be told from the
+// value alone.
+func valueSyslType(v *sysl.Value) *sysl.Type {
+	elemType := func(items []*sysl.Value) *sysl.Type {
+		if len(items) == 0 {
+			return nil
+		}
+		return valueSyslType(items[0])
+	}
+	switch x := v.GetValue().(type) {
+	case *sysl.Value_B:
+		return syslutil.TypeBool()
+	case *sysl.Value_I:
+		return syslutil.TypeInt()
+	case *sysl.Value_D:
+		return syslutil.TypeFloat()
+	case *sysl.Value_S:
+		return syslutil.TypeString()
+	case *sysl.Value_Decimal:
+		return syslutil.TypeDecimal()
+	case *sysl.Value_List_:
+		if t := elemType(x.List.Value); t != nil {
+			return &sysl.Type{Type: &sysl.Type_Sequence{Sequence: t}}
+		}
+	case *sysl.Value_Set:
+		if t := elemType(x.Set.Value); t != nil {
+			return &sysl.Type{Type: &sysl.Type_Set{Set: t}}
+		}
+	}
+	return nil
+}
+
+// valueTypeName names the type of a value in sysl terms.
+func valueTypeName(v *sysl.Value) string {
+	elemType := func(items []*sysl.Value) string {
+		if len(items) == 0 {
+			return "any"
+		}
+		return valueTypeName(items[0])
+	}
+	switch x := v.GetValue().(type) {
+	case *sysl.Value_B:
+		return "bool"
+	case *sysl.Value_I:
+		return "int"
+	case *sysl.Value_D:
+		return "float"
+	case *sysl.Value_S:
+		return "string"
+	case *sysl.Value_Decimal:
+		return "decimal"
+	case *sysl.Value_List_:
+		return "sequence of " + elemType(x.List.Value)
+	case *sysl.Value_Set:
+		return "set of " + elemType(x.Set.Value)
+	case *sysl.Value_Map_:
+		return "map"
+	}
+	return "null"
+}
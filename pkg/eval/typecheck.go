@@ -0,0 +1,646 @@
+package eval
+
+import (
+	"sort"
+	"strings"
+
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/pkg/errors"
+)
+
+// CheckTypes statically checks the views of the app named appName in mod, so
+// that type errors are reported with their positions before any evaluation
+// starts. It checks function calls against GoFuncMap and the app's views,
+// attribute access on tuples, operands against the operations the evaluator
+// supports, collection element types and view results against their declared
+// return types.
+//
+// Types that cannot be known without evaluating, such as those of names bound
+// by the caller's scope or of types defined outside mod, are not checked.
+// The errors found are returned as TypeErrors.
+func CheckTypes(mod *sysl.Module, appName string) error {
+	app, has := mod.Apps[appName]
+	if !has {
+		return errors.Errorf("app %s does not exist", appName)
+	}
+	tc := &typeChecker{
+		mod:   mod,
+		app:   app,
+		views: map[string]*sysl.Type{},
+	}
+	names := make([]string, 0, len(app.Views))
+	for name := range app.Views {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tc.checkView(name)
+	}
+	if len(tc.errs) > 0 {
+		return tc.errs
+	}
+	return nil
+}
+
+// typeEnv maps the names in scope to their types, nil if unknown.
+type typeEnv map[string]*sysl.Type
+
+func (env typeEnv) with(name string, t *sysl.Type) typeEnv {
+	result := make(typeEnv, len(env)+1)
+	for k, v := range env {
+		result[k] = v
+	}
+	result[name] = t
+	return result
+}
+
+type typeChecker struct {
+	mod   *sysl.Module
+	app   *sysl.Application
+	view  string
+	views map[string]*sysl.Type // result types of the views checked so far
+	stack []*sysl.Expr
+	errs  TypeErrors
+}
+
+// checkView checks the view once, returning the type of its result or nil if
+// it is unknown, e.g. because the view is recursive.
+func (tc *typeChecker) checkView(name string) *sysl.Type {
+	if t, has := tc.views[name]; has {
+		return t
+	}
+	tc.views[name] = nil
+
+	view := tc.app.Views[name]
+	if view.Expr == nil || syslutil.HasPattern(view.Attrs, "abstract") {
+		return nil
+	}
+
+	caller, stack := tc.view, tc.stack
+	tc.view, tc.stack = name, nil
+	defer func() { tc.view, tc.stack = caller, stack }()
+
+	env := typeEnv{}
+	for _, p := range view.Param {
+		env[p.Name] = p.Type
+	}
+	declared := view.Expr.Type
+	if declared == nil {
+		declared = view.RetType
+	}
+	t := tc.checkExpr(env, view.Expr, declared)
+	tc.views[name] = t
+	tc.checkReturn(view, t)
+	return t
+}
+
+// checkReturn checks the result of a view against its declared return type. A
+// view declared to return a primitive may return a tuple wrapping it, as the
+// filename view of a code generation transform does.
+func (tc *typeChecker) checkReturn(view *sysl.View, got *sysl.Type) {
+	want := tc.resolve(view.RetType)
+	if !known(want) || !known(got) {
+		return
+	}
+	wantElem, wantColl := elemType(want)
+	gotElem, gotColl := elemType(got)
+	switch {
+	case wantColl && !gotColl:
+		tc.errorf(view.Expr, "view %s returns %s, but is declared to return %s",
+			tc.view, syslutil.FormatType(got), syslutil.FormatType(view.RetType))
+		return
+	case !wantColl && gotColl:
+		if want.GetPrimitive() == sysl.Type_NO_Primitive {
+			tc.errorf(view.Expr, "view %s returns %s, but is declared to return %s",
+				tc.view, syslutil.FormatType(got), syslutil.FormatType(view.RetType))
+		}
+		return
+	case wantColl:
+		want, got = tc.resolve(wantElem), tc.resolve(gotElem)
+	}
+	if !known(want) || !known(got) {
+		return
+	}
+
+	if attrs := attrDefs(want); attrs != nil {
+		if attrDefs(got) == nil {
+			tc.errorf(view.Expr, "view %s returns %s, but is declared to return %s",
+				tc.view, syslutil.FormatType(got), syslutil.FormatType(view.RetType))
+			return
+		}
+		for _, stmt := range view.Expr.GetTransform().GetStmt() {
+			assign := stmt.GetAssign()
+			if assign == nil {
+				continue
+			}
+			attr, has := attrs[assign.Name]
+			if !has {
+				tc.errorf(assign.Expr, "attribute %s is not defined by %s",
+					assign.Name, syslutil.FormatType(view.RetType))
+				continue
+			}
+			gotAttr := attrDefs(got)[assign.Name]
+			if !tc.assignable(attr, gotAttr) {
+				tc.errorf(assign.Expr, "attribute %s is %s, but %s declares it %s", assign.Name,
+					syslutil.FormatType(gotAttr), syslutil.FormatType(view.RetType), syslutil.FormatType(attr))
+			}
+		}
+	} else if attrDefs(got) == nil && !tc.assignable(want, got) {
+		tc.errorf(view.Expr, "view %s returns %s, but is declared to return %s",
+			tc.view, syslutil.FormatType(got), syslutil.FormatType(view.RetType))
+	}
+}
+
+// checkExpr returns the type of expr, nil if it is unknown. declared is the
+// type the expression is declared to have, if any.
+func (tc *typeChecker) checkExpr(env typeEnv, expr *sysl.Expr, declared *sysl.Type) *sysl.Type {
+	if expr == nil {
+		return nil
+	}
+	tc.stack = append(tc.stack, expr)
+	defer func() { tc.stack = tc.stack[:len(tc.stack)-1] }()
+
+	switch e := expr.Expr.(type) {
+	case *sysl.Expr_Transform_:
+		return tc.checkTransform(env, e.Transform, declared)
+	case *sysl.Expr_Binexpr:
+		return tc.checkBinExpr(env, expr, e.Binexpr)
+	case *sysl.Expr_Unexpr:
+		return tc.checkUnExpr(env, expr, e.Unexpr)
+	case *sysl.Expr_Call_:
+		return tc.checkCall(env, expr, e.Call)
+	case *sysl.Expr_Name:
+		return env[e.Name]
+	case *sysl.Expr_GetAttr_:
+		return tc.checkGetAttr(env, expr, e.GetAttr)
+	case *sysl.Expr_Ifelse:
+		return tc.checkIfelse(env, expr, e.Ifelse)
+	case *sysl.Expr_Literal:
+		return literalType(e.Literal)
+	case *sysl.Expr_Set:
+		return &sysl.Type{Type: &sysl.Type_Set{Set: tc.checkElems(env, expr, e.Set.Expr)}}
+	case *sysl.Expr_List_:
+		return &sysl.Type{Type: &sysl.Type_List_{List: &sysl.Type_List{Type: tc.checkElems(env, expr, e.List.Expr)}}}
+	}
+	return nil
+}
+
+func (tc *typeChecker) checkTransform(env typeEnv, x *sysl.Expr_Transform, declared *sysl.Type) *sysl.Type {
+	arg := tc.resolve(tc.checkExpr(env, x.Arg, nil))
+	tuple := &sysl.Type{Type: &sysl.Type_Tuple_{Tuple: &sysl.Type_Tuple{AttrDefs: map[string]*sysl.Type{}}}}
+	collection := func(elem *sysl.Type) *sysl.Type {
+		if declared.GetSet() != nil {
+			return &sysl.Type{Type: &sysl.Type_Set{Set: elem}}
+		}
+		return &sysl.Type{Type: &sysl.Type_List_{List: &sysl.Type_List{Type: elem}}}
+	}
+
+	// Mirrors evalTransform: collections are transformed element by element,
+	// and tuples are unpacked into key/value entries unless the scope is '.'.
+	var scopeType, result *sysl.Type
+	elem, isColl := elemType(arg)
+	switch {
+	case isColl:
+		scopeType, result = tc.resolve(elem), collection(tuple)
+	case attrDefs(arg) != nil && x.Scopevar != ".":
+		scopeType, result = entryType(nil), collection(tuple)
+	case known(arg):
+		scopeType, result = arg, tuple
+	}
+
+	inner := env.with(x.Scopevar, scopeType)
+	attrs := tuple.GetTuple().AttrDefs
+	for _, stmt := range x.Stmt {
+		switch s := stmt.Stmt.(type) {
+		case *sysl.Expr_Transform_Stmt_Let:
+			inner = inner.with(s.Let.Name, tc.checkExpr(inner, s.Let.Expr, s.Let.Expr.GetType()))
+		case *sysl.Expr_Transform_Stmt_Assign_:
+			attrs[s.Assign.Name] = tc.checkExpr(inner, s.Assign.Expr, s.Assign.Expr.GetType())
+		}
+	}
+	return result
+}
+
+func (tc *typeChecker) checkBinExpr(env typeEnv, expr *sysl.Expr, x *sysl.Expr_BinExpr) *sysl.Type {
+	strategy, has := functionEvalStrategy[x.Op]
+	if !has {
+		tc.checkExpr(env, x.Lhs, nil)
+		tc.checkExpr(env, x.Rhs, nil)
+		tc.errorf(expr, "unsupported operator %s", x.Op)
+		return nil
+	}
+	if _, ok := strategy.(LHSOverRHSStrategy); ok {
+		return tc.checkLHSOverRHS(env, expr, x)
+	}
+
+	lhs := tc.resolve(tc.checkExpr(env, x.Lhs, nil))
+	rhs := tc.resolve(tc.checkExpr(env, x.Rhs, nil))
+	op := x.Op
+	if op == sysl.Expr_BinExpr_NE {
+		op = sysl.Expr_BinExpr_EQ
+	}
+	lhsType, lhsKnown := staticValueType(lhs)
+	rhsType, rhsKnown := staticValueType(rhs)
+	if lhsKnown && rhsKnown {
+		if _, has := valueFunctions[makeKey(op, lhsType, rhsType)]; !has {
+			tc.errorf(expr, "unsupported operation %s %s %s",
+				syslutil.FormatType(lhs), x.Op, syslutil.FormatType(rhs))
+			return nil
+		}
+	}
+
+	switch x.Op {
+	case sysl.Expr_BinExpr_ADD, sysl.Expr_BinExpr_SUB, sysl.Expr_BinExpr_MUL,
+		sysl.Expr_BinExpr_DIV, sysl.Expr_BinExpr_MOD:
+		if known(lhs) {
+			return lhs
+		}
+		return rhs
+	case sysl.Expr_BinExpr_BITOR:
+		elem, _ := elemType(lhs)
+		if !known(elem) {
+			elem, _ = elemType(rhs)
+		}
+		if lhsType == ValueSet && rhsType == ValueSet {
+			return &sysl.Type{Type: &sysl.Type_Set{Set: elem}}
+		}
+		if lhsKnown {
+			return &sysl.Type{Type: &sysl.Type_List_{List: &sysl.Type_List{Type: elem}}}
+		}
+		return nil
+	}
+	return syslutil.TypeBool()
+}
+
+// checkLHSOverRHS checks flatten and where, which evaluate their rhs for each
+// element of their lhs.
+func (tc *typeChecker) checkLHSOverRHS(env typeEnv, expr *sysl.Expr, x *sysl.Expr_BinExpr) *sysl.Type {
+	lhs := tc.resolve(tc.checkExpr(env, x.Lhs, nil))
+	lhsType, lhsKnown := staticValueType(lhs)
+	elem, isColl := elemType(lhs)
+	elem = tc.resolve(elem)
+	var scopeType *sysl.Type
+	switch {
+	case isColl:
+		scopeType = elem
+		if inner, ok := elemType(elem); ok && x.Op == sysl.Expr_BinExpr_FLATTEN {
+			scopeType = tc.resolve(inner)
+		}
+	case lhsType == ValueMap:
+		scopeType = entryType(nil)
+	}
+	rhs := tc.resolve(tc.checkExpr(env.with(x.Scopevar, scopeType), x.Rhs, nil))
+
+	if lhsKnown {
+		elemValueType, elemKnown := staticValueType(elem)
+		if !isColl {
+			elemValueType, elemKnown = ValueNoArg, true
+		}
+		if elemKnown {
+			if _, has := exprFunctions[makeKey(x.Op, lhsType, elemValueType)]; !has {
+				tc.errorf(expr, "unsupported operation %s %s", syslutil.FormatType(lhs), x.Op)
+				return nil
+			}
+		}
+	}
+
+	if x.Op == sysl.Expr_BinExpr_WHERE {
+		if known(rhs) && rhs.GetPrimitive() != sysl.Type_BOOL {
+			tc.errorf(x.Rhs, "where condition is %s, not bool", syslutil.FormatType(rhs))
+		}
+		return lhs
+	}
+	if rhsElem, ok := elemType(rhs); ok && rhs.GetSet() != nil {
+		rhs = rhsElem
+	}
+	switch lhsType {
+	case ValueSet:
+		return &sysl.Type{Type: &sysl.Type_Set{Set: rhs}}
+	case ValueList:
+		return &sysl.Type{Type: &sysl.Type_List_{List: &sysl.Type_List{Type: rhs}}}
+	}
+	return nil
+}
+
+func (tc *typeChecker) checkUnExpr(env typeEnv, expr *sysl.Expr, x *sysl.Expr_UnExpr) *sysl.Type {
+	arg := tc.resolve(tc.checkExpr(env, x.Arg, nil))
+	if _, has := unaryFunctions[x.Op]; !has {
+		tc.errorf(expr, "unsupported unary operator %s", x.Op)
+		return nil
+	}
+	switch x.Op {
+	case sysl.Expr_UnExpr_NEG:
+		if p := arg.GetPrimitive(); known(arg) && p != sysl.Type_INT && p != sysl.Type_BOOL {
+			tc.errorf(expr, "cannot negate %s", syslutil.FormatType(arg))
+			return nil
+		}
+		return arg
+	case sysl.Expr_UnExpr_SINGLE:
+		elem, ok := elemType(arg)
+		if known(arg) && !ok {
+			tc.errorf(expr, "single expects a sequence or set, got %s", syslutil.FormatType(arg))
+			return nil
+		}
+		return elem
+	case sysl.Expr_UnExpr_STRING:
+		return syslutil.TypeString()
+	}
+	return nil
+}
+
+func (tc *typeChecker) checkCall(env typeEnv, expr *sysl.Expr, x *sysl.Expr_Call) *sysl.Type {
+	args := make([]*sysl.Type, 0, len(x.Arg))
+	for _, arg := range x.Arg {
+		args = append(args, tc.resolve(tc.checkExpr(env, arg, nil)))
+	}
+
+	if view, has := tc.app.Views[x.Func]; has {
+		if len(args) != len(view.Param) {
+			tc.errorf(expr, "view %s expects %d arguments, got %d", x.Func, len(view.Param), len(args))
+			return nil
+		}
+		for i, p := range view.Param {
+			if !tc.assignable(p.Type, args[i]) {
+				tc.errorf(x.Arg[i], "argument %s of view %s is %s, got %s",
+					p.Name, x.Func, syslutil.FormatType(p.Type), syslutil.FormatType(args[i]))
+			}
+		}
+		// Declared return types of views often describe the value generated
+		// from the result rather than the result itself, so prefer the type
+		// inferred from the view's expression.
+		return tc.checkView(x.Func)
+	}
+
+	if strings.HasPrefix(x.Func, ".") {
+		if x.Func != ".count" {
+			tc.errorf(expr, "unimplemented function: %s", x.Func)
+			return nil
+		}
+		if len(args) == 1 && known(args[0]) {
+			if _, ok := elemType(args[0]); !ok && attrDefs(args[0]) == nil {
+				tc.errorf(expr, "count expects a collection, got %s", syslutil.FormatType(args[0]))
+			}
+		}
+		return syslutil.TypeInt()
+	}
+
+	f, has := GoFuncMap[x.Func]
+	if !has {
+		tc.errorf(expr, "unknown function %s", x.Func)
+		return nil
+	}
+	if len(args) != len(f.args) {
+		tc.errorf(expr, "%s expects %d arguments, got %d", x.Func, len(f.args), len(args))
+		return f.ret
+	}
+	for i, want := range f.args {
+		if !tc.assignable(want, args[i]) {
+			tc.errorf(x.Arg[i], "argument %d of %s is %s, got %s",
+				i+1, x.Func, syslutil.FormatType(want), syslutil.FormatType(args[i]))
+		}
+	}
+	return f.ret
+}
+
+func (tc *typeChecker) checkGetAttr(env typeEnv, expr *sysl.Expr, x *sysl.Expr_GetAttr) *sysl.Type {
+	declared := tc.checkExpr(env, x.Arg, nil)
+	arg := tc.resolve(declared)
+	if !known(arg) {
+		return nil
+	}
+	attrs := attrDefs(arg)
+	if attrs == nil {
+		tc.errorf(expr, "cannot get attribute %s of %s", x.Attr, syslutil.FormatType(declared))
+		return nil
+	}
+	t, has := attrs[x.Attr]
+	if !has {
+		if isEntryType(arg) {
+			// Entries forward other attributes to their value, as in evalGetAttr.
+			return nil
+		}
+		tc.errorf(expr, "%s has no attribute %s", syslutil.FormatType(declared), x.Attr)
+		return nil
+	}
+	return t
+}
+
+func (tc *typeChecker) checkIfelse(env typeEnv, expr *sysl.Expr, x *sysl.Expr_IfElse) *sysl.Type {
+	cond := tc.resolve(tc.checkExpr(env, x.Cond, nil))
+	if known(cond) && cond.GetPrimitive() != sysl.Type_BOOL {
+		tc.errorf(x.Cond, "condition is %s, not bool", syslutil.FormatType(cond))
+	}
+	ifTrue := tc.resolve(tc.checkExpr(env, x.IfTrue, nil))
+	if x.IfFalse == nil {
+		return ifTrue
+	}
+	ifFalse := tc.resolve(tc.checkExpr(env, x.IfFalse, nil))
+	switch {
+	case !known(ifTrue):
+		return ifFalse
+	case !tc.assignable(ifTrue, ifFalse) && !tc.assignable(ifFalse, ifTrue):
+		tc.errorf(expr, "if/else branches have different types: %s and %s",
+			syslutil.FormatType(ifTrue), syslutil.FormatType(ifFalse))
+	}
+	return ifTrue
+}
+
+// checkElems returns the element type of a list or set literal.
+func (tc *typeChecker) checkElems(env typeEnv, expr *sysl.Expr, exprs []*sysl.Expr) *sysl.Type {
+	var elem *sysl.Type
+	for _, e := range exprs {
+		t := tc.resolve(tc.checkExpr(env, e, nil))
+		switch {
+		case !known(t):
+		case !known(elem):
+			elem = t
+		case !tc.assignable(elem, t) && !tc.assignable(t, elem):
+			tc.errorf(e, "element is %s, but the collection holds %s",
+				syslutil.FormatType(t), syslutil.FormatType(elem))
+		}
+	}
+	return elem
+}
+
+// isStringRepresented returns whether values of the primitive type are
+// strings.
+func isStringRepresented(p sysl.Type_Primitive) bool {
+	switch p {
+	case sysl.Type_STRING, sysl.Type_DATE, sysl.Type_DATETIME, sysl.Type_UUID:
+		return true
+	}
+	return false
+}
+
+// assignable returns false only if a value of type got is known to be unusable
+// where type want is required. Sequences, sets and lists are interchangeable.
+func (tc *typeChecker) assignable(want, got *sysl.Type) bool {
+	want, got = tc.resolve(want), tc.resolve(got)
+	if !known(want) || !known(got) {
+		return true
+	}
+	if wp := want.GetPrimitive(); wp != sysl.Type_NO_Primitive {
+		gp := got.GetPrimitive()
+		switch {
+		case wp == sysl.Type_ANY || gp == sysl.Type_ANY:
+			return true
+		case gp == sysl.Type_NO_Primitive:
+			return false
+		}
+		wp, gp = normalisePrimitive(wp), normalisePrimitive(gp)
+		switch {
+		case wp == sysl.Type_DATETIME && gp == sysl.Type_DATE:
+			// Dates are midnight UTC.
+			return true
+		case isStringRepresented(wp) && isStringRepresented(gp):
+			// Dates, datetimes and UUIDs are represented as strings, and are
+			// only given as strings, as sysl has no literals for them.
+			return wp == sysl.Type_STRING || gp == sysl.Type_STRING || wp == gp
+		}
+		return wp == gp
+	}
+	if wantElem, ok := elemType(want); ok {
+		gotElem, ok := elemType(got)
+		return ok && tc.assignable(wantElem, gotElem)
+	}
+	if wantAttrs := attrDefs(want); wantAttrs != nil {
+		gotAttrs := attrDefs(got)
+		if gotAttrs == nil {
+			return false
+		}
+		for name, t := range gotAttrs {
+			w, has := wantAttrs[name]
+			if !has || !tc.assignable(w, t) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// resolve follows type references to the types they name in the module, and
+// returns nil if they are not defined there.
+func (tc *typeChecker) resolve(t *sysl.Type) *sysl.Type {
+	for i := 0; t.GetTypeRef() != nil; i++ {
+		ref := t.GetTypeRef().GetRef()
+		if i > 16 || ref == nil {
+			return nil
+		}
+		var types map[string]*sysl.Type
+		var name string
+		if len(ref.Path) == 0 {
+			types, name = tc.app.Types, strings.Join(ref.GetAppname().GetPart(), ".")
+		} else {
+			types, name = tc.mod.Apps[syslutil.GetAppName(ref.GetAppname())].GetTypes(), strings.Join(ref.Path, ".")
+		}
+		t = types[name]
+	}
+	return t
+}
+
+func (tc *typeChecker) errorf(expr *sysl.Expr, format string, args ...interface{}) {
+	sc := expr.GetSourceContext()
+	// Not all expressions record their position, so fall back to the nearest
+	// enclosing one that does.
+	for i := len(tc.stack) - 1; i >= 0 && sc == nil; i-- {
+		sc = tc.stack[i].SourceContext
+	}
+	tc.errs = append(tc.errs, &Error{
+		Err:           errors.Errorf(format, args...),
+		View:          tc.view,
+		SourceContext: sc,
+	})
+}
+
+func known(t *sysl.Type) bool {
+	return t != nil && t.Type != nil && t.GetNoType() == nil
+}
+
+// elemType returns the element type of a sequence, set or list.
+func elemType(t *sysl.Type) (*sysl.Type, bool) {
+	switch x := t.GetType().(type) {
+	case *sysl.Type_Sequence:
+		return x.Sequence, true
+	case *sysl.Type_Set:
+		return x.Set, true
+	case *sysl.Type_List_:
+		return x.List.Type, true
+	}
+	return nil, false
+}
+
+// attrDefs returns the attributes of a tuple or relation, nil for other types.
+func attrDefs(t *sysl.Type) map[string]*sysl.Type {
+	switch x := t.GetType().(type) {
+	case *sysl.Type_Tuple_:
+		if x.Tuple.AttrDefs == nil {
+			return map[string]*sysl.Type{}
+		}
+		return x.Tuple.AttrDefs
+	case *sysl.Type_Relation_:
+		if x.Relation.AttrDefs == nil {
+			return map[string]*sysl.Type{}
+		}
+		return x.Relation.AttrDefs
+	}
+	return nil
+}
+
+// entryType is the type of the key/value entries a tuple is unpacked into.
+func entryType(value *sysl.Type) *sysl.Type {
+	return &sysl.Type{Type: &sysl.Type_Tuple_{Tuple: &sysl.Type_Tuple{
+		AttrDefs: map[string]*sysl.Type{"key": syslutil.TypeString(), "value": value},
+	}}}
+}
+
+func isEntryType(t *sysl.Type) bool {
+	attrs := attrDefs(t)
+	_, hasKey := attrs["key"]
+	_, hasValue := attrs["value"]
+	return len(attrs) == 2 && hasKey && hasValue
+}
+
+func normalisePrimitive(p sysl.Type_Primitive) sysl.Type_Primitive {
+	if p == sysl.Type_STRING_8 {
+		return sysl.Type_STRING
+	}
+	return p
+}
+
+// staticValueType returns the kind of value a type describes, as used to look
+// up operations in valueFunctions and exprFunctions.
+func staticValueType(t *sysl.Type) (valueType, bool) {
+	switch x := t.GetType().(type) {
+	case *sysl.Type_Primitive_:
+		switch normalisePrimitive(x.Primitive) {
+		case sysl.Type_BOOL:
+			return ValueBool, true
+		case sysl.Type_INT:
+			return ValueInt, true
+		case sysl.Type_FLOAT:
+			return ValueFloat, true
+		case sysl.Type_STRING, sysl.Type_DATE, sysl.Type_DATETIME, sysl.Type_UUID:
+			return ValueString, true
+		case sysl.Type_DECIMAL:
+			return ValueStringDecimal, true
+		}
+	case *sysl.Type_Sequence, *sysl.Type_List_:
+		return ValueList, true
+	case *sysl.Type_Set:
+		return ValueSet, true
+	case *sysl.Type_Tuple_, *sysl.Type_Relation_:
+		return ValueMap, true
+	}
+	return ValueNoArg, false
+}
+
+func literalType(v *sysl.Value) *sysl.Type {
+	if p, has := valueTypeToPrimitiveType[getValueType(v)]; has {
+		return &sysl.Type{Type: &sysl.Type_Primitive_{Primitive: p}}
+	}
+	if _, ok := v.GetValue().(*sysl.Value_Decimal); ok {
+		return syslutil.TypeDecimal()
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTypes(t *testing.T) {
+	t.Parallel()
+
+	mod, err := parse.NewParser().Parse("typecheck.sysl", syslutil.NewChrootFs(afero.NewOsFs(), testDir))
+	require.NoError(t, err)
+
+	err = CheckTypes(mod, "TypeCheckApp")
+	require.Error(t, err)
+	require.IsType(t, TypeErrors{}, err)
+
+	messages := map[string][]string{}
+	for _, e := range err.(TypeErrors) {
+		require.NotNil(t, e.SourceContext, e.Error())
+		messages[e.View] = append(messages[e.View], e.Error())
+	}
+	assert.Equal(t, map[string][]string{
+		"BadAttr": {
+			"typecheck.sysl:15:6: Model.Person has no attribute email (in view BadAttr)",
+		},
+		"BadBranches": {
+			"typecheck.sysl:25:6: if/else branches have different types: string and int (in view BadBranches)",
+		},
+		"BadCalls": {
+			"typecheck.sysl:35:26: argument p of view Greeting is Model.Person, got int (in view BadCalls)",
+			"typecheck.sysl:36:22: argument 1 of ToUpper is string, got int (in view BadCalls)",
+			"typecheck.sysl:37:6: unknown function Unknown (in view BadCalls)",
+		},
+		"BadCollection": {
+			"typecheck.sysl:47:4: view BadCollection returns tuple, but is declared to return sequence of string" +
+				" (in view BadCollection)",
+		},
+		"BadElems": {
+			"typecheck.sysl:30:16: element is string, but the collection holds int (in view BadElems)",
+		},
+		"BadOperands": {
+			"typecheck.sysl:20:6: unsupported operation string ADD int (in view BadOperands)",
+		},
+		"BadReturn": {
+			"typecheck.sysl:42:6: attribute name is int, but Model.Person declares it string (in view BadReturn)",
+			"typecheck.sysl:43:6: attribute email is not defined by Model.Person (in view BadReturn)",
+		},
+	}, messages)
+}
+
+func TestCheckTypesGoFuncArgs(t *testing.T) {
+	t.Parallel()
+
+	mod, err := parse.NewParser().Parse("eval_expr.sysl", syslutil.NewChrootFs(afero.NewOsFs(), testDir))
+	require.NoError(t, err)
+
+	err = CheckTypes(mod, "TransformApp")
+	require.Error(t, err)
+	var views []string
+	for _, e := range err.(TypeErrors) {
+		views = append(views, e.View)
+	}
+	assert.Equal(t, []string{
+		"IncorrectArgsToGoFunc", "IncorrectArgsToGoFunc", "IncorrectArgsToGoFunc", "ListSetOps",
+	}, views)
+}
+
+func TestCheckTypesMissingApp(t *testing.T) {
+	t.Parallel()
+
+	mod, err := parse.NewParser().Parse("typecheck.sysl", syslutil.NewChrootFs(afero.NewOsFs(), testDir))
+	require.NoError(t, err)
+	assert.EqualError(t, CheckTypes(mod, "Missing"), "app Missing does not exist")
+}
+
+func TestCheckTypesCodegenTransform(t *testing.T) {
+	t.Parallel()
+
+	mod, err := parse.NewParser().Parse("test.gen.sysl", syslutil.NewChrootFs(afero.NewOsFs(), testDir))
+	require.NoError(t, err)
+	assert.NoError(t, CheckTypes(mod, "TransformApp"))
+}
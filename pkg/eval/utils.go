@@ -10,14 +10,15 @@ import (
 type exprData struct {
 	e    *sysl.Expr
 	args Scope
+	view string
 }
 
 type exprStack struct {
 	s []*exprData
 }
 
-func (e *exprStack) Push(scope Scope, expr *sysl.Expr) {
-	e.s = append(e.s, &exprData{expr, scope})
+func (e *exprStack) Push(scope Scope, expr *sysl.Expr, view string) {
+	e.s = append(e.s, &exprData{expr, scope, view})
 }
 
 func (e *exprStack) Pop() *exprData {
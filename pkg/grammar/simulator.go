@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+)
+
+// Simulator holds the ATNs and DFAs the lexer and parser predict with. The
+// generated NewSyslLexer and NewSyslParser share a global Simulator that the
+// Antlr runtime updates without locking, so each goroutine lexing and parsing
+// concurrently needs its own. A Simulator is not safe for concurrent use, but
+// reusing it keeps the DFAs it has learned.
+type Simulator struct {
+	lexerATN  *antlr.ATN
+	lexerDFA  []*antlr.DFA
+	parserATN *antlr.ATN
+	parserDFA []*antlr.DFA
+}
+
+func newDFA(atn *antlr.ATN) []*antlr.DFA {
+	dfa := make([]*antlr.DFA, len(atn.DecisionToState))
+	for index, ds := range atn.DecisionToState {
+		dfa[index] = antlr.NewDFA(ds, index)
+	}
+	return dfa
+}
+
+// NewSimulator returns a Simulator with empty DFAs.
+func NewSimulator() *Simulator {
+	lexerATN := antlr.NewATNDeserializer(nil).DeserializeFromUInt16(serializedLexerAtn)
+	parserATN := antlr.NewATNDeserializer(nil).DeserializeFromUInt16(parserATN)
+	return &Simulator{
+		lexerATN:  lexerATN,
+		lexerDFA:  newDFA(lexerATN),
+		parserATN: parserATN,
+		parserDFA: newDFA(parserATN),
+	}
+}
+
+// NewSyslLexer returns a lexer predicting with the Simulator.
+func (s *Simulator) NewSyslLexer(input antlr.CharStream) *SyslLexer {
+	l := NewSyslLexer(input)
+	l.Interpreter = antlr.NewLexerATNSimulator(l, s.lexerATN, s.lexerDFA, antlr.NewPredictionContextCache())
+	return l
+}
+
+// NewSyslParser returns a parser predicting with the Simulator.
+func (s *Simulator) NewSyslParser(input antlr.TokenStream) *SyslParser {
+	p := NewSyslParser(input)
+	p.Interpreter = antlr.NewParserATNSimulator(p, s.parserATN, s.parserDFA, antlr.NewPredictionContextCache())
+	return p
+}
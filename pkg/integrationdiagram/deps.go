@@ -0,0 +1,98 @@
+package integrationdiagram
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/pkg/errors"
+)
+
+type AppElement struct {
+	Name     string `json:"app"`
+	Endpoint string `json:"endpoint"`
+}
+
+type AppDependency struct {
+	Self, Target AppElement
+	Statement    *sysl.Statement
+}
+
+func (dep *AppDependency) String() string {
+	return fmt.Sprintf("%s:%s:%s:%s", dep.Self.Name, dep.Self.Endpoint, dep.Target.Name, dep.Target.Endpoint)
+}
+
+type dependencyEdge struct {
+	From AppElement `json:"from"`
+	To   AppElement `json:"to"`
+}
+
+// dependencyGraph is the JSON form of the graph exported by sysl deps.
+type dependencyGraph struct {
+	Apps         []string         `json:"apps"`
+	Dependencies []dependencyEdge `json:"dependencies"`
+}
+
+// buildDependencyGraph collects the calls between all the apps with endpoints,
+// as sysl ints does for the apps of a project.
+func buildDependencyGraph(m *sysl.Module, excludes syslutil.StrSet) *intsBuilder {
+	appNames := make([]string, 0, len(m.GetApps()))
+	for appName, app := range m.GetApps() {
+		if len(app.GetEndpoints()) > 0 && !excludes.Contains(appName) {
+			appNames = append(appNames, appName)
+		}
+	}
+	sort.Strings(appNames)
+
+	stmts := make([]*sysl.Statement, 0, len(appNames))
+	for _, appName := range appNames {
+		stmts = append(stmts, &sysl.Statement{
+			Stmt: &sysl.Statement_Action{Action: &sysl.Action{Action: appName}},
+		})
+	}
+	return makeBuilderfromStmt(m, stmts, excludes, syslutil.MakeStrSet())
+}
+
+// Dependencies returns the calls between all the apps with endpoints, except
+// the excluded ones.
+func Dependencies(m *sysl.Module, excludes syslutil.StrSet) []AppDependency {
+	return buildDependencyGraph(m, excludes).depsOut
+}
+
+// GenerateDependencyGraph returns the dependencies between the apps of a
+// module as a Graphviz digraph, or as JSON if output ends with ".json".
+func GenerateDependencyGraph(m *sysl.Module, output string, excludes []string, clustered bool) (string, error) {
+	b := buildDependencyGraph(m, syslutil.MakeStrSet(excludes...))
+
+	switch {
+	case strings.HasSuffix(output, ".json"):
+		graph := dependencyGraph{
+			Apps:         b.finalAppsMap.ToSortedSlice(),
+			Dependencies: make([]dependencyEdge, 0, len(b.depsOut)),
+		}
+		for _, dep := range b.depsOut {
+			graph.Dependencies = append(graph.Dependencies, dependencyEdge{From: dep.Self, To: dep.Target})
+		}
+		out, err := json.MarshalIndent(graph, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(out) + "\n", nil
+
+	case IsDotOutput(output):
+		g := &dotGraph{
+			mod:          m,
+			apps:         b.finalAppsMap.ToSortedSlice(),
+			integrations: b.depsOut,
+			clustered:    clustered,
+			label:        func(_, name string) string { return name },
+		}
+		return g.String(), nil
+
+	default:
+		return "", errors.Errorf("extension must be dot or json, not %q", output)
+	}
+}
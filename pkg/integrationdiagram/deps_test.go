@@ -0,0 +1,63 @@
+package integrationdiagram
+
+import (
+	"testing"
+
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppDependency_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "AppA:EndptA:AppB:EndptB", (&AppDependency{
+		Self:      AppElement{Name: "AppA", Endpoint: "EndptA"},
+		Target:    AppElement{Name: "AppB", Endpoint: "EndptB"},
+		Statement: &sysl.Statement{},
+	}).String())
+}
+
+func TestGenerateDependencyGraph(t *testing.T) {
+	t.Parallel()
+
+	mod, _, err := loadModule(testDir, "indirect_1.sysl")
+	require.NoError(t, err)
+
+	out, err := GenerateDependencyGraph(mod, "deps.dot", []string{"Project", "System_c"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, DotHeader+`digraph {
+  node [shape=box, style=filled, fillcolor=FloralWhite, color=Black];
+  edge [color="Crimson"];
+  "IntegratedSystem" [label="IntegratedSystem", style="filled"];
+  "System_a" [label="System_a", style="filled"];
+  "System_b" [label="System_b", style="filled"];
+  "IntegratedSystem" -> "System_a" [label="endpoint"];
+  "IntegratedSystem" -> "System_b" [label="endpoint"];
+  "System_a" -> "System_b" [label="endpoint"];
+  "System_a" -> "IntegratedSystem" [label="integrated_endpoint_3"];
+}
+`, out)
+
+	out, err = GenerateDependencyGraph(mod, "deps.json", []string{"Project"}, false)
+	require.NoError(t, err)
+	assert.Contains(t, out, `"apps": [
+    "IntegratedSystem",
+    "System_a",
+    "System_b",
+    "System_c"
+  ]`)
+	assert.Contains(t, out, `{
+      "from": {
+        "app": "System_b",
+        "endpoint": "endpoint2"
+      },
+      "to": {
+        "app": "System_c",
+        "endpoint": "endpoint2"
+      }
+    }`)
+
+	_, err = GenerateDependencyGraph(mod, "deps.txt", nil, false)
+	assert.EqualError(t, err, `extension must be dot or json, not "deps.txt"`)
+}
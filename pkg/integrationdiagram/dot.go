@@ -0,0 +1,224 @@
+package integrationdiagram
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/diagrams"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+)
+
+// DotExt is the extension of Graphviz DOT output. Integration diagrams written
+// to files with this extension are generated as DOT rather than PlantUML.
+const DotExt = ".dot"
+
+const DotHeader = `//////////////////////////////////////////
+//                                      //
+//  AUTOGENERATED CODE -- DO NOT EDIT!  //
+//                                      //
+//////////////////////////////////////////
+
+`
+
+//nolint:gochecknoglobals
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func IsDotOutput(output string) bool {
+	return strings.HasSuffix(output, DotExt)
+}
+
+// dotID quotes s as a DOT identifier.
+func dotID(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
+// dotGraph holds what is drawn of an integration graph. Apps are nodes, keyed
+// by their names so the output can be fed to other tools, and each pair of
+// apps has one edge labelled with the endpoints called. Without drawableApps
+// nothing is highlighted and no call is indirect.
+type dotGraph struct {
+	mod          *sysl.Module
+	title        string
+	apps         []string
+	drawableApps map[string]struct{}
+	integrations []AppDependency
+	clustered    bool
+	label        func(appName, name string) string
+
+	highLightColor     string
+	arrowColor         string
+	indirectArrowColor string
+}
+
+// GenerateDotView returns the integration diagram for an endpoint of the
+// project as a Graphviz digraph. The EPA and system views are drawn as the
+// plain view, with the endpoints as edge labels.
+func GenerateDotView(args *Args, params *IntsParam, mod *sysl.Module) string {
+	appAttrs := params.App.GetAttrs()
+	endptAttrs := params.Endpt.GetAttrs()
+
+	title := args.Title
+	if appAttrs["title"].GetS() != "" {
+		title = appAttrs["title"].GetS()
+	}
+	fp := diagrams.MakeFormatParser(mod.Apps[args.Project].GetAttrs()["appfmt"].GetS())
+
+	g := &dotGraph{
+		mod: mod,
+		title: diagrams.MakeFormatParser(title).Parse(map[string]string{
+			"epname":     params.Endpt.GetName(),
+			"eplongname": params.Endpt.GetLongName(),
+		}),
+		apps:         params.Apps,
+		drawableApps: params.DrawableApps,
+		integrations: params.Integrations,
+		clustered:    args.Clustered || endptAttrs["view"].GetS() == "clustered",
+		label: func(appName, name string) string {
+			attrs := diagrams.GetApplicationAttrs(mod, appName)
+			return fp.LabelApp(name, diagrams.GetSortedISOCtrlStr(attrs), attrs)
+		},
+		highLightColor:     appAttrs["highlight_color"].GetS(),
+		arrowColor:         appAttrs["arrow_color"].GetS(),
+		indirectArrowColor: appAttrs["indirect_arrow_color"].GetS(),
+	}
+	return g.String()
+}
+
+func (g *dotGraph) String() string {
+	var sb strings.Builder
+	sb.WriteString(DotHeader)
+	sb.WriteString("digraph {\n")
+	if g.title != "" {
+		fmt.Fprintf(&sb, "  label=%s;\n  labelloc=t;\n", dotID(g.title))
+	}
+	sb.WriteString("  node [shape=box, style=filled, fillcolor=FloralWhite, color=Black];\n")
+	arrowColor := g.arrowColor
+	if arrowColor == "" {
+		arrowColor = "Crimson"
+	}
+	fmt.Fprintf(&sb, "  edge [color=%s];\n", dotID(arrowColor))
+
+	clusters, rest := g.clusters()
+	for _, name := range sortedKeys(clusters) {
+		fmt.Fprintf(&sb, "  subgraph %s {\n    label=%s;\n", dotID("cluster_"+name), dotID(name))
+		for _, appName := range clusters[name] {
+			g.writeNode(&sb, "    ", appName, strings.TrimPrefix(appName, name+" :: "))
+		}
+		sb.WriteString("  }\n")
+	}
+	for _, appName := range rest {
+		g.writeNode(&sb, "  ", appName, appName)
+	}
+	g.writeEdges(&sb)
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// clusters groups the apps by namespace when clustering, as
+// buildClusterForIntsView does. Namespaces with a single app are not clustered.
+func (g *dotGraph) clusters() (map[string][]string, []string) {
+	apps := syslutil.MakeStrSet(g.apps...)
+	for _, dep := range g.integrations {
+		apps.Insert(dep.Self.Name)
+		apps.Insert(dep.Target.Name)
+	}
+	for _, app := range g.apps {
+		for _, mixin := range g.mod.GetApps()[app].GetMixin2() {
+			apps.Insert(strings.Join(mixin.Name.Part, " :: "))
+		}
+	}
+
+	clusters := map[string][]string{}
+	rest := []string{}
+	for _, appName := range apps.ToSortedSlice() {
+		if parts := strings.Split(appName, " :: "); g.clustered && len(parts) > 1 {
+			clusters[parts[0]] = append(clusters[parts[0]], appName)
+		} else {
+			rest = append(rest, appName)
+		}
+	}
+	for name, members := range clusters {
+		if len(members) <= 1 {
+			rest = append(rest, members...)
+			delete(clusters, name)
+		}
+	}
+	sort.Strings(rest)
+	return clusters, rest
+}
+
+// writeNode declares the node of an app, shown as name if it has no label.
+func (g *dotGraph) writeNode(sb *strings.Builder, indent, appName, name string) {
+	label := g.label(appName, name)
+	if label == "" {
+		label = name
+	}
+	attrs := []string{"label=" + dotID(label)}
+
+	patterns := syslutil.MakeStrSetFromAttr("patterns", diagrams.GetApplicationAttrs(g.mod, appName))
+	if patterns.Contains("db") {
+		attrs = append(attrs, "shape=cylinder")
+	}
+	style := "filled"
+	if patterns.Contains("external") {
+		style += ",dashed"
+	}
+	if _, ok := g.drawableApps[appName]; ok {
+		style += ",bold"
+		if g.highLightColor != "" {
+			attrs = append(attrs, "fillcolor="+dotID(g.highLightColor))
+		}
+	}
+	attrs = append(attrs, "style="+dotID(style))
+	fmt.Fprintf(sb, "%s%s [%s];\n", indent, dotID(appName), strings.Join(attrs, ", "))
+}
+
+func (g *dotGraph) writeEdges(sb *strings.Builder) {
+	pairs := map[AppPair]syslutil.StrSet{}
+	order := []AppPair{}
+	for _, dep := range g.integrations {
+		if dep.Self.Name == dep.Target.Name {
+			continue
+		}
+		pair := AppPair{Self: dep.Self.Name, Target: dep.Target.Name}
+		if _, has := pairs[pair]; !has {
+			pairs[pair] = syslutil.MakeStrSet()
+			order = append(order, pair)
+		}
+		pairs[pair].Insert(dep.Target.Endpoint)
+	}
+
+	for _, pair := range order {
+		attrs := []string{"label=" + dotID(strings.Join(pairs[pair].ToSortedSlice(), "\n"))}
+		_, directSelf := g.drawableApps[pair.Self]
+		_, directTarget := g.drawableApps[pair.Target]
+		if g.drawableApps != nil && !directSelf && !directTarget {
+			if g.indirectArrowColor == ArrowColorNone {
+				continue
+			}
+			attrs = append(attrs, "style=dashed")
+			if g.indirectArrowColor != "" {
+				attrs = append(attrs, "color="+dotID(g.indirectArrowColor))
+			}
+		}
+		fmt.Fprintf(sb, "  %s -> %s [%s];\n", dotID(pair.Self), dotID(pair.Target), strings.Join(attrs, ", "))
+	}
+
+	for _, app := range g.apps {
+		for _, mixin := range g.mod.GetApps()[app].GetMixin2() {
+			fmt.Fprintf(sb, "  %s -> %s [style=dashed, arrowhead=empty];\n",
+				dotID(app), dotID(strings.Join(mixin.Name.Part, " :: ")))
+		}
+	}
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,62 @@
+package integrationdiagram
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDotID(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `"A :: B"`, dotID("A :: B"))
+	assert.Equal(t, `"say \"hi\"\nC:\\"`, dotID("say \"hi\"\nC:\\"))
+}
+
+func TestGenerateDotViewWithIndirectArrow(t *testing.T) {
+	t.Parallel()
+
+	result, err := GenerateIntegrationsWithParams(testDir, "", "%(epname).dot", "Project", "", "indirect_1.sysl",
+		nil, false, false)
+	require.NoError(t, err)
+
+	expected := DotHeader + `digraph {
+  node [shape=box, style=filled, fillcolor=FloralWhite, color=Black];
+  edge [color="Crimson"];
+  "IntegratedSystem" [label="IntegratedSystem", style="filled,bold"];
+  "System_a" [label="System_a", style="filled"];
+  "System_b" [label="System_b", style="filled"];
+  "IntegratedSystem" -> "System_a" [label="endpoint"];
+  "IntegratedSystem" -> "System_b" [label="endpoint"];
+  "System_a" -> "IntegratedSystem" [label="integrated_endpoint_3"];
+  "System_a" -> "System_b" [label="endpoint", style=dashed, color="silver"];
+}
+`
+	assert.Equal(t, expected, result["indirect_arrow.dot"])
+}
+
+func TestGenerateDotViewClustered(t *testing.T) {
+	t.Parallel()
+
+	result, err := GenerateIntegrationsWithParams(testDir, "Ints %(epname)", "%(epname).dot", "Project", "",
+		"integration_with_cluster.sysl", nil, true, false)
+	require.NoError(t, err)
+
+	expected := DotHeader + `digraph {
+  label="Ints cluster";
+  labelloc=t;
+  node [shape=box, style=filled, fillcolor=FloralWhite, color=Black];
+  edge [color="Crimson"];
+  subgraph "cluster_System" {
+    label="System";
+    "System :: a" [label="a", style="filled,bold"];
+    "System :: b" [label="b", style="filled,bold"];
+  }
+  "IntegratedSystem" [label="IntegratedSystem", style="filled,bold"];
+  "IntegratedSystem" -> "System :: a" [label="endpoint"];
+  "IntegratedSystem" -> "System :: b" [label="endpoint"];
+}
+`
+	assert.Equal(t, expected, result["cluster.dot"])
+}
@@ -0,0 +1,86 @@
+package integrationdiagram
+
+import (
+	"regexp"
+
+	"github.com/anz-bank/sysl/pkg/diagrams"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const endpointWildcard = ".. * <- *"
+
+// Params configures the integration diagrams generated from a model, as the
+// flags of sysl ints do.
+type Params struct {
+	Title string
+	// Output is the output each diagram is keyed by, formatted for each
+	// endpoint of the project such as %(epname).svg.
+	Output string
+	// Project is the app whose endpoints list the apps to draw.
+	Project string
+	// Filter, if not empty, is a regular expression the outputs must match.
+	Filter string
+	// Exclude are the apps not drawn, by default the project.
+	Exclude   []string
+	Clustered bool
+	EPA       bool
+	Endpoints bool
+}
+
+// GenerateIntegrations returns an integration diagram for each endpoint of the
+// project, keyed by its output.
+func GenerateIntegrations(params *Params, model *sysl.Module, logger *logrus.Logger) (map[string]string, error) {
+	r := make(map[string]string)
+
+	logger.Debugf("project: %v\n", params.Project)
+	logger.Debugf("clustered: %t\n", params.Clustered)
+	logger.Debugf("exclude: %s\n", params.Exclude)
+	logger.Debugf("epa: %t\n", params.EPA)
+	logger.Debugf("endpoints: %t\n", params.Endpoints)
+	logger.Debugf("title: %s\n", params.Title)
+	logger.Debugf("filter: %s\n", params.Filter)
+	logger.Debugf("output: %s\n", params.Output)
+
+	exclude := params.Exclude
+	if len(exclude) == 0 && params.Project != "" {
+		exclude = []string{params.Project}
+	}
+	excludeStrSet := syslutil.MakeStrSet(exclude...)
+
+	var filter *regexp.Regexp
+	if params.Filter != "" {
+		var err error
+		if filter, err = regexp.Compile(params.Filter); err != nil {
+			return nil, errors.Wrap(err, "invalid filter")
+		}
+	}
+
+	// The "project" app that specifies the required view of the integration
+	app := model.GetApps()[params.Project]
+	of := diagrams.MakeFormatParser(params.Output)
+	// Iterate over each endpoint within the selected project
+	for epname, endpt := range app.GetEndpoints() {
+		outputDir := of.FmtOutput(params.Project, epname, endpt.GetLongName(), endpt.GetAttrs())
+		if filter != nil && !filter.MatchString(outputDir) {
+			continue
+		}
+		excludes := syslutil.MakeStrSetFromAttr("exclude", endpt.GetAttrs())
+		passthroughs := syslutil.MakeStrSetFromAttr("passthrough", endpt.GetAttrs())
+		b := makeBuilderfromStmt(model, endpt.GetStmt(), excludeStrSet.Union(excludes), passthroughs)
+		intsParam := &IntsParam{b.finalApps, b.seedAppsMap, b.depsOut, app, endpt}
+		args := &Args{params.Title, params.Project, params.Clustered, params.EPA, params.Endpoints}
+		switch {
+		case diagrams.IsMermaidOutput(outputDir):
+			r[outputDir] = GenerateMermaidView(args, intsParam, model)
+		case IsDotOutput(outputDir):
+			r[outputDir] = GenerateDotView(args, intsParam, model)
+		default:
+			r[outputDir] = GenerateView(args, intsParam, model)
+		}
+	}
+
+	return r, nil
+}
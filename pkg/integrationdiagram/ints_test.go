@@ -1,4 +1,4 @@
-package main
+package integrationdiagram
 
 import (
 	"io/ioutil"
@@ -7,15 +7,25 @@ import (
 
 	"github.com/sirupsen/logrus/hooks/test"
 
+	"github.com/anz-bank/sysl/pkg/diagrams"
 	"github.com/anz-bank/sysl/pkg/parse"
 	sysl "github.com/anz-bank/sysl/pkg/sysl"
 	"github.com/anz-bank/sysl/pkg/syslutil"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"gopkg.in/alecthomas/kingpin.v2"
 )
 
+const (
+	projDir = "../../"
+	testDir = "../../tests/"
+)
+
+// loadModule loads a model from root as sysl would with --root.
+func loadModule(root, filename string) (*sysl.Module, string, error) {
+	return parse.LoadAndGetDefaultApp(filename, syslutil.NewChrootFs(afero.NewOsFs(), root), parse.NewParser())
+}
+
 const plantumlHeader = `''''''''''''''''''''''''''''''''''''''''''
 ''                                      ''
 ''  AUTOGENERATED CODE -- DO NOT EDIT!  ''
@@ -40,7 +50,7 @@ func TestGenerateIntegrations(t *testing.T) {
 	require.NotNil(t, m)
 
 	stmt := &sysl.Statement{}
-	args := &Args{"", "Project", false, false}
+	args := &Args{"", "Project", false, false, false}
 	apps := []string{"System1", "IntegratedSystem", "System2"}
 	highlights := syslutil.MakeStrSet("IntegratedSystem", "System1", "System2")
 	s1 := AppElement{"IntegratedSystem", "integrated_endpoint_1"}
@@ -226,24 +236,6 @@ func TestGenerateIntegrationsWithPassthrough(t *testing.T) {
 	comparePUML(t, expected, result)
 }
 
-func TestDoGenerateIntegrations(t *testing.T) {
-	t.Parallel()
-
-	args := &intsArg{
-		modules: "indirect_1.sysl",
-		output:  "%(epname).png",
-		project: "Project",
-	}
-	argsData := []string{"sysl", "ints", "-o", args.output, "-j", args.project, args.modules}
-	sysl := kingpin.New("sysl", "System Modelling Language Toolkit")
-
-	r := cmdRunner{}
-	assert.NoError(t, r.Configure(sysl))
-	selectedCommand, err := sysl.Parse(argsData[1:])
-	assert.Nil(t, err, "Cmd line parse failed for sysl ints")
-	assert.Equal(t, selectedCommand, "integrations")
-}
-
 func TestGenerateIntegrationsWithCluster(t *testing.T) {
 	t.Parallel()
 
@@ -463,6 +455,51 @@ func TestGenerateIntegrationsWithPubSub(t *testing.T) {
 	comparePUML(t, expected, result)
 }
 
+func TestGenerateIntegrationsWithEndpoints(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	logger, _ := test.NewNullLogger()
+	mod, _, err := loadModule(testDir, "ints_endpoints.sysl")
+	require.NoError(t, err)
+
+	// When
+	result, err := GenerateIntegrations(&Params{
+		Output:    "%(epname).png",
+		Project:   "Project",
+		Endpoints: true,
+	}, mod, logger)
+	require.NoError(t, err)
+
+	expected := map[string]string{
+		"calls.png":   filepath.Join(testDir, "ints_endpoints-golden.puml"),
+		"audited.png": filepath.Join(testDir, "ints_endpoints_audited-golden.puml"),
+	}
+
+	// Then
+	comparePUML(t, expected, result)
+}
+
+func TestGenerateMermaidIntegrationsWithEndpoints(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	mod, _, err := loadModule(testDir, "ints_endpoints.sysl")
+	require.NoError(t, err)
+
+	result, err := GenerateIntegrations(&Params{
+		Output:    "%(epname).mmd",
+		Project:   "Project",
+		Endpoints: true,
+	}, mod, logger)
+	require.NoError(t, err)
+
+	mmd := result["calls.mmd"]
+	assert.Contains(t, mmd, "_0 -->|Place ×2<br/>Ship| _1\n")
+	assert.Contains(t, mmd, "_1 ==>|Shipped| _3\n")
+	assert.Contains(t, mmd, "_3 -.->|Charge| _2\n")
+}
+
 func TestAllStmts(t *testing.T) {
 	t.Parallel()
 
@@ -493,20 +530,53 @@ func GenerateIntegrationsWithParams(
 	exclude []string,
 	clustered, epa bool,
 ) (map[string]string, error) {
-	cmdContextParamIntgen := &CmdContextParamIntgen{
-		title:     title,
-		output:    output,
-		project:   project,
-		filter:    filter,
-		exclude:   exclude,
-		clustered: clustered,
-		epa:       epa,
+	params := &Params{
+		Title:     title,
+		Output:    output,
+		Project:   project,
+		Filter:    filter,
+		Exclude:   exclude,
+		Clustered: clustered,
+		EPA:       epa,
 	}
 
 	logger, _ := test.NewNullLogger()
-	mod, _, err := LoadSyslModule(rootModel, modules, afero.NewOsFs(), logger)
+	mod, _, err := loadModule(rootModel, modules)
 	if err != nil {
 		return nil, err
 	}
-	return GenerateIntegrations(cmdContextParamIntgen, mod, logger)
+	return GenerateIntegrations(params, mod, logger)
+}
+
+func TestGenerateMermaidIntegrations(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().Parse("demo/simple/sysl-ints.sysl",
+		syslutil.NewChrootFs(afero.NewOsFs(), projDir))
+	require.NoError(t, err)
+	require.NotNil(t, m)
+
+	args := &Args{"", "Project", false, false, false}
+	apps := []string{"System1", "IntegratedSystem", "System2"}
+	highlights := syslutil.MakeStrSet("IntegratedSystem", "System2")
+	deps := []AppDependency{
+		{
+			Self:      AppElement{"IntegratedSystem", "integrated_endpoint_1"},
+			Target:    AppElement{"System1", "endpoint"},
+			Statement: &sysl.Statement{},
+		},
+	}
+	endpt := &sysl.Endpoint{Name: "_"}
+	intsParam := &IntsParam{apps, highlights, deps, m.GetApps()["Project"], endpt}
+	r := GenerateMermaidView(args, intsParam, m)
+
+	expected := diagrams.MermaidHeader + `flowchart TD
+classDef default fill:FloralWhite,stroke:Black
+linkStyle default stroke:Crimson
+_0["IntegratedSystem"]:::highlight
+_1["System1"]
+_0 --> _1
+`
+
+	assert.Equal(t, expected, r)
 }
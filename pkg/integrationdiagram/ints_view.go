@@ -0,0 +1,765 @@
+package integrationdiagram
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/diagrams"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+)
+
+const ArrowColorNone = "none"
+const ComponentStart = `hide stereotype
+scale max 16384 height
+skinparam component {
+  BackgroundColor FloralWhite
+  BorderColor Black
+  ArrowColor Crimson
+`
+const StateStart = `left to right direction
+scale max 16384 height
+hide empty description
+skinparam state {
+  BackgroundColor FloralWhite
+  BorderColor Black
+  ArrowColor Crimson
+`
+
+// IntsParam is what is drawn of an endpoint of a project: the apps it
+// integrates, those of them it names, and the calls between them.
+type IntsParam struct {
+	Apps         []string
+	DrawableApps map[string]struct{}
+	Integrations []AppDependency
+	App          *sysl.Application
+	Endpt        *sysl.Endpoint
+}
+
+// Args are the options of an integration diagram.
+type Args struct {
+	Title     string
+	Project   string
+	Clustered bool
+	EPA       bool
+	Endpoints bool
+}
+
+type IntsDiagramVisitor struct {
+	mod           *sysl.Module
+	stringBuilder *strings.Builder
+	drawableApps  map[string]struct{}
+	symbols       map[string]*diagrams.Var
+	topSymbols    map[string]*_topVar
+	project       string
+	mermaid       bool
+}
+
+type _topVar struct {
+	topLabel string
+	topAlias string
+}
+
+type AppPair struct {
+	Self   string
+	Target string
+}
+
+type viewParams struct {
+	restrictBy         string
+	endptAttrs         map[string]*sysl.Attribute
+	highLightColor     string
+	arrowColor         string
+	indirectArrowColor string
+	diagramTitle       string
+}
+
+func MakeIntsDiagramVisitor(
+	mod *sysl.Module, stringBuilder *strings.Builder,
+	drawableApps map[string]struct{}, project string,
+) *IntsDiagramVisitor {
+	return &IntsDiagramVisitor{
+		mod:           mod,
+		stringBuilder: stringBuilder,
+		drawableApps:  drawableApps,
+		symbols:       map[string]*diagrams.Var{},
+		topSymbols:    map[string]*_topVar{},
+		project:       project,
+	}
+}
+
+func (v *IntsDiagramVisitor) VarManagerForComponent(appName string, nameMap map[string]string) string {
+	if key, ok := nameMap[appName]; ok {
+		appName = key
+	}
+	if s, ok := v.symbols[appName]; ok {
+		return s.Alias
+	}
+
+	i := len(v.symbols)
+	alias := fmt.Sprintf("_%d", i)
+	fp := diagrams.MakeFormatParser(v.mod.Apps[v.project].GetAttrs()["appfmt"].GetS())
+	attrs := diagrams.GetApplicationAttrs(v.mod, appName)
+	controls := diagrams.GetSortedISOCtrlStr(attrs)
+	label := fp.LabelApp(appName, controls, attrs)
+	s := &diagrams.Var{
+		Label: label,
+		Alias: alias,
+	}
+	v.symbols[appName] = s
+	_, highlight := v.drawableApps[appName]
+	switch {
+	case v.mermaid:
+		v.writeMermaidNode("", alias, label, highlight)
+	case highlight:
+		fmt.Fprintf(v.stringBuilder, "[%s] as %s <<highlight>>\n", label, alias)
+	default:
+		fmt.Fprintf(v.stringBuilder, "[%s] as %s\n", label, alias)
+	}
+	return s.Alias
+}
+
+func (v *IntsDiagramVisitor) VarManagerForTopState(appName string) string {
+	var alias, label string
+	if ts, ok := v.topSymbols[appName]; ok {
+		return ts.topAlias
+	}
+	i := len(v.topSymbols)
+	alias = fmt.Sprintf("_%d", i)
+
+	fp := diagrams.MakeFormatParser(v.mod.Apps[v.project].GetAttrs()["appfmt"].GetS())
+	attrs := diagrams.GetApplicationAttrs(v.mod, appName)
+	controls := diagrams.GetSortedISOCtrlStr(attrs)
+	label = fp.LabelApp(appName, controls, attrs)
+	ts := &_topVar{
+		topLabel: label,
+		topAlias: alias,
+	}
+	v.topSymbols[appName] = ts
+	if v.mermaid {
+		fmt.Fprintf(v.stringBuilder, "subgraph X%s[\"%s\"]\n", alias, diagrams.MermaidText(label))
+		return ts.topAlias
+	}
+	if _, ok := v.drawableApps[appName]; ok {
+		fmt.Fprintf(v.stringBuilder, "state \"%s\" as X%s <<highlight>> {\n", label, alias)
+	} else {
+		fmt.Fprintf(v.stringBuilder, "state \"%s\" as X%s {\n", label, alias)
+	}
+
+	return ts.topAlias
+}
+
+func (v *IntsDiagramVisitor) VarManagerForEPA(name string) string {
+	var appName, alias, label string
+	attrs := map[string]string{}
+
+	appName = strings.Split(name, " : ")[0]
+	epName := strings.Split(name, " : ")[1]
+
+	if s, ok := v.symbols[name]; ok {
+		return s.Alias
+	}
+	i := len(v.symbols)
+	alias = fmt.Sprintf("_%d", i)
+
+	if v.mod.Apps[appName].Endpoints[epName] != nil {
+		for k, v := range v.mod.Apps[appName].Endpoints[epName].Attrs {
+			attrs["@"+k] = v.GetS()
+		}
+	}
+	attrs["appname"] = epName
+	fp := diagrams.MakeFormatParser(v.mod.Apps[v.project].GetAttrs()["appfmt"].GetS())
+	label = fp.Parse(attrs)
+
+	s := &diagrams.Var{
+		Label: label,
+		Alias: alias,
+	}
+	v.symbols[name] = s
+
+	_, highlight := v.drawableApps[appName]
+	switch {
+	case v.mermaid:
+		v.writeMermaidNode("  ", alias, label, highlight)
+	case highlight:
+		fmt.Fprintf(v.stringBuilder, "  state \"%s\" as %s <<highlight>>\n", label, alias)
+	default:
+		fmt.Fprintf(v.stringBuilder, "  state \"%s\" as %s\n", label, alias)
+	}
+	return s.Alias
+}
+
+func (v *IntsDiagramVisitor) buildClusterForEPAView(deps []AppDependency, restrictBy string) {
+	clusters := map[string][]string{}
+	for _, dep := range deps {
+		appA := dep.Self.Name
+		appB := dep.Target.Name
+		epA := dep.Self.Endpoint
+		epB := dep.Target.Endpoint
+		_, okA := v.mod.Apps[appA].Endpoints[epA].Attrs[restrictBy]
+		_, okB := v.mod.Apps[appB].Endpoints[epB].Attrs[restrictBy]
+		if _, ok := v.mod.Apps[appA].Attrs[restrictBy]; !ok && restrictBy != "" {
+			if _, ok := v.mod.Apps[appB].Attrs[restrictBy]; !ok {
+				continue
+			}
+		}
+		if !okA && restrictBy != "" && !okB {
+			continue
+		}
+		clusters[appA] = append(clusters[appA], epA)
+		if appA != appB && !v.mod.Apps[appA].Endpoints[epA].IsPubsub {
+			clusters[appA] = append(clusters[appA], epB+" client")
+		}
+		clusters[appB] = append(clusters[appB], epB)
+	}
+
+	keys := []string{}
+	for k := range clusters {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v.VarManagerForTopState(k)
+		strSet := syslutil.MakeStrSet(clusters[k]...)
+		for _, m := range strSet.ToSortedSlice() {
+			v.VarManagerForEPA(k + " : " + m)
+		}
+		v.closeCluster()
+	}
+}
+
+func (v *IntsDiagramVisitor) buildClusterForIntsView(apps []string) map[string]string {
+	nameMap := map[string]string{}
+	clusters := map[string][]string{}
+	for _, v := range apps {
+		cluster := strings.Split(v, " :: ")
+		if len(cluster) > 1 {
+			clusters[cluster[0]] = append(clusters[cluster[0]], v)
+		}
+	}
+
+	for k, v := range clusters {
+		if len(v) <= 1 {
+			delete(clusters, k)
+		}
+		for _, s := range v {
+			nameMap[s] = strings.Split(s, " :: ")[1]
+		}
+	}
+
+	for k, apps := range clusters {
+		if v.mermaid {
+			fmt.Fprintf(v.stringBuilder, "subgraph P%d[\"%s\"]\n", len(v.symbols), diagrams.MermaidText(k))
+		} else {
+			fmt.Fprintf(v.stringBuilder, "package \"%s\" {\n", k)
+		}
+		for _, n := range apps {
+			v.VarManagerForComponent(n, nameMap)
+		}
+		v.closeCluster()
+	}
+
+	return nameMap
+}
+
+func (v *IntsDiagramVisitor) generateEPAView(viewParams viewParams, params *IntsParam) string {
+	if v.mermaid {
+		v.writeMermaidStart(viewParams, "LR")
+	} else {
+		v.stringBuilder.WriteString("@startuml\n")
+		if viewParams.diagramTitle != "" {
+			fmt.Fprintf(v.stringBuilder, "title %s\n", viewParams.diagramTitle)
+		}
+		v.stringBuilder.WriteString(StateStart)
+		v.writeSkinparamColors(viewParams)
+	}
+	v.buildClusterForEPAView(params.Integrations, viewParams.restrictBy)
+	var processed []string
+	for _, dep := range params.Integrations {
+		appA := dep.Self.Name
+		appB := dep.Target.Name
+		epA := dep.Self.Endpoint
+		epB := dep.Target.Endpoint
+		if !v.isRestrictedTo(dep, viewParams.restrictBy) {
+			continue
+		}
+		matchApp := appB
+		matchEp := epB
+		label := ""
+		needsInt := appA != matchApp
+
+		pubSubSrcPtrns := syslutil.MakeStrSetFromAttr("patterns", v.mod.Apps[appA].Endpoints[epA].Attrs)
+		attrs := map[string]string{}
+		for k, v := range dep.Statement.GetAttrs() {
+			attrs["@"+k] = v.GetS()
+		}
+		var ptrns string
+		var targetPatterns syslutil.StrSet
+		var srcPtrns syslutil.StrSet
+		if v.mod.Apps[matchApp].Endpoints[matchEp].Attrs["patterns"] != nil {
+			targetPatterns = syslutil.MakeStrSetFromAttr("patterns", v.mod.Apps[matchApp].Endpoints[matchEp].Attrs)
+		} else {
+			targetPatterns = syslutil.MakeStrSetFromAttr("patterns", v.mod.Apps[matchApp].Attrs)
+		}
+		if dep.Statement.GetAttrs()["patterns"] != nil {
+			srcPtrns = syslutil.MakeStrSetFromAttr("patterns", dep.Statement.GetAttrs())
+		} else {
+			srcPtrns = pubSubSrcPtrns
+		}
+		ptrns = strings.Join(srcPtrns.ToSlice(), ", ") + " → " + strings.Join(targetPatterns.ToSlice(), ", ")
+		attrs["patterns"] = ptrns
+		if needsInt {
+			attrs["needs_int"] = strconv.FormatBool(needsInt)
+		}
+		fp := diagrams.MakeFormatParser(params.App.Attrs["epfmt"].GetS())
+		label = fp.Parse(attrs)
+		flow := strings.Join([]string{appA, epB, appB, epB}, ".")
+		isPubSub := v.mod.Apps[appA].Endpoints[epA].GetIsPubsub()
+		epBClient := epB + " client"
+		if v.mermaid {
+			if v.writeMermaidEPACall(dep, isPubSub, label, !stringInSlice(flow, processed)) {
+				processed = append(processed, flow)
+			}
+			continue
+		}
+		if appA != appB {
+			if label != "" {
+				label = " : " + label
+			}
+			if isPubSub {
+				fmt.Fprintf(
+					v.stringBuilder,
+					"%s -%s> %s%s\n", v.VarManagerForEPA(appA+" : "+epA),
+					"[#blue]",
+					v.VarManagerForEPA(appB+" : "+epB),
+					label,
+				)
+			} else {
+				color := ""
+				if viewParams.indirectArrowColor == "" {
+					color = "[#silver]-"
+				} else {
+					color = "[#" + viewParams.indirectArrowColor + "]-"
+				}
+				fmt.Fprintf(
+					v.stringBuilder,
+					"%s -%s> %s\n",
+					v.VarManagerForEPA(appA+" : "+epA),
+					color,
+					v.VarManagerForEPA(appA+" : "+epBClient),
+				)
+				if !stringInSlice(flow, processed) {
+					fmt.Fprintf(
+						v.stringBuilder,
+						"%s -%s> %s%s\n",
+						v.VarManagerForEPA(appA+" : "+epBClient),
+						"[#black]",
+						v.VarManagerForEPA(appB+" : "+epB),
+						label,
+					)
+					processed = append(processed, flow)
+				}
+			}
+		} else {
+			color := ""
+			if viewParams.indirectArrowColor == "" {
+				color = "[#silver]-"
+			} else {
+				color = "[#" + viewParams.indirectArrowColor + "]-"
+			}
+			fmt.Fprintf(
+				v.stringBuilder,
+				"%s -%s> %s%s\n",
+				v.VarManagerForEPA(appA+" : "+epA),
+				color,
+				v.VarManagerForEPA(appB+" : "+epB),
+				label,
+			)
+		}
+	}
+	if !v.mermaid {
+		v.stringBuilder.WriteString("@enduml")
+	}
+	return v.stringBuilder.String()
+}
+
+func (v *IntsDiagramVisitor) drawIntsView(
+	args *Args, viewParams viewParams, params *IntsParam, nameMap map[string]string,
+) {
+	callsDrawn := map[AppPair]struct{}{}
+	switch {
+	case viewParams.endptAttrs["view"].GetS() == "system":
+		v.drawSystemView(viewParams, params, nameMap)
+	case args.Endpoints || viewParams.endptAttrs["view"].GetS() == "endpoints":
+		v.drawEndpointsView(viewParams, params, nameMap)
+	default:
+		for _, dep := range params.Integrations {
+			appA := dep.Self.Name
+			appB := dep.Target.Name
+			if appA == appB {
+				continue
+			}
+			appPair := AppPair{
+				Self:   appA,
+				Target: appB,
+			}
+			var direct []string
+			if _, ok := params.DrawableApps[appA]; ok {
+				direct = append(direct, appA)
+			}
+			if _, ok := params.DrawableApps[appB]; ok {
+				direct = append(direct, appB)
+			}
+			if _, ok := callsDrawn[appPair]; !ok {
+				if len(direct) > 0 || direct != nil || viewParams.indirectArrowColor != ArrowColorNone {
+					v.writeComponentCall(appA, appB, len(direct) == 0, nameMap)
+					callsDrawn[appPair] = struct{}{}
+				}
+			}
+		}
+		for _, app := range params.Apps {
+			for _, mixin := range v.mod.Apps[app].GetMixin2() {
+				mixinName := strings.Join(mixin.Name.Part, " :: ")
+				if v.mermaid {
+					v.writeMermaidEdge(
+						v.VarManagerForComponent(app, nameMap), v.VarManagerForComponent(mixinName, nameMap), true, "mixin",
+					)
+					continue
+				}
+				fmt.Fprintf(
+					v.stringBuilder,
+					"%s <|.. %s\n",
+					v.VarManagerForComponent(mixinName, nameMap),
+					v.VarManagerForComponent(app, nameMap),
+				)
+			}
+		}
+	}
+}
+
+func (v *IntsDiagramVisitor) drawSystemView(viewParams viewParams, params *IntsParam, nameMap map[string]string) {
+	callsDrawn := map[AppPair]struct{}{}
+	for _, dep := range params.Integrations {
+		appA := dep.Self.Name
+		appB := dep.Target.Name
+		if appA == appB {
+			continue
+		}
+		appPair := AppPair{
+			Self:   appA,
+			Target: appB,
+		}
+		var direct []string
+		if _, ok := params.DrawableApps[appA]; ok {
+			direct = append(direct, appA)
+		}
+		if _, ok := params.DrawableApps[appB]; ok {
+			direct = append(direct, appB)
+		}
+		appA = strings.Split(appA, " :: ")[0]
+		appB = strings.Split(appB, " :: ")[0]
+		if _, ok := callsDrawn[appPair]; !ok {
+			if len(direct) > 0 || direct != nil || viewParams.indirectArrowColor != ArrowColorNone {
+				v.writeComponentCall(appA, appB, len(direct) == 0, nameMap)
+				callsDrawn[appPair] = struct{}{}
+			}
+		}
+	}
+}
+
+// endpointEdge is an edge of the endpoints view: the calls one app makes to
+// the endpoints of another, or the events it publishes to a subscriber.
+type endpointEdge struct {
+	AppPair
+	pubsub   bool
+	indirect bool
+	calls    map[string]int
+}
+
+// label lists the endpoints called along the edge, with the number of calls
+// to those called more than once.
+func (e *endpointEdge) label() string {
+	eps := make([]string, 0, len(e.calls))
+	for ep := range e.calls {
+		eps = append(eps, ep)
+	}
+	sort.Strings(eps)
+	for i, ep := range eps {
+		if n := e.calls[ep]; n > 1 {
+			eps[i] = fmt.Sprintf("%s ×%d", ep, n)
+		}
+	}
+	return strings.Join(eps, "\n")
+}
+
+// endpointEdges aggregates the integrations of params into one edge for each
+// pair of apps, keeping pubsub edges apart from synchronous calls. Pubsub
+// edges are labelled with the events published rather than the subscribing
+// endpoints.
+func (v *IntsDiagramVisitor) endpointEdges(params *IntsParam, restrictBy string) []*endpointEdge {
+	type edgeKey struct {
+		AppPair
+		pubsub bool
+	}
+	edges := map[edgeKey]*endpointEdge{}
+	order := []*endpointEdge{}
+	for _, dep := range params.Integrations {
+		if dep.Self.Name == dep.Target.Name || !v.isRestrictedTo(dep, restrictBy) {
+			continue
+		}
+		key := edgeKey{
+			AppPair: AppPair{Self: dep.Self.Name, Target: dep.Target.Name},
+			pubsub:  v.mod.Apps[dep.Self.Name].Endpoints[dep.Self.Endpoint].GetIsPubsub(),
+		}
+		e, ok := edges[key]
+		if !ok {
+			_, directSelf := params.DrawableApps[key.Self]
+			_, directTarget := params.DrawableApps[key.Target]
+			e = &endpointEdge{
+				AppPair:  key.AppPair,
+				pubsub:   key.pubsub,
+				indirect: !directSelf && !directTarget,
+				calls:    map[string]int{},
+			}
+			edges[key] = e
+			order = append(order, e)
+		}
+		if e.pubsub {
+			e.calls[dep.Self.Endpoint]++
+		} else {
+			e.calls[dep.Target.Endpoint]++
+		}
+	}
+	return order
+}
+
+// isRestrictedTo reports whether dep is drawn in a view restricted to apps and
+// endpoints with the restrictBy attribute. Every dependency is drawn when
+// restrictBy is empty.
+func (v *IntsDiagramVisitor) isRestrictedTo(dep AppDependency, restrictBy string) bool {
+	if restrictBy == "" {
+		return true
+	}
+	appA, appB := v.mod.Apps[dep.Self.Name], v.mod.Apps[dep.Target.Name]
+	_, restrictByAppA := appA.GetAttrs()[restrictBy]
+	_, restrictByAppB := appB.GetAttrs()[restrictBy]
+	_, restrictByEpA := appA.GetEndpoints()[dep.Self.Endpoint].GetAttrs()[restrictBy]
+	_, restrictByEpB := appB.GetEndpoints()[dep.Target.Endpoint].GetAttrs()[restrictBy]
+	return (restrictByAppA || restrictByAppB) && (restrictByEpA || restrictByEpB)
+}
+
+// drawEndpointsView draws the apps of the integration view, with each edge
+// labelled with the endpoints called.
+func (v *IntsDiagramVisitor) drawEndpointsView(viewParams viewParams, params *IntsParam, nameMap map[string]string) {
+	for _, e := range v.endpointEdges(params, viewParams.restrictBy) {
+		if e.indirect && viewParams.indirectArrowColor == ArrowColorNone {
+			continue
+		}
+		from := v.VarManagerForComponent(e.Self, nameMap)
+		to := v.VarManagerForComponent(e.Target, nameMap)
+		if v.mermaid {
+			if e.pubsub {
+				fmt.Fprintf(v.stringBuilder, "%s ==>|%s| %s\n", from, diagrams.MermaidEdgeText(e.label()), to)
+			} else {
+				v.writeMermaidEdge(from, to, e.indirect, e.label())
+			}
+			continue
+		}
+		label := strings.ReplaceAll(e.label(), "\n", `\n`)
+		switch {
+		case e.pubsub:
+			fmt.Fprintf(v.stringBuilder, "%s .[#blue].> %s : %s\n", from, to, label)
+		case e.indirect:
+			fmt.Fprintf(v.stringBuilder, "%s --> %s <<indirect>> : %s\n", from, to, label)
+		default:
+			fmt.Fprintf(v.stringBuilder, "%s --> %s : %s\n", from, to, label)
+		}
+	}
+}
+
+func (v *IntsDiagramVisitor) generateIntsView(args *Args, viewParams viewParams, params *IntsParam) string {
+	if v.mermaid {
+		v.writeMermaidStart(viewParams, "TD")
+	} else {
+		v.stringBuilder.WriteString("@startuml\n")
+		if viewParams.diagramTitle != "" {
+			fmt.Fprintf(v.stringBuilder, "title %s\n", viewParams.diagramTitle)
+		}
+		v.stringBuilder.WriteString(ComponentStart)
+		v.writeSkinparamColors(viewParams)
+	}
+	nameMap := map[string]string{}
+	if args.Clustered || viewParams.endptAttrs["view"].GetS() == "clustered" {
+		nameMap = v.buildClusterForIntsView(params.Apps)
+	}
+	v.drawIntsView(args, viewParams, params, nameMap)
+	if !v.mermaid {
+		v.stringBuilder.WriteString("@enduml")
+	}
+	return v.stringBuilder.String()
+}
+
+// writeSkinparamColors completes the skinparam block opened by ComponentStart
+// or StateStart with the colours set on the project.
+func (v *IntsDiagramVisitor) writeSkinparamColors(viewParams viewParams) {
+	if viewParams.highLightColor != "" {
+		fmt.Fprintf(v.stringBuilder, "  BackgroundColor<<highlight>> %s\n", viewParams.highLightColor)
+	}
+	if viewParams.arrowColor != "" {
+		fmt.Fprintf(v.stringBuilder, "  ArrowColor %s\n", viewParams.arrowColor)
+	}
+
+	if viewParams.indirectArrowColor != "" && viewParams.indirectArrowColor != ArrowColorNone {
+		fmt.Fprintf(v.stringBuilder, "  ArrowColor<<indirect>> %s\n", viewParams.indirectArrowColor)
+	}
+	v.stringBuilder.WriteString("}\n")
+}
+
+// writeMermaidStart opens a Mermaid flowchart in the given direction. Mermaid
+// styles edges by index rather than by stereotype, so indirect calls are drawn
+// dashed instead of in indirectArrowColor.
+func (v *IntsDiagramVisitor) writeMermaidStart(viewParams viewParams, direction string) {
+	diagrams.WriteMermaidPreamble(v.stringBuilder, viewParams.diagramTitle)
+	fmt.Fprintf(v.stringBuilder, "flowchart %s\n", direction)
+	v.stringBuilder.WriteString("classDef default fill:FloralWhite,stroke:Black\n")
+	if viewParams.highLightColor != "" {
+		fmt.Fprintf(v.stringBuilder, "classDef highlight fill:%s\n", viewParams.highLightColor)
+	}
+	arrowColor := viewParams.arrowColor
+	if arrowColor == "" {
+		arrowColor = "Crimson"
+	}
+	fmt.Fprintf(v.stringBuilder, "linkStyle default stroke:%s\n", arrowColor)
+}
+
+func (v *IntsDiagramVisitor) writeMermaidNode(indent, alias, label string, highlight bool) {
+	class := ""
+	if highlight {
+		class = ":::highlight"
+	}
+	fmt.Fprintf(v.stringBuilder, "%s%s[\"%s\"]%s\n", indent, alias, diagrams.MermaidText(label), class)
+}
+
+func (v *IntsDiagramVisitor) writeMermaidEdge(from, to string, dashed bool, label string) {
+	arrow := "-->"
+	if dashed {
+		arrow = "-.->"
+	}
+	if label != "" {
+		arrow += "|" + diagrams.MermaidEdgeText(label) + "|"
+	}
+	fmt.Fprintf(v.stringBuilder, "%s %s %s\n", from, arrow, to)
+}
+
+// writeMermaidEPACall draws the call of dep, through the client of the target
+// endpoint unless it is a pubsub or internal call. It returns whether the
+// client's call of the target was drawn.
+func (v *IntsDiagramVisitor) writeMermaidEPACall(dep AppDependency, isPubSub bool, label string, drawTarget bool) bool {
+	appA, epA := dep.Self.Name, dep.Self.Endpoint
+	appB, epB := dep.Target.Name, dep.Target.Endpoint
+	from := v.VarManagerForEPA(appA + " : " + epA)
+	switch {
+	case appA == appB:
+		v.writeMermaidEdge(from, v.VarManagerForEPA(appB+" : "+epB), true, label)
+	case isPubSub:
+		v.writeMermaidEdge(from, v.VarManagerForEPA(appB+" : "+epB), false, label)
+	default:
+		client := v.VarManagerForEPA(appA + " : " + epB + " client")
+		v.writeMermaidEdge(from, client, true, "")
+		if drawTarget {
+			v.writeMermaidEdge(client, v.VarManagerForEPA(appB+" : "+epB), false, label)
+			return true
+		}
+	}
+	return false
+}
+
+func (v *IntsDiagramVisitor) writeComponentCall(appA, appB string, indirect bool, nameMap map[string]string) {
+	from := v.VarManagerForComponent(appA, nameMap)
+	to := v.VarManagerForComponent(appB, nameMap)
+	switch {
+	case v.mermaid:
+		v.writeMermaidEdge(from, to, indirect, "")
+	case indirect:
+		fmt.Fprintf(v.stringBuilder, "%s --> %s <<indirect>>\n", from, to)
+	default:
+		fmt.Fprintf(v.stringBuilder, "%s --> %s\n", from, to)
+	}
+}
+
+func (v *IntsDiagramVisitor) closeCluster() {
+	if v.mermaid {
+		v.stringBuilder.WriteString("end\n")
+	} else {
+		v.stringBuilder.WriteString("}\n")
+	}
+}
+
+// GenerateView returns the PlantUML integration diagram for an endpoint of the
+// project.
+func GenerateView(args *Args, params *IntsParam, mod *sysl.Module) string {
+	return generateView(args, params, mod, false)
+}
+
+// GenerateMermaidView returns the integration diagram as a Mermaid flowchart.
+func GenerateMermaidView(args *Args, params *IntsParam, mod *sysl.Module) string {
+	return generateView(args, params, mod, true)
+}
+
+func generateView(args *Args, params *IntsParam, mod *sysl.Module, mermaid bool) string {
+	var stringBuilder strings.Builder
+	var titleParser *diagrams.FormatParser
+	v := MakeIntsDiagramVisitor(mod, &stringBuilder, params.DrawableApps, args.Project)
+	v.mermaid = mermaid
+	restrictBy := ""
+	if params.Endpt.Attrs["restrict_by"] != nil {
+		restrictBy = params.Endpt.Attrs["restrict_by"].GetS()
+	}
+
+	appAttrs := params.App.Attrs
+	endptAttrs := params.Endpt.Attrs
+	highLightColor := appAttrs["highlight_color"].GetS()
+	arrowColor := appAttrs["arrow_color"].GetS()
+	indirectArrowColor := appAttrs["indirect_arrow_color"].GetS()
+
+	attrs := map[string]string{
+		"epname":     params.Endpt.Name,
+		"eplongname": params.Endpt.LongName,
+	}
+	title := args.Title
+	if appAttrs["title"].GetS() != "" {
+		title = appAttrs["title"].GetS()
+	}
+	titleParser = diagrams.MakeFormatParser(title)
+	diagramTitle := titleParser.Parse(attrs)
+
+	viewParams := &viewParams{
+		restrictBy:         restrictBy,
+		endptAttrs:         endptAttrs,
+		highLightColor:     highLightColor,
+		arrowColor:         arrowColor,
+		indirectArrowColor: indirectArrowColor,
+		diagramTitle:       diagramTitle,
+	}
+	if !mermaid {
+		v.stringBuilder.WriteString(diagrams.PumlHeader)
+	}
+
+	if args.EPA || endptAttrs["view"].GetS() == "epa" {
+		return v.generateEPAView(*viewParams, params)
+	}
+	return v.generateIntsView(args, *viewParams, params)
+}
+
+func stringInSlice(a string, list []string) bool {
+	for _, b := range list {
+		if b == a {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,10 @@
-package main
+package integrationdiagram
 
 import (
 	"strings"
 	"testing"
 
+	"github.com/anz-bank/sysl/pkg/diagrams"
 	sysl "github.com/anz-bank/sysl/pkg/sysl"
 	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
@@ -18,7 +19,7 @@ func TestVarManagerForComponent(t *testing.T) {
 		stringBuilder: &stringBuilder,
 		mod:           &sysl.Module{},
 		drawableApps:  map[string]struct{}{},
-		symbols:       map[string]*_var{},
+		symbols:       map[string]*diagrams.Var{},
 	}
 
 	//When
@@ -37,9 +38,9 @@ func TestVarManagerForComponentWithNameMap(t *testing.T) {
 		stringBuilder: &stringBuilder,
 		mod:           &sysl.Module{},
 		drawableApps:  map[string]struct{}{},
-		symbols: map[string]*_var{
+		symbols: map[string]*diagrams.Var{
 			"appName": {
-				alias: "_1",
+				Alias: "_1",
 			},
 		},
 	}
@@ -62,9 +63,9 @@ func TestVarManagerForComponentWithExistingName(t *testing.T) {
 		stringBuilder: &stringBuilder,
 		mod:           &sysl.Module{},
 		drawableApps:  map[string]struct{}{},
-		symbols: map[string]*_var{
+		symbols: map[string]*diagrams.Var{
 			"test": {
-				alias: "_1",
+				Alias: "_1",
 			},
 		},
 	}
@@ -93,7 +94,7 @@ func TestVarManagerForEPA(t *testing.T) {
 			},
 		},
 		drawableApps: map[string]struct{}{},
-		symbols:      map[string]*_var{},
+		symbols:      map[string]*diagrams.Var{},
 	}
 
 	//When
@@ -124,9 +125,9 @@ func TestVarManagerForEPAWithExistingName(t *testing.T) {
 			},
 		},
 		drawableApps: map[string]struct{}{},
-		symbols: map[string]*_var{
+		symbols: map[string]*diagrams.Var{
 			"a : b": {
-				alias: "_1",
+				Alias: "_1",
 			},
 		},
 	}
@@ -211,7 +212,7 @@ func TestBuildClusterForIntsView(t *testing.T) {
 		},
 		drawableApps: map[string]struct{}{},
 		topSymbols:   map[string]*_topVar{},
-		symbols:      map[string]*_var{},
+		symbols:      map[string]*diagrams.Var{},
 	}
 	deps := []AppDependency{
 		{
@@ -250,7 +251,7 @@ func TestBuildClusterForComponentView(t *testing.T) {
 		mod:           &sysl.Module{},
 		drawableApps:  map[string]struct{}{},
 		topSymbols:    map[string]*_topVar{},
-		symbols:       map[string]*_var{},
+		symbols:       map[string]*diagrams.Var{},
 	}
 	apps := []string{"a :: A", "a :: A", "b :: B", "c :: C"}
 
@@ -308,20 +309,20 @@ func TestGenerateIntsView(t *testing.T) {
 		project:      "project",
 		drawableApps: map[string]struct{}{},
 		topSymbols:   map[string]*_topVar{},
-		symbols:      map[string]*_var{},
+		symbols:      map[string]*diagrams.Var{},
 	}
 
 	v.generateIntsView(
 		&Args{},
 		viewParams{},
 		&IntsParam{
-			integrations: []AppDependency{
+			Integrations: []AppDependency{
 				{
 					Self:   AppElement{Name: "a", Endpoint: "epa"},
 					Target: AppElement{Name: "b", Endpoint: "epb"},
 				},
 			},
-			apps: []string{"a", "b"},
+			Apps: []string{"a", "b"},
 		},
 	)
 
@@ -383,7 +384,7 @@ func TestGenerateEPAView(t *testing.T) {
 		project:       "test",
 		drawableApps:  map[string]struct{}{},
 		topSymbols:    map[string]*_topVar{},
-		symbols:       map[string]*_var{},
+		symbols:       map[string]*diagrams.Var{},
 	}
 
 	//When
@@ -395,7 +396,7 @@ func TestGenerateEPAView(t *testing.T) {
 			indirectArrowColor: "grey",
 		},
 		&IntsParam{
-			integrations: []AppDependency{
+			Integrations: []AppDependency{
 				{
 					Self:   AppElement{Name: "a", Endpoint: "epa"},
 					Target: AppElement{Name: "b", Endpoint: "epb"},
@@ -404,7 +405,7 @@ func TestGenerateEPAView(t *testing.T) {
 					},
 				},
 			},
-			app: &sysl.Application{},
+			App: &sysl.Application{},
 		},
 	)
 
@@ -451,7 +452,7 @@ func TestGenerateEPAViewEndpointPattern(t *testing.T) {
 		project:       "test",
 		drawableApps:  map[string]struct{}{},
 		topSymbols:    map[string]*_topVar{},
-		symbols:       map[string]*_var{},
+		symbols:       map[string]*diagrams.Var{},
 	}
 
 	//When
@@ -463,7 +464,7 @@ func TestGenerateEPAViewEndpointPattern(t *testing.T) {
 			indirectArrowColor: "grey",
 		},
 		&IntsParam{
-			integrations: []AppDependency{
+			Integrations: []AppDependency{
 				{
 					Self:   AppElement{Name: "a", Endpoint: "epa"},
 					Target: AppElement{Name: "b", Endpoint: "epb"},
@@ -475,7 +476,7 @@ func TestGenerateEPAViewEndpointPattern(t *testing.T) {
 					},
 				},
 			},
-			app: &sysl.Application{},
+			App: &sysl.Application{},
 		},
 	)
 
@@ -518,7 +519,7 @@ func TestGenerateEPAViewSameApp(t *testing.T) {
 		project:       "test",
 		drawableApps:  map[string]struct{}{},
 		topSymbols:    map[string]*_topVar{},
-		symbols:       map[string]*_var{},
+		symbols:       map[string]*diagrams.Var{},
 	}
 
 	//When
@@ -530,7 +531,7 @@ func TestGenerateEPAViewSameApp(t *testing.T) {
 			indirectArrowColor: "grey",
 		},
 		&IntsParam{
-			integrations: []AppDependency{
+			Integrations: []AppDependency{
 				{
 					Self:   AppElement{Name: "b", Endpoint: "epa"},
 					Target: AppElement{Name: "b", Endpoint: "epb"},
@@ -547,7 +548,7 @@ func TestGenerateEPAViewSameApp(t *testing.T) {
 					},
 				},
 			},
-			app: &sysl.Application{},
+			App: &sysl.Application{},
 		},
 	)
 
@@ -590,9 +591,9 @@ func TestGenerateView(t *testing.T) {
 		},
 	}
 	params := &IntsParam{
-		integrations: deps,
-		app:          &sysl.Application{},
-		endpt: &sysl.Endpoint{
+		Integrations: deps,
+		App:          &sysl.Application{},
+		Endpt: &sysl.Endpoint{
 			Attrs: map[string]*sysl.Attribute{
 				"epa": nil,
 			},
@@ -655,9 +656,9 @@ func TestDrawSystemView(t *testing.T) {
 		stringBuilder: &stringBuilder,
 		mod:           &sysl.Module{},
 		drawableApps:  map[string]struct{}{},
-		symbols: map[string]*_var{
+		symbols: map[string]*diagrams.Var{
 			"test": {
-				alias: "_1",
+				Alias: "_1",
 			},
 		},
 	}
@@ -674,9 +675,9 @@ func TestDrawSystemView(t *testing.T) {
 		},
 	}
 	params := &IntsParam{
-		integrations: deps,
-		app:          &sysl.Application{},
-		endpt: &sysl.Endpoint{
+		Integrations: deps,
+		App:          &sysl.Application{},
+		Endpt: &sysl.Endpoint{
 			Attrs: map[string]*sysl.Attribute{
 				"epa": nil,
 			},
@@ -704,16 +705,16 @@ func TestMakeIntsParam(t *testing.T) {
 		&sysl.Application{}, &sysl.Endpoint{}}
 
 	assert.NotNil(t, p)
-	assert.Equal(t, "a", p.apps[0])
+	assert.Equal(t, "a", p.Apps[0])
 }
 
 func TestMakeArgs(t *testing.T) {
 	t.Parallel()
 
-	a := &Args{"a", "p", true, true}
+	a := &Args{"a", "p", true, true, false}
 
 	assert.NotNil(t, a)
-	assert.Equal(t, "a", a.title)
+	assert.Equal(t, "a", a.Title)
 }
 
 func TestStringInSlice(t *testing.T) {
@@ -0,0 +1,271 @@
+// Package loader loads sysl models the way the sysl command does, for programs
+// using sysl as a library.
+package loader
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/anz-bank/sysl/pkg/mod"
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/anz-bank/sysl/pkg/pbutil"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// RootMarker marks the root of a project, which absolute imports are relative
+// to.
+const RootMarker = ".sysl"
+
+// ModuleMode is how imports of files in other repositories are resolved.
+type ModuleMode int
+
+const (
+	// ModulesFromEnv resolves modules unless SYSL_MODULES is off.
+	ModulesFromEnv ModuleMode = iota
+	// ModulesOn resolves modules with the sysl.mod of the root, or with the go
+	// command when there is none.
+	ModulesOn
+	// ModulesOff only imports files under the root.
+	ModulesOff
+)
+
+// Options configure Load. The zero value loads models like the sysl command.
+type Options struct {
+	// Root is the directory absolute imports are relative to. When empty, it
+	// is the nearest directory above the module with a RootMarker, or else the
+	// directory of the module, which only allows relative imports.
+	Root string
+	// Logger logs how the project is configured and the import cycles and
+	// duplicate imports found, by default to the standard logger.
+	Logger *logrus.Logger
+	// QuietDiagnostics stops the import cycles and duplicate imports being
+	// logged, for callers reporting the diagnostics of the result themselves.
+	QuietDiagnostics bool
+	// Modules is how imports of other repositories are resolved.
+	Modules ModuleMode
+	// Jobs is how many files are parsed at once, by default the number of CPUs.
+	Jobs int
+	// Cache, unless nil, holds the files parsed before.
+	Cache *parse.Cache
+}
+
+// Result is a loaded model.
+type Result struct {
+	Module      *sysl.Module
+	DefaultApp  string
+	Diagnostics []parse.Diagnostic
+}
+
+// Load loads the model in the module file, either sysl source or a module
+// compiled by the protobuf command (.pb, .json or .textpb). Imports are not
+// parsed once ctx is done. The result holds the diagnostics found even when
+// loading fails.
+func Load(ctx context.Context, fs afero.Fs, module string, opts Options) (*Result, error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	logger.Debugf("Attempting to load module:%s (root:%s)", module, opts.Root)
+
+	result := &Result{}
+	if pbutil.IsModuleFile(module) {
+		if opts.Root != "" {
+			module = filepath.Join(opts.Root, module)
+		}
+		var err error
+		result.Module, result.DefaultApp, err = parse.LoadCompiledAndGetDefaultApp(module, fs)
+		return result, err
+	}
+
+	projectConfig, modelParser, err := newParser(fs, module, opts, logger)
+	if err != nil {
+		return result, err
+	}
+	result.Module, result.DefaultApp, err = parse.LoadContextAndGetDefaultApp(
+		ctx, projectConfig.module, projectConfig.fs, modelParser)
+	result.Diagnostics = modelParser.GetDiagnostics()
+	return result, err
+}
+
+// LoadImports returns how the files of the model in the module file import
+// each other, with the diagnostics found parsing them.
+func LoadImports(
+	ctx context.Context, fs afero.Fs, module string, opts Options,
+) (*parse.ImportGraph, []parse.Diagnostic, error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	projectConfig, modelParser, err := newParser(fs, module, opts, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	graph, err := modelParser.Imports(ctx, projectConfig.module, projectConfig.fs)
+	return graph, modelParser.GetDiagnostics(), err
+}
+
+// ProjectRoot returns the root of the project of the module, which Load
+// imports files from.
+func ProjectRoot(fs afero.Fs, root, module string) (string, error) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	projectConfig := newProjectConfiguration()
+	if err := projectConfig.configureProject(root, module, fs, logger); err != nil {
+		return "", err
+	}
+	return projectConfig.root, nil
+}
+
+// newParser configures the project of the module and returns a parser for it.
+func newParser(
+	fs afero.Fs, module string, opts Options, logger *logrus.Logger,
+) (*projectConfiguration, *parse.Parser, error) {
+	projectConfig := newProjectConfiguration()
+	projectConfig.resolveModules = opts.Modules == ModulesOn || opts.Modules == ModulesFromEnv && mod.SyslModules
+	if err := projectConfig.configureProject(opts.Root, module, fs, logger); err != nil {
+		return nil, nil, err
+	}
+
+	modelParser := parse.NewParser()
+	if !projectConfig.rootIsFound {
+		modelParser.RestrictToLocalImport()
+	}
+	if opts.Jobs > 0 {
+		modelParser.SetJobs(opts.Jobs)
+	}
+	modelParser.SetCache(opts.Cache)
+	if opts.QuietDiagnostics {
+		modelParser.SetLogger(quietLogger(logger))
+	} else {
+		modelParser.SetLogger(logger)
+	}
+	return projectConfig, modelParser, nil
+}
+
+// quietLogger returns a logger like logger that logs nothing.
+func quietLogger(logger *logrus.Logger) *logrus.Logger {
+	return &logrus.Logger{
+		Out:       ioutil.Discard,
+		Formatter: logger.Formatter,
+		Hooks:     make(logrus.LevelHooks),
+		Level:     logger.Level,
+	}
+}
+
+type projectConfiguration struct {
+	module, root   string
+	rootIsFound    bool
+	resolveModules bool
+	fs             afero.Fs
+}
+
+func newProjectConfiguration() *projectConfiguration {
+	return &projectConfiguration{
+		root:        "",
+		module:      "",
+		rootIsFound: false,
+		fs:          nil,
+	}
+}
+
+func (pc *projectConfiguration) configureProject(root, module string, fs afero.Fs, logger *logrus.Logger) error {
+	rootIsDefined := root != ""
+
+	modulePath := module
+	if rootIsDefined {
+		modulePath = filepath.Join(root, module)
+	}
+
+	syslRootPath, err := findRootFromSyslModule(modulePath, fs)
+	if err != nil {
+		return err
+	}
+
+	rootMarkerExists := syslRootPath != ""
+
+	if rootIsDefined {
+		pc.rootIsFound = true
+		pc.root = root
+		pc.module = module
+		if rootMarkerExists {
+			logger.Warningf("%s found in %s but will use %s instead",
+				RootMarker, syslRootPath, pc.root)
+		} else {
+			logger.Warningf("%s is not defined but root flag is defined in %s",
+				RootMarker, pc.root)
+		}
+	} else {
+		if rootMarkerExists {
+			pc.root = syslRootPath
+
+			// module has to be relative to the root
+			absModulePath, err := filepath.Abs(module)
+			if err != nil {
+				return err
+			}
+			pc.module, err = filepath.Rel(pc.root, absModulePath)
+			if err != nil {
+				return err
+			}
+			pc.rootIsFound = true
+		} else {
+			// uses the module directory as the root, changing the module to be relative to the root
+			pc.root = filepath.Dir(module)
+			pc.module = filepath.Base(module)
+			pc.rootIsFound = false
+			logger.Warningf("root and %s are undefined, %s will be used instead",
+				RootMarker, pc.root)
+		}
+	}
+
+	pc.fs = syslutil.NewChrootFs(fs, pc.root)
+	if pc.resolveModules {
+		modDir, err := mod.FindModDir(fs, pc.root)
+		if err != nil {
+			return err
+		}
+		if modDir == "" {
+			pc.fs = mod.NewFs(pc.fs)
+		} else {
+			resolver, err := mod.LoadResolver(fs, modDir)
+			if err != nil {
+				return err
+			}
+			pc.fs = mod.NewResolverFs(pc.fs, resolver)
+		}
+	}
+
+	return nil
+}
+
+func findRootFromSyslModule(modulePath string, fs afero.Fs) (string, error) {
+	currentPath, err := filepath.Abs(modulePath)
+	if err != nil {
+		return "", err
+	}
+
+	systemRoot, err := filepath.Abs(string(os.PathSeparator))
+	if err != nil {
+		return "", err
+	}
+
+	// Keep walking up the directories to find nearest root marker
+	for {
+		currentPath = filepath.Dir(currentPath)
+		exists, err := afero.Exists(fs, filepath.Join(currentPath, RootMarker))
+		reachedRoot := currentPath == systemRoot || (err != nil && os.IsPermission(err))
+		switch {
+		case exists:
+			return currentPath, nil
+		case reachedRoot:
+			return "", nil
+		case err != nil:
+			return "", err
+		}
+	}
+}
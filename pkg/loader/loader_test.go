@@ -0,0 +1,220 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const currentWorkingDirectory = "."
+
+type folderTestStructure struct {
+	name,
+	module,
+	root,
+	expectedRoot,
+	rootMarkerPath string
+	rootFound bool
+	structure folderStructure
+}
+
+type folderStructure struct {
+	folders, files []string
+}
+
+func TestHandleProjectRoot(t *testing.T) {
+	successfulTest := folderStructure{
+		folders: []string{
+			"./SuccessfulTest/path/to/module",
+			fmt.Sprintf("./SuccessfulTest/%s", RootMarker),
+			"./SuccessfulTest/path/to/another/module",
+			fmt.Sprintf("./SuccessfulTest/path/to/another/%s", RootMarker),
+		},
+		files: []string{
+			"./SuccessfulTest/path/to/module/test.sysl",
+			"./SuccessfulTest/test2.sysl",
+			"./SuccessfulTest/path/to/another/module/test3.sysl",
+		},
+	}
+
+	definedRootNoMarker := folderStructure{
+		folders: []string{
+			"./DefinedRootAndSyslRootUndefinedTest/path/to/module/",
+		},
+		files: []string{
+			"./DefinedRootAndSyslRootUndefinedTest/path/to/module/test.sysl",
+		},
+	}
+
+	definedRootFlagAndMarkerFound := folderStructure{
+		folders: []string{
+			"./DefinedRootAndSyslRootDefinedTest/path/to/module/",
+			fmt.Sprintf("./DefinedRootAndSyslRootDefinedTest/path/%s", RootMarker),
+		},
+		files: []string{
+			"./DefinedRootAndSyslRootDefinedTest/path/to/module/test.sysl",
+		},
+	}
+
+	undefinedRoot := folderStructure{
+		folders: []string{
+			"./UndefinedRootAndUndefinedSyslRoot/",
+		},
+		files: []string{
+			"./UndefinedRootAndUndefinedSyslRoot/test.sysl",
+		},
+	}
+	systemRoot := syslutil.MustAbsolute(t, string(os.PathSeparator))
+	tests := []folderTestStructure{
+		{
+			name:         "Successful test: finding a root marker",
+			root:         "",
+			module:       successfulTest.files[0],
+			structure:    successfulTest,
+			expectedRoot: syslutil.MustAbsolute(t, "SuccessfulTest"),
+			rootFound:    true,
+		},
+		{
+			name:         "Successful test: finding a root marker in the same directory as the module",
+			root:         "",
+			module:       successfulTest.files[1],
+			structure:    successfulTest,
+			expectedRoot: syslutil.MustAbsolute(t, "SuccessfulTest"),
+			rootFound:    true,
+		},
+		{
+			name:         "Successful test: finding the closest root marker",
+			root:         "",
+			module:       successfulTest.files[2],
+			structure:    successfulTest,
+			expectedRoot: syslutil.MustAbsolute(t, "SuccessfulTest/path/to/another"),
+			rootFound:    true,
+		},
+		{
+			name: "Root flag is defined and root marker does not exist",
+			root: "DefinedRootAndSyslRootUndefinedTest/path/",
+			module: syslutil.MustRelative(t, "DefinedRootAndSyslRootUndefinedTest/path/",
+				definedRootNoMarker.files[0]),
+			structure:    definedRootNoMarker,
+			expectedRoot: "DefinedRootAndSyslRootUndefinedTest/path/",
+			rootFound:    true,
+		},
+		{
+			name:         "Defined relative root",
+			root:         currentWorkingDirectory,
+			module:       filepath.Clean(definedRootNoMarker.files[0]),
+			structure:    definedRootNoMarker,
+			expectedRoot: currentWorkingDirectory,
+			rootFound:    true,
+		},
+		{
+			root:         systemRoot,
+			name:         "Defined absolute path root",
+			module:       syslutil.MustAbsolute(t, definedRootNoMarker.files[0]),
+			structure:    definedRootNoMarker,
+			expectedRoot: systemRoot,
+			rootFound:    true,
+		},
+		{
+			name:         "Defined relative root with absolute module path rooted at root",
+			root:         currentWorkingDirectory,
+			module:       filepath.Join(systemRoot, filepath.Clean(definedRootNoMarker.files[0])),
+			structure:    definedRootNoMarker,
+			expectedRoot: currentWorkingDirectory,
+			rootFound:    true,
+		},
+		{
+			name:           "Defined root flag and root",
+			root:           currentWorkingDirectory,
+			module:         syslutil.MustRelative(t, currentWorkingDirectory, definedRootFlagAndMarkerFound.files[0]),
+			structure:      definedRootFlagAndMarkerFound,
+			expectedRoot:   currentWorkingDirectory,
+			rootMarkerPath: syslutil.MustAbsolute(t, "./DefinedRootAndSyslRootDefinedTest/path/"),
+			rootFound:      true,
+		},
+		{
+			name:           "Defined root flag and root marker with absolute path module rooted at root",
+			root:           "./DefinedRootAndSyslRootDefinedTest/",
+			module:         "/path/to/module/test.sysl",
+			structure:      definedRootFlagAndMarkerFound,
+			expectedRoot:   "./DefinedRootAndSyslRootDefinedTest/",
+			rootMarkerPath: syslutil.MustAbsolute(t, "./DefinedRootAndSyslRootDefinedTest/path/"),
+			rootFound:      true,
+		},
+		{
+			name:         "Root is not defined",
+			root:         "",
+			module:       undefinedRoot.files[0],
+			structure:    undefinedRoot,
+			expectedRoot: filepath.Dir(undefinedRoot.files[0]),
+			rootFound:    false,
+		},
+	}
+
+	for _, ts := range tests {
+		ts := ts
+		t.Run(ts.name, func(t *testing.T) {
+			t.Parallel()
+
+			logger, _ := test.NewNullLogger()
+			fs := afero.NewMemMapFs()
+			syslutil.BuildFolderTest(t, fs, ts.structure.folders, ts.structure.files)
+
+			config := newProjectConfiguration()
+			err := config.configureProject(ts.root, ts.module, fs, logger)
+
+			require.Equal(t, ts.rootFound, config.rootIsFound)
+			require.NoError(t, err)
+			require.Equal(t, ts.expectedRoot, config.root)
+			require.Equal(t, ts.getExpectedModule(t), config.module)
+		})
+	}
+}
+
+func (ts folderTestStructure) getExpectedModule(t *testing.T) string {
+	// if root is defined, expected root and root param is the same and module is not changed
+	if ts.expectedRoot == ts.root {
+		return ts.module
+	}
+	return syslutil.MustRelative(t, ts.expectedRoot, ts.module)
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	fs := afero.NewMemMapFs()
+	require.NoError(t, syslutil.WriteFiles(fs, "", map[string]string{
+		"/specs/.sysl/.keep":        "",
+		"/specs/model.sysl":         "import //shared/a\n\nModel:\n    ...\n",
+		"/specs/shared/a.sysl":      "A:\n    ...\n",
+		"/specs/broken/broken.sysl": "import ../shared/a\n\nBroken:\n    ...\n  B <-\n",
+	}))
+	opts := Options{Logger: logger, Modules: ModulesOff}
+
+	result, err := Load(context.Background(), fs, "/specs/model.sysl", opts)
+	require.NoError(t, err)
+	assert.Len(t, result.Module.Apps, 2)
+	assert.Contains(t, result.Module.Apps, result.DefaultApp)
+	assert.Empty(t, result.Diagnostics)
+
+	result, err = Load(context.Background(), fs, "/specs/broken/broken.sysl", opts)
+	require.Error(t, err)
+	assert.Nil(t, result.Module)
+	require.Len(t, result.Diagnostics, 2)
+	assert.Equal(t, "broken/broken.sysl", result.Diagnostics[0].Filename)
+	assert.Equal(t, 5, result.Diagnostics[0].Line)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Load(ctx, fs, "/specs/model.sysl", opts)
+	assert.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,107 @@
+package mod
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// Fetcher downloads the versions of modules.
+type Fetcher interface {
+	// Latest returns the newest release of the module at path.
+	Latest(path string) (string, error)
+
+	// Fetch writes the files of the module at version to dir in fs.
+	Fetch(r Require, fs afero.Fs, dir string) error
+}
+
+// GitFetcher fetches modules from the git repositories at their paths, with
+// versions as tags.
+type GitFetcher struct{}
+
+func gitURL(path string) string {
+	return "https://" + path + ".git"
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	logrus.Debugf("running command `git %v`\n", strings.Join(args, " "))
+	if err := cmd.Run(); err != nil {
+		return "", errors.Errorf("git %s failed: %s %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func (GitFetcher) Latest(path string) (string, error) {
+	out, err := runGit("", "ls-remote", "--tags", gitURL(path))
+	if err != nil {
+		return "", err
+	}
+	latest := ""
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		tag := strings.TrimSuffix(strings.TrimPrefix(fields[1], "refs/tags/"), "^{}")
+		if _, pre, ok := parseVersion(tag); ok && pre == "" && compareVersions(tag, latest) > 0 {
+			latest = tag
+		}
+	}
+	if latest == "" {
+		return "", errors.Errorf("%s has no release tags", path)
+	}
+	return latest, nil
+}
+
+func (GitFetcher) Fetch(r Require, fs afero.Fs, dir string) error {
+	tmp, err := ioutil.TempDir("", "sysl-mod")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	_, err = runGit(tmp, "clone", "--quiet", "--depth", "1", "--branch", r.Version, gitURL(r.Path), "src")
+	if err != nil {
+		return err
+	}
+	src := filepath.Join(tmp, "src")
+	if err := os.RemoveAll(filepath.Join(src, ".git")); err != nil {
+		return err
+	}
+	return copyDir(afero.NewOsFs(), src, fs, dir)
+}
+
+// copyDir copies the files in src of srcFs to dst of dstFs.
+func copyDir(srcFs afero.Fs, src string, dstFs afero.Fs, dst string) error {
+	return afero.Walk(srcFs, src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return dstFs.MkdirAll(target, os.ModePerm)
+		}
+		data, err := afero.ReadFile(srcFs, path)
+		if err != nil {
+			return err
+		}
+		return afero.WriteFile(dstFs, target, data, info.Mode().Perm())
+	})
+}
@@ -10,20 +10,38 @@ import (
 )
 
 type Fs struct {
-	source afero.Fs
+	source   afero.Fs
+	resolver *Resolver
 }
 
 func NewFs(fs afero.Fs) *Fs {
 	return &Fs{source: fs}
 }
 
+// NewResolverFs returns an Fs opening the files missing from fs in the modules
+// of the resolver, instead of those the go command finds.
+func NewResolverFs(fs afero.Fs, resolver *Resolver) *Fs {
+	return &Fs{source: fs, resolver: resolver}
+}
+
+// find returns the module of a file missing from the source, and the
+// filesystem its directory is in.
+func (fs *Fs) find(name string) (*Module, afero.Fs, error) {
+	if fs.resolver != nil {
+		mod, err := fs.resolver.Find(name)
+		return mod, fs.resolver.Fs, err
+	}
+	mod, err := Find(name)
+	return mod, afero.NewOsFs(), err
+}
+
 func (fs *Fs) Open(name string) (afero.File, error) {
 	f, err := fs.source.Open(name)
 	if err == nil {
 		return f, nil
 	}
 
-	mod, err := Find(name)
+	mod, modFs, err := fs.find(name)
 	if err != nil {
 		return nil, err
 	}
@@ -32,7 +50,7 @@ func (fs *Fs) Open(name string) (afero.File, error) {
 		return nil, err
 	}
 
-	return afero.NewOsFs().Open(filepath.Join(mod.Dir, relpath))
+	return modFs.Open(filepath.Join(mod.Dir, relpath))
 }
 
 func (fs *Fs) Create(name string) (afero.File, error) {
@@ -53,7 +71,7 @@ func (fs *Fs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, err
 		return f, nil
 	}
 
-	mod, err := Find(name)
+	mod, modFs, err := fs.find(name)
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +80,7 @@ func (fs *Fs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, err
 		return nil, err
 	}
 
-	return syslutil.NewChrootFs(afero.NewOsFs(), mod.Dir).OpenFile(relpath, flag, perm)
+	return syslutil.NewChrootFs(modFs, mod.Dir).OpenFile(relpath, flag, perm)
 }
 
 func (fs *Fs) Remove(name string) error {
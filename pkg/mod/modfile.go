@@ -0,0 +1,278 @@
+package mod
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ModFileName names the file declaring a sysl module and its requirements.
+	ModFileName = "sysl.mod"
+
+	// SumFileName names the file of checksums of the required modules.
+	SumFileName = "sysl.sum"
+)
+
+// Require is a module required at a version.
+type Require struct {
+	Path    string
+	Version string
+}
+
+func (r Require) String() string {
+	return r.Path + "@" + r.Version
+}
+
+// Replace resolves a module to a local directory instead of its versions.
+type Replace struct {
+	Path string
+	Dir  string
+}
+
+// ModFile is the contents of a sysl.mod file:
+//
+//	module github.com/org/specs
+//
+//	require github.com/org/shared v1.2.0
+//
+//	replace github.com/org/shared => ../shared
+type ModFile struct {
+	Module  string
+	Require []Require
+	Replace []Replace
+}
+
+// ParseModFile parses the sysl.mod file read from filename.
+func ParseModFile(filename string, data []byte) (*ModFile, error) {
+	f := &ModFile{}
+	block := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(stripComment(scanner.Text()))
+		if len(fields) == 0 {
+			continue
+		}
+		verb := block
+		switch {
+		case block != "" && fields[0] == ")":
+			block = ""
+			continue
+		case block == "" && len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+			continue
+		case block == "":
+			verb, fields = fields[0], fields[1:]
+		}
+		if err := f.add(verb, fields); err != nil {
+			return nil, errors.Errorf("%s:%d: %s", filename, line, err)
+		}
+	}
+	if block != "" {
+		return nil, errors.Errorf("%s: unterminated %s block", filename, block)
+	}
+	if f.Module == "" {
+		return nil, errors.Errorf("%s: no module declaration", filename)
+	}
+	return f, nil
+}
+
+func stripComment(line string) string {
+	if i := strings.Index(line, "//"); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+func (f *ModFile) add(verb string, args []string) error {
+	switch verb {
+	case "module":
+		if len(args) != 1 {
+			return errors.New("usage: module path")
+		}
+		f.Module = args[0]
+	case "require":
+		if len(args) != 2 || !isVersion(args[1]) {
+			return errors.New("usage: require path vX.Y.Z")
+		}
+		f.AddRequire(args[0], args[1])
+	case "replace":
+		if len(args) != 3 || args[1] != "=>" {
+			return errors.New("usage: replace path => dir")
+		}
+		f.Replace = append(f.Replace, Replace{Path: args[0], Dir: args[2]})
+	default:
+		return errors.Errorf("unknown directive %q", verb)
+	}
+	return nil
+}
+
+// AddRequire requires the module at version, replacing any version already
+// required.
+func (f *ModFile) AddRequire(path, version string) {
+	for i, r := range f.Require {
+		if r.Path == path {
+			f.Require[i].Version = version
+			return
+		}
+	}
+	f.Require = append(f.Require, Require{Path: path, Version: version})
+}
+
+// Replacement returns the directory replacing the module, or "".
+func (f *ModFile) Replacement(path string) string {
+	for _, r := range f.Replace {
+		if r.Path == path {
+			return r.Dir
+		}
+	}
+	return ""
+}
+
+// Format returns the file in the form ParseModFile reads, with requirements
+// sorted by path.
+func (f *ModFile) Format() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "module %s\n", f.Module)
+
+	require := append([]Require{}, f.Require...)
+	sort.Slice(require, func(i, j int) bool { return require[i].Path < require[j].Path })
+	switch len(require) {
+	case 0:
+	case 1:
+		fmt.Fprintf(&b, "\nrequire %s %s\n", require[0].Path, require[0].Version)
+	default:
+		b.WriteString("\nrequire (\n")
+		for _, r := range require {
+			fmt.Fprintf(&b, "\t%s %s\n", r.Path, r.Version)
+		}
+		b.WriteString(")\n")
+	}
+
+	if len(f.Replace) > 0 {
+		b.WriteString("\n")
+	}
+	for _, r := range f.Replace {
+		fmt.Fprintf(&b, "replace %s => %s\n", r.Path, r.Dir)
+	}
+	return b.Bytes()
+}
+
+// Sums maps modules at versions to the checksums of their files.
+type Sums map[Require]string
+
+// ParseSumFile parses the sysl.sum file read from filename.
+func ParseSumFile(filename string, data []byte) (Sums, error) {
+	sums := Sums{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 0:
+		case len(fields) == 3 && isVersion(fields[1]):
+			sums[Require{Path: fields[0], Version: fields[1]}] = fields[2]
+		default:
+			return nil, errors.Errorf("%s:%d: malformed checksum", filename, line)
+		}
+	}
+	return sums, nil
+}
+
+// Format returns the checksums in the form ParseSumFile reads, sorted by
+// module and version.
+func (s Sums) Format() []byte {
+	mods := make([]Require, 0, len(s))
+	for r := range s {
+		mods = append(mods, r)
+	}
+	sort.Slice(mods, func(i, j int) bool {
+		if mods[i].Path != mods[j].Path {
+			return mods[i].Path < mods[j].Path
+		}
+		return compareVersions(mods[i].Version, mods[j].Version) < 0
+	})
+
+	var b bytes.Buffer
+	for _, r := range mods {
+		fmt.Fprintf(&b, "%s %s %s\n", r.Path, r.Version, s[r])
+	}
+	return b.Bytes()
+}
+
+// parseVersion splits vMAJOR.MINOR.PATCH[-PRERELEASE] into its numbers and
+// prerelease.
+func parseVersion(v string) ([3]int, string, bool) {
+	var nums [3]int
+	if !strings.HasPrefix(v, "v") {
+		return nums, "", false
+	}
+	core, pre := v[1:], ""
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core, pre = core[:i], strings.SplitN(core[i:], "+", 2)[0]
+	}
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return nums, "", false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nums, "", false
+		}
+		nums[i] = n
+	}
+	return nums, pre, true
+}
+
+func isVersion(v string) bool {
+	_, _, ok := parseVersion(v)
+	return ok
+}
+
+// compareVersions orders semantic versions, with prereleases before their
+// release and invalid versions before any valid one.
+func compareVersions(a, b string) int {
+	an, apre, aok := parseVersion(a)
+	bn, bpre, bok := parseVersion(b)
+	switch {
+	case !aok || !bok:
+		return boolCompare(aok, bok)
+	case an != bn:
+		for i := range an {
+			if an[i] != bn[i] {
+				return intCompare(an[i], bn[i])
+			}
+		}
+	case apre != bpre:
+		if apre == "" || bpre == "" {
+			return boolCompare(apre == "", bpre == "")
+		}
+		return strings.Compare(apre, bpre)
+	}
+	return 0
+}
+
+func intCompare(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolCompare(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
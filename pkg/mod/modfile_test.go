@@ -0,0 +1,99 @@
+package mod
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModFile(t *testing.T) {
+	t.Parallel()
+
+	f, err := ParseModFile("sysl.mod", []byte(`module github.com/org/specs // the specs
+
+require github.com/org/b v1.0.0
+require (
+	github.com/org/a v0.2.0-rc.1
+)
+
+replace (
+	github.com/org/a => ../a
+)
+`))
+	require.NoError(t, err)
+	assert.Equal(t, &ModFile{
+		Module:  "github.com/org/specs",
+		Require: []Require{{"github.com/org/b", "v1.0.0"}, {"github.com/org/a", "v0.2.0-rc.1"}},
+		Replace: []Replace{{"github.com/org/a", "../a"}},
+	}, f)
+	assert.Equal(t, "../a", f.Replacement("github.com/org/a"))
+	assert.Equal(t, "", f.Replacement("github.com/org/b"))
+
+	assert.Equal(t, `module github.com/org/specs
+
+require (
+	github.com/org/a v0.2.0-rc.1
+	github.com/org/b v1.0.0
+)
+
+replace github.com/org/a => ../a
+`, string(f.Format()))
+
+	again, err := ParseModFile("sysl.mod", f.Format())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, f.Require, again.Require)
+}
+
+func TestParseModFileErrors(t *testing.T) {
+	t.Parallel()
+
+	for text, msg := range map[string]string{
+		"require github.com/org/a v1\n":   "sysl.mod:1: usage: require path vX.Y.Z",
+		"module a\nrequire (\n":           "sysl.mod: unterminated require block",
+		"module a\nreplace a ../a\n":      "sysl.mod:2: usage: replace path => dir",
+		"module a\nexclude a v1.0.0\n":    `sysl.mod:2: unknown directive "exclude"`,
+		"require github.com/a v1.0.0\n\n": "sysl.mod: no module declaration",
+	} {
+		_, err := ParseModFile("sysl.mod", []byte(text))
+		if assert.Error(t, err, text) {
+			assert.Equal(t, msg, err.Error())
+		}
+	}
+}
+
+func TestAddRequire(t *testing.T) {
+	t.Parallel()
+
+	f := &ModFile{Module: "m"}
+	f.AddRequire("a", "v1.0.0")
+	f.AddRequire("a", "v1.1.0")
+	assert.Equal(t, []Require{{"a", "v1.1.0"}}, f.Require)
+	assert.Equal(t, "module m\n\nrequire a v1.1.0\n", string(f.Format()))
+}
+
+func TestSums(t *testing.T) {
+	t.Parallel()
+
+	text := "a v1.0.0 h1:x=\na v1.10.0 h1:z=\na v1.2.0 h1:y=\n"
+	sums, err := ParseSumFile("sysl.sum", []byte(text))
+	require.NoError(t, err)
+	assert.Equal(t, "h1:y=", sums[Require{"a", "v1.2.0"}])
+	assert.Equal(t, "a v1.0.0 h1:x=\na v1.2.0 h1:y=\na v1.10.0 h1:z=\n", string(sums.Format()))
+
+	_, err = ParseSumFile("sysl.sum", []byte("a v1.0.0\n"))
+	assert.EqualError(t, err, "sysl.sum:1: malformed checksum")
+}
+
+func TestCompareVersions(t *testing.T) {
+	t.Parallel()
+
+	ordered := []string{"v0.9.9", "v1.0.0-alpha", "v1.0.0-beta", "v1.0.0", "v1.0.1", "v1.2.0", "v1.10.0"}
+	for i := range ordered {
+		for j := range ordered {
+			assert.Equal(t, intCompare(i, j), compareVersions(ordered[i], ordered[j]), "%q %q", ordered[i], ordered[j])
+		}
+	}
+	assert.Equal(t, -1, compareVersions("", "v0.0.0"))
+	assert.Equal(t, 1, compareVersions("v0.0.0", "1.0"))
+}
@@ -0,0 +1,512 @@
+package mod
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// VendorDir holds copies of the required modules, relative to the sysl.mod.
+const VendorDir = "vendor"
+
+// Resolver finds the files of the modules required by a sysl.mod, from the
+// directories replacing them, the vendor directory or the cache, and only
+// fetches the versions of modules missing from all three.
+type Resolver struct {
+	// Dir holds the sysl.mod.
+	Dir  string
+	Mod  *ModFile
+	Sums Sums
+
+	// Cache holds the versions of modules fetched before.
+	Cache   string
+	Fs      afero.Fs
+	Fetcher Fetcher
+
+	mu sync.Mutex
+	// addSums records the checksums of modules fetched instead of requiring
+	// them in sysl.sum.
+	addSums bool
+	list    []Require
+}
+
+// DefaultCacheDir returns $SYSL_MODCACHE, or sysl/mod in the user's cache
+// directory.
+func DefaultCacheDir() (string, error) {
+	if dir := os.Getenv("SYSL_MODCACHE"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sysl", "mod"), nil
+}
+
+// FindModDir returns the nearest directory at or above dir with a sysl.mod,
+// or "" if there is none.
+func FindModDir(fs afero.Fs, dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		exists, err := afero.Exists(fs, filepath.Join(dir, ModFileName))
+		switch {
+		case err != nil && !os.IsPermission(err):
+			return "", err
+		case exists:
+			return dir, nil
+		case filepath.Dir(dir) == dir:
+			return "", nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// LoadResolver returns the Resolver of the sysl.mod in dir, which fetches
+// modules with git into DefaultCacheDir.
+func LoadResolver(fs afero.Fs, dir string) (*Resolver, error) {
+	filename := filepath.Join(dir, ModFileName)
+	data, err := afero.ReadFile(fs, filename)
+	if err != nil {
+		return nil, err
+	}
+	modFile, err := ParseModFile(filename, data)
+	if err != nil {
+		return nil, err
+	}
+
+	sums := Sums{}
+	filename = filepath.Join(dir, SumFileName)
+	if data, err := afero.ReadFile(fs, filename); err == nil {
+		if sums, err = ParseSumFile(filename, data); err != nil {
+			return nil, err
+		}
+	}
+
+	cache, err := DefaultCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{Dir: dir, Mod: modFile, Sums: sums, Cache: cache, Fs: fs, Fetcher: GitFetcher{}}, nil
+}
+
+// Save writes the sysl.mod and sysl.sum of the resolver.
+func (r *Resolver) Save() error {
+	if err := afero.WriteFile(r.Fs, filepath.Join(r.Dir, ModFileName), r.Mod.Format(), 0644); err != nil {
+		return err
+	}
+	return afero.WriteFile(r.Fs, filepath.Join(r.Dir, SumFileName), r.Sums.Format(), 0644)
+}
+
+// moduleDir returns the directory of the files of the module at a version,
+// fetching it into the cache if it is not elsewhere.
+func (r *Resolver) moduleDir(req Require) (string, error) {
+	if dir := r.Mod.Replacement(req.Path); dir != "" {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(r.Dir, dir)
+		}
+		return dir, nil
+	}
+
+	if dir := filepath.Join(r.Dir, VendorDir, req.Path); isDir(r.Fs, dir) {
+		return dir, nil
+	}
+
+	if _, has := r.Sums[req]; !has && !r.addSums {
+		return "", errors.Errorf("missing %s entry for %s; run sysl mod tidy", SumFileName, req)
+	}
+	dir := filepath.Join(r.Cache, req.String())
+	if partial, _ := afero.Exists(r.Fs, partialFile(dir)); partial || !isDir(r.Fs, dir) {
+		return dir, r.download(req, dir)
+	}
+	if _, has := r.Sums[req]; !has {
+		// Modules are verified as they are fetched, so only those fetched
+		// before their checksum was recorded need hashing.
+		return dir, r.verify(req, dir)
+	}
+	return dir, nil
+}
+
+func isDir(fs afero.Fs, dir string) bool {
+	exists, err := afero.DirExists(fs, dir)
+	return err == nil && exists
+}
+
+// partialFile returns the file marking the module fetched into dir as
+// incomplete.
+func partialFile(dir string) string {
+	return filepath.Join(filepath.Dir(dir), ".partial-"+filepath.Base(dir))
+}
+
+// download fetches the module into dir after checking its checksum, marking
+// it incomplete until then.
+func (r *Resolver) download(req Require, dir string) error {
+	partial := partialFile(dir)
+	if err := r.Fs.MkdirAll(filepath.Dir(dir), os.ModePerm); err != nil {
+		return err
+	}
+	if err := afero.WriteFile(r.Fs, partial, nil, 0644); err != nil {
+		return err
+	}
+	err := r.Fs.RemoveAll(dir)
+	if err == nil {
+		err = errors.Wrapf(r.Fetcher.Fetch(req, r.Fs, dir), "fetching %s", req)
+	}
+	if err == nil {
+		err = r.verify(req, dir)
+	}
+	if err != nil {
+		_ = r.Fs.RemoveAll(dir) //nolint:errcheck
+	}
+	if rerr := r.Fs.Remove(partial); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// verify checks the files of the module against its checksum, recording the
+// checksum if it has none.
+func (r *Resolver) verify(req Require, dir string) error {
+	want, has := r.Sums[req]
+	sum, err := HashDir(r.Fs, dir)
+	if err != nil {
+		return err
+	}
+	if !has {
+		r.Sums[req] = sum
+		return nil
+	}
+	if sum != want {
+		return errors.Errorf("checksum mismatch for %s: %s has %s, got %s", req, SumFileName, want, sum)
+	}
+	return nil
+}
+
+// walk visits the modules required by the sysl.mod and by the sysl.mod of
+// each module it requires, returning the highest version of each required and
+// the requirements of each module, keyed by the module at its version or by
+// the main module.
+func (r *Resolver) walk() (map[string]string, map[string][]Require, error) {
+	selected := map[string]string{}
+	edges := map[string][]Require{r.Mod.Module: r.Mod.Require}
+	queue := append([]Require{}, r.Mod.Require...)
+	for len(queue) > 0 {
+		req := queue[0]
+		queue = queue[1:]
+		if _, seen := edges[req.String()]; seen {
+			continue
+		}
+		if compareVersions(req.Version, selected[req.Path]) > 0 {
+			selected[req.Path] = req.Version
+		}
+
+		dir, err := r.moduleDir(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		filename := filepath.Join(dir, ModFileName)
+		var requires []Require
+		if data, err := afero.ReadFile(r.Fs, filename); err == nil {
+			modFile, err := ParseModFile(filename, data)
+			if err != nil {
+				return nil, nil, err
+			}
+			requires = modFile.Require
+		}
+		edges[req.String()] = requires
+		queue = append(queue, requires...)
+	}
+	return selected, edges, nil
+}
+
+// BuildList returns the modules needed to resolve imports, sorted by path, at
+// the highest version any of them requires.
+func (r *Resolver) BuildList() ([]Require, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.list != nil {
+		return r.list, nil
+	}
+
+	selected, _, err := r.walk()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Require, 0, len(selected))
+	for path, version := range selected {
+		list = append(list, Require{Path: path, Version: version})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	r.list = list
+	return list, nil
+}
+
+// Graph returns the requirements of each module as "module requirement" lines,
+// with the main module first and without a version.
+func (r *Resolver) Graph() ([]string, error) {
+	_, edges, err := r.walk()
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for from, requires := range edges {
+		for _, to := range requires {
+			lines = append(lines, from+" "+to.String())
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		a, b := strings.HasPrefix(lines[i], r.Mod.Module+" "), strings.HasPrefix(lines[j], r.Mod.Module+" ")
+		if a != b {
+			return a
+		}
+		return lines[i] < lines[j]
+	})
+	return lines, nil
+}
+
+// Find returns the module with the longest path prefixing the file name.
+func (r *Resolver) Find(name string) (*Module, error) {
+	list, err := r.BuildList()
+	if err != nil {
+		return nil, err
+	}
+	var found *Require
+	for i, req := range list {
+		if hasPathPrefix(req.Path, name) && (found == nil || len(req.Path) > len(found.Path)) {
+			found = &list[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no module in %s provides %s", ModFileName, name)
+	}
+
+	dir, err := r.moduleDir(*found)
+	if err != nil {
+		return nil, err
+	}
+	return &Module{Name: found.Path, Dir: dir}, nil
+}
+
+// Tidy requires the modules providing the remote imports, and only those,
+// finding the latest release of any module not already required, and records
+// the checksum of every version of a module required.
+func (r *Resolver) Tidy(imports []string) error {
+	r.mu.Lock()
+	r.addSums = true
+	r.list = nil
+	r.mu.Unlock()
+
+	used := map[string]bool{}
+	for _, name := range imports {
+		req, err := r.requireFor(name)
+		if err != nil {
+			return err
+		}
+		used[req.Path] = true
+	}
+	var require []Require
+	for _, req := range r.Mod.Require {
+		if used[req.Path] {
+			require = append(require, req)
+		}
+	}
+	r.Mod.Require = require
+
+	// The sysl.mod of every version required is read to build the list, not
+	// only those of the versions selected, so all their checksums are kept.
+	_, edges, err := r.walk()
+	if err != nil {
+		return err
+	}
+	sums := Sums{}
+	for req, sum := range r.Sums {
+		if _, visited := edges[req.String()]; visited {
+			sums[req] = sum
+		}
+	}
+	r.Sums = sums
+	return nil
+}
+
+// requireFor returns the requirement providing the file name, requiring the
+// latest release of the longest path with one if there is none.
+func (r *Resolver) requireFor(name string) (Require, error) {
+	for _, req := range r.Mod.Require {
+		if hasPathPrefix(req.Path, name) {
+			return req, nil
+		}
+	}
+	for _, rep := range r.Mod.Replace {
+		if hasPathPrefix(rep.Path, name) {
+			r.Mod.AddRequire(rep.Path, "v0.0.0")
+			return Require{Path: rep.Path, Version: "v0.0.0"}, nil
+		}
+	}
+
+	for prefix := path.Dir(filepath.ToSlash(name)); strings.Contains(prefix, "/"); prefix = path.Dir(prefix) {
+		if version, err := r.latest(prefix); err == nil {
+			r.Mod.AddRequire(prefix, version)
+			return Require{Path: prefix, Version: version}, nil
+		}
+	}
+	return Require{}, errors.Errorf("no module provides %s", name)
+}
+
+// latest returns the latest release of the module at path, or the latest in
+// the cache if it cannot be fetched.
+func (r *Resolver) latest(path string) (string, error) {
+	version, err := r.Fetcher.Latest(path)
+	if err == nil {
+		return version, nil
+	}
+	entries, _ := afero.ReadDir(r.Fs, filepath.Join(r.Cache, filepath.Dir(path)))
+	cached := ""
+	for _, e := range entries {
+		name := strings.TrimPrefix(e.Name(), filepath.Base(path)+"@")
+		if e.IsDir() && name != e.Name() && isVersion(name) && compareVersions(name, cached) > 0 {
+			cached = name
+		}
+	}
+	if cached == "" {
+		return "", err
+	}
+	return cached, nil
+}
+
+// Vendor copies the modules of the build list to the vendor directory, listing
+// them in its modules.txt.
+func (r *Resolver) Vendor() error {
+	list, err := r.BuildList()
+	if err != nil {
+		return err
+	}
+
+	// Modules may be in the vendor directory being replaced, so they are
+	// copied aside first.
+	vendor := filepath.Join(r.Dir, VendorDir)
+	tmp := filepath.Join(r.Dir, ".vendor.tmp")
+	if err := r.Fs.RemoveAll(tmp); err != nil {
+		return err
+	}
+	var modules strings.Builder
+	for _, req := range list {
+		dir, err := r.moduleDir(req)
+		if err != nil {
+			return err
+		}
+		if err := copyDir(r.Fs, dir, r.Fs, filepath.Join(tmp, req.Path)); err != nil {
+			return err
+		}
+		fmt.Fprintf(&modules, "# %s %s\n", req.Path, req.Version)
+	}
+	if err := r.Fs.RemoveAll(vendor); err != nil {
+		return err
+	}
+	if err := r.Fs.MkdirAll(tmp, os.ModePerm); err != nil {
+		return err
+	}
+	if err := copyDir(r.Fs, tmp, r.Fs, vendor); err != nil {
+		return err
+	}
+	if err := r.Fs.RemoveAll(tmp); err != nil {
+		return err
+	}
+	return afero.WriteFile(r.Fs, filepath.Join(vendor, "modules.txt"), []byte(modules.String()), 0644)
+}
+
+// HashDir returns the checksum of the files in dir: the SHA-256 of a line of
+// the SHA-256 and path of each file, sorted by path.
+func HashDir(fs afero.Fs, dir string) (string, error) {
+	var files []string
+	err := afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	summary := sha256.New()
+	for _, file := range files {
+		f, err := fs.Open(file)
+		if err != nil {
+			return "", err
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(summary, "%x  %s\n", h.Sum(nil), filepath.ToSlash(rel))
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
+
+//nolint:gochecknoglobals
+var remoteImport = regexp.MustCompile(`(?m)^\s*import\s+//(\S+)`)
+
+// Imports returns the files the sysl files under dir import from other
+// modules: those imported with a leading // that are not under dir.
+func Imports(fs afero.Fs, dir string) ([]string, error) {
+	found := map[string]bool{}
+	err := afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case info.IsDir() && path != dir:
+			if info.Name() == VendorDir || strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		case info.IsDir() || filepath.Ext(path) != ".sysl":
+			return nil
+		}
+		data, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return err
+		}
+		for _, m := range remoteImport.FindAllStringSubmatch(string(data), -1) {
+			name := m[1]
+			if !strings.Contains(filepath.Base(name), ".") {
+				name += ".sysl"
+			}
+			if exists, _ := afero.Exists(fs, filepath.Join(dir, name)); !exists {
+				found[name] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	imports := make([]string, 0, len(found))
+	for name := range found {
+		imports = append(imports, name)
+	}
+	sort.Strings(imports)
+	return imports, nil
+}
@@ -0,0 +1,213 @@
+package mod
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFetcher fetches the files of modules at versions from memory.
+type fakeFetcher map[Require]map[string]string
+
+func (f fakeFetcher) Latest(path string) (string, error) {
+	latest := ""
+	for r := range f {
+		if r.Path == path && compareVersions(r.Version, latest) > 0 {
+			latest = r.Version
+		}
+	}
+	if latest == "" {
+		return "", errors.Errorf("%s not found", path)
+	}
+	return latest, nil
+}
+
+func (f fakeFetcher) Fetch(r Require, fs afero.Fs, dir string) error {
+	files, has := f[r]
+	if !has {
+		return errors.Errorf("%s not found", r)
+	}
+	return syslutil.WriteFiles(fs, dir, files)
+}
+
+func newTestResolver(t *testing.T, modFile string, fetcher fakeFetcher) *Resolver {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/specs/sysl.mod", []byte(modFile), 0644))
+	r, err := LoadResolver(fs, "/specs")
+	require.NoError(t, err)
+	r.Cache = "/cache"
+	r.Fetcher = fetcher
+	return r
+}
+
+func testFetcher() fakeFetcher {
+	return fakeFetcher{
+		{"github.com/org/a", "v1.0.0"}: {"a.sysl": "A:\n    ...\n"},
+		{"github.com/org/a", "v1.1.0"}: {"a.sysl": "A:\n    ...\n", "sysl.mod": "module github.com/org/a\n"},
+		{"github.com/org/b", "v0.1.0"}: {
+			"b/b.sysl": "B:\n    ...\n",
+			"sysl.mod": "module github.com/org/b\n\nrequire github.com/org/a v1.1.0\n",
+		},
+	}
+}
+
+func TestFindModDir(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/specs/sysl.mod", []byte("module specs\n"), 0644))
+	require.NoError(t, fs.MkdirAll("/specs/models/bank", 0755))
+
+	dir, err := FindModDir(fs, "/specs/models/bank")
+	require.NoError(t, err)
+	assert.Equal(t, "/specs", dir)
+
+	dir, err = FindModDir(fs, "/other")
+	require.NoError(t, err)
+	assert.Equal(t, "", dir)
+}
+
+func TestResolverTidy(t *testing.T) {
+	t.Parallel()
+
+	r := newTestResolver(t, "module github.com/org/specs\n\nrequire github.com/org/unused v1.0.0\n", testFetcher())
+	require.NoError(t, r.Tidy([]string{"github.com/org/b/b/b.sysl"}))
+	assert.Equal(t, []Require{{"github.com/org/b", "v0.1.0"}}, r.Mod.Require)
+
+	list, err := r.BuildList()
+	require.NoError(t, err)
+	assert.Equal(t, []Require{{"github.com/org/a", "v1.1.0"}, {"github.com/org/b", "v0.1.0"}}, list)
+	assert.Len(t, r.Sums, 2)
+	for _, req := range list {
+		sum, err := HashDir(r.Fs, filepath.Join("/cache", req.String()))
+		require.NoError(t, err)
+		assert.Equal(t, sum, r.Sums[req])
+	}
+
+	require.NoError(t, r.Save())
+	saved, err := LoadResolver(r.Fs, "/specs")
+	require.NoError(t, err)
+	assert.Equal(t, r.Mod, saved.Mod)
+	assert.Equal(t, r.Sums, saved.Sums)
+}
+
+func TestResolverTidyDiamond(t *testing.T) {
+	t.Parallel()
+
+	// b requires a later version of a than the main module, whose sysl.mod
+	// has to be read again, with its checksum, after tidying.
+	mod := "module github.com/org/specs\n\nrequire (\n\tgithub.com/org/a v1.0.0\n\tgithub.com/org/b v0.1.0\n)\n"
+	r := newTestResolver(t, mod, testFetcher())
+	require.NoError(t, r.Tidy([]string{"github.com/org/a/a.sysl", "github.com/org/b/b/b.sysl"}))
+	assert.Contains(t, r.Sums, Require{"github.com/org/a", "v1.0.0"})
+	require.NoError(t, r.Save())
+
+	saved, err := LoadResolver(r.Fs, "/specs")
+	require.NoError(t, err)
+	saved.Cache = "/cache"
+	saved.Fetcher = fakeFetcher{}
+	m, err := saved.Find("github.com/org/a/a.sysl")
+	require.NoError(t, err)
+	assert.Equal(t, &Module{Name: "github.com/org/a", Dir: "/cache/github.com/org/a@v1.1.0"}, m)
+}
+
+func TestResolverTidyNoModule(t *testing.T) {
+	t.Parallel()
+
+	r := newTestResolver(t, "module github.com/org/specs\n", testFetcher())
+	assert.EqualError(t, r.Tidy([]string{"github.com/org/c/c.sysl"}), "no module provides github.com/org/c/c.sysl")
+}
+
+func TestResolverFind(t *testing.T) {
+	t.Parallel()
+
+	r := newTestResolver(t, "module github.com/org/specs\n\nrequire github.com/org/b v0.1.0\n", testFetcher())
+	require.NoError(t, r.Tidy([]string{"github.com/org/b/b/b.sysl"}))
+
+	// Without the fetcher, modules are found in the cache.
+	r.Fetcher = fakeFetcher{}
+	r.list = nil
+	m, err := r.Find("github.com/org/b/b/b.sysl")
+	require.NoError(t, err)
+	assert.Equal(t, &Module{Name: "github.com/org/b", Dir: "/cache/github.com/org/b@v0.1.0"}, m)
+
+	// The vendor directory is ahead of the cache.
+	require.NoError(t, r.Vendor())
+	modules, err := afero.ReadFile(r.Fs, "/specs/vendor/modules.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "# github.com/org/a v1.1.0\n# github.com/org/b v0.1.0\n", string(modules))
+	require.NoError(t, r.Fs.RemoveAll("/cache"))
+	m, err = r.Find("github.com/org/a/a.sysl")
+	require.NoError(t, err)
+	assert.Equal(t, &Module{Name: "github.com/org/a", Dir: "/specs/vendor/github.com/org/a"}, m)
+
+	_, err = r.Find("github.com/org/c/c.sysl")
+	assert.EqualError(t, err, "no module in sysl.mod provides github.com/org/c/c.sysl")
+}
+
+func TestResolverReplace(t *testing.T) {
+	t.Parallel()
+
+	r := newTestResolver(t, "module github.com/org/specs\n\nreplace github.com/org/a => ../a\n", fakeFetcher{})
+	require.NoError(t, r.Tidy([]string{"github.com/org/a/a.sysl"}))
+	assert.Equal(t, []Require{{"github.com/org/a", "v0.0.0"}}, r.Mod.Require)
+	assert.Empty(t, r.Sums)
+
+	m, err := r.Find("github.com/org/a/a.sysl")
+	require.NoError(t, err)
+	assert.Equal(t, &Module{Name: "github.com/org/a", Dir: "/a"}, m)
+}
+
+func TestResolverChecksums(t *testing.T) {
+	t.Parallel()
+
+	fetcher := testFetcher()
+	r := newTestResolver(t, "module github.com/org/specs\n\nrequire github.com/org/a v1.0.0\n", fetcher)
+	_, err := r.BuildList()
+	assert.EqualError(t, err, "missing sysl.sum entry for github.com/org/a@v1.0.0; run sysl mod tidy")
+
+	r.Sums[Require{"github.com/org/a", "v1.0.0"}] = "h1:wrong="
+	_, err = r.BuildList()
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "checksum mismatch for github.com/org/a@v1.0.0"), err.Error())
+	exists, err := afero.Exists(r.Fs, "/cache/github.com/org/a@v1.0.0")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestResolverGraph(t *testing.T) {
+	t.Parallel()
+
+	r := newTestResolver(t, "module github.com/org/specs\n", testFetcher())
+	require.NoError(t, r.Tidy([]string{"github.com/org/a/a.sysl", "github.com/org/b/b/b.sysl"}))
+	lines, err := r.Graph()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"github.com/org/specs github.com/org/a@v1.1.0",
+		"github.com/org/specs github.com/org/b@v0.1.0",
+		"github.com/org/b@v0.1.0 github.com/org/a@v1.1.0",
+	}, lines)
+}
+
+func TestImports(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, syslutil.WriteFiles(fs, "", map[string]string{
+		"/specs/model.sysl":         "import //github.com/org/a/a\nimport //shared/local\nimport bank\n",
+		"/specs/bank.sysl":          "  import //github.com/org/b/b.yaml as b.B\n",
+		"/specs/shared/local.sysl":  "Local:\n    ...\n",
+		"/specs/vendor/x/x.sysl":    "import //github.com/org/x/x\n",
+		"/specs/.sysl/cache/c.sysl": "import //github.com/org/c/c\n",
+	}))
+
+	imports, err := Imports(fs, "/specs")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"github.com/org/a/a.sysl", "github.com/org/b/b.yaml"}, imports)
+}
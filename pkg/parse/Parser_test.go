@@ -2,14 +2,17 @@ package parse
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"path"
 	"strings"
 	"testing"
 
+	"github.com/antlr/antlr4/runtime/Go/antlr"
 	"github.com/pmezard/go-difflib/difflib"
 
+	parser "github.com/anz-bank/sysl/pkg/grammar"
 	"github.com/anz-bank/sysl/pkg/msg"
 	"github.com/anz-bank/sysl/pkg/pbutil"
 	"github.com/anz-bank/sysl/pkg/sysl"
@@ -316,6 +319,139 @@ func TestParseBadFile(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestParseStringFallsBackToLL(t *testing.T) {
+	t.Parallel()
+
+	sim := parser.NewSimulator()
+	var lexers []*parser.SyslLexer
+	defer func() {
+		for _, lexer := range lexers {
+			parser.DeleteLexerState(lexer)
+		}
+	}()
+	tokens := func(text string) antlr.TokenStream {
+		lexer := sim.NewSyslLexer(antlr.NewInputStream(text))
+		lexers = append(lexers, lexer)
+		return antlr.NewCommonTokenStream(lexer, 0)
+	}
+	assert.NotNil(t, parseSLL(sim, tokens("A:\n    ...\n")))
+	assert.Nil(t, parseSLL(sim, tokens("A:\n    ...\n  B <-\n")))
+
+	_, _, err := parseString("bad.sysl", antlr.NewInputStream("A:\n    ...\n  B <-\n"))
+	require.Error(t, err)
+	assert.Equal(t, ParseError, err.(Exit).Code)
+
+	// Valid input SLL gives up on, after consuming its tokens, is parsed by LL.
+	rejected := 0
+	rejectAll := func(sim *parser.Simulator, stream antlr.TokenStream) parser.ISysl_fileContext {
+		rejected++
+		parseSLL(sim, stream)
+		return nil
+	}
+	tree, diagnostics, err := parseStringWith("good.sysl", antlr.NewInputStream("A:\n    ...\n"), rejectAll)
+	require.NoError(t, err)
+	assert.Empty(t, diagnostics)
+	assert.Equal(t, 1, rejected)
+	require.NotNil(t, tree)
+	assert.Len(t, tree.(*parser.Sysl_fileContext).AllApplication(), 1)
+}
+
+func TestParseImportAs(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, syslutil.WriteFiles(fs, "", map[string]string{
+		"root.sysl": "import lib as Lib\nimport lib as Other.Lib\n\nStore:\n    Get:\n        Lib :: Shared <- Get\n",
+		"lib.sysl": "Shared:\n    !type Account:\n        other <: Other\n    !type Other:\n        id <: int\n" +
+			"    Get:\n        Store <- Get\n\nStore:\n    Get: ...\n",
+		"clash.sysl": "import lib as Lib\nimport store as Lib\n\nRoot:\n    ...\n",
+		"store.sysl": "Store:\n    Put: ...\n",
+	}))
+
+	m, err := NewParser().Parse("root.sysl", fs)
+	require.NoError(t, err)
+	apps := make([]string, 0, len(m.Apps))
+	for name := range m.Apps {
+		apps = append(apps, name)
+	}
+	assert.ElementsMatch(t,
+		[]string{"Store", "Lib :: Shared", "Lib :: Store", "Other :: Lib :: Shared", "Other :: Lib :: Store"}, apps)
+
+	shared := m.Apps["Other :: Lib :: Shared"]
+	assert.Equal(t, []string{"Other", "Lib", "Shared"}, shared.Name.Part)
+	assert.Equal(t, []string{"Other", "Lib", "Store"}, shared.Endpoints["Get"].Stmt[0].GetCall().Target.Part)
+	ref := shared.Types["Account"].GetTuple().AttrDefs["other"].GetTypeRef()
+	assert.Equal(t, []string{"Other", "Lib", "Shared"}, ref.Context.Appname.Part)
+	assert.Equal(t, []string{"Lib", "Shared"}, m.Apps["Store"].Endpoints["Get"].Stmt[0].GetCall().Target.Part)
+
+	_, err = NewParser().Parse("clash.sysl", fs)
+	assert.EqualError(t, err, `error importing "./store.sysl": app Lib :: Store is already defined`)
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, syslutil.WriteFiles(fs, "", map[string]string{
+		"root.sysl": "import a\nimport b\n\nRoot:\n    ...\n",
+		"a.sysl":    "A:\n    ...\n  B <-\n",
+		"b.sysl":    "B:\n    ...\n  C <-\n",
+	}))
+
+	p := NewParser()
+	_, err := p.Parse("root.sysl", fs)
+	require.Error(t, err)
+	diagnostics := p.GetDiagnostics()
+	require.Len(t, diagnostics, 4)
+	assert.Equal(t, "./a.sysl:3:3: error: extraneous input 'A' expecting {<EOF>, SYSL_COMMENT, TEXT_LINE, Name, E_Name}",
+		diagnostics[0].String())
+	assert.Equal(t, "./a.sysl", diagnostics[1].Filename)
+	assert.Equal(t, "./b.sysl", diagnostics[2].Filename)
+	assert.Equal(t, logrus.ErrorLevel, diagnostics[3].Level)
+}
+
+func TestParseContextCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fs := syslutil.NewChrootFs(afero.NewOsFs(), "tests/concurrent_import")
+	_, err := NewParser().ParseContext(ctx, "root.sysl", fs)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestParseConcurrentImports(t *testing.T) {
+	t.Parallel()
+
+	fs := syslutil.NewChrootFs(afero.NewOsFs(), "tests/concurrent_import")
+	p := NewParser()
+	p.SetJobs(1)
+	expected, err := p.Parse("root.sysl", fs)
+	require.NoError(t, err)
+	require.Len(t, expected.Apps, 6)
+
+	for i := 0; i < 10; i++ {
+		p := NewParser()
+		p.SetJobs(8)
+		actual, err := p.Parse("root.sysl", fs)
+		require.NoError(t, err)
+		require.True(t, proto.Equal(expected, actual), "modules differ on run %d", i)
+	}
+}
+
+func TestParseConcurrentImportsMissingImport(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "a.sysl", []byte("import b\nimport c\n\nA:\n    ...\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "b.sysl", []byte("import missing\n\nB:\n    ...\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "c.sysl", []byte("C:\n    ...\n"), 0644))
+
+	_, err := NewParser().Parse("a.sysl", fs)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `error parsing "./missing.sysl"`)
+}
+
 func TestSimpleEP(t *testing.T) {
 	t.Parallel()
 
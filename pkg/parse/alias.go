@@ -0,0 +1,98 @@
+package parse
+
+import (
+	"reflect"
+	"strings"
+
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/golang/protobuf/proto"
+)
+
+// alias returns the namespace the apps of an imported sysl file are mounted
+// under, or nil if the import has none. Other formats take the alias as the
+// package and name of the app they import as.
+func (d importDef) alias() []string {
+	if d.appname == "" {
+		return nil
+	}
+	mode := d.mode
+	if mode == "" {
+		mode = guessMode(d.filename)
+	}
+	if mode != "~sysl" {
+		return nil
+	}
+	var namespace []string
+	if d.pkg != "" {
+		namespace = strings.Split(d.pkg, ".")
+	}
+	return append(namespace, d.appname)
+}
+
+// key identifies what the import adds to a model, so that a file imported
+// under several namespaces is mounted under each.
+func (d importDef) key() string {
+	if alias := d.alias(); alias != nil {
+		return d.filename + " as " + strings.Join(alias, ".")
+	}
+	return d.filename
+}
+
+// mountApps adds the apps of the file parsed into src to dst under the
+// namespace. The references of the file to its own apps refer to them under
+// the namespace too.
+func mountApps(dst, src *sysl.Module, namespace []string, filename string) error {
+	src = proto.Clone(src).(*sysl.Module)
+	own := map[string]bool{}
+	for name := range src.Apps {
+		own[name] = true
+	}
+	walkAppNames(reflect.ValueOf(src), func(name *sysl.AppName) {
+		if own[syslutil.GetAppName(name)] {
+			name.Part = append(append([]string{}, namespace...), name.Part...)
+		}
+	})
+
+	for _, app := range src.Apps {
+		name := syslutil.GetAppName(app.Name)
+		if _, has := dst.Apps[name]; has {
+			return Exitf(ImportError, "error importing %#v: app %s is already defined", filename, name)
+		}
+		dst.Apps[name] = app
+	}
+	return nil
+}
+
+// walkAppNames calls f with each app name in v, which holds a message.
+func walkAppNames(v reflect.Value, f func(*sysl.AppName)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		if name, ok := v.Interface().(*sysl.AppName); ok {
+			f(name)
+			return
+		}
+		walkAppNames(v.Elem(), f)
+	case reflect.Interface:
+		if !v.IsNil() {
+			walkAppNames(v.Elem(), f)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				walkAppNames(v.Field(i), f)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkAppNames(v.Index(i), f)
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			walkAppNames(v.MapIndex(key), f)
+		}
+	}
+}
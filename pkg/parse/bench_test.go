@@ -0,0 +1,83 @@
+package parse
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+	parser "github.com/anz-bank/sysl/pkg/grammar"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// syslFiles returns the sysl files under root without syntax errors.
+func syslFiles(b *testing.B, root string) []string {
+	var files []string
+	require.NoError(b, filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || filepath.Ext(path) != ".sysl" {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sim := parser.NewSimulator()
+		lexer := sim.NewSyslLexer(antlr.NewInputStream(string(data)))
+		defer parser.DeleteLexerState(lexer)
+		if parseSLL(sim, antlr.NewCommonTokenStream(lexer, 0)) != nil {
+			files = append(files, string(data))
+		}
+		return nil
+	}))
+	require.NotEmpty(b, files)
+	return files
+}
+
+// benchmarkParse parses the files under root with SLL prediction, as every
+// file without syntax errors is, and with LL prediction to compare.
+func benchmarkParse(b *testing.B, root string) {
+	files := syslFiles(b, root)
+	var size int64
+	for _, f := range files {
+		size += int64(len(f))
+	}
+
+	b.Run("SLL", func(b *testing.B) {
+		b.SetBytes(size)
+		sim := parser.NewSimulator()
+		for i := 0; i < b.N; i++ {
+			for _, f := range files {
+				lexer := sim.NewSyslLexer(antlr.NewInputStream(f))
+				tree := parseSLL(sim, antlr.NewCommonTokenStream(lexer, 0))
+				parser.DeleteLexerState(lexer)
+				require.NotNil(b, tree)
+			}
+		}
+	})
+	b.Run("LL", func(b *testing.B) {
+		// LL prediction reports where it attempts full context.
+		defer logrus.SetLevel(logrus.GetLevel())
+		logrus.SetLevel(logrus.WarnLevel)
+
+		b.SetBytes(size)
+		sim := parser.NewSimulator()
+		for i := 0; i < b.N; i++ {
+			for _, f := range files {
+				lexer := sim.NewSyslLexer(antlr.NewInputStream(f))
+				_, _, err := parseLL(root, sim, antlr.NewCommonTokenStream(lexer, 0))
+				parser.DeleteLexerState(lexer)
+				require.NoError(b, err)
+			}
+		}
+	})
+}
+
+func BenchmarkParseTests(b *testing.B) {
+	benchmarkParse(b, mainTestDir)
+}
+
+func BenchmarkParseDemo(b *testing.B) {
+	benchmarkParse(b, "../../demo")
+}
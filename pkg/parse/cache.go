@@ -0,0 +1,107 @@
+package parse
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/spf13/afero"
+)
+
+// Cache stores the module parsed from each file in Dir, so files parsed before
+// skip Antlr. Entries are keyed on the file's path, how it was imported, its
+// content and Version, which must change whenever the parser does.
+type Cache struct {
+	Dir     string
+	Version string
+	Fs      afero.Fs
+}
+
+// cacheEntry is the module and imports of a file as stored in the cache. The
+// module is stored as JSON rather than in the binary wire format, which cannot
+// tell an empty repeated field from one never set, so that it is output the
+// same whether it was parsed or taken from the cache.
+type cacheEntry struct {
+	Imports []cacheImport   `json:"imports,omitempty"`
+	Module  json.RawMessage `json:"module"`
+}
+
+type cacheImport struct {
+	Filename string `json:"filename"`
+	AppName  string `json:"appname,omitempty"`
+	Pkg      string `json:"pkg,omitempty"`
+	Mode     string `json:"mode,omitempty"`
+}
+
+func (c *Cache) path(source importDef, text string) string {
+	sum := sha256.Sum256([]byte(c.Version + "\n" + source.filename + "\n" + source.appname + "\n" +
+		source.pkg + "\n" + source.mode + "\n" + text))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load returns the module and imports of a file parsed before, or false if it
+// is not in the cache.
+func (c *Cache) load(path string) (*sysl.Module, []importDef, bool) {
+	data, err := afero.ReadFile(c.Fs, path)
+	if err != nil {
+		return nil, nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, nil, false
+	}
+	module := &sysl.Module{}
+	if err := jsonpb.Unmarshal(bytes.NewReader(entry.Module), module); err != nil {
+		return nil, nil, false
+	}
+	if module.Apps == nil {
+		module.Apps = map[string]*sysl.Application{}
+	}
+	imports := make([]importDef, 0, len(entry.Imports))
+	for _, i := range entry.Imports {
+		imports = append(imports, importDef{filename: i.Filename, appname: i.AppName, pkg: i.Pkg, mode: i.Mode})
+	}
+	return module, imports, true
+}
+
+// store writes an entry through a temporary file, so concurrent parses of the
+// same file never read a partial entry.
+func (c *Cache) store(path string, module *sysl.Module, imports []importDef) error {
+	entry := cacheEntry{Imports: make([]cacheImport, 0, len(imports))}
+	for _, i := range imports {
+		entry.Imports = append(entry.Imports, cacheImport{Filename: i.filename, AppName: i.appname, Pkg: i.pkg, Mode: i.mode})
+	}
+	var b bytes.Buffer
+	if err := (&jsonpb.Marshaler{}).Marshal(&b, module); err != nil {
+		return err
+	}
+	entry.Module = b.Bytes()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Fs.MkdirAll(c.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	f, err := afero.TempFile(c.Fs, c.Dir, "parse")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = c.Fs.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = c.Fs.Remove(f.Name())
+	}
+	return err
+}
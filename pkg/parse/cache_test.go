@@ -0,0 +1,114 @@
+package parse
+
+import (
+	"testing"
+
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCache(t *testing.T) {
+	t.Parallel()
+
+	// concurrent_import adds to Ledger in two files, so the second of them
+	// has to be parsed again when taken from the cache.
+	fs := syslutil.NewChrootFs(afero.NewOsFs(), "tests/concurrent_import")
+	expected, err := NewParser().Parse("root.sysl", fs)
+	require.NoError(t, err)
+
+	cache := &Cache{Dir: "cache", Version: "test", Fs: afero.NewMemMapFs()}
+	for i := 0; i < 2; i++ {
+		p := NewParser()
+		p.SetCache(cache)
+		actual, err := p.Parse("root.sysl", fs)
+		require.NoError(t, err)
+		require.True(t, proto.Equal(expected, actual), "modules differ on run %d", i)
+	}
+	entries, err := afero.ReadDir(cache.Fs, "cache")
+	require.NoError(t, err)
+	assert.Len(t, entries, 6)
+}
+
+func TestParseCacheMatchesParse(t *testing.T) {
+	t.Parallel()
+
+	// Unlike proto.Equal, the JSON of a module tells empty repeated fields,
+	// such as the args of a call without any, from unset ones.
+	marshal := func(m *sysl.Module) string {
+		s, err := (&jsonpb.Marshaler{Indent: " "}).MarshalToString(m)
+		require.NoError(t, err)
+		return s
+	}
+	fs := syslutil.NewChrootFs(afero.NewOsFs(), "tests")
+	for _, filename := range []string{"funcs.sysl", "transform.sysl"} {
+		expected, err := NewParser().Parse(filename, fs)
+		require.NoError(t, err)
+
+		cache := &Cache{Dir: "cache", Version: "test", Fs: afero.NewMemMapFs()}
+		for i := 0; i < 2; i++ {
+			p := NewParser()
+			p.SetCache(cache)
+			actual, err := p.Parse(filename, fs)
+			require.NoError(t, err)
+			require.Equal(t, marshal(expected), marshal(actual), "%s differs on run %d", filename, i)
+		}
+	}
+}
+
+func TestParseCacheSkipsParsing(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "a.sysl", []byte("import b\n\nA:\n    ...\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "b.sysl", []byte("B:\n    ...\n"), 0644))
+	cache := &Cache{Dir: "cache", Version: "test", Fs: afero.NewMemMapFs()}
+
+	p := NewParser()
+	p.SetCache(cache)
+	_, err := p.Parse("a.sysl", fs)
+	require.NoError(t, err)
+
+	// Only an entry taken from the cache can name an app not in b.sysl.
+	b := importDef{filename: "./b.sysl"}
+	module, _, ok := cache.load(cache.path(b, "B:\n    ...\n"))
+	require.True(t, ok)
+	module.Apps["Cached"] = module.Apps["B"]
+	require.NoError(t, cache.store(cache.path(b, "B:\n    ...\n"), module, nil))
+
+	p = NewParser()
+	p.SetCache(cache)
+	actual, err := p.Parse("a.sysl", fs)
+	require.NoError(t, err)
+	assert.Contains(t, actual.Apps, "Cached")
+
+	// A new version of sysl does not use the entries of the old one.
+	p = NewParser()
+	p.SetCache(&Cache{Dir: "cache", Version: "next", Fs: cache.Fs})
+	actual, err = p.Parse("a.sysl", fs)
+	require.NoError(t, err)
+	assert.NotContains(t, actual.Apps, "Cached")
+}
+
+func TestParseCacheIgnoresCorruptEntries(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "a.sysl", []byte("A:\n    ...\n"), 0644))
+	cache := &Cache{Dir: "cache", Version: "test", Fs: afero.NewMemMapFs()}
+	path := cache.path(importDef{filename: "a.sysl"}, "A:\n    ...\n")
+	require.NoError(t, afero.WriteFile(cache.Fs, path, []byte("{"), 0644))
+
+	p := NewParser()
+	p.SetCache(cache)
+	actual, err := p.Parse("a.sysl", fs)
+	require.NoError(t, err)
+	assert.Contains(t, actual.Apps, "A")
+
+	_, _, ok := cache.load(path)
+	assert.True(t, ok, "entry not replaced")
+}
@@ -0,0 +1,24 @@
+package parse
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Diagnostic is a problem found parsing a file. Line and Column count from 1,
+// and are 0 when the problem is not at a position in the file.
+type Diagnostic struct {
+	Filename string
+	Line     int
+	Column   int
+	Level    logrus.Level
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", d.Filename, d.Level, d.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.Filename, d.Line, d.Column, d.Level, d.Message)
+}
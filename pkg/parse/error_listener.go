@@ -8,7 +8,8 @@ import (
 // SyslParserErrorListener ...
 type SyslParserErrorListener struct {
 	*antlr.DefaultErrorListener
-	hasErrors bool
+	hasErrors   bool
+	diagnostics []Diagnostic
 }
 
 // SyntaxError ...
@@ -20,8 +21,17 @@ func (d *SyslParserErrorListener) SyntaxError(
 	e antlr.RecognitionException,
 ) {
 	d.hasErrors = true
-	tokenType := offendingSymbol.(*antlr.CommonToken).GetTokenType()
-	logrus.Printf("SyntaxError: Token: %s\n", recognizer.GetSymbolicNames()[tokenType])
+	token := "EOF"
+	if tokenType := offendingSymbol.(*antlr.CommonToken).GetTokenType(); tokenType != antlr.TokenEOF {
+		token = recognizer.GetSymbolicNames()[tokenType]
+	}
+	logrus.Printf("SyntaxError: Token: %s\n", token)
+	d.diagnostics = append(d.diagnostics, Diagnostic{
+		Line:    line,
+		Column:  column + 1,
+		Level:   logrus.ErrorLevel,
+		Message: msg,
+	})
 }
 
 // ReportAttemptingFullContext ...
@@ -70,6 +70,10 @@ func TestSyslParserErrorListenerSyntaxError(t *testing.T) {
 	assertLog(t, `SyntaxError: Token: some_other_token\n`, func() {
 		listener.SyntaxError(recognizer, offendingSymbol, 1, 1, "some error", nil)
 	})
+	eof := antlr.NewCommonToken(source, antlr.TokenEOF, 0, 0, 0)
+	assertLog(t, `SyntaxError: Token: EOF\n`, func() {
+		listener.SyntaxError(recognizer, eof, 1, 1, "some error", nil)
+	})
 }
 
 func TestSyslParserErrorListenerReportAttemptingFullContext(t *testing.T) {
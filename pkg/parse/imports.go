@@ -0,0 +1,294 @@
+package parse
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/spf13/afero"
+)
+
+// ImportGraph is how the files of a model import each other.
+type ImportGraph struct {
+	Root   string          `json:"root"`
+	Files  []*ImportedFile `json:"files"`
+	Cycles [][]string      `json:"cycles,omitempty"`
+}
+
+// ImportedFile is a file of a model and the files it imports. A file is unused
+// when no other file refers to any of its apps or types, or, when its apps
+// define no endpoints, types or views, when it imports no used file.
+type ImportedFile struct {
+	Filename string   `json:"filename"`
+	Imports  []string `json:"imports,omitempty"`
+	Unused   bool     `json:"unused,omitempty"`
+}
+
+// File returns the file named filename, or nil if the model has none.
+func (g *ImportGraph) File(filename string) *ImportedFile {
+	i := sort.Search(len(g.Files), func(i int) bool { return g.Files[i].Filename >= filename })
+	if i < len(g.Files) && g.Files[i].Filename == filename {
+		return g.Files[i]
+	}
+	return nil
+}
+
+// newImportGraph returns the graph of the parsed files imported from root.
+func newImportGraph(root importDef, files map[importDef]*parsedFile) *ImportGraph {
+	byName := map[string]*ImportedFile{}
+	for source, f := range files {
+		filename := filepath.Clean(source.filename)
+		file, has := byName[filename]
+		if !has {
+			file = &ImportedFile{Filename: filename}
+			byName[filename] = file
+		}
+		for _, imp := range f.imports {
+			if imported := filepath.Clean(imp.filename); !contains(file.Imports, imported) {
+				file.Imports = append(file.Imports, imported)
+			}
+		}
+	}
+
+	g := &ImportGraph{Root: filepath.Clean(root.filename)}
+	for _, file := range byName {
+		g.Files = append(g.Files, file)
+	}
+	sort.Slice(g.Files, func(i, j int) bool { return g.Files[i].Filename < g.Files[j].Filename })
+	g.Cycles = g.cycles()
+	return g
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// cycles returns each cycle of imports found walking the graph from the root,
+// starting and ending with the first file of the cycle walked to.
+func (g *ImportGraph) cycles() [][]string {
+	var cycles [][]string
+	var stack []string
+	visited := map[string]bool{}
+
+	var walk func(filename string)
+	walk = func(filename string) {
+		for i, f := range stack {
+			if f == filename {
+				cycle := append(append([]string{}, stack[i:]...), filename)
+				cycles = append(cycles, cycle)
+				return
+			}
+		}
+		if visited[filename] {
+			return
+		}
+		visited[filename] = true
+		file := g.File(filename)
+		if file == nil {
+			return
+		}
+		stack = append(stack, filename)
+		for _, imported := range file.Imports {
+			walk(imported)
+		}
+		stack = stack[:len(stack)-1]
+	}
+	walk(g.Root)
+	return cycles
+}
+
+// Imports parses the file and everything it imports, returning how they import
+// each other.
+func (p *Parser) Imports(ctx context.Context, filename string, fs afero.Fs) (*ImportGraph, error) {
+	if !strings.HasSuffix(filename, ".sysl") {
+		filename += ".sysl"
+	}
+	if !fileExists(filename, fs) {
+		return nil, Exitf(ImportError, "input file does not exist: %#v", filename)
+	}
+
+	source := importDef{filename: filename}
+	files := p.parseImports(ctx, source, fs)
+	p.addDiagnostics(files)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	g := newImportGraph(source, files)
+
+	defined := map[string][]string{}
+	referenced := map[string]map[string]bool{}
+	defines := map[string]bool{}
+	for source, f := range files {
+		if f.err != nil {
+			return nil, f.err
+		}
+		filename := filepath.Clean(source.filename)
+		refs := referenced[filename]
+		if refs == nil {
+			refs = map[string]bool{}
+			referenced[filename] = refs
+		}
+		for name, app := range f.module.Apps {
+			if !isPlaceholder(app) {
+				defines[filename] = true
+			}
+			if namespace := source.alias(); namespace != nil {
+				name = strings.Join(append(append([]string{}, namespace...), app.Name.Part...), " :: ")
+			}
+			defined[name] = append(defined[name], filename)
+			for typeName := range app.Types {
+				defined[name+"."+typeName] = append(defined[name+"."+typeName], filename)
+			}
+			appReferences(app, refs)
+		}
+	}
+
+	used := map[string]bool{g.Root: true}
+	for filename, refs := range referenced {
+		for ref := range refs {
+			for _, definer := range defined[ref] {
+				if definer != filename {
+					used[definer] = true
+				}
+			}
+		}
+	}
+	// Files of only imports, whose apps are placeholders defining nothing to
+	// refer to, are used when they import a used file.
+	for changed := true; changed; {
+		changed = false
+		for _, file := range g.Files {
+			if used[file.Filename] || defines[file.Filename] {
+				continue
+			}
+			for _, imported := range file.Imports {
+				if used[imported] {
+					used[file.Filename] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	for _, file := range g.Files {
+		file.Unused = !used[file.Filename]
+	}
+	return g, nil
+}
+
+// isPlaceholder returns whether the app defines no endpoints, types or views,
+// as with an app of only "...".
+func isPlaceholder(app *sysl.Application) bool {
+	for name := range app.Endpoints {
+		if name != "..." {
+			return false
+		}
+	}
+	return len(app.Types) == 0 && len(app.Views) == 0
+}
+
+// appReferences adds the apps, and types as app.type, the app refers to.
+func appReferences(app *sysl.Application, refs map[string]bool) {
+	for _, mixin := range app.Mixin2 {
+		refs[syslutil.GetAppName(mixin.Name)] = true
+	}
+	for _, t := range app.Types {
+		typeReferences(t, refs)
+	}
+	for _, ep := range app.Endpoints {
+		if ep.Source != nil {
+			refs[syslutil.GetAppName(ep.Source)] = true
+		}
+		for _, param := range ep.Param {
+			typeReferences(param.Type, refs)
+		}
+		if rest := ep.RestParams; rest != nil {
+			for _, param := range append(append([]*sysl.Endpoint_RestParams_QueryParam{}, rest.QueryParam...),
+				rest.UrlParam...) {
+				typeReferences(param.Type, refs)
+			}
+		}
+		stmtReferences(ep.Stmt, refs)
+	}
+	for _, view := range app.Views {
+		for _, param := range view.Param {
+			typeReferences(param.Type, refs)
+		}
+		typeReferences(view.RetType, refs)
+	}
+}
+
+func stmtReferences(stmts []*sysl.Statement, refs map[string]bool) {
+	for _, stmt := range stmts {
+		switch s := stmt.Stmt.(type) {
+		case *sysl.Statement_Call:
+			refs[syslutil.GetAppName(s.Call.Target)] = true
+		case *sysl.Statement_Cond:
+			stmtReferences(s.Cond.Stmt, refs)
+		case *sysl.Statement_Loop:
+			stmtReferences(s.Loop.Stmt, refs)
+		case *sysl.Statement_LoopN:
+			stmtReferences(s.LoopN.Stmt, refs)
+		case *sysl.Statement_Foreach:
+			stmtReferences(s.Foreach.Stmt, refs)
+		case *sysl.Statement_Alt:
+			for _, choice := range s.Alt.Choice {
+				stmtReferences(choice.Stmt, refs)
+			}
+		case *sysl.Statement_Group:
+			stmtReferences(s.Group.Stmt, refs)
+		}
+	}
+}
+
+func typeReferences(t *sysl.Type, refs map[string]bool) {
+	switch t := t.GetType().(type) {
+	case *sysl.Type_TypeRef:
+		path := t.TypeRef.GetRef().GetPath()
+		if appName := t.TypeRef.GetRef().GetAppname(); appName != nil {
+			refs[syslutil.GetAppName(appName)] = true
+			if len(path) > 0 {
+				refs[syslutil.GetAppName(appName)+"."+path[0]] = true
+			}
+			break
+		}
+		// Before the module is resolved, a path without an app is a type of
+		// the app it is in or a type of the app it starts with.
+		if len(path) > 0 {
+			refs[syslutil.GetAppName(t.TypeRef.GetContext().GetAppname())+"."+path[0]] = true
+		}
+		if len(path) > 1 {
+			refs[path[0]] = true
+			refs[path[0]+"."+path[1]] = true
+		}
+	case *sysl.Type_Tuple_:
+		for _, attr := range t.Tuple.AttrDefs {
+			typeReferences(attr, refs)
+		}
+	case *sysl.Type_Relation_:
+		for _, attr := range t.Relation.AttrDefs {
+			typeReferences(attr, refs)
+		}
+	case *sysl.Type_List_:
+		typeReferences(t.List.Type, refs)
+	case *sysl.Type_Map_:
+		typeReferences(t.Map.Key, refs)
+		typeReferences(t.Map.Value, refs)
+	case *sysl.Type_OneOf_:
+		for _, option := range t.OneOf.Type {
+			typeReferences(option, refs)
+		}
+	case *sysl.Type_Set:
+		typeReferences(t.Set, refs)
+	case *sysl.Type_Sequence:
+		typeReferences(t.Sequence, refs)
+	}
+}
@@ -0,0 +1,80 @@
+package parse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func importsTestFs(t *testing.T) afero.Fs {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, syslutil.WriteFiles(fs, "", map[string]string{
+		"root.sysl":   "import a\nimport b\n\nRoot:\n    Ep:\n        A <- Ep\n",
+		"a.sysl":      "import c\n\nA:\n    !type Thing:\n        b <: B.Thing\n    Ep:\n        ...\n",
+		"b.sysl":      "import a\n\nB:\n    !type Thing:\n        x <: int\n",
+		"c.sysl":      "import a\n\nC:\n    Ep:\n        ...\n",
+		"extend.sysl": "B:\n    !type Other:\n        x <: int\n",
+	}))
+	return fs
+}
+
+func TestImportGraph(t *testing.T) {
+	t.Parallel()
+
+	g, err := NewParser().Imports(context.Background(), "root", importsTestFs(t))
+	require.NoError(t, err)
+	assert.Equal(t, &ImportGraph{
+		Root: "root.sysl",
+		Files: []*ImportedFile{
+			{Filename: "a.sysl", Imports: []string{"c.sysl"}},
+			{Filename: "b.sysl", Imports: []string{"a.sysl"}},
+			{Filename: "c.sysl", Imports: []string{"a.sysl"}, Unused: true},
+			{Filename: "root.sysl", Imports: []string{"a.sysl", "b.sysl"}},
+		},
+		Cycles: [][]string{{"a.sysl", "c.sysl", "a.sysl"}},
+	}, g)
+	assert.Nil(t, g.File("extend.sysl"))
+
+	fs := importsTestFs(t)
+	require.NoError(t, afero.WriteFile(fs, "root.sysl", []byte("import b as Lib\n\nRoot:\n    ...\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "a.sysl", []byte("import c\n\nA:\n    Ep:\n        Lib :: B <- Ep\n"), 0644))
+	g, err = NewParser().Imports(context.Background(), "root", fs)
+	require.NoError(t, err)
+	assert.False(t, g.File("b.sysl").Unused)
+	assert.True(t, g.File("c.sysl").Unused)
+}
+
+func TestImportGraphAggregatorFiles(t *testing.T) {
+	t.Parallel()
+
+	fs := importsTestFs(t)
+	root := "import all\nimport more\n\nRoot:\n    Ep:\n        A <- Ep\n"
+	require.NoError(t, afero.WriteFile(fs, "root.sysl", []byte(root), 0644))
+	require.NoError(t, afero.WriteFile(fs, "all.sysl", []byte("import more\nimport a\n\nAll:\n    ...\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "more.sysl", []byte("import c\n\nMore:\n    ...\n"), 0644))
+	g, err := NewParser().Imports(context.Background(), "root", fs)
+	require.NoError(t, err)
+	assert.False(t, g.File("all.sysl").Unused)
+	assert.False(t, g.File("a.sysl").Unused)
+	assert.True(t, g.File("more.sysl").Unused)
+	assert.True(t, g.File("c.sysl").Unused)
+}
+
+func TestParseReportsImportCycles(t *testing.T) {
+	t.Parallel()
+
+	p := NewParser()
+	m, err := p.Parse("root", importsTestFs(t))
+	require.NoError(t, err)
+	assert.Len(t, m.Apps, 4)
+	assert.Equal(t, []Diagnostic{{
+		Filename: "a.sysl",
+		Level:    logrus.WarnLevel,
+		Message:  "import cycle a.sysl -> c.sysl -> a.sysl",
+	}}, p.GetDiagnostics())
+}
@@ -1,16 +1,20 @@
 package parse
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/anz-bank/sysl/pkg/importer"
 
 	"github.com/antlr/antlr4/runtime/Go/antlr"
 	parser "github.com/anz-bank/sysl/pkg/grammar"
 	"github.com/anz-bank/sysl/pkg/msg"
+	"github.com/anz-bank/sysl/pkg/pbutil"
 	sysl "github.com/anz-bank/sysl/pkg/sysl"
 	"github.com/anz-bank/sysl/pkg/syslutil"
 	"github.com/pkg/errors"
@@ -31,24 +35,98 @@ type Parser struct {
 	LetTypes            map[string]TypeData
 	Messages            map[string][]msg.Msg
 	allowAbsoluteImport bool
+	jobs                int
+	cache               *Cache
+	diagnostics         []Diagnostic
+	logger              *logrus.Logger
 }
 
-func parseString(filename string, input antlr.CharStream) (parser.ISysl_fileContext, error) {
-	errorListener := SyslParserErrorListener{}
-	lexer := parser.NewSyslLexer(input)
+//nolint:gochecknoglobals
+var (
+	// simulators are the lexer and parser simulators of the goroutines
+	// parsing files. Each goroutine takes its own, and keeps the DFAs learned
+	// for the next file.
+	simulators = sync.Pool{New: func() interface{} { return parser.NewSimulator() }}
+)
+
+func parseString(filename string, input antlr.CharStream) (parser.ISysl_fileContext, []Diagnostic, error) {
+	return parseStringWith(filename, input, parseSLL)
+}
+
+// parseStringWith parses the input with sll first, falling back to LL
+// prediction where it returns nil.
+func parseStringWith(
+	filename string, input antlr.CharStream, sll func(*parser.Simulator, antlr.TokenStream) parser.ISysl_fileContext,
+) (parser.ISysl_fileContext, []Diagnostic, error) {
+	sim := simulators.Get().(*parser.Simulator)
+	defer simulators.Put(sim)
+
+	lexer := sim.NewSyslLexer(input)
 	defer parser.DeleteLexerState(lexer)
 	stream := antlr.NewCommonTokenStream(lexer, 0)
-	p := parser.NewSyslParser(stream)
+
+	// SLL prediction is faster than LL but can reject valid input LL parses,
+	// so only the files SLL gives up on are parsed again with LL.
+	if tree := sll(sim, stream); tree != nil {
+		return tree, nil, nil
+	}
+	logrus.Debugf("Parsing %s with LL prediction", filename)
+	stream.Seek(0)
+	return parseLL(filename, sim, stream)
+}
+
+// bailErrorStrategy stops parsing at the first syntax error. The Recover of
+// antlr.BailErrorStrategy panics asserting the parent of the root context.
+type bailErrorStrategy struct {
+	*antlr.BailErrorStrategy
+}
+
+func (b *bailErrorStrategy) Recover(antlr.Parser, antlr.RecognitionException) {
+	panic(antlr.NewParseCancellationException())
+}
+
+func (b *bailErrorStrategy) RecoverInline(antlr.Parser) antlr.Token {
+	panic(antlr.NewParseCancellationException())
+}
+
+// parseSLL parses the tokens with SLL prediction, returning nil instead of
+// reporting the first syntax error.
+func parseSLL(sim *parser.Simulator, stream antlr.TokenStream) (tree parser.ISysl_fileContext) {
+	p := sim.NewSyslParser(stream)
 	p.GetInterpreter().SetPredictionMode(antlr.PredictionModeSLL)
-	p.AddErrorListener(antlr.NewDiagnosticErrorListener(true))
+	p.RemoveErrorListeners()
+	p.SetErrorHandler(&bailErrorStrategy{antlr.NewBailErrorStrategy()})
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(*antlr.ParseCancellationException); !ok {
+				panic(r)
+			}
+			tree = nil
+		}
+	}()
+	p.BuildParseTrees = true
+	return p.Sysl_file()
+}
+
+// parseLL parses the tokens with LL prediction, reporting any syntax errors.
+func parseLL(
+	filename string, sim *parser.Simulator, stream antlr.TokenStream,
+) (parser.ISysl_fileContext, []Diagnostic, error) {
+	errorListener := SyslParserErrorListener{}
+	p := sim.NewSyslParser(stream)
+	p.GetInterpreter().SetPredictionMode(antlr.PredictionModeLL)
 	p.AddErrorListener(&errorListener)
 
 	p.BuildParseTrees = true
 	tree := p.Sysl_file()
 	if errorListener.hasErrors {
-		return nil, Exitf(ParseError, fmt.Sprintf("%s has syntax errors\n", filename))
+		for i := range errorListener.diagnostics {
+			errorListener.diagnostics[i].Filename = filename
+		}
+		return nil, errorListener.diagnostics, Exitf(ParseError, fmt.Sprintf("%s has syntax errors\n", filename))
 	}
-	return tree, nil
+	return tree, nil, nil
 }
 
 func guessMode(filename string) string {
@@ -92,7 +170,145 @@ func (p *Parser) RestrictToLocalImport() {
 	p.allowAbsoluteImport = false
 }
 
+// SetJobs sets how many imported files are parsed at once, by default the
+// number of CPUs.
+func (p *Parser) SetJobs(jobs int) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	p.jobs = jobs
+}
+
+// SetLogger sets the logger the import cycles and duplicate imports found are
+// logged to, by default the standard logger.
+func (p *Parser) SetLogger(logger *logrus.Logger) {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	p.logger = logger
+}
+
+// SetCache sets the cache of parsed files, or disables it if cache is nil.
+func (p *Parser) SetCache(cache *Cache) {
+	p.cache = cache
+}
+
+// parsedFile is the parse tree and imports of a file, or why it could not be
+// parsed. Files found in the cache have their module instead of a tree.
+type parsedFile struct {
+	tree        parser.ISysl_fileContext
+	module      *sysl.Module
+	imports     []importDef
+	diagnostics []Diagnostic
+	err         error
+}
+
+func parseFile(source importDef, fs afero.Fs, cache *Cache) *parsedFile {
+	filename := source.filename
+
+	fsinput, err := newFSFileStream(filename, fs)
+	if err != nil {
+		return &parsedFile{err: Exitf(ImportError, fmt.Sprintf("error parsing %#v: %v\n", filename, err))}
+	}
+
+	var cachePath string
+	if cache != nil {
+		cachePath = cache.path(source, fsinput.GetText(0, fsinput.Size()))
+		if module, imports, ok := cache.load(cachePath); ok {
+			logrus.Debugf("Parsed from cache: " + filename)
+			return &parsedFile{module: module, imports: imports}
+		}
+	}
+	logrus.Debugf("Parsing: " + filename)
+
+	input, err := importForeign(source, fsinput)
+	if err != nil {
+		return &parsedFile{err: err}
+	}
+
+	tree, diagnostics, err := parseString(filename, input)
+	if err != nil {
+		return &parsedFile{diagnostics: diagnostics, err: err}
+	}
+
+	localListener := NewTreeShapeListener()
+	localListener.sc = sourceCtxHelper{source.filename}
+	localListener.base = filepath.Dir(filename)
+	antlr.NewParseTreeWalker().Walk(localListener, tree)
+
+	if cache != nil {
+		// The cache only saves time, so failing to fill it is not an error.
+		_ = cache.store(cachePath, localListener.module, localListener.imports)
+	}
+	return &parsedFile{tree: tree, module: localListener.module, imports: localListener.imports}
+}
+
+// overlaps returns whether any app of b is also in a.
+func overlaps(a, b *sysl.Module) bool {
+	for name := range b.Apps {
+		if _, has := a.Apps[name]; has {
+			return true
+		}
+	}
+	return false
+}
+
+// parseImports parses the file and everything it imports with up to jobs
+// files at a time. Files not parsed before ctx is done fail with its error.
+func (p *Parser) parseImports(ctx context.Context, source importDef, fs afero.Fs) map[importDef]*parsedFile {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	files := map[importDef]*parsedFile{}
+	sem := make(chan struct{}, p.jobs)
+
+	var parse func(source importDef)
+	parse = func(source importDef) {
+		if !p.allowAbsoluteImport && strings.HasPrefix(source.filename, "/") {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if _, has := files[source]; has {
+			return
+		}
+		files[source] = nil
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var f *parsedFile
+			select {
+			case sem <- struct{}{}:
+				if err := ctx.Err(); err != nil {
+					f = &parsedFile{err: err}
+				} else {
+					f = parseFile(source, fs, p.cache)
+				}
+				<-sem
+			case <-ctx.Done():
+				f = &parsedFile{err: ctx.Err()}
+			}
+
+			mu.Lock()
+			files[source] = f
+			mu.Unlock()
+			for _, imp := range f.imports {
+				parse(imp)
+			}
+		}()
+	}
+	parse(source)
+	wg.Wait()
+	return files
+}
+
 func (p *Parser) Parse(filename string, fs afero.Fs) (*sysl.Module, error) {
+	return p.ParseContext(context.Background(), filename, fs)
+}
+
+// ParseContext parses like Parse, giving up with the error of ctx once it is
+// done.
+func (p *Parser) ParseContext(ctx context.Context, filename string, fs afero.Fs) (*sysl.Module, error) {
 	if !strings.HasSuffix(filename, ".sysl") {
 		filename += ".sysl"
 	}
@@ -107,36 +323,51 @@ func (p *Parser) Parse(filename string, fs afero.Fs) (*sysl.Module, error) {
 		filename: filename,
 	}
 
+	files := p.parseImports(ctx, source, fs)
+	p.addDiagnostics(files)
+	for _, cycle := range newImportGraph(source, files).Cycles {
+		p.logger.Warnf("Import cycle: %s", strings.Join(cycle, " -> "))
+		p.diagnostics = append(p.diagnostics, Diagnostic{
+			Filename: cycle[0],
+			Level:    logrus.WarnLevel,
+			Message:  "import cycle " + strings.Join(cycle, " -> "),
+		})
+	}
+
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		filename := source.filename
-		logrus.Debugf("Parsing: " + filename)
-
-		fsinput, err := newFSFileStream(filename, fs)
-		if err != nil {
-			return nil, Exitf(ImportError, fmt.Sprintf("error parsing %#v: %v\n", filename, err))
+		f := files[source]
+		if f.err != nil {
+			return nil, f.err
 		}
 
 		listener.sc = sourceCtxHelper{source.filename}
 		listener.base = filepath.Dir(filename)
-
-		input, err := importForeign(source, fsinput)
-		if err != nil {
-			return nil, err
-		}
-
-		tree, err := parseString(filename, input)
-		if err != nil {
-			return nil, err
+		if namespace := source.alias(); namespace != nil {
+			if err := mountApps(listener.module, f.module, namespace, filename); err != nil {
+				return nil, err
+			}
+			listener.imports = append(listener.imports, f.imports...)
+		} else if f.tree == nil && !overlaps(listener.module, f.module) {
+			// Walking the file would only add its apps.
+			for name, app := range f.module.Apps {
+				listener.module.Apps[name] = app
+			}
+			listener.imports = append(listener.imports, f.imports...)
+		} else {
+			if f.tree == nil {
+				// The file adds to apps of files before it, which only walking
+				// its tree over them does.
+				if f = parseFile(source, fs, nil); f.err != nil {
+					return nil, f.err
+				}
+			}
+			antlr.NewParseTreeWalker().Walk(listener, f.tree)
 		}
 
-		localListener := NewTreeShapeListener()
-		localListener.sc = sourceCtxHelper{source.filename}
-		localListener.base = filepath.Dir(filename)
-
-		walker := antlr.NewParseTreeWalker()
-		walker.Walk(localListener, tree)
-		walker.Walk(listener, tree)
-
 		if len(listener.imports) == 0 {
 			break
 		}
@@ -146,19 +377,24 @@ func (p *Parser) Parse(filename string, fs afero.Fs) (*sysl.Module, error) {
 			set := make(map[string]byte)
 			for _, value := range list {
 				if count := set[value.filename]; count == 1 {
-					logrus.Warnf("Duplicate import: '%s' in file: '%s'\n", value.filename, source.filename)
+					p.logger.Warnf("Duplicate import: '%s' in file: '%s'\n", value.filename, source.filename)
+					p.diagnostics = append(p.diagnostics, Diagnostic{
+						Filename: source.filename,
+						Level:    logrus.WarnLevel,
+						Message:  fmt.Sprintf("duplicate import %s", value.filename),
+					})
 				}
 				set[value.filename]++
 			}
 		}
-		duplicateImportCheck(localListener.imports)
+		duplicateImportCheck(f.imports)
 
-		imported[filename] = struct{}{}
+		imported[source.key()] = struct{}{}
 
 		for len(listener.imports) > 0 {
 			source = listener.imports[0]
 			listener.imports = listener.imports[1:]
-			if _, has := imported[source.filename]; !has {
+			if _, has := imported[source.key()]; !has {
 				if !p.allowAbsoluteImport && strings.HasPrefix(source.filename, "/") {
 					return nil, Exitf(2,
 						"error importing: importing outside current directory is only allowed when root is defined")
@@ -167,7 +403,7 @@ func (p *Parser) Parse(filename string, fs afero.Fs) (*sysl.Module, error) {
 			}
 		}
 
-		if _, has := imported[source.filename]; has {
+		if _, has := imported[source.key()]; has {
 			break
 		}
 	}
@@ -469,7 +705,7 @@ func (p *Parser) inferExprType(mod *sysl.Module,
 		if t.Ifelse.GetIfFalse() != nil {
 			exprTypeIfFalse, _, _ := p.inferExprType(mod, appName, t.Ifelse.GetIfFalse(), true, anonCount,
 				viewName, scope, refType)
-			// TODO if exprTypeIfTrue != exprTypeIfFalse, raise an error. Then remove following 3 lines
+			// Mismatched branch types are reported by eval.CheckTypes.
 			if exprTypeIfFalse != nil {
 				expr.Type = exprTypeIfFalse
 			}
@@ -478,7 +714,7 @@ func (p *Parser) inferExprType(mod *sysl.Module,
 		exprTypeLHS, _, _ := p.inferExprType(mod, appName, t.Binexpr.GetLhs(), true, anonCount, viewName, scope, refType)
 		expr.Type = exprTypeLHS
 		exprTypeRHS, _, _ := p.inferExprType(mod, appName, t.Binexpr.GetRhs(), true, anonCount, viewName, scope, refType)
-		// TODO if exprTypeRHS != exprTypeLHS, raise an error. Then remove following 3 lines
+		// Unsupported operand types are reported by eval.CheckTypes.
 		if exprTypeRHS != nil {
 			expr.Type = exprTypeRHS
 		}
@@ -513,6 +749,16 @@ func (p *Parser) inferExprType(mod *sysl.Module,
 	return expr.Type, anonCount, expr.Type
 }
 
+// InferExprType returns the type of a standalone expression as inferred at
+// parse time, or the none type if it can only be known by evaluating it.
+func (p *Parser) InferExprType(mod *sysl.Module, appName string, expr *sysl.Expr) *sysl.Type {
+	exprType, _, _ := p.inferExprType(mod, appName, expr, true, 0, "", "", nil)
+	if exprType == nil {
+		return syslutil.TypeNone()
+	}
+	return exprType
+}
+
 func (p *Parser) inferTypes(mod *sysl.Module, appName string) {
 	for viewName, view := range mod.Apps[appName].Views {
 		if syslutil.HasPattern(view.Attrs, "abstract") {
@@ -651,8 +897,16 @@ func getDefaultAppName(mod *sysl.Module) string {
 }
 
 func LoadAndGetDefaultApp(model string, fs afero.Fs, p *Parser) (*sysl.Module, string, error) {
+	return LoadContextAndGetDefaultApp(context.Background(), model, fs, p)
+}
+
+// LoadContextAndGetDefaultApp loads like LoadAndGetDefaultApp, giving up with
+// the error of ctx once it is done.
+func LoadContextAndGetDefaultApp(
+	ctx context.Context, model string, fs afero.Fs, p *Parser,
+) (*sysl.Module, string, error) {
 	// Model we want to generate code for
-	mod, err := p.Parse(model, fs)
+	mod, err := p.ParseContext(ctx, model, fs)
 	if err != nil {
 		return nil, "", err
 	}
@@ -660,6 +914,16 @@ func LoadAndGetDefaultApp(model string, fs afero.Fs, p *Parser) (*sysl.Module, s
 	return mod, modelAppName, nil
 }
 
+// LoadCompiledAndGetDefaultApp reads a module compiled by the protobuf
+// command rather than parsing sysl source.
+func LoadCompiledAndGetDefaultApp(model string, fs afero.Fs) (*sysl.Module, string, error) {
+	mod, err := pbutil.ReadModule(model, fs)
+	if err != nil {
+		return nil, "", Exitf(ImportError, "%s", err)
+	}
+	return mod, getDefaultAppName(mod), nil
+}
+
 func (p *Parser) GetAssigns() map[string]TypeData {
 	return p.AssignTypes
 }
@@ -672,11 +936,34 @@ func (p *Parser) GetMessages() map[string][]msg.Msg {
 	return p.Messages
 }
 
+// GetDiagnostics returns the problems found in the files parsed so far.
+func (p *Parser) GetDiagnostics() []Diagnostic {
+	return p.diagnostics
+}
+
+// addDiagnostics adds the diagnostics of the files, in order of filename.
+func (p *Parser) addDiagnostics(files map[importDef]*parsedFile) {
+	var diagnostics []Diagnostic
+	for _, f := range files {
+		diagnostics = append(diagnostics, f.diagnostics...)
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	p.diagnostics = append(p.diagnostics, diagnostics...)
+}
+
 func NewParser() *Parser {
 	return &Parser{
 		AssignTypes:         map[string]TypeData{},
 		LetTypes:            map[string]TypeData{},
 		Messages:            map[string][]msg.Msg{},
 		allowAbsoluteImport: true,
+		jobs:                runtime.NumCPU(),
+		logger:              logrus.StandardLogger(),
 	}
 }
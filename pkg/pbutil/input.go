@@ -0,0 +1,44 @@
+package pbutil
+
+import (
+	"bytes"
+	"path/filepath"
+
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// IsModuleFile reports whether filename names a compiled module: binary
+// protobuf (.pb), JSON (.json) or text protobuf (.textpb).
+func IsModuleFile(filename string) bool {
+	switch filepath.Ext(filename) {
+	case ".pb", ".json", ".textpb":
+		return true
+	}
+	return false
+}
+
+// ReadModule reads a module compiled by the protobuf command, in the format
+// given by the extension of filename.
+func ReadModule(filename string, fs afero.Fs) (*sysl.Module, error) {
+	data, err := afero.ReadFile(fs, filename)
+	if err != nil {
+		return nil, err
+	}
+	m := &sysl.Module{}
+	switch filepath.Ext(filename) {
+	case ".json":
+		err = jsonpb.Unmarshal(bytes.NewReader(data), m)
+	case ".textpb":
+		err = proto.UnmarshalText(string(data), m)
+	default:
+		err = proto.Unmarshal(data, m)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s is not a compiled module", filename)
+	}
+	return m, nil
+}
@@ -52,3 +52,30 @@ func FTextPB(w io.Writer, m proto.Message) error {
 	}
 	return proto.MarshalText(w, m)
 }
+
+// BinaryPB writes m to the file named filename in the protobuf wire format.
+func BinaryPB(m proto.Message, filename string, fs afero.Fs) error {
+	if m == nil {
+		return fmt.Errorf("module is nil: %#v", filename)
+	}
+
+	f, err := fs.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return FBinaryPB(f, m)
+}
+
+// FBinaryPB writes m to w in the protobuf wire format.
+func FBinaryPB(w io.Writer, m proto.Message) error {
+	if m == nil {
+		return fmt.Errorf("module is nil")
+	}
+	data, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/anz-bank/sysl/pkg/syslutil"
 
 	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/golang/protobuf/proto"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -158,3 +159,45 @@ func TestFTextPBNilModule(t *testing.T) {
 	require.Error(t, FTextPB(&output, nil))
 	assert.Equal(t, "", output.String())
 }
+
+func TestBinaryPB(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	filename := "/out.pb"
+	require.NoError(t, BinaryPB(testModule(), filename, fs))
+	output, err := afero.ReadFile(fs, filename)
+	require.NoError(t, err)
+	var m sysl.Module
+	require.NoError(t, proto.Unmarshal(output, &m))
+	assert.True(t, proto.Equal(testModule(), &m))
+}
+
+func TestBinaryPBNilModule(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	filename := "/out.pb"
+	require.Error(t, BinaryPB(nil, filename, fs))
+	syslutil.AssertFsHasExactly(t, fs)
+}
+
+func TestReadModule(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, BinaryPB(testModule(), "/out.pb", fs))
+	require.NoError(t, afero.WriteFile(fs, "/out.json", []byte(testModuleJSONPB()), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/out.textpb", []byte(testModuleTextPB()), 0644))
+	for _, filename := range []string{"/out.pb", "/out.json", "/out.textpb"} {
+		assert.True(t, IsModuleFile(filename))
+		m, err := ReadModule(filename, fs)
+		require.NoError(t, err, filename)
+		assert.True(t, proto.Equal(testModule(), m), filename)
+	}
+	assert.False(t, IsModuleFile("/out.sysl"))
+
+	require.NoError(t, afero.WriteFile(fs, "/bad.json", []byte("{"), 0644))
+	_, err := ReadModule("/bad.json", fs)
+	assert.Error(t, err)
+}
@@ -0,0 +1,324 @@
+package sequencediagram
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/diagrams"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// Params configures the sequence diagrams generated from a model, as the
+// flags of sysl sd do.
+type Params struct {
+	// EndpointFormat and AppFormat label endpoints and participants, and Title
+	// the diagrams. They may be overridden by the epfmt, appfmt and seqtitle
+	// attributes of apps.
+	EndpointFormat string
+	AppFormat      string
+	Title          string
+	// Output is the output each diagram is keyed by. It may include
+	// %(appname) and %(epname) to generate a diagram for every endpoint.
+	Output string
+	// Endpoints are the endpoints drawn, such as "App <- Endpoint", and Apps
+	// the apps whose endpoints are each drawn to a templated Output.
+	Endpoints []string
+	Apps      []string
+	// BlackboxesFlag maps the endpoints drawn as blackboxes to a comment.
+	// Blackboxes, if not empty, is used instead as pairs of endpoint and
+	// comment.
+	BlackboxesFlag map[string]string
+	Blackboxes     [][]string
+	// Group is the attribute apps are grouped by in boxes.
+	Group string
+	// Depth limits how many levels of calls are followed, if positive.
+	Depth int
+}
+
+// SequenceDiagParam describes a single sequence diagram.
+type SequenceDiagParam struct {
+	diagrams.AppLabeler
+	diagrams.EndpointLabeler
+	Endpoints  []string
+	Title      string
+	Blackboxes map[string]*Upto
+	AppName    string
+	Group      string
+	Mermaid    bool
+	Depth      int
+}
+
+// GenerateSequenceDiag returns the PlantUML, or Mermaid, sequence diagram of
+// the endpoints of p.
+func GenerateSequenceDiag(m *sysl.Module, p *SequenceDiagParam, logger *logrus.Logger) (string, error) {
+	w := MakeSequenceDiagramWriter(true, "skinparam maxMessageSize 250")
+	if p.Mermaid {
+		w = MakeMermaidSequenceDiagramWriter(true)
+	}
+	v := MakeSequenceDiagramVisitor(p.AppLabeler, p.EndpointLabeler, w, m, p.AppName, p.Group, logger)
+	v.maxDepth = p.Depth
+	e := MakeEndpointCollectionElement(p.Title, p.Endpoints, p.Blackboxes)
+
+	if err := e.Accept(v); err != nil {
+		return "", err
+	}
+	if p.Mermaid {
+		return w.String(), nil
+	}
+
+	const color = "#LightBlue"
+	for boxname, appset := range v.groupboxes {
+		fmt.Fprintf(w, "box \"%s\" %s\n", boxname, color)
+		for key := range appset {
+			fmt.Fprintf(w, "\tparticipant %s\n", v.UniqueVarForAppName(key))
+		}
+		fmt.Fprintf(w, "end box\n")
+	}
+
+	return w.String(), nil
+}
+
+// DoConstructSequenceDiagrams returns the sequence diagrams described by
+// params, keyed by their outputs.
+func DoConstructSequenceDiagrams(
+	params *Params,
+	model *sysl.Module,
+	logger *logrus.Logger,
+) (map[string]string, error) {
+	var blackboxes [][]string
+
+	logger.Debugf("endpoints: %v\n", params.Endpoints)
+	logger.Debugf("app: %v\n", params.Apps)
+	logger.Debugf("endpoint_format: %s\n", params.EndpointFormat)
+	logger.Debugf("app_format: %s\n", params.AppFormat)
+	logger.Debugf("title: %s\n", params.Title)
+	logger.Debugf("output: %s\n", params.Output)
+
+	if len(params.Blackboxes) == 0 {
+		blackboxes = ParseBlackBoxesFromArgument(params.BlackboxesFlag)
+		logger.Debugf("blackbox: %s\n", params.BlackboxesFlag)
+	} else {
+		blackboxes = params.Blackboxes
+	}
+
+	result := make(map[string]string)
+
+	if strings.Contains(params.Output, "%(epname)") {
+		if len(blackboxes) > 0 {
+			logger.Warnf("Ignoring blackboxes passed from command line")
+		}
+		spout := diagrams.MakeFormatParser(params.Output)
+		for _, appName := range params.Apps {
+			app := model.Apps[appName]
+			bbs := TransformBlackBoxes(app.GetAttrs()["blackboxes"].GetA().GetElt())
+			spseqtitle := diagrams.ConstructFormatParser(app.GetAttrs()["seqtitle"].GetS(), params.Title)
+			spep := diagrams.ConstructFormatParser(app.GetAttrs()["epfmt"].GetS(), params.EndpointFormat)
+			spapp := diagrams.ConstructFormatParser(app.GetAttrs()["appfmt"].GetS(), params.AppFormat)
+			keys := []string{}
+			for k := range app.GetEndpoints() {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			bbsAll := map[string]*Upto{}
+			TransformBlackboxesToUptos(bbsAll, bbs, BBApplication)
+			var sd *SequenceDiagParam
+			for _, k := range keys {
+				endpoint := app.GetEndpoints()[k]
+				epAttrs := endpoint.GetAttrs()
+				outputDir := spout.FmtOutput(appName, k, endpoint.GetLongName(), epAttrs)
+				bbs2 := TransformBlackBoxes(endpoint.GetAttrs()["blackboxes"].GetA().GetElt())
+				varrefs := diagrams.MergeAttributes(app.GetAttrs(), endpoint.GetAttrs())
+				sdEndpoints := []string{}
+				for _, stmt := range endpoint.GetStmt() {
+					_, ok := stmt.Stmt.(*sysl.Statement_Call)
+					if ok {
+						parts := stmt.GetCall().GetTarget().GetPart()
+						ep := stmt.GetCall().GetEndpoint()
+						sdEndpoints = append(sdEndpoints, strings.Join(parts, " :: ")+" <- "+ep)
+					}
+				}
+				if len(sdEndpoints) == 0 {
+					return nil, fmt.Errorf("no call statements to build sequence diagram for endpoint %s",
+						endpoint.Name)
+				}
+				groupAttr := epAttrs["groupby"].GetS()
+				if len(groupAttr) == 0 {
+					groupAttr = params.Group
+				} else if len(params.Group) > 0 {
+					logger.Warnf("Ignoring groupby passed from command line")
+				}
+				TransformBlackboxesToUptos(bbsAll, bbs2, BBEndpointCollection)
+				sd = &SequenceDiagParam{
+					Endpoints:       sdEndpoints,
+					AppLabeler:      spapp,
+					EndpointLabeler: spep,
+					Title:           spseqtitle.FmtSeq(endpoint.GetName(), endpoint.GetLongName(), varrefs),
+					Blackboxes:      bbsAll,
+					AppName:         fmt.Sprintf("'%s :: %s'", appName, endpoint.GetName()),
+					Group:           groupAttr,
+					Mermaid:         diagrams.IsMermaidOutput(outputDir),
+					Depth:           params.Depth,
+				}
+				out, err := GenerateSequenceDiag(model, sd, logger)
+				if err != nil {
+					return nil, err
+				}
+				for indx := range bbs2 {
+					delete(bbsAll, bbs2[indx][0])
+				}
+				result[outputDir] = out
+			}
+			for bbKey, bbVal := range bbsAll {
+				if bbVal.VisitCount == 0 && bbVal.ValueType == BBApplication {
+					logger.Warnf("blackbox '%s' not hit in app '%s'\n", bbKey, appName)
+				}
+			}
+		}
+	} else {
+		if len(params.Endpoints) == 0 {
+			return result, nil
+		}
+		spep := diagrams.ConstructFormatParser("", params.EndpointFormat)
+		spapp := diagrams.ConstructFormatParser("", params.AppFormat)
+		bbsAll := map[string]*Upto{}
+		TransformBlackboxesToUptos(bbsAll, blackboxes, BBCommandLine)
+		sd := &SequenceDiagParam{
+			Endpoints:       params.Endpoints,
+			AppLabeler:      spapp,
+			EndpointLabeler: spep,
+			Title:           params.Title,
+			Blackboxes:      bbsAll,
+			Group:           params.Group,
+			Mermaid:         diagrams.IsMermaidOutput(params.Output),
+			Depth:           params.Depth,
+		}
+		out, err := GenerateSequenceDiag(model, sd, logger)
+		if err != nil {
+			return nil, err
+		}
+		for bbKey, bbVal := range bbsAll {
+			if bbVal.VisitCount == 0 && bbVal.ValueType == BBCommandLine {
+				logger.Warnf("blackbox '%s' passed on commandline not hit\n", bbKey)
+			}
+		}
+		result[params.Output] = out
+	}
+
+	return result, nil
+}
+
+// AutoDiagram is a diagram generated for an endpoint by
+// DoConstructAutoSequenceDiagrams.
+type AutoDiagram struct {
+	AppName  string
+	Endpoint string
+	Output   string
+}
+
+// DoConstructAutoSequenceDiagrams generates a sequence diagram for every
+// endpoint with statements of the apps in appsFlag, or of every app if none is
+// given, without the SEQ attributes of a project. The output is formatted with
+// the app and endpoint names, e.g. %(appname)/%(epname).svg, and calls are
+// followed to the depth given, stopping at blackboxes as in other modes.
+func DoConstructAutoSequenceDiagrams(
+	params *Params,
+	model *sysl.Module,
+	logger *logrus.Logger,
+) (map[string]string, []AutoDiagram, error) {
+	appNames := params.Apps
+	if len(appNames) == 0 {
+		for appName := range model.GetApps() {
+			appNames = append(appNames, appName)
+		}
+		sort.Strings(appNames)
+	}
+	blackboxes := params.Blackboxes
+	if len(blackboxes) == 0 {
+		blackboxes = ParseBlackBoxesFromArgument(params.BlackboxesFlag)
+	}
+
+	result := map[string]string{}
+	generated := []AutoDiagram{}
+	sources := map[string]string{}
+	spout := diagrams.MakeFormatParser(params.Output)
+	for _, appName := range appNames {
+		app, ok := model.GetApps()[appName]
+		if !ok {
+			return nil, nil, fmt.Errorf("app %q not found", appName)
+		}
+		spseqtitle := diagrams.ConstructFormatParser(app.GetAttrs()["seqtitle"].GetS(), params.Title)
+		spep := diagrams.ConstructFormatParser(app.GetAttrs()["epfmt"].GetS(), params.EndpointFormat)
+		spapp := diagrams.ConstructFormatParser(app.GetAttrs()["appfmt"].GetS(), params.AppFormat)
+		keys := []string{}
+		for k, endpoint := range app.GetEndpoints() {
+			if k != "..." && len(endpoint.GetStmt()) > 0 {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			endpoint := app.GetEndpoints()[k]
+			source := appName + " <- " + k
+			outputDir := spout.FmtOutput(appName, k, endpoint.GetLongName(), endpoint.GetAttrs())
+			if other, exists := sources[outputDir]; exists {
+				return nil, nil, fmt.Errorf("the diagrams of %s and %s would both be written to %s",
+					other, source, outputDir)
+			}
+			sources[outputDir] = source
+
+			bbsAll := map[string]*Upto{}
+			TransformBlackboxesToUptos(bbsAll, TransformBlackBoxes(app.GetAttrs()["blackboxes"].GetA().GetElt()),
+				BBApplication)
+			TransformBlackboxesToUptos(bbsAll, TransformBlackBoxes(endpoint.GetAttrs()["blackboxes"].GetA().GetElt()),
+				BBEndpointCollection)
+			TransformBlackboxesToUptos(bbsAll, blackboxes, BBCommandLine)
+			sd := &SequenceDiagParam{
+				Endpoints:       []string{source},
+				AppLabeler:      spapp,
+				EndpointLabeler: spep,
+				Title: spseqtitle.FmtSeq(endpoint.GetName(), endpoint.GetLongName(),
+					diagrams.MergeAttributes(app.GetAttrs(), endpoint.GetAttrs())),
+				Blackboxes: bbsAll,
+				Group:      params.Group,
+				Mermaid:    diagrams.IsMermaidOutput(outputDir),
+				Depth:      params.Depth,
+			}
+			out, err := GenerateSequenceDiag(model, sd, logger)
+			if err != nil {
+				return nil, nil, err
+			}
+			result[outputDir] = out
+			generated = append(generated, AutoDiagram{AppName: appName, Endpoint: k, Output: outputDir})
+		}
+	}
+	return result, generated, nil
+}
+
+// WriteIndex writes a Markdown index of the diagrams generated by
+// DoConstructAutoSequenceDiagrams to path, linking to each diagram relative to
+// the index.
+func WriteIndex(path string, generated []AutoDiagram, fs afero.Fs) error {
+	var sb strings.Builder
+	sb.WriteString("# Sequence diagrams\n")
+	appName := ""
+	for _, d := range generated {
+		if d.AppName != appName {
+			appName = d.AppName
+			fmt.Fprintf(&sb, "\n## %s\n\n", appName)
+		}
+		link, err := filepath.Rel(filepath.Dir(path), diagrams.DiagramFile(d.Output))
+		if err != nil {
+			link = diagrams.DiagramFile(d.Output)
+		}
+		fmt.Fprintf(&sb, "- [%s](%s)\n", d.Endpoint, filepath.ToSlash(link))
+	}
+	if err := fs.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return afero.WriteFile(fs, path, []byte(sb.String()), os.ModePerm)
+}
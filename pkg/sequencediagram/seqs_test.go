@@ -1,19 +1,30 @@
-package main
+package sequencediagram
 
 import (
 	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/anz-bank/sysl/pkg/diagrams"
 	"github.com/anz-bank/sysl/pkg/parse"
+	sysl "github.com/anz-bank/sysl/pkg/sysl"
 	"github.com/anz-bank/sysl/pkg/syslutil"
 	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"gopkg.in/alecthomas/kingpin.v2"
 )
 
+const (
+	projDir = "../../"
+	testDir = "../../tests/"
+)
+
+// loadModule loads a model from testDir as sysl would with --root.
+func loadModule(root, filename string) (*sysl.Module, string, error) {
+	return parse.LoadAndGetDefaultApp(filename, syslutil.NewChrootFs(afero.NewOsFs(), root), parse.NewParser())
+}
+
 func TestGenerateSequenceDiagFail(t *testing.T) {
 	t.Parallel()
 
@@ -29,12 +40,12 @@ func TestGenerateSequenceDiag(t *testing.T) {
 		syslutil.NewChrootFs(afero.NewOsFs(), projDir))
 	require.NoError(t, err)
 	l := &labeler{}
-	p := &sequenceDiagParam{}
-	p.endpoints = []string{"WebFrontend <- RequestProfile"}
+	p := &SequenceDiagParam{}
+	p.Endpoints = []string{"WebFrontend <- RequestProfile"}
 	p.AppLabeler = l
 	p.EndpointLabeler = l
-	p.title = "Profile"
-	r, err := generateSequenceDiag(m, p, logger)
+	p.Title = "Profile"
+	r, err := GenerateSequenceDiag(m, p, logger)
 
 	expected := `''''''''''''''''''''''''''''''''''''''''''
 ''                                      ''
@@ -78,12 +89,12 @@ func TestGenerateSequenceDiag2(t *testing.T) {
 		syslutil.NewChrootFs(afero.NewOsFs(), projDir))
 	require.NoError(t, err)
 	l := &labeler{}
-	p := &sequenceDiagParam{}
-	p.endpoints = []string{"WebFrontend <- RequestProfile"}
+	p := &SequenceDiagParam{}
+	p.Endpoints = []string{"WebFrontend <- RequestProfile"}
 	p.AppLabeler = l
 	p.EndpointLabeler = l
-	p.title = "Profile"
-	r, err := generateSequenceDiag(m, p, logger)
+	p.Title = "Profile"
+	r, err := GenerateSequenceDiag(m, p, logger)
 
 	expected := `''''''''''''''''''''''''''''''''''''''''''
 ''                                      ''
@@ -128,14 +139,14 @@ func TestGenerateSequenceDiagramsToFormatNameAttributes(t *testing.T) {
 	m, err := parse.NewParser().Parse("sequence_diagram_name_format.sysl", fs)
 	require.NoError(t, err)
 	syslutil.AssertFsHasExactly(t, memFs)
-	al := MakeFormatParser(`%(@status?<color red>%(appname)</color>|%(appname))`)
-	el := MakeFormatParser(`%(@status? <color green>%(epname)</color>|%(epname))`)
-	p := &sequenceDiagParam{}
-	p.endpoints = []string{"User <- Check Balance"}
+	al := diagrams.MakeFormatParser(`%(@status?<color red>%(appname)</color>|%(appname))`)
+	el := diagrams.MakeFormatParser(`%(@status? <color green>%(epname)</color>|%(epname))`)
+	p := &SequenceDiagParam{}
+	p.Endpoints = []string{"User <- Check Balance"}
 	p.AppLabeler = al
 	p.EndpointLabeler = el
-	r, err := generateSequenceDiag(m, p, logger)
-	p.title = "Diagram"
+	r, err := GenerateSequenceDiag(m, p, logger)
+	p.Title = "Diagram"
 	expected := `''''''''''''''''''''''''''''''''''''''''''
 ''                                      ''
 ''  AUTOGENERATED CODE -- DO NOT EDIT!  ''
@@ -183,14 +194,14 @@ func TestGenerateSequenceDiagramsToFormatComplexAttributes(t *testing.T) {
 	m, err := parse.NewParser().Parse("sequence_diagram_name_format.sysl", fs)
 	require.NoError(t, err)
 	syslutil.AssertFsHasExactly(t, memFs)
-	al := MakeFormatParser(`%(@status?<color red>%(appname)</color>|%(appname))`)
-	el := MakeFormatParser(`%(@status? <color green>%(epname)</color>|%(epname))`)
-	p := &sequenceDiagParam{}
-	p.endpoints = []string{"User <- Check Balance"}
+	al := diagrams.MakeFormatParser(`%(@status?<color red>%(appname)</color>|%(appname))`)
+	el := diagrams.MakeFormatParser(`%(@status? <color green>%(epname)</color>|%(epname))`)
+	p := &SequenceDiagParam{}
+	p.Endpoints = []string{"User <- Check Balance"}
 	p.AppLabeler = al
 	p.EndpointLabeler = el
-	r, err := generateSequenceDiag(m, p, logger)
-	p.title = "Diagram"
+	r, err := GenerateSequenceDiag(m, p, logger)
+	p.Title = "Diagram"
 	expected := `''''''''''''''''''''''''''''''''''''''''''
 ''                                      ''
 ''  AUTOGENERATED CODE -- DO NOT EDIT!  ''
@@ -230,56 +241,6 @@ skinparam maxMessageSize 250
 	assert.Equal(t, expected, r)
 }
 
-type loadAppArgs struct {
-	root   string
-	models string
-}
-
-func TestLoadAppReturnError(t *testing.T) {
-	t.Parallel()
-
-	args := loadAppArgs{
-		"demo/simple/", "",
-	}
-	_, fs := syslutil.WriteToMemOverlayFs(args.root)
-	logger, _ := test.NewNullLogger()
-	_, _, err := LoadSyslModule(args.root, args.models, fs, logger)
-	assert.Error(t, err)
-}
-
-func TestLoadApp(t *testing.T) {
-	t.Parallel()
-
-	args := loadAppArgs{
-		testDir, "sequence_diagram_test.sysl",
-	}
-	memFs, fs := syslutil.WriteToMemOverlayFs("/")
-	logger, _ := test.NewNullLogger()
-	mod, name, err := LoadSyslModule(args.root, args.models, fs, logger)
-	require.NoError(t, err)
-	assert.NotNil(t, mod)
-	syslutil.AssertFsHasExactly(t, memFs)
-	apps := mod.GetApps()
-	app := apps["Database"]
-
-	assert.Equal(t, "Database", name)
-	assert.Equal(t, []string{"Database"}, app.GetName().GetPart())
-
-	appPatternsAttr := app.GetAttrs()["patterns"].GetA().GetElt()
-	patterns := make([]string, 0, len(appPatternsAttr))
-	for _, val := range appPatternsAttr {
-		patterns = append(patterns, val.GetS())
-	}
-	assert.Equal(t, []string{"db"}, patterns)
-
-	queryUserParams := app.GetEndpoints()["QueryUser"].GetParam()
-	params := make([]string, 0, len(queryUserParams))
-	for _, val := range queryUserParams {
-		params = append(params, val.GetName())
-	}
-	assert.Equal(t, []string{"user_id"}, params)
-}
-
 type sdArgs struct {
 	rootModel      string
 	endpointFormat string
@@ -341,12 +302,12 @@ func TestDoConstructSequenceDiagramsNoSyslSdFilters(t *testing.T) {
 	}
 
 	// When
-	assert.Panics(t, func() {
-		_, err := DoConstructSequenceDiagramsWithParams(args.rootModel, args.endpointFormat, args.appFormat,
-			args.title, args.output, args.modules, args.endpoints, args.apps, args.blackboxes,
-			args.groupbox)
-		assert.NoError(t, err)
-	})
+	_, err := DoConstructSequenceDiagramsWithParams(args.rootModel, args.endpointFormat, args.appFormat,
+		args.title, args.output, args.modules, args.endpoints, args.apps, args.blackboxes,
+		args.groupbox)
+
+	// Then
+	assert.EqualError(t, err, `app "" not found`)
 }
 
 func TestDoConstructSequenceDiagrams(t *testing.T) {
@@ -486,23 +447,6 @@ activate _0
 	assert.Equal(t, map[string]string{"Seq.png": expectContent}, result)
 }
 
-func TestDoGenerateSequenceDiagrams(t *testing.T) {
-	t.Parallel()
-
-	args := &sdArgs{
-		modules: "sequence_diagram_complex_format.sysl",
-		output:  "%(epname).png",
-		apps:    []string{"Project"},
-	}
-	argsData := []string{"sysl", "sd", "-o", args.output, "-a", args.apps[0], args.modules}
-	sysl := kingpin.New("sysl", "System Modelling Language Toolkit")
-	r := cmdRunner{}
-	assert.NoError(t, r.Configure(sysl))
-	selectedCommand, err := sysl.Parse(argsData[1:])
-	assert.Nil(t, err, "Cmd line parse failed for sysl sd")
-	assert.Equal(t, "sd", selectedCommand)
-}
-
 func TestDoConstructSequenceDiagramsWithParams(t *testing.T) {
 
 }
@@ -620,19 +564,162 @@ func DoConstructSequenceDiagramsWithParams(
 	group string,
 ) (map[string]string, error) {
 	logger, _ := test.NewNullLogger()
-	mod, _, err := LoadSyslModule(rootModel, modules, afero.NewOsFs(), logger)
+	mod, _, err := loadModule(rootModel, modules)
 	if err != nil {
 		return nil, err
 	}
-	cmdContextParamSeqgen := &CmdContextParamSeqgen{
-		endpointFormat: endpointFormat,
-		appFormat:      appFormat,
-		title:          title,
-		output:         output,
-		endpointsFlag:  endpoints,
-		appsFlag:       apps,
-		blackboxes:     blackboxes,
-		group:          group,
+	params := &Params{
+		EndpointFormat: endpointFormat,
+		AppFormat:      appFormat,
+		Title:          title,
+		Output:         output,
+		Endpoints:      endpoints,
+		Apps:           apps,
+		Blackboxes:     blackboxes,
+		Group:          group,
 	}
-	return DoConstructSequenceDiagrams(cmdContextParamSeqgen, mod, logger)
+	return DoConstructSequenceDiagrams(params, mod, logger)
+}
+
+func TestDoConstructMermaidSequenceDiagrams(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	args := &sdArgs{
+		rootModel:      testDir,
+		endpointFormat: "%(epname)",
+		appFormat:      "%(appname)",
+		modules:        "groupby.sysl",
+		output:         "%(epname).mmd",
+		apps:           []string{"Project :: Sequences"},
+	}
+
+	// When
+	result, err := DoConstructSequenceDiagramsWithParams(args.rootModel, args.endpointFormat, args.appFormat,
+		args.title, args.output, args.modules, args.endpoints, args.apps, args.blackboxes,
+		args.groupbox)
+	require.NoError(t, err)
+
+	// Then
+	expected := diagrams.MermaidHeader + `sequenceDiagram
+participant _caller as Client
+box LightBlue cloud
+	participant _0 as SystemApp_1
+	participant _1 as SystemApp_2
+end
+box LightBlue onpremise
+	participant _2 as ExternalApi
+	participant _3 as ExternalApi_1
+end
+Note over _0: SystemApp_1 #lt;- FooEndpoint
+_caller->>_0: FooEndpoint
+activate _0
+ _0->>_1: BarEndpoint
+ activate _1
+  _1->>_2: Endpoint
+  activate _2
+  _2->>_2: ...
+  deactivate _2
+  _1->>_3: GET /foo
+  activate _3
+  _3->>_3: ...
+  deactivate _3
+ opt value == one
+  _1->>_1: do something
+ end
+ rect rgb(240, 240, 240)
+ Note over _1: else if value == two
+  _1->>_1: do something else
+ end
+ rect rgb(240, 240, 240)
+ Note over _1: else
+  _1-->>_0: ok
+ end
+ deactivate _1
+deactivate _0
+`
+	assert.Equal(t, expected, result["SEQ-One.mmd"])
+	assert.NotContains(t, result["SEQ-Two.mmd"], "box")
+}
+
+func loadAutoSequenceDiagrams(t *testing.T, params *Params) (map[string]string, []AutoDiagram) {
+	logger, _ := test.NewNullLogger()
+	mod, _, err := loadModule(testDir, "sequence_diagram_auto.sysl")
+	require.NoError(t, err)
+	params.EndpointFormat = "%(epname)"
+	params.AppFormat = "%(appname)"
+	result, generated, err := DoConstructAutoSequenceDiagrams(params, mod, logger)
+	require.NoError(t, err)
+	return result, generated
+}
+
+func TestDoConstructAutoSequenceDiagrams(t *testing.T) {
+	t.Parallel()
+
+	result, generated := loadAutoSequenceDiagrams(t, &Params{Output: "%(appname)/%(epname).svg"})
+	assert.Equal(t, []AutoDiagram{
+		{AppName: "Api", Endpoint: "GetItems", Output: "Api/GetItems.svg"},
+		{AppName: "Db", Endpoint: "Query", Output: "Db/Query.svg"},
+		{AppName: "Web", Endpoint: "Browse", Output: "Web/Browse.svg"},
+	}, generated)
+	assert.Len(t, result, 3)
+	assert.Contains(t, result["Web/Browse.svg"], "== Web <- Browse ==")
+	assert.Contains(t, result["Web/Browse.svg"], ": Read")
+}
+
+func TestDoConstructAutoSequenceDiagramsWithDepth(t *testing.T) {
+	t.Parallel()
+
+	result, _ := loadAutoSequenceDiagrams(t, &Params{Output: "%(appname)/%(epname).svg", Depth: 1})
+	assert.Contains(t, result["Web/Browse.svg"], ": GetItems")
+	assert.NotContains(t, result["Web/Browse.svg"], ": Query")
+	assert.Contains(t, result["Api/GetItems.svg"], ": Query")
+	assert.NotContains(t, result["Api/GetItems.svg"], ": Read")
+}
+
+func TestDoConstructAutoSequenceDiagramsWithApps(t *testing.T) {
+	t.Parallel()
+
+	result, _ := loadAutoSequenceDiagrams(t, &Params{
+		Output:         "%(epname).svg",
+		Apps:           []string{"Api"},
+		BlackboxesFlag: map[string]string{"Db <- Query": "not drawn"},
+	})
+	assert.Len(t, result, 1)
+	assert.Contains(t, result["GetItems.svg"], "not drawn")
+	assert.NotContains(t, result["GetItems.svg"], ": Read")
+}
+
+func TestDoConstructAutoSequenceDiagramsWithClashingOutputs(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := test.NewNullLogger()
+	mod, _, err := loadModule(testDir, "sequence_diagram_auto.sysl")
+	require.NoError(t, err)
+	_, _, err = DoConstructAutoSequenceDiagrams(&Params{Output: "diagram.svg"}, mod, logger)
+	assert.EqualError(t, err, "the diagrams of Api <- GetItems and Db <- Query would both be written to diagram.svg")
+}
+
+func TestWriteIndex(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, WriteIndex("docs/index.md", []AutoDiagram{
+		{AppName: "Api", Endpoint: "GetItems", Output: "Api/GetItems.svg"},
+		{AppName: "Api", Endpoint: "Health", Output: "Api/Health.uml"},
+		{AppName: "Web", Endpoint: "Browse", Output: "Web/Browse.svg"},
+	}, fs))
+	index, err := afero.ReadFile(fs, "docs/index.md")
+	require.NoError(t, err)
+	assert.Equal(t, `# Sequence diagrams
+
+## Api
+
+- [GetItems](../Api/GetItems.svg)
+- [Health](../Api/Health.puml)
+
+## Web
+
+- [Browse](../Web/Browse.svg)
+`, string(index))
 }
@@ -1,9 +1,8 @@
-package main
+package sequencediagram
 
 import (
 	"fmt"
 	"regexp"
-	"sort"
 	"strings"
 
 	sysl "github.com/anz-bank/sysl/pkg/sysl"
@@ -12,7 +11,6 @@ import (
 
 //nolint:gochecknoglobals
 var (
-	isoCtrlRE                = regexp.MustCompile("^iso_ctrl_(.*)_txt$")
 	returnTypeValueSpliterRE = regexp.MustCompile(`\s*<:\s*`)
 	typeSetOfRE              = regexp.MustCompile(`set\s+of\s+(.+)$`)
 	typeOneOfRE              = regexp.MustCompile(`one\s+of\s*{(.+)}$`)
@@ -49,18 +47,6 @@ func ParseBlackBoxesFromArgument(blackboxFlags map[string]string) [][]string {
 	return bbs
 }
 
-func MergeAttributes(app, edpnt map[string]*sysl.Attribute) map[string]*sysl.Attribute {
-	result := map[string]*sysl.Attribute{}
-	for k, v := range app {
-		result[k] = v
-	}
-	for k, v := range edpnt {
-		result[k] = v
-	}
-
-	return result
-}
-
 func TransformBlackboxesToUptos(m map[string]*Upto, bbs [][]string, uptoType UptoType) {
 	for _, val := range bbs {
 		m[val[0]] = &Upto{
@@ -71,30 +57,6 @@ func TransformBlackboxesToUptos(m map[string]*Upto, bbs [][]string, uptoType Upt
 	}
 }
 
-func getApplicationAttrs(m *sysl.Module, appName string) map[string]*sysl.Attribute {
-	if app, ok := m.Apps[appName]; ok {
-		return app.Attrs
-	}
-	return nil
-}
-
-func getSortedISOCtrlSlice(attrs map[string]*sysl.Attribute) []string {
-	s := make([]string, 0, len(attrs))
-
-	for k := range attrs {
-		match := isoCtrlRE.FindStringSubmatch(k)
-		if len(match) > 1 {
-			s = append(s, match[1])
-		}
-	}
-	sort.Strings(s)
-	return s
-}
-
-func getSortedISOCtrlStr(attrs map[string]*sysl.Attribute) string {
-	return strings.Join(getSortedISOCtrlSlice(attrs), ", ")
-}
-
 func formatArgs(s *sysl.Module, appName, parameterTypeName string) string {
 	val := func(a *sysl.Attribute) string {
 		if s := a.GetS(); len(s) > 0 {
@@ -187,39 +149,6 @@ func formatReturnParam(s *sysl.Module, payload string) []string {
 	return rargs
 }
 
-func getReturnPayload(stmts []*sysl.Statement) string {
-	for _, v := range stmts {
-		var subStmts []*sysl.Statement
-		switch stmt := v.Stmt.(type) {
-		case *sysl.Statement_Call, *sysl.Statement_Action:
-			continue
-		case *sysl.Statement_Ret:
-			return stmt.Ret.GetPayload()
-		case *sysl.Statement_Alt:
-			for _, c := range stmt.Alt.Choice {
-				if p := getReturnPayload(c.Stmt); len(p) > 0 {
-					return p
-				}
-			}
-		case *sysl.Statement_Cond:
-			subStmts = stmt.Cond.Stmt
-		case *sysl.Statement_Loop:
-			subStmts = stmt.Loop.Stmt
-		case *sysl.Statement_LoopN:
-			subStmts = stmt.LoopN.Stmt
-		case *sysl.Statement_Foreach:
-			subStmts = stmt.Foreach.Stmt
-		case *sysl.Statement_Group:
-			subStmts = stmt.Group.Stmt
-		}
-
-		if p := getReturnPayload(subStmts); len(p) > 0 {
-			return p
-		}
-	}
-	return ""
-}
-
 func getAndFmtParam(s *sysl.Module, params []*sysl.Param) []string {
 	r := make([]string, 0, len(params))
 	for _, v := range params {
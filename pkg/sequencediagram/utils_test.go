@@ -1,4 +1,4 @@
-package main
+package sequencediagram
 
 import (
 	"reflect"
@@ -89,73 +89,6 @@ func TestParseBlackBoxesFromArgument(t *testing.T) {
 	}
 }
 
-func TestMergeAttributes(t *testing.T) {
-	t.Parallel()
-
-	type args struct {
-		app   map[string]*sysl.Attribute
-		edpnt map[string]*sysl.Attribute
-	}
-
-	appAttr := &sysl.Attribute{
-		Attribute: &sysl.Attribute_S{
-			S: "Value A",
-		},
-	}
-	appMap := map[string]*sysl.Attribute{
-		"app": appAttr,
-	}
-	epAttr := &sysl.Attribute{
-		Attribute: &sysl.Attribute_S{
-			S: "Value B",
-		},
-	}
-	epMap := map[string]*sysl.Attribute{
-		"ep": epAttr,
-	}
-	tests := []struct {
-		name string
-		args args
-		want map[string]*sysl.Attribute
-	}{
-		{
-			"Case-Null",
-			args{},
-			map[string]*sysl.Attribute{},
-		},
-		{
-			"Case-Merge app",
-			args{appMap, map[string]*sysl.Attribute{}},
-			map[string]*sysl.Attribute{
-				"app": appAttr,
-			},
-		},
-		{
-			"Case-Merge ep",
-			args{map[string]*sysl.Attribute{}, epMap},
-			map[string]*sysl.Attribute{
-				"ep": epAttr,
-			},
-		},
-		{
-			"Case-Merge app and ep",
-			args{appMap, epMap},
-			map[string]*sysl.Attribute{
-				"app": appAttr,
-				"ep":  epAttr,
-			},
-		},
-	}
-	for _, tt := range tests {
-		tt := tt
-		t.Run(tt.name, func(t *testing.T) {
-			if got := MergeAttributes(tt.args.app, tt.args.edpnt); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("MergeAttributes() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 func TestTransformBlackboxesToUptos(t *testing.T) {
 	t.Parallel()
 
@@ -186,101 +119,6 @@ func TestTransformBlackboxesToUptosByNil(t *testing.T) {
 	assert.Empty(t, bbs)
 }
 
-func TestGetAppAttr(t *testing.T) {
-	t.Parallel()
-
-	// given
-	attr := map[string]*sysl.Attribute{
-		"attr1": {},
-	}
-	m := &sysl.Module{
-		Apps: map[string]*sysl.Application{
-			"test": {Attrs: attr},
-		},
-	}
-
-	// when
-	actual := getApplicationAttrs(m, "test")
-
-	// then
-	assert.Equal(t, attr, actual)
-}
-
-func TestGetAppAttrWhenAppNotExist(t *testing.T) {
-	t.Parallel()
-
-	// given
-	m := &sysl.Module{
-		Apps: make(map[string]*sysl.Application),
-	}
-
-	// when
-	actual := getApplicationAttrs(m, "test")
-
-	// then
-	assert.Nil(t, actual)
-}
-
-func TestSortedISOCtrlSlice(t *testing.T) {
-	t.Parallel()
-
-	// given
-	attrs := map[string]*sysl.Attribute{
-		"iso_ctrl_11_txt": {},
-		"iso_ctrl_12_txt": {},
-		"iso_ctrl_5_txt":  {},
-	}
-
-	// when
-	actual := getSortedISOCtrlSlice(attrs)
-
-	// then
-	assert.Equal(t, []string{"11", "12", "5"}, actual)
-}
-
-func TestSortedISOCtrlSliceEmpty(t *testing.T) {
-	t.Parallel()
-
-	// given
-	attrs := make(map[string]*sysl.Attribute)
-
-	// when
-	actual := getSortedISOCtrlSlice(attrs)
-
-	// then
-	assert.Equal(t, []string{}, actual)
-}
-
-func TestSortedISOCtrlStr(t *testing.T) {
-	t.Parallel()
-
-	// given
-	attrs := map[string]*sysl.Attribute{
-		"iso_ctrl_11_txt": {},
-		"iso_ctrl_12_txt": {},
-		"iso_ctrl_5_txt":  {},
-	}
-
-	// when
-	actual := getSortedISOCtrlStr(attrs)
-
-	// then
-	assert.Equal(t, "11, 12, 5", actual)
-}
-
-func TestSortedISOCtrlStrEmpty(t *testing.T) {
-	t.Parallel()
-
-	// given
-	attrs := make(map[string]*sysl.Attribute)
-
-	// when
-	actual := getSortedISOCtrlStr(attrs)
-
-	// then
-	assert.Equal(t, "", actual)
-}
-
 func TestFormatArgs(t *testing.T) {
 	t.Parallel()
 
@@ -510,201 +348,6 @@ func TestFormatReturnParamSplit(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
-func TestGetReturnPayload(t *testing.T) {
-	t.Parallel()
-
-	stmts := []*sysl.Statement{
-		{
-			Stmt: &sysl.Statement_Call{},
-		},
-		{
-			Stmt: &sysl.Statement_Action{},
-		},
-		{
-			Stmt: &sysl.Statement_Ret{
-				Ret: &sysl.Return{
-					Payload: "test",
-				},
-			},
-		},
-	}
-
-	actual := getReturnPayload(stmts)
-
-	assert.Equal(t, "test", actual)
-}
-
-func TestGetReturnPayloadWithAlt(t *testing.T) {
-	t.Parallel()
-
-	stmts := []*sysl.Statement{
-		{
-			Stmt: &sysl.Statement_Alt{
-				Alt: &sysl.Alt{
-					Choice: []*sysl.Alt_Choice{
-						{
-							Cond: "cond 1",
-							Stmt: []*sysl.Statement{},
-						},
-						{
-							Cond: "cond 2",
-							Stmt: []*sysl.Statement{
-								{
-									Stmt: &sysl.Statement_Ret{
-										Ret: &sysl.Return{
-											Payload: "test",
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	actual := getReturnPayload(stmts)
-
-	assert.Equal(t, "test", actual)
-}
-
-func TestGetReturnPayloadWithCond(t *testing.T) {
-	t.Parallel()
-
-	stmts := []*sysl.Statement{
-		{
-			Stmt: &sysl.Statement_Cond{
-				Cond: &sysl.Cond{
-					Test: "cond 1",
-					Stmt: []*sysl.Statement{
-						{
-							Stmt: &sysl.Statement_Ret{
-								Ret: &sysl.Return{
-									Payload: "test",
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	actual := getReturnPayload(stmts)
-
-	assert.Equal(t, "test", actual)
-}
-
-func TestGetReturnPayloadWithLoop(t *testing.T) {
-	t.Parallel()
-
-	stmts := []*sysl.Statement{
-		{
-			Stmt: &sysl.Statement_Loop{
-				Loop: &sysl.Loop{
-					Mode:      sysl.Loop_WHILE,
-					Criterion: "criterion",
-					Stmt: []*sysl.Statement{
-						{
-							Stmt: &sysl.Statement_Ret{
-								Ret: &sysl.Return{
-									Payload: "test",
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	actual := getReturnPayload(stmts)
-
-	assert.Equal(t, "test", actual)
-}
-
-func TestGetReturnPayloadWithLoopN(t *testing.T) {
-	t.Parallel()
-
-	stmts := []*sysl.Statement{
-		{
-			Stmt: &sysl.Statement_LoopN{
-				LoopN: &sysl.LoopN{
-					Count: 10,
-					Stmt: []*sysl.Statement{
-						{
-							Stmt: &sysl.Statement_Ret{
-								Ret: &sysl.Return{
-									Payload: "test",
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	actual := getReturnPayload(stmts)
-
-	assert.Equal(t, "test", actual)
-}
-
-func TestGetReturnPayloadWithForeach(t *testing.T) {
-	t.Parallel()
-
-	stmts := []*sysl.Statement{
-		{
-			Stmt: &sysl.Statement_Foreach{
-				Foreach: &sysl.Foreach{
-					Collection: "collection 1",
-					Stmt: []*sysl.Statement{
-						{
-							Stmt: &sysl.Statement_Ret{
-								Ret: &sysl.Return{
-									Payload: "test",
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	actual := getReturnPayload(stmts)
-
-	assert.Equal(t, "test", actual)
-}
-
-func TestGetReturnPayloadWithGroup(t *testing.T) {
-	t.Parallel()
-
-	stmts := []*sysl.Statement{
-		{
-			Stmt: &sysl.Statement_Group{
-				Group: &sysl.Group{
-					Title: "group 1",
-					Stmt: []*sysl.Statement{
-						{
-							Stmt: &sysl.Statement_Ret{
-								Ret: &sysl.Return{
-									Payload: "test",
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	actual := getReturnPayload(stmts)
-
-	assert.Equal(t, "test", actual)
-}
-
 func TestGetAndFmtParam(t *testing.T) {
 	t.Parallel()
 
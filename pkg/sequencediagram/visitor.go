@@ -1,15 +1,29 @@
-package main
+package sequencediagram
 
 import (
 	"fmt"
 	"sort"
 	"strings"
 
+	"github.com/anz-bank/sysl/pkg/diagrams"
 	sysl "github.com/anz-bank/sysl/pkg/sysl"
 	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
+// mermaidCaller is the participant that stands in for PlantUML's "[" when an
+// endpoint is called from outside the diagram.
+const mermaidCaller = "_caller"
+
+type Visitor interface {
+	Visit(Element) error
+}
+
+type Element interface {
+	Accept(Visitor) error
+}
+
 type entry struct {
 	appName      string
 	endpointName string
@@ -109,7 +123,7 @@ func (e *EndpointElement) Accept(v Visitor) error {
 	return v.Visit(e)
 }
 
-func (e *EndpointElement) sender(v VarManager) string {
+func (e *EndpointElement) sender(v diagrams.VarManager) string {
 	if e.fromApp != nil {
 		return v.UniqueVarForAppName(syslutil.GetAppName(e.fromApp))
 	}
@@ -117,26 +131,26 @@ func (e *EndpointElement) sender(v VarManager) string {
 	return "["
 }
 
-func (e *EndpointElement) agent(v VarManager) string {
+func (e *EndpointElement) agent(v diagrams.VarManager) string {
 	return v.UniqueVarForAppName(e.appName)
 }
 
-func (e *EndpointElement) application(m *sysl.Module) *sysl.Application {
+func (e *EndpointElement) application(m *sysl.Module) (*sysl.Application, error) {
 	if app, ok := m.Apps[e.appName]; ok {
-		return app
+		return app, nil
 	}
-	panic(fmt.Sprintf("app %#v not found", e.appName))
+	return nil, errors.Errorf("app %#v not found", e.appName)
 }
 
-func (e *EndpointElement) endpoint(a *sysl.Application) *sysl.Endpoint {
+func (e *EndpointElement) endpoint(a *sysl.Application) (*sysl.Endpoint, error) {
 	if ep, ok := a.Endpoints[e.endpointName]; ok {
-		return ep
+		return ep, nil
 	}
-	panic(fmt.Sprintf("endpoint %#v not found in app %#v", e.endpointName, e.appName))
+	return nil, errors.Errorf("endpoint %#v not found in app %#v", e.endpointName, e.appName)
 }
 
 func (e *EndpointElement) label(
-	l EndpointLabeler,
+	l diagrams.EndpointLabeler,
 	m *sysl.Module,
 	ep *sysl.Endpoint,
 	epp syslutil.StrSet,
@@ -152,7 +166,7 @@ func (e *EndpointElement) label(
 			return ""
 		}(e.senderEndpointPatterns, epp)
 
-		isoctrl := getSortedISOCtrlStr(ep.Attrs)
+		isoctrl := diagrams.GetSortedISOCtrlStr(ep.Attrs)
 		epargs := getAndFmtParam(m, ep.Param)
 
 		str := func(t bool, v string) string {
@@ -162,7 +176,7 @@ func (e *EndpointElement) label(
 			return ""
 		}
 
-		param := &EndpointLabelerParam{
+		param := &diagrams.EndpointLabelerParam{
 			EndpointName: label,
 			Human:        str(isHuman, "human"),
 			HumanSender:  str(isHumanSender, "human sender"),
@@ -194,21 +208,26 @@ func (e *StatementElement) isLastStmt(i int) bool {
 }
 
 type SequenceDiagramVisitor struct {
-	AppLabeler
-	EndpointLabeler
+	diagrams.AppLabeler
+	diagrams.EndpointLabeler
 	w          *SequenceDiagramWriter
 	m          *sysl.Module
 	visited    map[string]int
-	symbols    map[string]*_var
+	symbols    map[string]*diagrams.Var
 	currentApp string
 	groupby    string
 	groupboxes map[string]syslutil.StrSet
 	logger     *logrus.Logger
+	callerUsed bool
+	// maxDepth limits how many levels of calls are followed, if positive.
+	// Endpoints any deeper are drawn as calls only, like blackboxes.
+	maxDepth int
+	depth    int
 }
 
 func MakeSequenceDiagramVisitor(
-	a AppLabeler,
-	e EndpointLabeler,
+	a diagrams.AppLabeler,
+	e diagrams.EndpointLabeler,
 	w *SequenceDiagramWriter,
 	m *sysl.Module,
 	appName string,
@@ -221,7 +240,7 @@ func MakeSequenceDiagramVisitor(
 		w:               w,
 		m:               m,
 		visited:         make(map[string]int),
-		symbols:         make(map[string]*_var),
+		symbols:         make(map[string]*diagrams.Var),
 		currentApp:      appName,
 		groupby:         group,
 		groupboxes:      map[string]syslutil.StrSet{},
@@ -249,28 +268,28 @@ func (v *SequenceDiagramVisitor) Visit(e Element) error {
 
 func (v *SequenceDiagramVisitor) UniqueVarForAppName(appName string) string {
 	if s, ok := v.symbols[appName]; ok {
-		return s.alias
+		return s.Alias
 	}
 
 	i := len(v.symbols)
 	alias := fmt.Sprintf("_%d", i)
-	attrs := getApplicationAttrs(v.m, appName)
-	controls := getSortedISOCtrlStr(attrs)
+	attrs := diagrams.GetApplicationAttrs(v.m, appName)
+	controls := diagrams.GetSortedISOCtrlStr(attrs)
 	label := v.LabelApp(appName, controls, attrs)
-	s := &_var{
-		agent: makeAgent(attrs),
-		order: i,
-		label: label,
-		alias: alias,
+	s := &diagrams.Var{
+		Agent: diagrams.MakeAgent(attrs),
+		Order: i,
+		Label: label,
+		Alias: alias,
 	}
 	v.symbols[appName] = s
 
-	return s.alias
+	return s.Alias
 }
 
 func (v *SequenceDiagramVisitor) visitEndpointCollection(e *EndpointCollectionElement) error {
 	if len(e.title) > 0 {
-		fmt.Fprintln(v.w, "title", e.title)
+		fmt.Fprintln(v.w, "title", v.text(e.title))
 	}
 
 	for _, entry := range e.entries {
@@ -281,7 +300,12 @@ func (v *SequenceDiagramVisitor) visitEndpointCollection(e *EndpointCollectionEl
 		}
 		allUptos.Insert(entry.upto)
 
-		fmt.Fprintf(v.w, "== %s <- %s ==\n", entry.appName, entry.endpointName)
+		if v.w.mermaid {
+			fmt.Fprintf(v.w, "Note over %s: %s\n",
+				v.UniqueVarForAppName(entry.appName), v.text(entry.appName+" <- "+entry.endpointName))
+		} else {
+			fmt.Fprintf(v.w, "== %s <- %s ==\n", entry.appName, entry.endpointName)
+		}
 
 		visiting := fmt.Sprintf("%s <- %s", entry.appName, entry.endpointName)
 		delete(allUptos, visiting)
@@ -307,16 +331,19 @@ func (v *SequenceDiagramVisitor) visitEndpointCollection(e *EndpointCollectionEl
 		}
 	}
 
-	s := make([]*_var, 0, len(v.symbols))
+	s := make([]*diagrams.Var, 0, len(v.symbols))
 	for _, item := range v.symbols {
 		s = append(s, item)
 	}
 	sort.Slice(s, func(i, j int) bool {
-		if s[i].category == s[j].category {
-			return s[i].order < s[j].order
+		if s[i].Category == s[j].Category {
+			return s[i].Order < s[j].Order
 		}
-		return s[i].category < s[j].category
+		return s[i].Category < s[j].Category
 	})
+	if v.w.mermaid {
+		return v.writeMermaidParticipants(s)
+	}
 	for _, item := range s {
 		if _, err := v.w.WriteHead(item.String()); err != nil {
 			return err
@@ -325,11 +352,90 @@ func (v *SequenceDiagramVisitor) visitEndpointCollection(e *EndpointCollectionEl
 	return nil
 }
 
+// writeMermaidParticipants declares the participants of a Mermaid diagram.
+// Unlike PlantUML, Mermaid boxes must enclose the declarations themselves.
+func (v *SequenceDiagramVisitor) writeMermaidParticipants(s []*diagrams.Var) error {
+	head := []string{}
+	if v.callerUsed {
+		head = append(head, fmt.Sprintf("participant %s as Client", mermaidCaller))
+	}
+
+	boxed := map[string]string{}
+	boxnames := make([]string, 0, len(v.groupboxes))
+	for boxname, appset := range v.groupboxes {
+		boxnames = append(boxnames, boxname)
+		for appName := range appset {
+			boxed[v.UniqueVarForAppName(appName)] = boxname
+		}
+	}
+	sort.Strings(boxnames)
+	for _, boxname := range boxnames {
+		head = append(head, "box LightBlue "+v.text(boxname))
+		for _, item := range s {
+			if boxed[item.Alias] == boxname {
+				head = append(head, "\t"+item.MermaidString())
+			}
+		}
+		head = append(head, "end")
+	}
+	for _, item := range s {
+		if _, has := boxed[item.Alias]; !has {
+			head = append(head, item.MermaidString())
+		}
+	}
+
+	for _, line := range head {
+		if _, err := v.w.WriteHead(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// participant returns the name of a message end in the diagram syntax.
+func (v *SequenceDiagramVisitor) participant(name string) string {
+	if v.w.mermaid && name == "[" {
+		v.callerUsed = true
+		return mermaidCaller
+	}
+	return name
+}
+
+// text escapes free text for the diagram syntax.
+func (v *SequenceDiagramVisitor) text(s string) string {
+	if v.w.mermaid {
+		return diagrams.MermaidText(s)
+	}
+	return s
+}
+
+func (v *SequenceDiagramVisitor) writeCall(from, to, label string) {
+	if v.w.mermaid {
+		fmt.Fprintf(v.w, "%s->>%s: %s\n", v.participant(from), to, v.text(label))
+	} else {
+		fmt.Fprintf(v.w, "%s->%s : %s\n", from, to, label)
+	}
+}
+
+func (v *SequenceDiagramVisitor) writeReturn(to, from, payload string) {
+	if v.w.mermaid {
+		fmt.Fprintf(v.w, "%s-->>%s: %s\n", from, v.participant(to), v.text(payload))
+	} else {
+		fmt.Fprintf(v.w, "%s<--%s : %s\n", to, from, payload)
+	}
+}
+
 func (v *SequenceDiagramVisitor) visitEndpoint(e *EndpointElement) error {
 	sender := e.sender(v)
 	agent := e.agent(v)
-	app := e.application(v.m)
-	endpoint := e.endpoint(app)
+	app, err := e.application(v.m)
+	if err != nil {
+		return err
+	}
+	endpoint, err := e.endpoint(app)
+	if err != nil {
+		return err
+	}
 
 	appPatterns := syslutil.MakeStrSetFromAttr("patterns", app.Attrs)
 	endPointPatterns := syslutil.MakeStrSetFromAttr("patterns", endpoint.Attrs)
@@ -352,16 +458,16 @@ func (v *SequenceDiagramVisitor) visitEndpoint(e *EndpointElement) error {
 	if !((isHuman && sender == "[") || isCron) {
 		label := e.label(v, v.m, endpoint, endPointPatterns, isHuman, isHumanSender, needsInt)
 		icon := func(a syslutil.StrSet) string {
-			if a.Contains("cron") {
+			if a.Contains("cron") && !v.w.mermaid {
 				return "<&timer>"
 			}
 			return ""
 		}(endPointPatterns)
 
-		fmt.Fprintf(v.w, "%s->%s : %s%s\n", sender, agent, icon, label)
+		v.writeCall(sender, agent, icon+label)
 	}
 
-	payload := strings.Join(formatReturnParam(v.m, getReturnPayload(endpoint.Stmt)), " | ")
+	payload := strings.Join(formatReturnParam(v.m, diagrams.GetReturnPayload(endpoint.Stmt)), " | ")
 
 	isCallingSelf := e.fromApp != nil && syslutil.GetAppName(e.fromApp) == e.appName
 
@@ -376,29 +482,39 @@ func (v *SequenceDiagramVisitor) visitEndpoint(e *EndpointElement) error {
 			e.uptos[visiting].VisitCount++
 		}
 		_, hitVisited := v.visited[visiting]
+		tooDeep := v.maxDepth > 0 && v.depth >= v.maxDepth
 
-		if hitUpto || hitVisited {
+		if hitUpto || hitVisited || tooDeep {
 			if upto != nil {
 				if len(payload) > 0 {
 					v.w.Activate(agent)
 					if len(upto.Comment) > 0 {
-						fmt.Fprintf(v.w, "note over %s: %s\n", agent, upto.Comment)
+						if v.w.mermaid {
+							fmt.Fprintf(v.w, "Note over %s: %s\n", agent, v.text(upto.Comment))
+						} else {
+							fmt.Fprintf(v.w, "note over %s: %s\n", agent, upto.Comment)
+						}
 					}
 				} else {
 					direct := "right"
 					if sender > agent {
 						direct = "left"
 					}
-					fmt.Fprintf(v.w, "note %s: %s\n", direct, upto.Comment)
+					if v.w.mermaid {
+						fmt.Fprintf(v.w, "Note %s of %s: %s\n", direct, agent, v.text(upto.Comment))
+					} else {
+						fmt.Fprintf(v.w, "note %s: %s\n", direct, upto.Comment)
+					}
 				}
 			}
 			if len(payload) > 0 {
-				fmt.Fprintf(v.w, "%s<--%s : %s\n", sender, agent, payload)
+				v.writeReturn(sender, agent, payload)
 				v.w.Deactivate(agent)
 			}
 		} else {
 			deactivate := v.w.Activated(agent, isHuman || isCron)
 			v.visited[visiting]++
+			v.depth++
 
 			p := &StatementElement{
 				EndpointElement:  *e,
@@ -411,6 +527,7 @@ func (v *SequenceDiagramVisitor) visitEndpoint(e *EndpointElement) error {
 			}
 
 			deactivate()
+			v.depth--
 			v.visited[visiting]--
 			if v.visited[visiting] == 0 {
 				delete(v.visited, visiting)
@@ -454,10 +571,17 @@ func (v *SequenceDiagramVisitor) visitStatment(e *StatementElement) error {
 
 func (v *SequenceDiagramVisitor) visitCall(e *StatementElement, i int, c *sysl.Call) error {
 	isLastStmt := e.isLastStmt(i)
-	app := e.application(v.m)
+	app, err := e.application(v.m)
+	if err != nil {
+		return err
+	}
+	endpoint, err := e.endpoint(app)
+	if err != nil {
+		return err
+	}
 	stmtPatterns := syslutil.MakeStrSetFromAttr("patterns", e.stmts[i].Attrs)
 	senderPatterns := syslutil.MakeStrSetFromAttr("patterns", app.Attrs)
-	endpointPatterns := syslutil.MakeStrSetFromAttr("patterns", e.endpoint(app).Attrs)
+	endpointPatterns := syslutil.MakeStrSetFromAttr("patterns", endpoint.Attrs)
 
 	p := &EndpointElement{
 		fromApp:                app.GetName(),
@@ -482,6 +606,10 @@ func (v *SequenceDiagramVisitor) visitCall(e *StatementElement, i int, c *sysl.C
 }
 
 func (v *SequenceDiagramVisitor) visitAction(e *StatementElement, c *sysl.Action) error {
+	if v.w.mermaid {
+		_, err := fmt.Fprintf(v.w, "%s->>%s: %s\n", e.agent(v), e.agent(v), v.text(c.GetAction()))
+		return err
+	}
 	_, err := fmt.Fprintf(v.w, "%s -> %s : %s\n", e.agent(v), e.agent(v), c.GetAction())
 	return err
 }
@@ -506,6 +634,11 @@ func (v *SequenceDiagramVisitor) visitForeach(e *StatementElement, i int, c *sys
 }
 
 func (v *SequenceDiagramVisitor) visitGroup(e *StatementElement, i int, c *sysl.Group) error {
+	if v.w.mermaid {
+		return v.visitGroupStmt(
+			e, c.GetStmt(), e.isLastStmt(i), "rect rgb(240, 240, 240)\nNote over %s: %s\n", e.agent(v), c.GetTitle(),
+		)
+	}
 	return v.visitGroupStmt(e, c.GetStmt(), e.isLastStmt(i), "group %s\n", c.GetTitle())
 }
 
@@ -525,8 +658,8 @@ func (v *SequenceDiagramVisitor) visitAlt(e *StatementElement, i int, c *sysl.Al
 
 func (v *SequenceDiagramVisitor) visitRet(e *StatementElement, c *sysl.Return) error {
 	rargs := formatReturnParam(v.m, c.GetPayload())
-	_, err := fmt.Fprintf(v.w, "%s<--%s : %s\n", e.sender(v), e.agent(v), strings.Join(rargs, " | "))
-	return err
+	v.writeReturn(e.sender(v), e.agent(v), strings.Join(rargs, " | "))
+	return nil
 }
 
 func (v *SequenceDiagramVisitor) visitBlockStmt(
@@ -536,6 +669,13 @@ func (v *SequenceDiagramVisitor) visitBlockStmt(
 	fmtStr string,
 	args ...interface{},
 ) error {
+	if v.w.mermaid {
+		for i, arg := range args {
+			if s, ok := arg.(string); ok {
+				args[i] = v.text(s)
+			}
+		}
+	}
 	fmt.Fprintf(v.w, fmtStr, args...)
 	v.w.Indent()
 	p := &StatementElement{
@@ -564,42 +704,3 @@ func (v *SequenceDiagramVisitor) visitGroupStmt(
 	_, err := fmt.Fprintln(v.w, "end")
 	return err
 }
-
-type agent struct {
-	category int
-	name     string
-}
-
-//nolint:gochecknoglobals
-var agents = map[string]agent{
-	"human":    {0, "actor"},
-	"ui":       {1, "boundary"},
-	"cron":     {2, "control"},
-	"db":       {4, "database"},
-	"external": {5, "control"},
-}
-
-func makeAgent(attrs map[string]*sysl.Attribute) agent {
-	if patterns, ok := attrs["patterns"]; ok {
-		if x := patterns.GetA(); x != nil {
-			for _, y := range x.Elt {
-				if v, ok := agents[y.GetS()]; ok {
-					return v
-				}
-			}
-		}
-	}
-
-	return agent{3, "control"}
-}
-
-type _var struct {
-	agent
-	order int
-	label string
-	alias string
-}
-
-func (s _var) String() string {
-	return fmt.Sprintf(`%s "%s" as %s`, s.name, s.label, s.alias)
-}
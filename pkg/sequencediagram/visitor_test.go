@@ -1,10 +1,11 @@
-package main
+package sequencediagram
 
 import (
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/anz-bank/sysl/pkg/diagrams"
 	sysl "github.com/anz-bank/sysl/pkg/sysl"
 	"github.com/anz-bank/sysl/pkg/syslutil"
 	"github.com/golang/protobuf/jsonpb"
@@ -141,12 +142,13 @@ func TestEndpointElementApplication(t *testing.T) {
 		appName: "test",
 	}
 
-	s := e.application(m)
+	s, err := e.application(m)
 
+	require.NoError(t, err)
 	assert.NotNil(t, s)
 }
 
-func TestEndpointElementApplicationPanic(t *testing.T) {
+func TestEndpointElementApplicationNotFound(t *testing.T) {
 	t.Parallel()
 
 	m := &sysl.Module{}
@@ -154,7 +156,8 @@ func TestEndpointElementApplicationPanic(t *testing.T) {
 		appName: "test",
 	}
 
-	assert.Panics(t, func() { e.application(m) })
+	_, err := e.application(m)
+	assert.EqualError(t, err, `app "test" not found`)
 }
 
 func TestEndpointElementEndpoint(t *testing.T) {
@@ -169,12 +172,13 @@ func TestEndpointElementEndpoint(t *testing.T) {
 		endpointName: "test",
 	}
 
-	s := e.endpoint(m)
+	s, err := e.endpoint(m)
 
+	require.NoError(t, err)
 	assert.NotNil(t, s)
 }
 
-func TestEndpointElementEndpointPanic(t *testing.T) {
+func TestEndpointElementEndpointNotFound(t *testing.T) {
 	t.Parallel()
 
 	m := &sysl.Application{}
@@ -182,14 +186,15 @@ func TestEndpointElementEndpointPanic(t *testing.T) {
 		endpointName: "test",
 	}
 
-	assert.Panics(t, func() { e.endpoint(m) })
+	_, err := e.endpoint(m)
+	assert.EqualError(t, err, `endpoint "test" not found in app ""`)
 }
 
 type mockEndpointLabeler struct {
 	mock.Mock
 }
 
-func (m *mockEndpointLabeler) LabelEndpoint(p *EndpointLabelerParam) string {
+func (m *mockEndpointLabeler) LabelEndpoint(p *diagrams.EndpointLabelerParam) string {
 	args := m.Called(p)
 
 	return args.String(0)
@@ -290,7 +295,7 @@ func (l *labeler) LabelApp(appName, controls string, attrs map[string]*sysl.Attr
 	return appName
 }
 
-func (l *labeler) LabelEndpoint(p *EndpointLabelerParam) string {
+func (l *labeler) LabelEndpoint(p *diagrams.EndpointLabelerParam) string {
 	return p.EndpointName
 }
 
@@ -358,8 +363,8 @@ func TestSequenceDiagramToFormatNameAttributesVisitorVisit(t *testing.T) {
 	t.Parallel()
 
 	logger, _ := test.NewNullLogger()
-	al := MakeFormatParser(`%(@status?<color red>%(appname)</color>|%(appname))`)
-	el := MakeFormatParser(`%(@status? <color green>%(epname)</color>|%(epname))`)
+	al := diagrams.MakeFormatParser(`%(@status?<color red>%(appname)</color>|%(appname))`)
+	el := diagrams.MakeFormatParser(`%(@status? <color green>%(epname)</color>|%(epname))`)
 	w := MakeSequenceDiagramWriter(true, "skinparam maxMessageSize 250")
 	m, err := readModule(filepath.Join(testDir, "sequence_diagram_name_format.golden.json"))
 	require.NoError(t, err)
@@ -1,16 +1,19 @@
-package main
+package sequencediagram
 
 import (
 	"bytes"
 	"fmt"
 	"io"
 	"strings"
+
+	"github.com/anz-bank/sysl/pkg/diagrams"
 )
 
 type SequenceDiagramWriter struct {
 	ind            int
 	atBeginOfLine  bool
 	autogenWarning bool
+	mermaid        bool
 	active         map[string]int
 	properties     []string
 	head           bytes.Buffer
@@ -28,6 +31,14 @@ func MakeSequenceDiagramWriter(autogenWarning bool, properties ...string) *Seque
 	}
 }
 
+// MakeMermaidSequenceDiagramWriter returns a writer for a Mermaid sequenceDiagram
+// rather than a PlantUML one.
+func MakeMermaidSequenceDiagramWriter(autogenWarning bool) *SequenceDiagramWriter {
+	w := MakeSequenceDiagramWriter(autogenWarning)
+	w.mermaid = true
+	return w
+}
+
 func (s *SequenceDiagramWriter) WriteTo(w io.Writer) (n int64, err error) {
 	i, err := fmt.Fprint(w, s.String())
 
@@ -152,6 +163,15 @@ func (s *SequenceDiagramWriter) String() string {
 	}
 
 	var sb strings.Builder
+	if s.mermaid {
+		if s.autogenWarning {
+			sb.WriteString(diagrams.MermaidHeader)
+		}
+		fmt.Fprintln(&sb, "sequenceDiagram")
+		sb.WriteString(s.head.String())
+		sb.WriteString(s.body.String())
+		return sb.String()
+	}
 	if s.autogenWarning {
 		fmt.Fprintln(&sb, "''''''''''''''''''''''''''''''''''''''''''")
 		fmt.Fprintln(&sb, "''                                      ''")
@@ -1,9 +1,10 @@
-package main
+package sequencediagram
 
 import (
 	"bytes"
 	"testing"
 
+	"github.com/anz-bank/sysl/pkg/diagrams"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -350,3 +351,25 @@ func TestWriteTo(t *testing.T) {
 	assert.Equal(t, int64(41), n)
 	assert.Equal(t, "@startuml\nhead\nproperties 1\nbody\n@enduml\n", b.String())
 }
+
+func TestMermaidStringer(t *testing.T) {
+	t.Parallel()
+
+	// Given
+	w := MakeMermaidSequenceDiagramWriter(true)
+	_, err := w.WriteHead("participant _0 as A")
+	require.NoError(t, err)
+	w.Activate("_0")
+	w.Deactivate("_0")
+
+	// When
+	s := w.String()
+
+	// Then
+	expected := diagrams.MermaidHeader + `sequenceDiagram
+participant _0 as A
+activate _0
+deactivate _0
+`
+	assert.Equal(t, expected, s)
+}
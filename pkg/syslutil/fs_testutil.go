@@ -76,6 +76,21 @@ func BuildFolderTest(t *testing.T, fs afero.Fs, folders, files []string) {
 	}
 }
 
+// WriteFiles writes the text of each of the files, keyed on its path under dir,
+// creating the directories they are in.
+func WriteFiles(fs afero.Fs, dir string, files map[string]string) error {
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		if err := fs.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return err
+		}
+		if err := afero.WriteFile(fs, path, []byte(text), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func HandleCRLF(text []byte) []byte {
 	if runtime.GOOS == "windows" {
 		re := regexp.MustCompile("(\r\n|\r)")
@@ -1,6 +1,8 @@
 package syslutil
 
 import (
+	"strings"
+
 	sysl "github.com/anz-bank/sysl/pkg/sysl"
 )
 
@@ -43,6 +45,38 @@ func GetTypeDetail(t *sysl.Type) (typeName string, typeDetail string) {
 	return typeName, typeDetail
 }
 
+// FormatType returns the type as it would be written in a sysl specification
+func FormatType(t *sysl.Type) string {
+	switch x := t.GetType().(type) {
+	case *sysl.Type_Primitive_:
+		return strings.ToLower(x.Primitive.String())
+	case *sysl.Type_TypeRef:
+		var parts []string
+		if ref := x.TypeRef.GetRef(); ref != nil {
+			parts = append(parts, ref.GetAppname().GetPart()...)
+			parts = append(parts, ref.GetPath()...)
+		}
+		return strings.Join(parts, ".")
+	case *sysl.Type_Sequence:
+		return "sequence of " + FormatType(x.Sequence)
+	case *sysl.Type_Set:
+		return "set of " + FormatType(x.Set)
+	case *sysl.Type_List_:
+		return "list of " + FormatType(x.List.Type)
+	case *sysl.Type_Map_:
+		return "map of " + FormatType(x.Map.Key) + ":" + FormatType(x.Map.Value)
+	case *sysl.Type_Tuple_:
+		return "tuple"
+	case *sysl.Type_Relation_:
+		return "relation"
+	case *sysl.Type_OneOf_:
+		return "union"
+	case *sysl.Type_Enum_:
+		return "enum"
+	}
+	return "none"
+}
+
 // TypeNone returns none-type
 func TypeNone() *sysl.Type {
 	return &sysl.Type{Type: &sysl.Type_NoType_{NoType: &sysl.Type_NoType{}}}
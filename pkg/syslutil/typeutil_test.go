@@ -251,3 +251,17 @@ func TestTypeBool(t *testing.T) {
 
 	assert.Equal(t, &sysl.Type{Type: &sysl.Type_Primitive_{Primitive: sysl.Type_BOOL}}, TypeBool())
 }
+
+func TestFormatType(t *testing.T) {
+	t.Parallel()
+
+	ref := &sysl.Type{Type: &sysl.Type_TypeRef{TypeRef: &sysl.ScopedRef{
+		Ref: &sysl.Scope{Appname: &sysl.AppName{Part: []string{"Bank"}}, Path: []string{"Account"}},
+	}}}
+	assert.Equal(t, "int", FormatType(TypeInt()))
+	assert.Equal(t, "none", FormatType(TypeNone()))
+	assert.Equal(t, "none", FormatType(nil))
+	assert.Equal(t, "Bank.Account", FormatType(ref))
+	assert.Equal(t, "sequence of Bank.Account", FormatType(&sysl.Type{Type: &sysl.Type_Sequence{Sequence: ref}}))
+	assert.Equal(t, "set of string", FormatType(&sysl.Type{Type: &sysl.Type_Set{Set: TypeString()}}))
+}